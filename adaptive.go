@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// AdaptiveRepetitions schedules extra repetitions of the cells whose two
+// fastest variants cannot be told apart, their 95% confidence intervals
+// overlapping, like the cells around the index vs table scan crossover. Every
+// time the scheduled runs are done, the cells are checked again and another
+// round of Batch repetitions of both variants is scheduled, until they are
+// significantly different or MaxExtra extra repetitions were run.
+type AdaptiveRepetitions struct {
+	MaxExtra int
+	Batch    int
+
+	schedule *Schedule
+	// index is the scenario index of each cell and variant, like "point_1K_10/Index"
+	index map[string]int
+	extra map[int]int
+	// Added is the number of extra executions scheduled, in Rounds rounds, and
+	// Unresolved the cells still not significant at the cap
+	Added      int
+	Rounds     int
+	Unresolved []string
+}
+
+// newAdaptiveRepetitions creates the adaptive repetitions of a schedule
+func newAdaptiveRepetitions(schedule *Schedule, maxExtra, batch int) *AdaptiveRepetitions {
+	a := &AdaptiveRepetitions{
+		MaxExtra: maxExtra,
+		Batch:    max(batch, 2),
+		schedule: schedule,
+		index:    make(map[string]int),
+		extra:    make(map[int]int),
+	}
+	for i, scenario := range schedule.Scenarios() {
+		if !scenario.ExplainOnly {
+			a.index[scenario.ID+"/"+scenario.Variant] = i
+		}
+	}
+	return a
+}
+
+// significantlyDifferent returns true if the mean latencies of two variants
+// differ by more than the 95% confidence interval of the difference, by
+// Welch's t-test, as the variants have different variances
+func significantlyDifferent(a, b []float64) bool {
+	if len(a) < 2 || len(b) < 2 {
+		return false
+	}
+	meanA, meanB := mean(a), mean(b)
+	varA, varB := stdDev(a)*stdDev(a)/float64(len(a)), stdDev(b)*stdDev(b)/float64(len(b))
+	if varA+varB == 0 {
+		return meanA != meanB
+	}
+	// The Welch-Satterthwaite degrees of freedom
+	df := (varA + varB) * (varA + varB) / (varA*varA/float64(len(a)-1) + varB*varB/float64(len(b)-1))
+	return math.Abs(meanA-meanB) > t95(df)*math.Sqrt(varA+varB)
+}
+
+// twoFastest returns the two variants with the lowest mean latency
+func twoFastest(variants map[string][]float64) (string, string) {
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		mi, mj := mean(variants[names[i]]), mean(variants[names[j]])
+		if mi != mj {
+			return mi < mj
+		}
+		return names[i] < names[j]
+	})
+	if len(names) < 2 {
+		return "", ""
+	}
+	return names[0], names[1]
+}
+
+// round checks the cells of the measured results so far, and schedules
+// another round of repetitions for the ones not significant yet
+func (a *AdaptiveRepetitions) round(results []*TestExecutionResult) {
+	cells := make(map[string]map[string][]float64)
+	for _, r := range results {
+		if r.ExplainOnly || r.Warmup || r.Plan == nil {
+			continue
+		}
+		if cells[r.ScenarioID] == nil {
+			cells[r.ScenarioID] = make(map[string][]float64)
+		}
+		cells[r.ScenarioID][r.Variant] = append(cells[r.ScenarioID][r.Variant], float64(r.Plan.ExecutionTime.Microseconds())/1000.0)
+	}
+	ids := make([]string, 0, len(cells))
+	for id := range cells {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	a.Unresolved = nil
+	added := 0
+	for _, id := range ids {
+		fastest, second := twoFastest(cells[id])
+		if second == "" || significantlyDifferent(cells[id][fastest], cells[id][second]) {
+			continue
+		}
+		scheduled := false
+		for _, variant := range []string{fastest, second} {
+			i, ok := a.index[id+"/"+variant]
+			if !ok {
+				continue
+			}
+			if n := min(a.Batch, a.MaxExtra-a.extra[i]); n > 0 {
+				a.schedule.Add(i, n)
+				a.extra[i] += n
+				added += n
+				scheduled = true
+			}
+		}
+		if !scheduled {
+			a.Unresolved = append(a.Unresolved, id)
+		}
+	}
+	if added > 0 {
+		a.Added += added
+		a.Rounds++
+		fmt.Printf("🔁 Scheduled %d extra executions of the cells whose fastest variants overlap\n", added)
+	}
+}
+
+// outputAdaptiveSummary prints the extra repetitions of the run, and the cells
+// still not significant at the cap
+func (a *AdaptiveRepetitions) outputAdaptiveSummary() {
+	fmt.Println("\n🔁 Adaptive Repetitions")
+	fmt.Println("====================")
+	fmt.Printf("Extra_executions\tRounds\tUnresolved_cells\n")
+	fmt.Printf("%d\t%d\t%d\n", a.Added, a.Rounds, len(a.Unresolved))
+	if len(a.Unresolved) > 0 {
+		fmt.Printf("The fastest variants of these cells still overlap after %d extra repetitions: %s\n",
+			a.MaxExtra, strings.Join(a.Unresolved, ", "))
+	}
+}
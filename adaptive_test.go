@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// runAdaptive iterates a schedule of one cell, with the latency of each
+// execution of a variant given by latency, and returns the executions per variant
+func runAdaptive(t *testing.T, maxExtra int, latency func(variant string, repetition int) float64) (map[string]int, *AdaptiveRepetitions) {
+	t.Helper()
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1})
+	schedule := NewSchedule(scenarios, 3)
+	adaptive := newAdaptiveRepetitions(schedule, maxExtra, 3)
+	var results []*TestExecutionResult
+	schedule.OnDrained(func() { adaptive.round(results) })
+	executions := make(map[string]int)
+	for run := range schedule.All() {
+		executions[run.Scenario.Variant]++
+		results = append(results, &TestExecutionResult{
+			ScenarioID:  run.Scenario.ID,
+			Variant:     run.Scenario.Variant,
+			ExplainOnly: run.Scenario.ExplainOnly,
+			Plan:        &ExecutionPlan{ExecutionTime: time.Duration(latency(run.Scenario.Variant, run.Repetition) * float64(time.Millisecond))},
+		})
+	}
+	return executions, adaptive
+}
+
+func TestAdaptiveRepetitions(t *testing.T) {
+	// Clearly different variants need no extra repetitions
+	executions, adaptive := runAdaptive(t, 10, func(variant string, repetition int) float64 {
+		if variant == "Index" {
+			return 1 + 0.1*float64(repetition%2)
+		}
+		return 5 + 0.1*float64(repetition%2)
+	})
+	if executions["Index"] != 3 || executions["TableScan"] != 3 || adaptive.Added != 0 {
+		t.Fatalf("expected no extra repetitions, got %v", executions)
+	}
+
+	// Noisy variants of the same latency never separate, up to the cap
+	executions, adaptive = runAdaptive(t, 7, func(variant string, repetition int) float64 {
+		return 2 + float64(repetition%3)
+	})
+	if executions["Index"] != 3+7 || executions["TableScan"] != 3+7 || executions["ExplainOnly"] != 1 {
+		t.Fatalf("expected 7 extra repetitions of both variants, got %v", executions)
+	}
+	if adaptive.Rounds != 3 || len(adaptive.Unresolved) != 1 {
+		t.Fatalf("expected 3 rounds and an unresolved cell, got %d rounds, %v", adaptive.Rounds, adaptive.Unresolved)
+	}
+
+	// Variants that separate with more samples stop getting repetitions
+	executions, adaptive = runAdaptive(t, 30, func(variant string, repetition int) float64 {
+		base := 2.0
+		if variant == "TableScan" {
+			base = 2.5
+		}
+		return base + float64(repetition%2)
+	})
+	if adaptive.Added == 0 || executions["Index"] >= 3+30 || len(adaptive.Unresolved) != 0 {
+		t.Fatalf("expected the cell to be resolved before the cap, got %v", executions)
+	}
+}
+
+func TestSignificantlyDifferent(t *testing.T) {
+	if significantlyDifferent([]float64{1}, []float64{5}) {
+		t.Fatal("a single sample is never significant")
+	}
+	if !significantlyDifferent([]float64{1, 1.1, 0.9}, []float64{5, 5.1, 4.9}) {
+		t.Fatal("expected 1ms vs 5ms to be significant")
+	}
+	if significantlyDifferent([]float64{1, 3, 2}, []float64{2, 1, 3}) {
+		t.Fatal("expected the same samples not to be significant")
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CellAnalysis compares the optimizer's choice with the measured plans of one
+// cell (scenario ID) of the test matrix
+type CellAnalysis struct {
+	ScenarioID string
+	RowCount   int
+	// Chosen is the plan type of the ExplainOnly (unhinted) query
+	Chosen string
+	// Best is the plan type with the lowest average latency
+	Best string
+	// BestRU is the plan type with the lowest average RU
+	BestRU  string
+	AvgTime map[string]time.Duration
+	AvgRU   map[string]float64
+	// EstCost is the average estimated cost of the plan of each plan type
+	EstCost map[string]float64
+	// Measured is false if the chosen plan type was not among the measured variants
+	Measured bool
+	// Frequency is the production rate of the query shape, 0 if not known, see applyQueryFrequencies
+	Frequency float64
+	// RUReported is set if the executions of the cell reported an RU, see ruReported
+	RUReported bool
+
+	// excluded are the patterns of the plan types not considered as the best plan
+	excluded []string
+}
+
+// Regret is the slowdown factor of the chosen plan compared to the best plan (1.0 is optimal)
+func (a *CellAnalysis) Regret() float64 {
+	if !a.Measured || a.AvgTime[a.Best] <= 0 {
+		return 1.0
+	}
+	return float64(a.AvgTime[a.Chosen]) / float64(a.AvgTime[a.Best])
+}
+
+// Impact is how much the plan choice matters in the cell: log of the slowest vs fastest plan latency
+func (a *CellAnalysis) Impact() float64 {
+	var fastest, slowest time.Duration
+	for _, t := range a.AvgTime {
+		if fastest == 0 || t < fastest {
+			fastest = t
+		}
+		slowest = max(slowest, t)
+	}
+	if fastest <= 0 {
+		return 0
+	}
+	return math.Log(float64(slowest) / float64(fastest))
+}
+
+// parsePlanTypePatterns parses a comma-separated list of plan types, or
+// patterns like tiflash_*
+func parsePlanTypePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid plan type pattern '%s': %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// planTypeExcluded returns true if the plan type matches one of the excluded
+// plan type patterns, the plan types not allowed in production, like
+// tiflash_*, never chosen as the best plan of a cell
+func planTypeExcluded(excluded []string, planType string) bool {
+	for _, p := range excluded {
+		if ok, _ := path.Match(p, planType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeCells compares the optimizer choice with the measurements, per
+// scenario ID. The excluded plan types are measured, but not considered as
+// the best plan, so cells without an allowed measured plan are left out.
+func analyzeCells(results []*TestExecutionResult, excluded []string) []*CellAnalysis {
+	chosen := make(map[string]string)
+	for _, r := range results {
+		if r.ExplainOnly && r.PredicateValue == 0 {
+			chosen[r.ScenarioID] = r.PlanType
+		}
+	}
+	// With -predicate-values the plan of the first value is the chosen one,
+	// the cells where the other values chose another plan are reported
+	var disagreeing []string
+	for _, r := range results {
+		if !r.ExplainOnly || r.PredicateValue == 0 {
+			continue
+		}
+		choice, ok := chosen[r.ScenarioID]
+		if !ok {
+			chosen[r.ScenarioID] = r.PlanType
+		} else if choice != r.PlanType && !slices.Contains(disagreeing, r.ScenarioID) {
+			disagreeing = append(disagreeing, r.ScenarioID)
+		}
+	}
+	if len(disagreeing) > 0 {
+		slog.Warn("The optimizer chose different plans for the predicate values of some cells, reporting the plan of the first value", "cells", disagreeing)
+	}
+	scenarioIDs, groups := groupByScenario(results)
+	cells := make([]*CellAnalysis, 0, len(scenarioIDs))
+	for _, scenarioID := range scenarioIDs {
+		choice, ok := chosen[scenarioID]
+		if !ok {
+			continue
+		}
+		cell := &CellAnalysis{
+			ScenarioID: scenarioID,
+			Chosen:     choice,
+			AvgTime:    make(map[string]time.Duration),
+			AvgRU:      make(map[string]float64),
+			EstCost:    make(map[string]float64),
+			excluded:   excluded,
+		}
+		group := groups[scenarioID]
+		for _, pt := range sortedPlanTypes(group) {
+			cell.RowCount = group[pt][0].RowCount
+			cell.RUReported = cell.RUReported || ruReported(group[pt])
+			var total time.Duration
+			var ru, cost float64
+			for _, r := range group[pt] {
+				total += r.Plan.ExecutionTime
+				ru += getRU(r.Plan)
+				cost += r.EstCost.Cost
+			}
+			n := len(group[pt])
+			cell.AvgTime[pt] = total / time.Duration(n)
+			cell.AvgRU[pt] = ru / float64(n)
+			cell.EstCost[pt] = cost / float64(n)
+			if planTypeExcluded(excluded, pt) {
+				continue
+			}
+			if cell.Best == "" || cell.AvgTime[pt] < cell.AvgTime[cell.Best] {
+				cell.Best = pt
+			}
+			if cell.BestRU == "" || cell.AvgRU[pt] < cell.AvgRU[cell.BestRU] {
+				cell.BestRU = pt
+			}
+		}
+		if cell.Best == "" {
+			continue
+		}
+		_, cell.Measured = group[choice]
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// TimeLost is the latency lost per execution by the chosen plan compared to the fastest plan
+func (a *CellAnalysis) TimeLost() time.Duration {
+	if !a.Measured {
+		return 0
+	}
+	return a.AvgTime[a.Chosen] - a.AvgTime[a.Best]
+}
+
+// RULost is the RU lost per execution by the chosen plan compared to the cheapest plan
+func (a *CellAnalysis) RULost() float64 {
+	if !a.Measured {
+		return 0
+	}
+	return a.AvgRU[a.Chosen] - a.AvgRU[a.BestRU]
+}
+
+// Misprediction summarizes how often the optimizer choice matched the best
+// measured plan, and what the mismatches cost, for a group of cells
+type Misprediction struct {
+	Cells      int
+	Unmeasured int
+	// MatchedTime and MatchedRU are the number of cells where the choice was the fastest and cheapest plan
+	MatchedTime int
+	MatchedRU   int
+	// TimeLost and RULost are summed over the cells, per execution of each cell
+	TimeLost time.Duration
+	RULost   float64
+}
+
+func (m *Misprediction) add(cell *CellAnalysis) {
+	if !cell.Measured {
+		m.Unmeasured++
+		return
+	}
+	m.Cells++
+	if cell.Chosen == cell.Best {
+		m.MatchedTime++
+	}
+	if cell.Chosen == cell.BestRU {
+		m.MatchedRU++
+	}
+	m.TimeLost += cell.TimeLost()
+	m.RULost += cell.RULost()
+}
+
+// mispredictionsByTableSize summarizes the mispredictions per table size, returning the sorted table sizes
+func mispredictionsByTableSize(cells []*CellAnalysis) ([]int, map[int]*Misprediction) {
+	bySize := make(map[int]*Misprediction)
+	var sizes []int
+	for _, cell := range cells {
+		if bySize[cell.RowCount] == nil {
+			bySize[cell.RowCount] = &Misprediction{}
+			sizes = append(sizes, cell.RowCount)
+		}
+		bySize[cell.RowCount].add(cell)
+	}
+	slices.Sort(sizes)
+	return sizes, bySize
+}
+
+// outputMispredictionSummary prints, per table size, how often the optimizer
+// chose the fastest and the cheapest (RU) plan and what the wrong choices cost
+func outputMispredictionSummary(cells []*CellAnalysis) {
+	fmt.Println("\n🔍 Optimizer Misprediction Summary")
+	fmt.Println("====================")
+	withRU := cellsReportRU(cells)
+	if withRU {
+		fmt.Printf("Table_size\tCells\tUnmeasured\tMatched_time\tMatched_RU\tTime_lost_ms\tRU_lost\n")
+	} else {
+		fmt.Printf("Table_size\tCells\tUnmeasured\tMatched_time\tTime_lost_ms\n")
+	}
+	sizes, bySize := mispredictionsByTableSize(cells)
+	total := &Misprediction{}
+	for _, cell := range cells {
+		total.add(cell)
+	}
+	print := func(name string, m *Misprediction) {
+		if !withRU {
+			fmt.Printf("%s\t%d\t%d\t%d/%d\t%.03f\n", name, m.Cells, m.Unmeasured,
+				m.MatchedTime, m.Cells, m.TimeLost.Seconds()*1000.0)
+			return
+		}
+		fmt.Printf("%s\t%d\t%d\t%d/%d\t%d/%d\t%.03f\t%.03f\n", name, m.Cells, m.Unmeasured,
+			m.MatchedTime, m.Cells, m.MatchedRU, m.Cells, m.TimeLost.Seconds()*1000.0, m.RULost)
+	}
+	for _, size := range sizes {
+		print(formatRowCountName(size), bySize[size])
+	}
+	print("Total", total)
+}
+
+// CalibrationScore is the headline number of how well the optimizer is
+// calibrated for the cluster, across the whole test matrix
+type CalibrationScore struct {
+	Cells      int `json:"cells"`
+	Unmeasured int `json:"unmeasured"`
+	// Agreement is the fraction of cells where the optimizer chose the fastest plan
+	Agreement float64 `json:"agreement"`
+	// WeightedAgreement weights each cell by the impact of the plan choice
+	WeightedAgreement float64 `json:"weighted_agreement"`
+	// GeoMeanRegret is the geometric mean of the chosen vs fastest plan latency
+	GeoMeanRegret float64 `json:"geomean_regret"`
+	// Score is 100 / GeoMeanRegret, 100 meaning the optimizer always chose the fastest plan
+	Score float64 `json:"score"`
+	// FrequencyCells are the cells with a production query frequency, and
+	// FrequencyAgreement and FrequencyGeoMeanRegret weight them by it
+	FrequencyCells         int     `json:"frequency_cells,omitempty"`
+	FrequencyAgreement     float64 `json:"frequency_agreement,omitempty"`
+	FrequencyGeoMeanRegret float64 `json:"frequency_geomean_regret,omitempty"`
+}
+
+// computeCalibrationScore computes the calibration score over all cells where the chosen plan was measured
+func computeCalibrationScore(cells []*CellAnalysis) CalibrationScore {
+	score := CalibrationScore{}
+	var agree, weight, weightedAgree, logRegret float64
+	var frequency, frequencyAgree, frequencyLogRegret float64
+	for _, cell := range cells {
+		if !cell.Measured {
+			score.Unmeasured++
+			continue
+		}
+		score.Cells++
+		impact := cell.Impact()
+		weight += impact
+		if cell.Chosen == cell.Best {
+			agree++
+			weightedAgree += impact
+		}
+		logRegret += math.Log(cell.Regret())
+		if cell.Frequency > 0 {
+			score.FrequencyCells++
+			frequency += cell.Frequency
+			if cell.Chosen == cell.Best {
+				frequencyAgree += cell.Frequency
+			}
+			frequencyLogRegret += cell.Frequency * math.Log(cell.Regret())
+		}
+	}
+	if frequency > 0 {
+		score.FrequencyAgreement = frequencyAgree / frequency
+		score.FrequencyGeoMeanRegret = math.Exp(frequencyLogRegret / frequency)
+	}
+	if score.Cells == 0 {
+		return score
+	}
+	score.Agreement = agree / float64(score.Cells)
+	score.WeightedAgreement = score.Agreement
+	if weight > 0 {
+		score.WeightedAgreement = weightedAgree / weight
+	}
+	score.GeoMeanRegret = math.Exp(logRegret / float64(score.Cells))
+	score.Score = 100.0 / score.GeoMeanRegret
+	return score
+}
+
+// outputCalibrationScore prints the calibration score of the run
+func outputCalibrationScore(score CalibrationScore) {
+	fmt.Println("\n🎯 Calibration Score")
+	fmt.Println("====================")
+	fmt.Printf("Cells\tUnmeasured\tAgreement\tWeighted_agreement\tGeomean_regret\tScore\n")
+	fmt.Printf("%d\t%d\t%.03f\t%.03f\t%.03f\t%.01f\n", score.Cells, score.Unmeasured,
+		score.Agreement, score.WeightedAgreement, score.GeoMeanRegret, score.Score)
+	if score.FrequencyCells > 0 {
+		fmt.Printf("\nWeighted by production query frequency, over %d cells\n", score.FrequencyCells)
+		fmt.Printf("Agreement\tGeomean_regret\tScore\n")
+		fmt.Printf("%.03f\t%.03f\t%.01f\n", score.FrequencyAgreement, score.FrequencyGeoMeanRegret,
+			100.0/score.FrequencyGeoMeanRegret)
+	}
+}
+
+// HistoryEntry is one run in the calibration history file, as used by the trend command
+type HistoryEntry struct {
+	Metadata *RunMetadata     `json:"metadata"`
+	Score    CalibrationScore `json:"score"`
+}
+
+// appendHistory appends the run to a JSON lines history file
+func appendHistory(path string, entry HistoryEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// readHistory reads all runs from a JSON lines history file
+func readHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// runTrend implements the trend command, showing the calibration score over the stored runs
+func runTrend(args []string) error {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	var history = fs.String("history", "calibration_history.jsonl", "Calibration history file")
+	var byEngine = fs.Bool("by-engine", false, "Show a separate trend per storage engine configuration")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	entries, err := readHistory(*history)
+	if err != nil {
+		return err
+	}
+	if !*byEngine {
+		outputTrend("📈 Calibration Score Trend", entries)
+		return nil
+	}
+	var engines []string
+	segments := make(map[string][]HistoryEntry)
+	for _, e := range entries {
+		engine := "unknown"
+		if e.Metadata != nil && e.Metadata.EngineConfig != "" {
+			engine = e.Metadata.EngineConfig
+		}
+		if _, ok := segments[engine]; !ok {
+			engines = append(engines, engine)
+		}
+		segments[engine] = append(segments[engine], e)
+	}
+	for _, engine := range engines {
+		outputTrend("📈 Calibration Score Trend - storage engine: "+engine, segments[engine])
+	}
+	return nil
+}
+
+// outputTrend prints the calibration score of each run, with the change from the previous run
+func outputTrend(title string, entries []HistoryEntry) {
+	fmt.Println("\n" + title)
+	fmt.Println("====================")
+	fmt.Printf("Run\tStarted\tLabel\tServer_version\tCells\tAgreement\tWeighted_agreement\tGeomean_regret\tScore\tDelta\n")
+	for i, e := range entries {
+		m := e.Metadata
+		if m == nil {
+			m = &RunMetadata{}
+		}
+		delta := ""
+		if i > 0 {
+			delta = fmt.Sprintf("%+.01f", e.Score.Score-entries[i-1].Score.Score)
+			// A score change on other data is not a change of the optimizer
+			if prev := entries[i-1].Metadata; prev != nil {
+				if data, _ := dataDifferences(prev, m); len(data) > 0 {
+					delta = "data changed"
+				}
+			}
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%d\t%.03f\t%.03f\t%.03f\t%.01f\t%s\n", m.RunID, m.StartTime.Format(time.RFC3339),
+			m.Label, m.ServerVersion, e.Score.Cells, e.Score.Agreement, e.Score.WeightedAgreement,
+			e.Score.GeoMeanRegret, e.Score.Score, delta)
+	}
+}
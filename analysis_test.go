@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestResult creates an executed result with the given latency
+func newTestResult(scenarioID, planType string, ms float64) *TestExecutionResult {
+	return &TestExecutionResult{
+		ScenarioID: scenarioID,
+		PlanType:   planType,
+		Plan:       &ExecutionPlan{ExecutionTime: time.Duration(ms * float64(time.Millisecond))},
+	}
+}
+
+// newChoiceResult creates an ExplainOnly result with the optimizer's choice
+func newChoiceResult(scenarioID, planType string) *TestExecutionResult {
+	return &TestExecutionResult{ScenarioID: scenarioID, PlanType: planType, ExplainOnly: true}
+}
+
+func TestCalibrationScore(t *testing.T) {
+	results := []*TestExecutionResult{
+		// Optimal choice
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 1),
+		newTestResult("index_1K_10", "index_lookup", 3),
+		newTestResult("index_1K_10", "table_scan", 4),
+		// Chosen plan is 4x slower
+		newChoiceResult("index_1M_500000", "index_lookup"),
+		newTestResult("index_1M_500000", "index_lookup", 400),
+		newTestResult("index_1M_500000", "table_scan", 100),
+		// Chosen plan not measured
+		newChoiceResult("index_1M_10", "unknown"),
+		newTestResult("index_1M_10", "index_lookup", 1),
+	}
+	score := computeCalibrationScore(analyzeCells(results, nil))
+	if score.Cells != 2 || score.Unmeasured != 1 {
+		t.Fatalf("unexpected cell counts: %+v", score)
+	}
+	if score.Agreement != 0.5 {
+		t.Fatalf("expected agreement 0.5, got %f", score.Agreement)
+	}
+	// Both cells have a 2x vs 4x gap, so the impact weighted agreement is log(2)/(log(2)+log(4))
+	if math.Abs(score.WeightedAgreement-1.0/3.0) > 1e-9 {
+		t.Fatalf("expected weighted agreement 1/3, got %f", score.WeightedAgreement)
+	}
+	if math.Abs(score.GeoMeanRegret-2.0) > 1e-9 || math.Abs(score.Score-50.0) > 1e-9 {
+		t.Fatalf("expected regret 2 and score 50, got %f and %f", score.GeoMeanRegret, score.Score)
+	}
+}
+
+func TestMispredictionsByTableSize(t *testing.T) {
+	results := []*TestExecutionResult{
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 1),
+		newTestResult("index_1K_10", "table_scan", 4),
+		newChoiceResult("index_1M_500000", "index_lookup"),
+		newTestResult("index_1M_500000", "index_lookup", 400),
+		newTestResult("index_1M_500000", "table_scan", 100),
+	}
+	for _, r := range results {
+		r.RowCount = 1000
+		if strings.HasPrefix(r.ScenarioID, "index_1M") {
+			r.RowCount = 1000000
+		}
+	}
+	sizes, bySize := mispredictionsByTableSize(analyzeCells(results, nil))
+	if len(sizes) != 2 || sizes[0] != 1000 || sizes[1] != 1000000 {
+		t.Fatalf("unexpected table sizes: %v", sizes)
+	}
+	if m := bySize[1000]; m.Cells != 1 || m.MatchedTime != 1 || m.TimeLost != 0 {
+		t.Fatalf("unexpected 1K summary: %+v", m)
+	}
+	if m := bySize[1000000]; m.Cells != 1 || m.MatchedTime != 0 || m.TimeLost != 300*time.Millisecond {
+		t.Fatalf("unexpected 1M summary: %+v", m)
+	}
+}
+
+func TestExcludedPlanTypes(t *testing.T) {
+	results := []*TestExecutionResult{
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 2),
+		newTestResult("index_1K_10", "table_scan", 4),
+		newTestResult("index_1K_10", "tiflash_table_scan", 1),
+		newChoiceResult("merge_1K_10", "index_merge"),
+		newTestResult("merge_1K_10", "index_merge", 1),
+	}
+	patterns, err := parsePlanTypePatterns("tiflash_*, index_merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := analyzeCells(results, patterns)
+	if len(cells) != 1 {
+		t.Fatalf("expected only the cell with an allowed plan, got %d cells", len(cells))
+	}
+	if cells[0].Best != "index_lookup" || cells[0].Regret() != 1.0 {
+		t.Fatalf("expected index_lookup as the best allowed plan, got %s with regret %f", cells[0].Best, cells[0].Regret())
+	}
+	if _, err = parsePlanTypePatterns("index_["); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
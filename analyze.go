@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnalyzeSpec is how the tables are analyzed before the run, set by -analyze
+type AnalyzeSpec struct {
+	// Skip drops the statistics instead, so the run measures the plans chosen on pseudo statistics
+	Skip bool
+	// SampleRate is the WITH n SAMPLERATE of the analyze, 1 for a full analyze, 0 for the server default
+	SampleRate float64
+	// AllColumns collects the statistics of all columns, not only of the indexes and predicate columns
+	AllColumns bool
+}
+
+// parseAnalyzeSpec parses a comma-separated list of analyze options: skip,
+// full, samplerate=<rate> and all-columns
+func parseAnalyzeSpec(s string) (*AnalyzeSpec, error) {
+	spec := &AnalyzeSpec{}
+	for _, option := range strings.Split(s, ",") {
+		option = strings.TrimSpace(option)
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "skip":
+			spec.Skip = true
+		case "full":
+			spec.SampleRate = 1
+		case "samplerate":
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid analyze sample rate '%s', expected a rate in (0, 1]", value)
+			}
+			spec.SampleRate = rate
+		case "all-columns":
+			spec.AllColumns = true
+		default:
+			return nil, fmt.Errorf("unknown analyze option '%s', expected skip, full, samplerate=<rate> or all-columns", option)
+		}
+	}
+	if spec.Skip && (spec.SampleRate > 0 || spec.AllColumns) {
+		return nil, fmt.Errorf("the analyze option skip cannot be combined with other options")
+	}
+	return spec, nil
+}
+
+// String returns the options of the spec, like "samplerate=0.1,all-columns"
+func (a *AnalyzeSpec) String() string {
+	if a.Skip {
+		return "skip"
+	}
+	var options []string
+	switch {
+	case a.SampleRate == 1:
+		options = append(options, "full")
+	case a.SampleRate > 0:
+		options = append(options, "samplerate="+strconv.FormatFloat(a.SampleRate, 'g', -1, 64))
+	}
+	if a.AllColumns {
+		options = append(options, "all-columns")
+	}
+	if len(options) == 0 {
+		return "default"
+	}
+	return strings.Join(options, ",")
+}
+
+// statement returns the statement applying the spec to a table
+func (a *AnalyzeSpec) statement(tableName string) string {
+	if a.Skip {
+		return "DROP STATS " + tableName
+	}
+	stmt := "ANALYZE TABLE " + tableName
+	if a.AllColumns {
+		stmt += " ALL COLUMNS"
+	}
+	if a.SampleRate > 0 {
+		stmt += " WITH " + strconv.FormatFloat(a.SampleRate, 'g', -1, 64) + " SAMPLERATE"
+	}
+	return stmt
+}
+
+// AnalyzeTables re-analyzes the tables, or drops their statistics, as given
+// by the spec, whether they were just set up or reused, so the statistics the
+// run measures are fresh and collected the same way. The statistics are then
+// locked, so auto-analyze cannot update or bring them back during the run,
+// until UnlockTableStats. The time the statistics were updated is printed per
+// table and recorded in the run timeline.
+func AnalyzeTables(tables []string, spec *AnalyzeSpec, config *TiDBConfig, meta *RunMetadata) error {
+	c := NewTiDBClient()
+	if err := c.Connect(config); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, tableName := range tables {
+		// Locked by an interrupted run, the analyze would be skipped
+		c.unlockStats(tableName)
+		stmt := spec.statement(tableName)
+		start := time.Now()
+		if _, err := c.ExecuteQuery(stmt); err != nil {
+			return fmt.Errorf("failed to %s: %w", stmt, err)
+		}
+		took := time.Since(start)
+		if err := c.ExecuteStatement("LOCK STATS " + tableName); err != nil {
+			slog.Warn("Failed to lock the statistics, auto-analyze may update them during the run", "table", tableName, "error", err)
+		}
+		state, err := c.GetTableStatsState(tableName)
+		if err != nil {
+			return err
+		}
+		updated := state.MetaUpdated
+		if spec.Skip {
+			updated = "none, pseudo statistics"
+		}
+		fmt.Printf("📊 %s: %s, took %s, statistics updated %s\n", tableName, stmt, took.Round(time.Millisecond), updated)
+		meta.AddEvent("analyze", took, stmt)
+	}
+	return nil
+}
+
+// UnlockTableStats unlocks the statistics of the tables locked by
+// AnalyzeTables, so auto-analyze and the analyze of the next setup apply again
+func UnlockTableStats(tables []string, config *TiDBConfig) error {
+	c := NewTiDBClient()
+	if err := c.Connect(config); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, tableName := range tables {
+		c.unlockStats(tableName)
+	}
+	return nil
+}
+
+// unlockStats unlocks the statistics of a table. Unlocking a table that is
+// not locked is only a warning, and servers without LOCK STATS cannot have
+// locked it, so the error is only logged.
+func (c *TiDBClient) unlockStats(tableName string) {
+	if err := c.ExecuteStatement("UNLOCK STATS " + tableName); err != nil {
+		slog.Debug("Failed to unlock the statistics", "table", tableName, "error", err)
+	}
+}
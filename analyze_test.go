@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestParseAnalyzeSpec(t *testing.T) {
+	tests := []struct {
+		spec, want, stmt string
+	}{
+		{"full", "full", "ANALYZE TABLE t1K WITH 1 SAMPLERATE"},
+		{"samplerate=0.05,all-columns", "samplerate=0.05,all-columns", "ANALYZE TABLE t1K ALL COLUMNS WITH 0.05 SAMPLERATE"},
+		{"all-columns", "all-columns", "ANALYZE TABLE t1K ALL COLUMNS"},
+		{"skip", "skip", "DROP STATS t1K"},
+	}
+	for _, tt := range tests {
+		spec, err := parseAnalyzeSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		if got := spec.String(); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.spec, tt.want, got)
+		}
+		if got := spec.statement("t1K"); got != tt.stmt {
+			t.Errorf("%s: expected %s, got %s", tt.spec, tt.stmt, got)
+		}
+	}
+	for _, invalid := range []string{"samplerate=0", "samplerate=2", "sampled", "skip,full"} {
+		if _, err := parseAnalyzeSpec(invalid); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
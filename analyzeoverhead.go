@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ExplainAnalyzeTime executes the query plainly and then under EXPLAIN
+// ANALYZE, and returns both latencies. It is called after the measured
+// execution, so both run warm, and the overhead is not mixed up with the cache
+// misses of a cold measured execution.
+func (c *TiDBClient) ExplainAnalyzeTime(query string) (plain, analyze time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.db == nil {
+		return 0, 0, fmt.Errorf("database connection not established")
+	}
+	if plain, err = c.timeQuery(query); err != nil {
+		return 0, 0, err
+	}
+	if analyze, err = c.timeQuery("EXPLAIN ANALYZE " + query); err != nil {
+		return 0, 0, fmt.Errorf("failed to explain analyze: %w", err)
+	}
+	return plain, analyze, nil
+}
+
+// timeQuery executes a query and returns its latency until all rows are read,
+// measured like the measured executions
+func (c *TiDBClient) timeQuery(query string) (time.Duration, error) {
+	slog.Debug("Executing query", "query", query)
+	c.limiter.Wait()
+	if c.simulatedRTT > 0 {
+		time.Sleep(c.simulatedRTT)
+	}
+	startTime := time.Now()
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	var raw sql.RawBytes
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	dest := make([]any, len(columns))
+	for i := range dest {
+		dest[i] = &raw
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return 0, err
+	}
+	return time.Since(startTime), nil
+}
+
+// AnalyzeOverhead is the EXPLAIN ANALYZE instrumentation overhead of a plan type
+type AnalyzeOverhead struct {
+	PlanType string
+	Samples  int
+	// Plain and Analyze are the average warm latencies of the sampled
+	// executions, executed plainly and under EXPLAIN ANALYZE after the measured one
+	Plain   time.Duration
+	Analyze time.Duration
+}
+
+// Overhead returns the relative latency increase under EXPLAIN ANALYZE
+func (o AnalyzeOverhead) Overhead() float64 {
+	if o.Plain <= 0 {
+		return 0
+	}
+	return float64(o.Analyze-o.Plain) / float64(o.Plain)
+}
+
+// analyzeOverheads returns the EXPLAIN ANALYZE overhead per plan type, of the
+// executions that were also sampled under EXPLAIN ANALYZE
+func analyzeOverheads(results []*TestExecutionResult) []AnalyzeOverhead {
+	byPlanType := make(map[string]*AnalyzeOverhead)
+	for _, r := range results {
+		if r.ExplainOnly || r.AnalyzeTime <= 0 {
+			continue
+		}
+		o, ok := byPlanType[r.PlanType]
+		if !ok {
+			o = &AnalyzeOverhead{PlanType: r.PlanType}
+			byPlanType[r.PlanType] = o
+		}
+		o.Samples++
+		o.Plain += r.AnalyzePlainTime
+		o.Analyze += r.AnalyzeTime
+	}
+	overheads := make([]AnalyzeOverhead, 0, len(byPlanType))
+	for _, o := range byPlanType {
+		o.Plain /= time.Duration(o.Samples)
+		o.Analyze /= time.Duration(o.Samples)
+		overheads = append(overheads, *o)
+	}
+	sort.Slice(overheads, func(i, j int) bool { return overheads[i].PlanType < overheads[j].PlanType })
+	return overheads
+}
+
+// outputAnalyzeOverhead reports how much EXPLAIN ANALYZE slows down the
+// sampled executions per plan type, the distortion of the ANALYZE based metrics
+func outputAnalyzeOverhead(results []*TestExecutionResult) {
+	fmt.Println("\n🔬 EXPLAIN ANALYZE Overhead (warm)")
+	fmt.Println("====================")
+	fmt.Println("The samples are executed plainly and under EXPLAIN ANALYZE right after the measured execution, both warm.")
+	overheads := analyzeOverheads(results)
+	if len(overheads) == 0 {
+		fmt.Println("No executions were sampled under EXPLAIN ANALYZE")
+		return
+	}
+	fmt.Printf("Plan\tSamples\tms-plain\tms-analyze\tOverhead\n")
+	for _, o := range overheads {
+		fmt.Printf("%s\t%d\t%.03f\t%.03f\t%+.01f%%\n", o.PlanType, o.Samples, o.Plain.Seconds()*1000.0,
+			o.Analyze.Seconds()*1000.0, o.Overhead()*100)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeOverheads(t *testing.T) {
+	results := []*TestExecutionResult{
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 2),
+		newTestResult("index_1K_10", "index_lookup", 4),
+		newTestResult("index_1K_10", "index_lookup", 100),
+		newTestResult("index_1K_10", "table_scan", 10),
+	}
+	// The 100ms execution was not sampled, the samples are compared with
+	// their warm plain executions, not the measured ones
+	results[1].AnalyzePlainTime, results[1].AnalyzeTime = 2*time.Millisecond, 3*time.Millisecond
+	results[2].AnalyzePlainTime, results[2].AnalyzeTime = 4*time.Millisecond, 5*time.Millisecond
+	results[4].AnalyzePlainTime, results[4].AnalyzeTime = 10*time.Millisecond, 15*time.Millisecond
+	results[4].Plan.ExecutionTime = 40 * time.Millisecond
+	overheads := analyzeOverheads(results)
+	if len(overheads) != 2 {
+		t.Fatalf("expected 2 plan types, got %+v", overheads)
+	}
+	lookup, scan := overheads[0], overheads[1]
+	if lookup.PlanType != "index_lookup" || lookup.Samples != 2 || lookup.Plain != 3*time.Millisecond || lookup.Analyze != 4*time.Millisecond {
+		t.Fatalf("unexpected index lookup overhead %+v", lookup)
+	}
+	if math.Abs(lookup.Overhead()-1.0/3) > 1e-9 || scan.Overhead() != 0.5 {
+		t.Fatalf("unexpected overheads %f and %f", lookup.Overhead(), scan.Overhead())
+	}
+}
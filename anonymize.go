@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// testTableColumns are the column (and index) names of the test tables
+var testTableColumns = []string{"id", "b", "c", "d", "bc", "uu", "un"}
+
+// anonymizedName returns a stable hash based replacement for an identifier,
+// so anonymized findings from different runs can still be correlated
+func anonymizedName(prefix, name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return prefix + hex.EncodeToString(sum[:4])
+}
+
+// anonymizer replaces table and column names, and the database names
+// qualifying the tables, in the text of exported results
+type anonymizer struct {
+	names map[string]string
+	re    *regexp.Regexp
+}
+
+// newAnonymizer returns an anonymizer for the given table and column names
+func newAnonymizer(tables, columns []string) *anonymizer {
+	a := &anonymizer{names: make(map[string]string)}
+	quote := func(names []string, prefix string) string {
+		// Longest first, so t1K_join is not replaced as t1K
+		names = slices.Clone(names)
+		slices.SortFunc(names, func(x, y string) int { return len(y) - len(x) })
+		quoted := make([]string, 0, len(names))
+		for _, name := range names {
+			a.names[name] = anonymizedName(prefix, name)
+			quoted = append(quoted, regexp.QuoteMeta(name))
+		}
+		return strings.Join(quoted, "|")
+	}
+	a.re = regexp.MustCompile(fmt.Sprintf(`\b(?:(\w+)\.)?(%s)\b|\b(%s)\b`, quote(tables, "t_"), quote(columns, "col_")))
+	return a
+}
+
+// newResultsAnonymizer returns an anonymizer for the test tables of the
+// results, and the custom tables and columns recorded in the run metadata
+func newResultsAnonymizer(results []*TestExecutionResult, meta *RunMetadata) *anonymizer {
+	var tables []string
+	for _, r := range results {
+		if r.TableName != "" && !slices.Contains(tables, r.TableName) {
+			tables = append(tables, r.TableName, r.TableName+"_join", r.TableName+"_imerge", r.TableName+"_composite", r.TableName+"_unique")
+		}
+	}
+	columns := testTableColumns
+	if meta != nil {
+		for _, table := range meta.CustomTables {
+			if !slices.Contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+		for _, column := range meta.CustomColumns {
+			if !slices.Contains(columns, column) {
+				columns = append(slices.Clip(columns), column)
+			}
+		}
+	}
+	return newAnonymizer(tables, columns)
+}
+
+// text returns s with the known names replaced
+func (a *anonymizer) text(s string) string {
+	return a.re.ReplaceAllStringFunc(s, func(match string) string {
+		m := a.re.FindStringSubmatch(match)
+		if m[3] != "" {
+			return a.names[m[3]]
+		}
+		if m[1] != "" {
+			return anonymizedName("db_", m[1]) + "." + a.names[m[2]]
+		}
+		return a.names[m[2]]
+	})
+}
+
+// plan returns an anonymized copy of the plan
+func (a *anonymizer) plan(p *ExecutionPlan) *ExecutionPlan {
+	if p == nil {
+		return nil
+	}
+	anonymized := *p
+	anonymized.AccessObject = a.text(p.AccessObject)
+	anonymized.OperatorInfo = a.text(p.OperatorInfo)
+	anonymized.ExecutionInfo = a.text(p.ExecutionInfo)
+	anonymized.Next = a.plan(p.Next)
+	return &anonymized
+}
+
+// results returns anonymized copies of the results
+func (a *anonymizer) results(results []*TestExecutionResult) []*TestExecutionResult {
+	anonymized := make([]*TestExecutionResult, 0, len(results))
+	for _, r := range results {
+		c := *r
+		c.TableName = a.text(r.TableName)
+		c.ScenarioName = a.text(r.ScenarioName)
+		c.Query = a.text(r.Query)
+		c.Plan = a.plan(r.Plan)
+		anonymized = append(anonymized, &c)
+	}
+	return anonymized
+}
+
+// metadata returns an anonymized copy of the run metadata, without the store addresses
+func (a *anonymizer) metadata(meta *RunMetadata) *RunMetadata {
+	if meta == nil {
+		return nil
+	}
+	anonymized := *meta
+	anonymized.Stores = make([]StoreInfo, 0, len(meta.Stores))
+	for i, store := range meta.Stores {
+		store.Instance = fmt.Sprintf("store-%d", i+1)
+		anonymized.Stores = append(anonymized.Stores, store)
+	}
+	anonymized.StatsChanges = make([]string, 0, len(meta.StatsChanges))
+	for _, change := range meta.StatsChanges {
+		anonymized.StatsChanges = append(anonymized.StatsChanges, a.text(change))
+	}
+	anonymized.CustomTables = make([]string, 0, len(meta.CustomTables))
+	for _, table := range meta.CustomTables {
+		anonymized.CustomTables = append(anonymized.CustomTables, a.text(table))
+	}
+	anonymized.CustomColumns = make([]string, 0, len(meta.CustomColumns))
+	for _, column := range meta.CustomColumns {
+		anonymized.CustomColumns = append(anonymized.CustomColumns, a.text(column))
+	}
+	anonymized.Timeline = make([]TimelineEvent, 0, len(meta.Timeline))
+	for _, event := range meta.Timeline {
+		event.Detail = a.text(event.Detail)
+		anonymized.Timeline = append(anonymized.Timeline, event)
+	}
+	return &anonymized
+}
+
+var backquotedIdentifierRegex = regexp.MustCompile("`([^`]+)`")
+
+// anonymizeDDL replaces every quoted identifier of a SHOW CREATE TABLE statement
+func anonymizeDDL(ddl string, table string) string {
+	return backquotedIdentifierRegex.ReplaceAllStringFunc(ddl, func(quoted string) string {
+		name := strings.Trim(quoted, "`")
+		if name == table {
+			return "`" + anonymizedName("t_", name) + "`"
+		}
+		return "`" + anonymizedName("col_", name) + "`"
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymizer(t *testing.T) {
+	results := []*TestExecutionResult{{
+		TableName: "t1K",
+		Query:     "SELECT t.id, t.b, j.c FROM t1K t JOIN t1K_join j ON j.id = t.id WHERE t.b = 100",
+		Plan: &ExecutionPlan{ID: "IndexJoin_12", Next: &ExecutionPlan{
+			ID:           "IndexRangeScan_8",
+			AccessObject: "table:t1K, index:b(b)",
+			OperatorInfo: "eq(test.t1K.b, 100), keep order:false",
+		}},
+	}}
+	a := newResultsAnonymizer(results, nil)
+	anonymized := a.results(results)
+	if results[0].TableName != "t1K" || results[0].Plan.Next.AccessObject != "table:t1K, index:b(b)" {
+		t.Fatalf("the original results were modified: %+v", results[0])
+	}
+	table, join, b := anonymizedName("t_", "t1K"), anonymizedName("t_", "t1K_join"), anonymizedName("col_", "b")
+	r := anonymized[0]
+	if r.TableName != table {
+		t.Fatalf("expected table name %s, got %s", table, r.TableName)
+	}
+	expected := "SELECT t." + anonymizedName("col_", "id") + ", t." + b + ", j." + anonymizedName("col_", "c") + " FROM " + table + " t JOIN " + join + " j"
+	if !strings.HasPrefix(r.Query, expected) {
+		t.Fatalf("expected query starting with %s, got %s", expected, r.Query)
+	}
+	if got := r.Plan.Next.AccessObject; got != "table:"+table+", index:"+b+"("+b+")" {
+		t.Fatalf("unexpected access object %s", got)
+	}
+	if got := r.Plan.Next.OperatorInfo; got != "eq("+anonymizedName("db_", "test")+"."+table+"."+b+", 100), keep order:false" {
+		t.Fatalf("unexpected operator info %s", got)
+	}
+
+	meta := a.metadata(&RunMetadata{Stores: []StoreInfo{{Instance: "tikv-0.example.com:20160", Type: "tikv"}}})
+	if meta.Stores[0].Instance != "store-1" {
+		t.Fatalf("expected the store address to be stripped, got %s", meta.Stores[0].Instance)
+	}
+
+	// The custom tables and columns of -scenarios are in the run metadata
+	custom := &RunMetadata{CustomTables: []string{"orders_1K", "customers_1K"}, CustomColumns: []string{"id", "customer"}}
+	results = []*TestExecutionResult{{TableName: "orders_1K", Query: "SELECT COUNT(*) FROM orders_1K o JOIN customers_1K cu ON o.customer = cu.id"}}
+	a = newResultsAnonymizer(results, custom)
+	expected = "SELECT COUNT(*) FROM " + anonymizedName("t_", "orders_1K") + " o JOIN " + anonymizedName("t_", "customers_1K") +
+		" cu ON o." + anonymizedName("col_", "customer") + " = cu." + anonymizedName("col_", "id")
+	if got := a.results(results)[0].Query; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if meta = a.metadata(custom); meta.CustomColumns[1] != anonymizedName("col_", "customer") {
+		t.Fatalf("expected the custom columns to be anonymized, got %v", meta.CustomColumns)
+	}
+}
+
+func TestAnonymizeDDL(t *testing.T) {
+	ddl := "CREATE TABLE `orders` (\n  `id` int NOT NULL,\n  KEY `idx_customer` (`customer`)\n)"
+	got := anonymizeDDL(ddl, "orders")
+	for _, name := range []string{"orders", "id", "idx_customer", "customer"} {
+		if strings.Contains(got, "`"+name+"`") {
+			t.Fatalf("%s not anonymized in %s", name, got)
+		}
+	}
+	if !strings.Contains(got, "`"+anonymizedName("t_", "orders")+"`") {
+		t.Fatalf("expected the hashed table name in %s", got)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// staleValue is the b value the experiment moves rows to, never present
+	// after the setup (which replaces b <= 0), so the statistics know nothing about it
+	staleValue = -1
+	// autoAnalyzeMinRows is the smallest table TiDB auto analyzes
+	autoAnalyzeMinRows = 1000
+	// statsPollInterval is how often the statistics are checked while waiting
+	statsPollInterval = 5 * time.Second
+)
+
+// AutoAnalyzeStep is the optimizer state and plan behavior after modifying a fraction of the table
+type AutoAnalyzeStep struct {
+	ModifiedRatio float64
+	ModifyCount   int64
+	EstRows       float64
+	ActualRows    int
+	Plan          string
+	PlanTime      time.Duration
+	IndexTime     time.Duration
+	ScanTime      time.Duration
+	Analyzed      bool
+}
+
+// runAutoAnalyze implements the auto-analyze command: it modifies a growing
+// fraction of a test table, and observes at which modification ratio auto
+// analyze is triggered and how the plan choice behaves with the stale statistics
+func runAutoAnalyze(args []string) error {
+	fs := flag.NewFlagSet("auto-analyze", flag.ExitOnError)
+	var logLevel = fs.String("l", "info", "Log level: debug, info, warn, error")
+	var rowCount = fs.String("s", "100K", "Table size of the experiment table")
+	var fillerSize = fs.Int("f", 100, "Filler column size")
+	var step = fs.Float64("step", 0.05, "Fraction of the table modified per step")
+	var maxRatio = fs.Float64("max-ratio", 1.0, "Stop after modifying this fraction of the table, if auto analyze did not trigger")
+	var wait = fs.Duration("wait", 2*time.Minute, "How long to wait per step for the modifications to be reflected in the statistics")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupLogging(*logLevel)
+	config, err := connectionConfig()
+	if err != nil {
+		return err
+	}
+	rows, err := parseRowCounts(*rowCount)
+	if err != nil || len(rows) != 1 {
+		return fmt.Errorf("invalid table size '%s': expected a single size", *rowCount)
+	}
+	if *step <= 0 || *step > 1 {
+		return fmt.Errorf("invalid -step %v: expected a fraction between 0 and 1", *step)
+	}
+	if rows[0] < autoAnalyzeMinRows {
+		fmt.Printf("⚠️  Tables smaller than %d rows are not auto analyzed\n", autoAnalyzeMinRows)
+	}
+
+	// The experiment modifies the table, so it always starts from a fresh one
+	table := TableSpec{RowCount: rows[0], Suffix: "autoanalyze"}
+	if err = DropTables([]TableSpec{table}, nil, config); err != nil {
+		return err
+	}
+	err = CheckAndSetupTables(rows, []float64{1}, SetupOptions{FillerSize: *fillerSize, TableSuffix: table.Suffix, TiDB: config})
+	if err != nil {
+		return err
+	}
+
+	c := NewTiDBClient()
+	if err = c.Connect(config); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	settings, err := c.autoAnalyzeSettings()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n🧪 Auto Analyze Experiment")
+	fmt.Println("====================")
+	for _, name := range autoAnalyzeVariables {
+		fmt.Printf("%s:\t%s\n", name, settings[name])
+	}
+
+	start := time.Now()
+	var steps []*AutoAnalyzeStep
+	modified := 0
+	for ratio := *step; ratio <= *maxRatio+1e-9; ratio += *step {
+		target := int(float64(table.RowCount) * ratio)
+		if err = c.moveRowsToStaleValue(table.Name(), target-modified); err != nil {
+			return err
+		}
+		modified = target
+		s, err := c.observeAutoAnalyzeStep(table.Name(), modified, start, *wait)
+		if err != nil {
+			return err
+		}
+		s.ModifiedRatio = ratio
+		steps = append(steps, s)
+		fmt.Printf("Modified %.0f%%: modify count %d, plan %s, est rows %.0f vs %d actual, analyzed %t\n",
+			ratio*100, s.ModifyCount, s.Plan, s.EstRows, s.ActualRows, s.Analyzed)
+		if s.Analyzed {
+			break
+		}
+	}
+	outputAutoAnalyzeReport(steps, settings)
+	return nil
+}
+
+// autoAnalyzeVariables are the system variables that control auto analyze
+var autoAnalyzeVariables = []string{
+	"tidb_enable_auto_analyze",
+	"tidb_auto_analyze_ratio",
+	"tidb_auto_analyze_start_time",
+	"tidb_auto_analyze_end_time",
+}
+
+// autoAnalyzeSettings returns the global auto analyze settings
+func (c *TiDBClient) autoAnalyzeSettings() (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, name := range autoAnalyzeVariables {
+		var value string
+		query := "SELECT @@GLOBAL." + name
+		slog.Debug("Executing query", "query", query)
+		if err := c.db.QueryRow(query).Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", name, err)
+		}
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// moveRowsToStaleValue sets b to the stale value for n more rows
+func (c *TiDBClient) moveRowsToStaleValue(tableName string, n int) error {
+	batchSize := 50000
+	for n > 0 {
+		limit := min(n, batchSize)
+		err := c.ExecuteStatement(fmt.Sprintf("UPDATE %s SET b = %d WHERE b <> %d ORDER BY id LIMIT %d",
+			tableName, staleValue, staleValue, limit))
+		if err != nil {
+			return fmt.Errorf("failed to modify rows: %w", err)
+		}
+		n -= limit
+	}
+	return nil
+}
+
+// autoAnalyzed returns true if an auto analyze of the table finished since the given time
+func (c *TiDBClient) autoAnalyzed(tableName string, since time.Time) (bool, error) {
+	jobs, err := queryNamedRows(c.dbPlan, fmt.Sprintf("SHOW ANALYZE STATUS WHERE Table_schema = DATABASE() AND Table_name = '%s'", tableName))
+	if err != nil {
+		return false, fmt.Errorf("failed to get analyze status: %w", err)
+	}
+	for _, job := range jobs {
+		if !strings.HasPrefix(job["Job_info"], "auto analyze") || job["State"] != "finished" {
+			continue
+		}
+		started, err := time.ParseInLocation(time.DateTime, job["Start_time"], time.Local)
+		if err == nil && started.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// observeAutoAnalyzeStep waits until the modifications are reflected in the
+// statistics or auto analyze ran, and then measures the plan choice
+func (c *TiDBClient) observeAutoAnalyzeStep(tableName string, modified int, start time.Time, wait time.Duration) (*AutoAnalyzeStep, error) {
+	s := &AutoAnalyzeStep{ActualRows: modified}
+	deadline := time.Now().Add(wait)
+	for {
+		state, err := c.GetTableStatsState(tableName)
+		if err != nil {
+			return nil, err
+		}
+		s.ModifyCount, _ = strconv.ParseInt(state.ModifyCount, 10, 64)
+		if s.Analyzed, err = c.autoAnalyzed(tableName, start); err != nil {
+			return nil, err
+		}
+		if s.Analyzed || s.ModifyCount >= int64(modified) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(statsPollInterval)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE b = %d", tableName, staleValue)
+	plan, err := c.ExecuteQueryGetPlan(query)
+	if err != nil {
+		return nil, err
+	}
+	s.Plan = determinePlanType(plan)
+	s.EstRows = plan.EstRows
+	s.PlanTime = plan.ExecutionTime
+	plan, err = c.ExecuteQueryGetPlan(fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE b = %d", tableName, tableName, staleValue))
+	if err != nil {
+		return nil, err
+	}
+	s.IndexTime = plan.ExecutionTime
+	plan, err = c.ExecuteQueryGetPlan(fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE b = %d", tableName, tableName, staleValue))
+	if err != nil {
+		return nil, err
+	}
+	s.ScanTime = plan.ExecutionTime
+	return s, nil
+}
+
+// misestimate returns the factor between the estimated and actual rows (1.0 is exact)
+func (s *AutoAnalyzeStep) misestimate() float64 {
+	est, actual := max(s.EstRows, 1), max(float64(s.ActualRows), 1)
+	return max(est/actual, actual/est)
+}
+
+// staleRegret returns the slowdown of the chosen plan compared to the faster forced plan
+func (s *AutoAnalyzeStep) staleRegret() float64 {
+	best := min(s.IndexTime, s.ScanTime)
+	if best <= 0 {
+		return 1
+	}
+	return max(float64(s.PlanTime)/float64(best), 1)
+}
+
+// outputAutoAnalyzeReport prints the steps of the experiment and guidance for tidb_auto_analyze_ratio
+func outputAutoAnalyzeReport(steps []*AutoAnalyzeStep, settings map[string]string) {
+	fmt.Println("\n📈 Auto Analyze Experiment Results")
+	fmt.Println("====================")
+	fmt.Printf("Modified\tModify_count\tEst_rows\tActual_rows\tMisestimate\tPlan\tPlan_ms\tIndex_ms\tScan_ms\tAnalyzed\n")
+	for _, s := range steps {
+		fmt.Printf("%.03f\t%d\t%.0f\t%d\t%.01fx\t%s\t%.03f\t%.03f\t%.03f\t%t\n", s.ModifiedRatio, s.ModifyCount,
+			s.EstRows, s.ActualRows, s.misestimate(), s.Plan, s.PlanTime.Seconds()*1000.0,
+			s.IndexTime.Seconds()*1000.0, s.ScanTime.Seconds()*1000.0, s.Analyzed)
+	}
+	fmt.Println()
+	if len(steps) == 0 {
+		return
+	}
+	last := steps[len(steps)-1]
+	if last.Analyzed {
+		fmt.Printf("Auto analyze triggered after modifying %.0f%% of the table (tidb_auto_analyze_ratio %s)\n",
+			last.ModifiedRatio*100, settings["tidb_auto_analyze_ratio"])
+	} else {
+		fmt.Printf("Auto analyze did not trigger up to %.0f%% modified rows (tidb_enable_auto_analyze %s, tidb_auto_analyze_ratio %s, window %s-%s)\n",
+			last.ModifiedRatio*100, settings["tidb_enable_auto_analyze"], settings["tidb_auto_analyze_ratio"],
+			settings["tidb_auto_analyze_start_time"], settings["tidb_auto_analyze_end_time"])
+	}
+	// The stale window is harmful from the first step where the stale statistics made the plan choice slower
+	for _, s := range steps {
+		if !s.Analyzed && s.staleRegret() > 1.2 {
+			fmt.Printf("Plan choice degraded (%.01fx slower than the best plan, %.01fx misestimate) from %.0f%% modified rows,\n",
+				s.staleRegret(), s.misestimate(), s.ModifiedRatio*100)
+			fmt.Printf("consider tidb_auto_analyze_ratio <= %.03f for tables with this kind of churn\n", s.ModifiedRatio)
+			return
+		}
+	}
+	fmt.Println("Plan choice did not degrade in the stale window, the current tidb_auto_analyze_ratio is adequate for this churn")
+}
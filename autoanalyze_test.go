@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoAnalyzeStep(t *testing.T) {
+	s := &AutoAnalyzeStep{EstRows: 10, ActualRows: 1000, PlanTime: 30 * time.Millisecond, IndexTime: 30 * time.Millisecond, ScanTime: 10 * time.Millisecond}
+	if got := s.misestimate(); got != 100 {
+		t.Fatalf("expected misestimate 100, got %v", got)
+	}
+	if got := s.staleRegret(); got != 3 {
+		t.Fatalf("expected stale regret 3, got %v", got)
+	}
+	s = &AutoAnalyzeStep{EstRows: 0, ActualRows: 0}
+	if got := s.misestimate(); got != 1 {
+		t.Fatalf("expected misestimate 1 for empty results, got %v", got)
+	}
+	if got := s.staleRegret(); got != 1 {
+		t.Fatalf("expected no regret without timings, got %v", got)
+	}
+}
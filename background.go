@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	compactionFlowQuery = `sum(rate(tikv_engine_compaction_flow_bytes[1m]))`
+	gcKeysQuery         = `sum(rate(tikv_gcworker_gc_keys[1m]))`
+	// backgroundCheckInterval limits how often Prometheus is queried
+	backgroundCheckInterval = 5 * time.Second
+	// maxBackgroundPause is the longest time to wait for background work to calm down
+	maxBackgroundPause = 10 * time.Minute
+)
+
+// BackgroundMonitor detects heavy TiKV background work (GC and compaction)
+// through the Prometheus metrics of the cluster
+type BackgroundMonitor struct {
+	PrometheusURL string
+	// Thresholds above which the background work is considered heavy
+	CompactionBytesPerSec float64
+	GCKeysPerSec          float64
+	// Pause execution while the background work is heavy
+	Pause bool
+
+	client    *http.Client
+	lastCheck time.Time
+	lastBusy  string
+}
+
+// NewBackgroundMonitor creates a monitor querying the given Prometheus server
+func NewBackgroundMonitor(prometheusURL string, compactionMBPerSec, gcKeysPerSec float64, pause bool) *BackgroundMonitor {
+	return &BackgroundMonitor{
+		PrometheusURL:         prometheusURL,
+		CompactionBytesPerSec: compactionMBPerSec * 1024 * 1024,
+		GCKeysPerSec:          gcKeysPerSec,
+		Pause:                 pause,
+		client:                &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// queryPrometheus evaluates an instant query returning a single scalar value
+func (m *BackgroundMonitor) queryPrometheus(query string) (float64, error) {
+	u := m.PrometheusURL + "/api/v1/query?query=" + url.QueryEscape(query)
+	resp, err := m.client.Get(u)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Value []any `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode prometheus response: %w", err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed with status %s", body.Status)
+	}
+	if len(body.Data.Result) == 0 || len(body.Data.Result[0].Value) != 2 {
+		// No samples, so no activity
+		return 0, nil
+	}
+	s, ok := body.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected prometheus value %v", body.Data.Result[0].Value[1])
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Busy returns a description of the heavy background work, or "" if there is none
+func (m *BackgroundMonitor) Busy() (string, error) {
+	if time.Since(m.lastCheck) < backgroundCheckInterval {
+		return m.lastBusy, nil
+	}
+	m.lastCheck = time.Now()
+	m.lastBusy = ""
+	compaction, err := m.queryPrometheus(compactionFlowQuery)
+	if err != nil {
+		return "", err
+	}
+	gcKeys, err := m.queryPrometheus(gcKeysQuery)
+	if err != nil {
+		return "", err
+	}
+	slog.Debug("Background activity", "compaction_bytes_per_sec", compaction, "gc_keys_per_sec", gcKeys)
+	switch {
+	case compaction > m.CompactionBytesPerSec:
+		m.lastBusy = fmt.Sprintf("compaction %.1f MB/s", compaction/1024/1024)
+	case gcKeys > m.GCKeysPerSec:
+		m.lastBusy = fmt.Sprintf("GC %.0f keys/s", gcKeys)
+	}
+	return m.lastBusy, nil
+}
+
+// WaitForQuiet checks for heavy background work and, if pausing is enabled,
+// waits until it has calmed down. Pauses and detected activity are recorded
+// in the run timeline. Returns true if the next sample will run during heavy
+// background work.
+func (m *BackgroundMonitor) WaitForQuiet(meta *RunMetadata) bool {
+	busy, err := m.Busy()
+	if err != nil {
+		slog.Warn("Failed to check background activity", "error", err)
+		return false
+	}
+	if busy == "" {
+		return false
+	}
+	if !m.Pause {
+		meta.AddEvent("background_work", 0, busy)
+		return true
+	}
+	start := time.Now()
+	fmt.Printf("⏸️  Pausing, heavy background work: %s\n", busy)
+	for busy != "" && time.Since(start) < maxBackgroundPause {
+		time.Sleep(backgroundCheckInterval)
+		if busy, err = m.Busy(); err != nil {
+			slog.Warn("Failed to check background activity", "error", err)
+			break
+		}
+	}
+	meta.AddEvent("pause", time.Since(start), "background work")
+	if busy != "" {
+		slog.Warn("Background work still heavy after maximum pause, continuing", "activity", busy)
+		return true
+	}
+	return false
+}
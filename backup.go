@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// runBackup implements the backup command, backing up generated test tables
+// with BR (the BACKUP statement), once per table size, so they can be restored
+// on other clusters instead of generating the largest sizes again
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	var logLevel = fs.String("l", "info", "Log level: debug, info, warn, error")
+	var rowCounts = fs.String("s", "", "Comma-separated list of table sizes to back up (e.g. 100M,1G)")
+	var storage = fs.String("storage", "", "External storage URL shared by the TiKV stores, e.g. 's3://bucket/calibration?region=us-west-2', one backup per table size is written below it")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupLogging(*logLevel)
+	c, _, tables, err := connectBackupStorage(connectionConfig, *rowCounts, *storage)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, table := range tables {
+		names, err := c.backupTableNames(table)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("table %s does not exist, generate it first", table.Name())
+		}
+		fmt.Printf("💾 Backing up %s to %s\n", strings.Join(names, ", "), backupURL(*storage, table))
+		stmt := fmt.Sprintf("BACKUP TABLE %s TO '%s'", strings.Join(names, ", "), backupURL(*storage, table))
+		result, err := queryNamedRows(c.db, stmt)
+		if err != nil {
+			return fmt.Errorf("failed to back up %s: %w", table.Name(), err)
+		}
+		for _, r := range result {
+			fmt.Printf("✅ Backed up %s: %s bytes, backup TS %s, took %s\n", table.Name(), r["Size"], r["BackupTS"], r["Execution Time"])
+		}
+	}
+	return nil
+}
+
+// runRestore implements the restore command, restoring test tables backed up
+// by the backup command, to skip the data generation of a run
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var logLevel = fs.String("l", "info", "Log level: debug, info, warn, error")
+	var rowCounts = fs.String("s", "", "Comma-separated list of table sizes to restore (e.g. 100M,1G)")
+	var storage = fs.String("storage", "", "External storage URL the tables were backed up to")
+	var replace = fs.Bool("replace", false, "Drop existing tables of the restored sizes first")
+	var analyze = fs.Bool("analyze", true, "Analyze the restored tables, in case the backup did not include the statistics")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupLogging(*logLevel)
+	c, config, tables, err := connectBackupStorage(connectionConfig, *rowCounts, *storage)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if *replace {
+		if err = DropTables(tables, nil, config); err != nil {
+			return err
+		}
+	}
+	database, err := c.currentDatabase()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		fmt.Printf("📥 Restoring %s from %s\n", table.Name(), backupURL(*storage, table))
+		// Only the tables of the target database are restored, a backup made
+		// from another database restores nothing
+		stmt := fmt.Sprintf("RESTORE DATABASE `%s` FROM '%s'", database, backupURL(*storage, table))
+		result, err := queryNamedRows(c.db, stmt)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s (use -replace if the tables exist): %w", table.Name(), err)
+		}
+		for _, r := range result {
+			fmt.Printf("✅ Restored %s: %s bytes, took %s\n", table.Name(), r["Size"], r["Execution Time"])
+		}
+		if exists, err := c.tableExists(table.Name()); err != nil {
+			return err
+		} else if !exists {
+			return fmt.Errorf("the backup of %s has no table %s in database %s, it was backed up from another database", table.Name(), table.Name(), database)
+		}
+		if !*analyze {
+			continue
+		}
+		names, err := c.backupTableNames(table)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, err = c.ExecuteQuery("ANALYZE TABLE " + name); err != nil {
+				return fmt.Errorf("failed to analyze table %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// connectBackupStorage validates the common backup and restore flags and connects
+func connectBackupStorage(connectionConfig func() (*TiDBConfig, error), rowCounts, storage string) (*TiDBClient, *TiDBConfig, []TableSpec, error) {
+	config, err := connectionConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if rowCounts == "" || storage == "" {
+		return nil, nil, nil, fmt.Errorf("-s and -storage are required")
+	}
+	rows, err := parseRowCounts(rowCounts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c := NewTiDBClient()
+	if err = c.Connect(config); err != nil {
+		return nil, nil, nil, err
+	}
+	return c, config, tableSpecs(rows, "", MatrixOptions{}), nil
+}
+
+// backupURL returns the storage URL of the backup of a table size, a
+// directory named after the table below the storage URL, keeping its options
+func backupURL(storage string, table TableSpec) string {
+	path, options, _ := strings.Cut(storage, "?")
+	url := strings.TrimSuffix(path, "/") + "/" + table.Name()
+	if options != "" {
+		url += "?" + options
+	}
+	return url
+}
+
+// currentDatabase returns the database of the connection, the -database of the run
+func (c *TiDBClient) currentDatabase() (string, error) {
+	var database string
+	slog.Debug("Executing query", "query", "SELECT DATABASE()")
+	if err := c.db.QueryRow("SELECT DATABASE()").Scan(&database); err != nil {
+		return "", fmt.Errorf("failed to get the current database: %w", err)
+	}
+	return database, nil
+}
+
+// backupTableNames returns the existing test table and partner tables of a table size,
+// qualified with the current database
+func (c *TiDBClient) backupTableNames(table TableSpec) ([]string, error) {
+	database, err := c.currentDatabase()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range []string{table.Name(), table.JoinName(), table.IndexMergeName(), table.CompositeName(), table.UniqueName()} {
+		exists, err := c.tableExists(name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			names = append(names, database+"."+name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestBackupURL(t *testing.T) {
+	table := TableSpec{RowCount: 1000000000}
+	for storage, expected := range map[string]string{
+		"s3://bucket/calibration?region=us-west-2": "s3://bucket/calibration/t1000M?region=us-west-2",
+		"local:///mnt/backup/":                     "local:///mnt/backup/t1000M",
+	} {
+		if got := backupURL(storage, table); got != expected {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	}
+}
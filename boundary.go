@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// histogramBucket is a bucket of the histogram of b, with inclusive bounds
+type histogramBucket struct {
+	Lower, Upper int
+}
+
+// boundaryPredicate is a predicate on b at, just inside or just outside the
+// edges of a histogram bucket, with the number of rows it matches
+type boundaryPredicate struct {
+	ID        string
+	Name      string
+	Predicate string
+	Matching  int
+}
+
+// defaultBoundaryBuckets is the default number of histogram buckets the boundary family targets per table
+const defaultBoundaryBuckets = 3
+
+// parseBucketBound parses a bucket bound of SHOW STATS_BUCKETS, index bounds are shown like (42)
+func parseBucketBound(s string) (int, error) {
+	return strconv.Atoi(strings.Trim(strings.TrimSpace(s), "()"))
+}
+
+// histogramBuckets returns the buckets of the histogram of b,
+// of the column or, if there is no column histogram, of the index
+func (c *TiDBClient) histogramBuckets(tableName string) ([]histogramBucket, error) {
+	rows, err := queryNamedRows(c.dbPlan, fmt.Sprintf("SHOW STATS_BUCKETS WHERE Db_name = DATABASE() AND Table_name = '%s' AND Column_name = 'b'", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the histogram buckets of %s: %w", tableName, err)
+	}
+	byKind := make(map[string][]histogramBucket)
+	for _, row := range rows {
+		lower, err := parseBucketBound(row["Lower_Bound"])
+		if err != nil {
+			continue
+		}
+		upper, err := parseBucketBound(row["Upper_Bound"])
+		if err != nil {
+			continue
+		}
+		byKind[row["Is_index"]] = append(byKind[row["Is_index"]], histogramBucket{Lower: lower, Upper: upper})
+	}
+	buckets := byKind["0"]
+	if len(buckets) == 0 {
+		buckets = byKind["1"]
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Lower < buckets[j].Lower })
+	return buckets, nil
+}
+
+// selectBuckets returns n buckets evenly spread over the histogram
+func selectBuckets(buckets []histogramBucket, n int) []int {
+	n = min(n, len(buckets))
+	if n <= 0 {
+		return nil
+	}
+	var selected []int
+	for k := range n {
+		selected = append(selected, k*len(buckets)/n+len(buckets)/(2*n))
+	}
+	return selected
+}
+
+// bucketPredicates returns the predicates of a bucket: equality at the upper
+// bound, just inside and just outside it, and the range of the bucket, of the
+// bucket without its bounds and of the bucket and the values next to it
+func bucketPredicates(bucket histogramBucket, index int) []boundaryPredicate {
+	l, u := bucket.Lower, bucket.Upper
+	preds := []boundaryPredicate{
+		{ID: fmt.Sprintf("bucketeqat%d", index), Name: fmt.Sprintf("b at the upper bound of bucket %d", index),
+			Predicate: fmt.Sprintf("b = %d", u)},
+	}
+	if u-1 >= l {
+		preds = append(preds, boundaryPredicate{ID: fmt.Sprintf("bucketeqinside%d", index),
+			Name: fmt.Sprintf("b just inside the upper bound of bucket %d", index), Predicate: fmt.Sprintf("b = %d", u-1)})
+	}
+	preds = append(preds,
+		boundaryPredicate{ID: fmt.Sprintf("bucketeqoutside%d", index),
+			Name: fmt.Sprintf("b just outside the upper bound of bucket %d", index), Predicate: fmt.Sprintf("b = %d", u+1)},
+		boundaryPredicate{ID: fmt.Sprintf("bucketrangeat%d", index),
+			Name: fmt.Sprintf("b over bucket %d", index), Predicate: fmt.Sprintf("b BETWEEN %d AND %d", l, u)})
+	if l+1 <= u-1 {
+		preds = append(preds, boundaryPredicate{ID: fmt.Sprintf("bucketrangeinside%d", index),
+			Name: fmt.Sprintf("b just inside the bounds of bucket %d", index), Predicate: fmt.Sprintf("b BETWEEN %d AND %d", l+1, u-1)})
+	}
+	return append(preds, boundaryPredicate{ID: fmt.Sprintf("bucketrangeoutside%d", index),
+		Name: fmt.Sprintf("b just outside the bounds of bucket %d", index), Predicate: fmt.Sprintf("b BETWEEN %d AND %d", l-1, u+1)})
+}
+
+// setupBoundaryPredicates reads the histograms of the analyzed test tables,
+// and counts the rows matched by the predicates at the edges of bucketCount
+// of their buckets, returning the predicates per table
+func setupBoundaryPredicates(tables []TableSpec, bucketCount int, config *TiDBConfig) (map[string][]boundaryPredicate, error) {
+	c := NewTiDBClient()
+	if err := c.Connect(config); err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	boundaryPredicates := make(map[string][]boundaryPredicate)
+	for _, table := range tables {
+		tableName := table.Name()
+		buckets, err := c.histogramBuckets(tableName)
+		if err != nil {
+			return nil, err
+		}
+		if len(buckets) == 0 {
+			fmt.Printf("⚠️  No histogram of b on %s, skipping the boundary family\n", tableName)
+			continue
+		}
+		var preds []boundaryPredicate
+		for _, i := range selectBuckets(buckets, bucketCount) {
+			for _, p := range bucketPredicates(buckets[i], i) {
+				query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", tableName, p.Predicate)
+				if err = c.db.QueryRow(query).Scan(&p.Matching); err != nil {
+					return nil, fmt.Errorf("failed to count the rows of %s: %w", query, err)
+				}
+				preds = append(preds, p)
+			}
+		}
+		boundaryPredicates[tableName] = preds
+	}
+	return boundaryPredicates, nil
+}
+
+// boundaryScenarios generates the cells of the predicates at the histogram
+// bucket edges of the table, index lookup vs table scan, where the estimation
+// errors cluster, missed by the uniform grid of the selectivities
+func boundaryScenarios(table TableSpec, _ float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	var scenarios []TestScenario
+	for _, p := range opts.BoundaryPredicates[tableName] {
+		base := TestScenario{
+			ID:           fmt.Sprintf("%s_%s_%d", p.ID, tableSizeName, p.Matching),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: p.Matching,
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("%s - %s rows, %d matching", p.Name, tableSizeName, p.Matching)
+		explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, p.Predicate)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("Index lookup, %s - %s rows, %d matching", p.Name, tableSizeName, p.Matching)
+		index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, p.Predicate)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("Table Scan, %s - %s rows, %d matching", p.Name, tableSizeName, p.Matching)
+		scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, p.Predicate)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
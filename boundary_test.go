@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBucketPredicates(t *testing.T) {
+	if v, err := parseBucketBound(" (42)"); err != nil || v != 42 {
+		t.Fatalf("unexpected bound %d: %v", v, err)
+	}
+	buckets := make([]histogramBucket, 100)
+	for i := range buckets {
+		buckets[i] = histogramBucket{Lower: i * 10, Upper: i*10 + 9}
+	}
+	if got := selectBuckets(buckets, 3); !slices.Equal(got, []int{16, 49, 82}) {
+		t.Fatalf("unexpected selected buckets %v", got)
+	}
+	if got := selectBuckets(buckets[:2], 3); !slices.Equal(got, []int{0, 1}) {
+		t.Fatalf("unexpected selected buckets %v", got)
+	}
+	preds := bucketPredicates(buckets[16], 16)
+	var got []string
+	for _, p := range preds {
+		got = append(got, p.ID+": "+p.Predicate)
+	}
+	expected := []string{"bucketeqat16: b = 169", "bucketeqinside16: b = 168", "bucketeqoutside16: b = 170",
+		"bucketrangeat16: b BETWEEN 160 AND 169", "bucketrangeinside16: b BETWEEN 161 AND 168", "bucketrangeoutside16: b BETWEEN 159 AND 170"}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("unexpected predicates %v", got)
+	}
+	// A bucket of a single value has no inside
+	if preds = bucketPredicates(histogramBucket{Lower: 5, Upper: 5}, 0); len(preds) != 4 {
+		t.Fatalf("expected 4 predicates, got %+v", preds)
+	}
+}
+
+func TestBoundaryFamily(t *testing.T) {
+	opts := MatrixOptions{BoundaryPredicates: map[string][]boundaryPredicate{
+		"t1K": {{ID: "bucketeqat16", Name: "b at the upper bound of bucket 16", Predicate: "b = 169", Matching: 3}},
+	}}
+	scenarios := GetTestScenarios(tableSpecs([]int{1000}, "", opts), []float64{10, 100}, opts, "boundary")
+	// The boundary cells do not depend on the selectivity
+	if len(scenarios) != 3 {
+		t.Fatalf("expected 3 scenarios, got %d", len(scenarios))
+	}
+	if scenarios[1].ID != "bucketeqat16_1K_3" || scenarios[1].Query != "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 169" {
+		t.Fatalf("unexpected scenario %+v", scenarios[1])
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// checkpointEntry is a line of the checkpoint file: the run metadata, written
+// once the table snapshots are taken, then one completed execution per line
+type checkpointEntry struct {
+	Metadata *RunMetadata         `json:"metadata,omitempty"`
+	Result   *TestExecutionResult `json:"result,omitempty"`
+}
+
+// Checkpoint persists the completed executions of a run as they finish, so
+// an interrupted run can be resumed with -resume instead of starting over
+type Checkpoint struct {
+	path string
+	file *os.File
+	enc  *json.Encoder
+	// resumed are the executions of the interrupted run, by checkpointKey
+	resumed map[string]*TestExecutionResult
+	// resumedMeta is the metadata of the interrupted run, nil for a new checkpoint
+	resumedMeta *RunMetadata
+	// appending is set when resuming, the metadata is then already written
+	appending bool
+}
+
+// checkpointKey identifies an execution of the schedule
+func checkpointKey(scenarioID, variant string, repetition int, warmup bool) string {
+	return fmt.Sprintf("%s/%s/%d/%t", scenarioID, variant, repetition, warmup)
+}
+
+// openCheckpoint opens the checkpoint file of a run. With resume the
+// executions of an existing file are read back, if its run is compatible,
+// and appended to, otherwise the file is started over. The data of the run is
+// only checked, and the metadata of a new checkpoint only written, by Start.
+func openCheckpoint(path string, meta *RunMetadata, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{path: path, resumed: make(map[string]*TestExecutionResult)}
+	if resume {
+		err := c.read(meta)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("⚠️  No checkpoint %s to resume, starting the run from the beginning\n", path)
+			resume = false
+		case err != nil:
+			return nil, err
+		case len(c.resumed) == 0:
+			resume = false
+		}
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_RDWR | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c.file, c.enc, c.appending = f, json.NewEncoder(f), resume
+	if resume {
+		// End a line cut short by the interruption, so the next entry starts on its own line
+		last := make([]byte, 1)
+		if info, err := f.Stat(); err == nil && info.Size() > 0 {
+			if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				if _, err := f.WriteString("\n"); err != nil {
+					f.Close()
+					return nil, err
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// Start checks that the interrupted run measured the same data, from the
+// table snapshots of the metadata, taken at the start of the run, or writes
+// the metadata of a new checkpoint
+func (c *Checkpoint) Start(meta *RunMetadata) error {
+	if c == nil {
+		return nil
+	}
+	if !c.appending {
+		return c.enc.Encode(checkpointEntry{Metadata: meta})
+	}
+	if c.resumedMeta != nil {
+		if data, _ := dataDifferences(meta, c.resumedMeta); len(data) > 0 {
+			return fmt.Errorf("cannot resume from checkpoint %s, the tables changed: %s", c.path, strings.Join(data, ", "))
+		}
+	}
+	return nil
+}
+
+// read reads the executions of an interrupted run, failing if its metadata
+// is not compatible with the run resuming it
+func (c *Checkpoint) read(meta *RunMetadata) error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line may be cut short by the interruption
+			fmt.Printf("⚠️  Skipping line %d of checkpoint %s: %v\n", line, c.path, err)
+			continue
+		}
+		switch {
+		case entry.Metadata != nil:
+			if err := meta.compatibleWith(entry.Metadata); err != nil {
+				return fmt.Errorf("cannot resume from checkpoint %s: %w", c.path, err)
+			}
+			c.resumedMeta = entry.Metadata
+		case entry.Result != nil:
+			r := entry.Result
+			c.resumed[checkpointKey(r.ScenarioID, r.Variant, r.Repetition, r.Warmup)] = r
+		}
+	}
+	return scanner.Err()
+}
+
+// Done returns true if the execution was completed by the interrupted run
+func (c *Checkpoint) Done(run ScheduledRun) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.resumed[checkpointKey(run.Scenario.ID, run.Scenario.Variant, run.Repetition, run.Warmup)]
+	return ok
+}
+
+// Resumed returns the executions of the interrupted run of the scenarios of
+// the schedule
+func (c *Checkpoint) Resumed(schedule *Schedule) []*TestExecutionResult {
+	if c == nil {
+		return nil
+	}
+	scheduled := make(map[string]bool)
+	for _, scenario := range schedule.Scenarios() {
+		scheduled[scenario.ID+"/"+scenario.Variant] = true
+	}
+	var results []*TestExecutionResult
+	for _, r := range c.resumed {
+		if scheduled[r.ScenarioID+"/"+r.Variant] {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// Record appends a completed execution to the checkpoint file
+func (c *Checkpoint) Record(result *TestExecutionResult) error {
+	if c == nil {
+		return nil
+	}
+	return c.enc.Encode(checkpointEntry{Result: result})
+}
+
+// Close closes the checkpoint file
+func (c *Checkpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.file.Close()
+}
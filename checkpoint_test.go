@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	snapshots := []TableSnapshot{{Table: "t1K", TSO: 1, Checksum: "123", KVs: 2000}}
+	meta := &RunMetadata{RunID: "first", ServerVersion: "v8.5.0", TableSnapshots: snapshots}
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1})
+	schedule := NewSchedule(scenarios, 2)
+
+	c, err := openCheckpoint(path, meta, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Start(meta); err != nil {
+		t.Fatal(err)
+	}
+	var done, pending ScheduledRun
+	for run := range schedule.All() {
+		if run.Scenario.ExplainOnly {
+			continue
+		}
+		if done.Scenario == nil {
+			done = run
+		} else if pending.Scenario == nil {
+			pending = run
+		}
+	}
+	if err = c.Record(&TestExecutionResult{ScenarioID: done.Scenario.ID, Variant: done.Scenario.Variant, Repetition: done.Repetition}); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	// An interruption may leave a partial line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"result": {"scenario_id": "ind`)
+	f.Close()
+
+	second := &RunMetadata{RunID: "second", ServerVersion: "v8.5.0"}
+	resumed, err := openCheckpoint(path, second, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tables are snapshot at the start of the resumed run, at a later TSO
+	second.TableSnapshots = []TableSnapshot{{Table: "t1K", TSO: 2, Checksum: "123", KVs: 2000}}
+	if err = resumed.Start(second); err != nil {
+		t.Fatal(err)
+	}
+	if err = resumed.Record(&TestExecutionResult{ScenarioID: pending.Scenario.ID, Variant: pending.Scenario.Variant, Repetition: pending.Repetition}); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Close()
+	if resumed, err = openCheckpoint(path, &RunMetadata{RunID: "second", ServerVersion: "v8.5.0"}, true); err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if !resumed.Done(done) || !resumed.Done(pending) {
+		t.Fatal("expected the executions of both runs to be done")
+	}
+	if got := resumed.Resumed(schedule); len(got) != 2 {
+		t.Fatalf("unexpected resumed executions %v", got)
+	}
+	if _, err = openCheckpoint(path, &RunMetadata{RunID: "third", ServerVersion: "v9.0.0"}, true); err == nil {
+		t.Fatal("expected an error resuming on another server version")
+	}
+	changed := &RunMetadata{RunID: "fourth", ServerVersion: "v8.5.0"}
+	other, err := openCheckpoint(path, changed, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	changed.TableSnapshots = []TableSnapshot{{Table: "t1K", TSO: 3, Checksum: "456", KVs: 2000}}
+	if err = other.Start(changed); err == nil {
+		t.Fatal("expected an error resuming on other data")
+	}
+	var none *Checkpoint
+	if none.Done(done) || none.Record(&TestExecutionResult{}) != nil || none.Start(meta) != nil {
+		t.Fatal("expected no checkpoint to do nothing")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// clientRTTPings is the number of SELECT 1 round trips measuring the client RTT
+const clientRTTPings = 20
+
+// ClientInfo describes the host running the tool. Client side costs, like
+// TLS and the driver parsing the result sets, are part of the latency of
+// short queries, so stored runs are only comparable from similar clients.
+type ClientInfo struct {
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	CPUModel   string `json:"cpu_model,omitempty"`
+	NumCPU     int    `json:"num_cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	GoVersion  string `json:"go_version"`
+	// Driver is the version of the MySQL driver
+	Driver string `json:"driver,omitempty"`
+	// TLS is the tls mode of the connections, empty if disabled
+	TLS string `json:"tls,omitempty"`
+	// RTT is the median SELECT 1 round trip time to the server
+	RTT time.Duration `json:"rtt,omitempty"`
+}
+
+// newClientInfo returns the description of this host, without the RTT
+func newClientInfo() *ClientInfo {
+	info := &ClientInfo{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		GoVersion:  runtime.Version(),
+	}
+	if cpuinfo, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		info.CPUModel = parseCPUModel(string(cpuinfo))
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			if dep.Path == "github.com/go-sql-driver/mysql" {
+				info.Driver = dep.Version
+			}
+		}
+	}
+	return info
+}
+
+// parseCPUModel returns the CPU model of /proc/cpuinfo, the "model name" of
+// x86 or the "Model" of ARM
+func parseCPUModel(cpuinfo string) string {
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "model name", "Model":
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// String summarizes the client, like "linux/amd64, Intel(R) Xeon(R) 8 CPUs, GOMAXPROCS 8, go1.24.1, TLS"
+func (ci *ClientInfo) String() string {
+	parts := []string{ci.OS + "/" + ci.Arch}
+	cpus := fmt.Sprintf("%d CPUs", ci.NumCPU)
+	if ci.CPUModel != "" {
+		cpus = ci.CPUModel + " " + cpus
+	}
+	parts = append(parts, cpus, fmt.Sprintf("GOMAXPROCS %d", ci.GOMAXPROCS), ci.GoVersion)
+	if ci.Driver != "" {
+		parts = append(parts, "driver "+ci.Driver)
+	}
+	if ci.TLS != "" {
+		parts = append(parts, "TLS "+ci.TLS)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// clientDifferences returns the differences of two clients that affect the
+// client side part of the latencies
+func clientDifferences(a, b *ClientInfo) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	var diffs []string
+	if a.CPUModel != b.CPUModel || a.NumCPU != b.NumCPU || a.GOMAXPROCS != b.GOMAXPROCS {
+		diffs = append(diffs, fmt.Sprintf("client CPU %s (%d CPUs, GOMAXPROCS %d) vs %s (%d CPUs, GOMAXPROCS %d)",
+			a.CPUModel, a.NumCPU, a.GOMAXPROCS, b.CPUModel, b.NumCPU, b.GOMAXPROCS))
+	}
+	if a.GoVersion != b.GoVersion || a.Driver != b.Driver {
+		diffs = append(diffs, fmt.Sprintf("client %s driver %s vs %s driver %s", a.GoVersion, a.Driver, b.GoVersion, b.Driver))
+	}
+	if a.TLS != b.TLS {
+		diffs = append(diffs, fmt.Sprintf("TLS '%s' vs '%s'", a.TLS, b.TLS))
+	}
+	// Within a factor 2, the RTT of a shared network varies that much
+	if a.RTT > 0 && b.RTT > 0 && (a.RTT > 2*b.RTT || b.RTT > 2*a.RTT) {
+		diffs = append(diffs, fmt.Sprintf("RTT %s vs %s", a.RTT.Round(time.Microsecond), b.RTT.Round(time.Microsecond)))
+	}
+	return diffs
+}
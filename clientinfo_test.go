@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCPUModel(t *testing.T) {
+	x86 := "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel\t\t: 85\nmodel name\t: Intel(R) Xeon(R) Platinum 8259CL CPU @ 2.50GHz\n"
+	if got := parseCPUModel(x86); got != "Intel(R) Xeon(R) Platinum 8259CL CPU @ 2.50GHz" {
+		t.Errorf("unexpected x86 model %q", got)
+	}
+	arm := "processor\t: 0\nBogoMIPS\t: 108.00\n\nHardware\t: BCM2835\nModel\t\t: Raspberry Pi 4 Model B Rev 1.4\n"
+	if got := parseCPUModel(arm); got != "Raspberry Pi 4 Model B Rev 1.4" {
+		t.Errorf("unexpected ARM model %q", got)
+	}
+	if got := parseCPUModel(""); got != "" {
+		t.Errorf("expected no model, got %q", got)
+	}
+}
+
+func TestClientDifferences(t *testing.T) {
+	a := &ClientInfo{CPUModel: "x", NumCPU: 8, GOMAXPROCS: 8, GoVersion: "go1.24.1", Driver: "v1.7.1", RTT: time.Millisecond}
+	b := *a
+	b.RTT = 1500 * time.Microsecond
+	if diffs := clientDifferences(a, &b); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+	b.TLS = "true"
+	b.RTT = 3 * time.Millisecond
+	if diffs := clientDifferences(a, &b); len(diffs) != 2 {
+		t.Errorf("expected the TLS and RTT differences, got %v", diffs)
+	}
+	if diffs := clientDifferences(a, nil); diffs != nil {
+		t.Errorf("expected no differences without client info, got %v", diffs)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the run configuration that can be given in a TOML or YAML file
+// with -config. Every setting corresponds to a command line flag, flags given
+// on the command line override the file. Unset settings keep the flag default.
+type Config struct {
+	LogLevel   *string          `toml:"log_level" yaml:"log_level"`
+	Connection ConnectionConfig `toml:"connection" yaml:"connection"`
+
+	// Table sizes and selectivities are lists of the values accepted by -s and -c, e.g. "1K" or "0.5"
+	RowCounts       []string `toml:"row_counts" yaml:"row_counts"`
+	Selectivities   []string `toml:"selectivities" yaml:"selectivities"`
+	FillerSize      *int     `toml:"filler_size" yaml:"filler_size"`
+	FillerSizes     []int    `toml:"filler_sizes" yaml:"filler_sizes"`
+	FillerColumns   []int    `toml:"filler_columns" yaml:"filler_columns"`
+	BPosition       *string  `toml:"b_position" yaml:"b_position"`
+	Generators      []string `toml:"generators" yaml:"generators"`
+	Distribution    *string  `toml:"distribution" yaml:"distribution"`
+	Seed            *int     `toml:"seed" yaml:"seed"`
+	NullRatio       *string  `toml:"null_ratio" yaml:"null_ratio"`
+	PredicateValues *int     `toml:"predicate_values" yaml:"predicate_values"`
+	Recreate        *bool    `toml:"recreate" yaml:"recreate"`
+	SkipSetup       *bool    `toml:"skip_setup" yaml:"skip_setup"`
+	SetupOnly       *bool    `toml:"setup_only" yaml:"setup_only"`
+	Analyze         *string  `toml:"analyze" yaml:"analyze"`
+	LoadConcurrency *int     `toml:"load_concurrency" yaml:"load_concurrency"`
+	LoadBatchSize   *int     `toml:"load_batch_size" yaml:"load_batch_size"`
+	Loader          *string  `toml:"loader" yaml:"loader"`
+	UniqueTables    *bool    `toml:"unique_tables" yaml:"unique_tables"`
+	Cleanup         *bool    `toml:"cleanup" yaml:"cleanup"`
+
+	Families          []string `toml:"families" yaml:"families"`
+	Scenarios         *string  `toml:"scenarios" yaml:"scenarios"`
+	RangeSpan         *int     `toml:"range_span" yaml:"range_span"`
+	InListLengths     []int    `toml:"in_list_lengths" yaml:"in_list_lengths"`
+	BoundaryBuckets   *int     `toml:"boundary_buckets" yaml:"boundary_buckets"`
+	PrimaryKey        *string  `toml:"primary_key" yaml:"primary_key"`
+	Partitions        *string  `toml:"partitions" yaml:"partitions"`
+	TiFlash           *bool    `toml:"tiflash" yaml:"tiflash"`
+	TiFlashWait       *string  `toml:"tiflash_wait" yaml:"tiflash_wait"`
+	Repetitions       *int     `toml:"repetitions" yaml:"repetitions"`
+	Warmup            *int     `toml:"warmup" yaml:"warmup"`
+	Adaptive          *int     `toml:"adaptive" yaml:"adaptive"`
+	Strict            *bool    `toml:"strict" yaml:"strict"`
+	Checkpoint        *string  `toml:"checkpoint" yaml:"checkpoint"`
+	Resume            *bool    `toml:"resume" yaml:"resume"`
+	ChecksumTables    *bool    `toml:"checksum_tables" yaml:"checksum_tables"`
+	Outliers          *string  `toml:"outliers" yaml:"outliers"`
+	QueryFrequencies  *string  `toml:"query_frequencies" yaml:"query_frequencies"`
+	QueryFreqColumns  *string  `toml:"query_frequency_columns" yaml:"query_frequency_columns"`
+	Expectations      *string  `toml:"expectations" yaml:"expectations"`
+	HookCommand       *string  `toml:"hook_command" yaml:"hook_command"`
+	ConfirmOver       *string  `toml:"confirm_over" yaml:"confirm_over"`
+	Shard             *string  `toml:"shard" yaml:"shard"`
+	Filter            *string  `toml:"filter" yaml:"filter"`
+	Exclude           *string  `toml:"exclude" yaml:"exclude"`
+	RCWait            *bool    `toml:"rc_wait" yaml:"rc_wait"`
+	KeepAlive         *string  `toml:"keepalive" yaml:"keepalive"`
+	AnalyzeOverhead   *int     `toml:"analyze_overhead_every" yaml:"analyze_overhead_every"`
+	RUSplit           *bool    `toml:"ru_split" yaml:"ru_split"`
+	DoubleRead        *bool    `toml:"double_read" yaml:"double_read"`
+	Crossover         *bool    `toml:"crossover" yaml:"crossover"`
+	CostGap           *bool    `toml:"cost_gap" yaml:"cost_gap"`
+	ByTableSize       *bool    `toml:"by_table_size" yaml:"by_table_size"`
+	Parallelism       *bool    `toml:"parallelism" yaml:"parallelism"`
+	ExcludePlanTypes  []string `toml:"exclude_plan_types" yaml:"exclude_plan_types"`
+	IgnorePlanCache   *bool    `toml:"ignore_plan_cache" yaml:"ignore_plan_cache"`
+	ExplainAnalyze    *bool    `toml:"explain_analyze" yaml:"explain_analyze"`
+	CoprCache         *string  `toml:"copr_cache" yaml:"copr_cache"`
+	SimulatedRTT      *string  `toml:"simulated_rtt" yaml:"simulated_rtt"`
+	PingEvery         *int     `toml:"ping_every" yaml:"ping_every"`
+	PingDrift         *float64 `toml:"ping_drift" yaml:"ping_drift"`
+	Prometheus        *string  `toml:"prometheus" yaml:"prometheus"`
+	PauseOnBackground *bool    `toml:"pause_on_background" yaml:"pause_on_background"`
+	BGCompactionMBps  *float64 `toml:"bg_compaction_mbps" yaml:"bg_compaction_mbps"`
+	BGGCKeys          *float64 `toml:"bg_gc_keys" yaml:"bg_gc_keys"`
+	HealthCheckEvery  *int     `toml:"health_check_every" yaml:"health_check_every"`
+	HealthMaxErrors   *float64 `toml:"health_max_error_rate" yaml:"health_max_error_rate"`
+	HealthMaxPingP99  *string  `toml:"health_max_ping_p99" yaml:"health_max_ping_p99"`
+	HealthMaxPause    *string  `toml:"health_max_pause" yaml:"health_max_pause"`
+	LockStats         *bool    `toml:"lock_stats" yaml:"lock_stats"`
+	MaxLoadQPS        *float64 `toml:"max_load_qps" yaml:"max_load_qps"`
+	MaxLoadRUPerSec   *float64 `toml:"max_load_ru_per_sec" yaml:"max_load_ru_per_sec"`
+
+	Label       *string      `toml:"label" yaml:"label"`
+	Description *string      `toml:"description" yaml:"description"`
+	Output      OutputConfig `toml:"output" yaml:"output"`
+}
+
+// ConnectionConfig holds the TiDB connection settings of a Config
+type ConnectionConfig struct {
+	Host     *string `toml:"host" yaml:"host"`
+	Port     *int    `toml:"port" yaml:"port"`
+	User     *string `toml:"user" yaml:"user"`
+	Password *string `toml:"password" yaml:"password"`
+	Database *string `toml:"database" yaml:"database"`
+	DSN      *string `toml:"dsn" yaml:"dsn"`
+	TLS      *string `toml:"tls" yaml:"tls"`
+	TLSCA    *string `toml:"tls_ca" yaml:"tls_ca"`
+	TLSCert  *string `toml:"tls_cert" yaml:"tls_cert"`
+	TLSKey   *string `toml:"tls_key" yaml:"tls_key"`
+}
+
+// OutputConfig holds the report and result file settings of a Config
+type OutputConfig struct {
+	Detailed    *bool    `toml:"detailed" yaml:"detailed"`
+	Aggregated  *bool    `toml:"aggregated" yaml:"aggregated"`
+	JSON        *string  `toml:"json" yaml:"json"`
+	CSV         *string  `toml:"csv" yaml:"csv"`
+	XLSX        *string  `toml:"xlsx" yaml:"xlsx"`
+	Anonymize   *bool    `toml:"anonymize" yaml:"anonymize"`
+	History     *string  `toml:"history" yaml:"history"`
+	SLA         []string `toml:"sla" yaml:"sla"`
+	Extrapolate []string `toml:"extrapolate" yaml:"extrapolate"`
+}
+
+// loadConfig reads a TOML or YAML config file, the format is chosen by the file extension
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown setting '%s' in %s", undecoded[0], path)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		if err = dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file '%s': expected a .toml, .yaml or .yml extension", path)
+	}
+	return cfg, nil
+}
+
+// flagValues returns the config settings as flag name and command line value pairs
+func (cfg *Config) flagValues() map[string][]string {
+	values := make(map[string][]string)
+	set := func(name string, value string) {
+		values[name] = []string{value}
+	}
+	setString := func(name string, v *string) {
+		if v != nil {
+			set(name, *v)
+		}
+	}
+	setInt := func(name string, v *int) {
+		if v != nil {
+			set(name, strconv.Itoa(*v))
+		}
+	}
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			set(name, strconv.FormatFloat(*v, 'g', -1, 64))
+		}
+	}
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			set(name, strconv.FormatBool(*v))
+		}
+	}
+	setList := func(name string, v []string) {
+		if len(v) > 0 {
+			set(name, strings.Join(v, ","))
+		}
+	}
+
+	setString("l", cfg.LogLevel)
+	setString("host", cfg.Connection.Host)
+	setInt("port", cfg.Connection.Port)
+	setString("user", cfg.Connection.User)
+	setString("password", cfg.Connection.Password)
+	setString("database", cfg.Connection.Database)
+	setString("dsn", cfg.Connection.DSN)
+	setString("tls", cfg.Connection.TLS)
+	setString("tls-ca", cfg.Connection.TLSCA)
+	setString("tls-cert", cfg.Connection.TLSCert)
+	setString("tls-key", cfg.Connection.TLSKey)
+
+	setList("s", cfg.RowCounts)
+	setList("c", cfg.Selectivities)
+	setInt("f", cfg.FillerSize)
+	if len(cfg.FillerSizes) > 0 {
+		sizes := make([]string, 0, len(cfg.FillerSizes))
+		for _, size := range cfg.FillerSizes {
+			sizes = append(sizes, strconv.Itoa(size))
+		}
+		setList("f", sizes)
+	}
+	if len(cfg.FillerColumns) > 0 {
+		sizes := make([]string, 0, len(cfg.FillerColumns))
+		for _, size := range cfg.FillerColumns {
+			sizes = append(sizes, strconv.Itoa(size))
+		}
+		setList("fillers", sizes)
+	}
+	setString("b-position", cfg.BPosition)
+	if len(cfg.Generators) > 0 {
+		values["gen"] = cfg.Generators
+	}
+	setString("distribution", cfg.Distribution)
+	setInt("seed", cfg.Seed)
+	setString("null-ratio", cfg.NullRatio)
+	setInt("predicate-values", cfg.PredicateValues)
+	setBool("recreate", cfg.Recreate)
+	setBool("skip-setup", cfg.SkipSetup)
+	setBool("setup-only", cfg.SetupOnly)
+	setString("analyze", cfg.Analyze)
+	setInt("load-concurrency", cfg.LoadConcurrency)
+	setInt("load-batch-size", cfg.LoadBatchSize)
+	setString("loader", cfg.Loader)
+	setBool("unique-tables", cfg.UniqueTables)
+	setBool("cleanup", cfg.Cleanup)
+
+	setList("families", cfg.Families)
+	setString("scenarios", cfg.Scenarios)
+	setInt("range-span", cfg.RangeSpan)
+	setString("pk", cfg.PrimaryKey)
+	setInt("boundary-buckets", cfg.BoundaryBuckets)
+	setString("partitions", cfg.Partitions)
+	if len(cfg.InListLengths) > 0 {
+		lengths := make([]string, 0, len(cfg.InListLengths))
+		for _, length := range cfg.InListLengths {
+			lengths = append(lengths, strconv.Itoa(length))
+		}
+		setList("in-list-lengths", lengths)
+	}
+	setBool("tiflash", cfg.TiFlash)
+	setString("tiflash-wait", cfg.TiFlashWait)
+	setInt("n", cfg.Repetitions)
+	setInt("warmup", cfg.Warmup)
+	setInt("adaptive", cfg.Adaptive)
+	setBool("strict", cfg.Strict)
+	setString("checkpoint", cfg.Checkpoint)
+	setBool("resume", cfg.Resume)
+	setBool("checksum-tables", cfg.ChecksumTables)
+	setString("outliers", cfg.Outliers)
+	setString("query-frequencies", cfg.QueryFrequencies)
+	setString("query-frequency-columns", cfg.QueryFreqColumns)
+	setString("expectations", cfg.Expectations)
+	setString("hook-command", cfg.HookCommand)
+	setString("confirm-over", cfg.ConfirmOver)
+	setString("shard", cfg.Shard)
+	setString("filter", cfg.Filter)
+	setString("exclude", cfg.Exclude)
+	setBool("rc-wait", cfg.RCWait)
+	setString("keepalive", cfg.KeepAlive)
+	setInt("analyze-overhead-every", cfg.AnalyzeOverhead)
+	setBool("ru-split", cfg.RUSplit)
+	setBool("double-read", cfg.DoubleRead)
+	setBool("crossover", cfg.Crossover)
+	setBool("cost-gap", cfg.CostGap)
+	setBool("by-table-size", cfg.ByTableSize)
+	setBool("parallelism", cfg.Parallelism)
+	setList("exclude-plan-types", cfg.ExcludePlanTypes)
+	setBool("ignore-plan-cache", cfg.IgnorePlanCache)
+	setBool("explain-analyze", cfg.ExplainAnalyze)
+	setString("copr-cache", cfg.CoprCache)
+	setString("simulated-rtt", cfg.SimulatedRTT)
+	setInt("ping-every", cfg.PingEvery)
+	setFloat("ping-drift", cfg.PingDrift)
+	setString("prometheus", cfg.Prometheus)
+	setBool("pause-on-background", cfg.PauseOnBackground)
+	setFloat("bg-compaction-mbps", cfg.BGCompactionMBps)
+	setFloat("bg-gc-keys", cfg.BGGCKeys)
+	setInt("health-check-every", cfg.HealthCheckEvery)
+	setFloat("health-max-error-rate", cfg.HealthMaxErrors)
+	setString("health-max-ping-p99", cfg.HealthMaxPingP99)
+	setString("health-max-pause", cfg.HealthMaxPause)
+	setBool("lock-stats", cfg.LockStats)
+	setFloat("max-load-qps", cfg.MaxLoadQPS)
+	setFloat("max-load-ru-per-sec", cfg.MaxLoadRUPerSec)
+
+	setString("label", cfg.Label)
+	setString("desc", cfg.Description)
+	setBool("d", cfg.Output.Detailed)
+	setBool("a", cfg.Output.Aggregated)
+	setString("output-json", cfg.Output.JSON)
+	setString("output-csv", cfg.Output.CSV)
+	setString("output-xlsx", cfg.Output.XLSX)
+	setBool("anonymize", cfg.Output.Anonymize)
+	setString("history", cfg.Output.History)
+	if len(cfg.Output.SLA) > 0 {
+		values["sla"] = cfg.Output.SLA
+	}
+	setList("extrapolate", cfg.Output.Extrapolate)
+	return values
+}
+
+// applyConfig sets the flags from the config file, except the ones given on the command line
+func applyConfig(fs *flag.FlagSet, cfg *Config) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, values := range cfg.flagValues() {
+		if explicit[name] {
+			continue
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid config setting for -%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
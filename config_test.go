@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+	}{
+		{"calibration.toml", "row_counts = [\"1K\", \"10K\"]\nrepetitions = 3\n\n[connection]\nhost = \"tidb.example.com\"\nport = 4001\n"},
+		{"calibration.yaml", "row_counts: [1K, 10K]\nrepetitions: 3\nconnection:\n  host: tidb.example.com\n  port: 4001\n"},
+	} {
+		path := filepath.Join(t.TempDir(), tc.name)
+		if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		rowCounts := fs.String("s", "1K,1M", "")
+		repetitions := fs.Int("n", 1, "")
+		connectionConfig := registerConnectionFlags(fs)
+		// The command line overrides the file
+		if err = fs.Parse([]string{"-port", "4002"}); err != nil {
+			t.Fatal(err)
+		}
+		if err = applyConfig(fs, cfg); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		tidbConfig, err := connectionConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *rowCounts != "1K,10K" || *repetitions != 3 || tidbConfig.Host != "tidb.example.com" || tidbConfig.Port != 4002 {
+			t.Fatalf("%s: unexpected settings: %s %d %+v", tc.name, *rowCounts, *repetitions, tidbConfig)
+		}
+	}
+}
+
+func TestLoadConfigUnknownSetting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.toml")
+	if err := os.WriteFile(path, []byte("repetition = 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Fatalf("expected error for unknown setting")
+	}
+}
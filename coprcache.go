@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The handling of executions served from the coprocessor cache, which
+// pollutes the latencies of the repeated identical queries: bust invalidates
+// the cached regions and executes again, exclude leaves the execution out of
+// the results, and keep records it with its hit ratio, to report separately
+const (
+	coprCacheBust    = "bust"
+	coprCacheExclude = "exclude"
+	coprCacheKeep    = "keep"
+)
+
+// parseCoprCacheMode validates the -copr-cache value
+func parseCoprCacheMode(s string) (string, error) {
+	switch s {
+	case coprCacheBust, coprCacheExclude, coprCacheKeep:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown copr cache mode '%s', expected %s, %s or %s", s, coprCacheBust, coprCacheExclude, coprCacheKeep)
+}
+
+// coprCacheHitRatio returns the highest coprocessor cache hit ratio of the
+// readers of an executed plan, 0 if no cop task was served from the cache
+func coprCacheHitRatio(plan *ExecutionPlan) float64 {
+	ratio := 0.0
+	for p := plan; p != nil; p = p.Next {
+		if exec := p.execInfo(); exec != nil {
+			ratio = max(ratio, exec.CoprCacheHitRatio)
+		}
+	}
+	return ratio
+}
+
+// coprBustOffset is added to b of the rows written by bustCoprCache, above
+// the b values of the test tables, and subtracted again
+const coprBustOffset = 1 << 30
+
+// joinAlias matches the alias of the test table in the join family queries
+var joinAlias = regexp.MustCompile(`\bt\.`)
+
+// wherePredicate returns the WHERE predicate of a scenario query on its test
+// table, without the alias of the join family, or "" if it has none
+func wherePredicate(query string) string {
+	_, where, ok := strings.Cut(query, " WHERE ")
+	if !ok {
+		return ""
+	}
+	for _, clause := range []string{" GROUP BY ", " ORDER BY ", " LIMIT "} {
+		where, _, _ = strings.Cut(where, clause)
+	}
+	return joinAlias.ReplaceAllString(where, "")
+}
+
+// bustCoprCache invalidates the coprocessor cache of the regions read by a
+// scenario, by moving b of up to 50000 random rows matching the predicate of
+// its query, or of the whole table without one, above the b values and back,
+// writing to their record and index regions
+func (c *TiDBClient) bustCoprCache(scenario TestScenario) error {
+	where := ""
+	if predicate := wherePredicate(scenario.Query); predicate != "" {
+		where = " WHERE " + predicate
+	}
+	_, err := c.ExecuteQuery(fmt.Sprintf("UPDATE %s SET b = b + %d%s ORDER BY %s LIMIT 50000",
+		scenario.TableName, coprBustOffset, where, sqlRandomOrder()))
+	if err != nil {
+		return fmt.Errorf("failed to invalidate the coprocessor cache: %w", err)
+	}
+	for {
+		_, err = c.ExecuteQuery(fmt.Sprintf("UPDATE %s SET b = b - %d WHERE b >= %d LIMIT 50000",
+			scenario.TableName, coprBustOffset, coprBustOffset))
+		if err != nil {
+			return fmt.Errorf("failed to restore the rows of the coprocessor cache invalidation: %w", err)
+		}
+		var count int
+		err = c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE b >= %d", scenario.TableName, coprBustOffset)).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to get count: %w", err)
+		}
+		if count == 0 {
+			return nil
+		}
+	}
+}
+
+// outputCoprCacheReport prints the latency of the executions served from the
+// coprocessor cache next to the others, per table size and plan type
+func outputCoprCacheReport(results []*TestExecutionResult) {
+	type key struct {
+		rowCount int
+		planType string
+	}
+	type latencies struct {
+		hits, misses      int
+		hitTime, missTime time.Duration
+		hitRatio          float64
+	}
+	stats := make(map[key]*latencies)
+	var keys []key
+	for _, r := range results {
+		if r.ExplainOnly || r.Plan == nil {
+			continue
+		}
+		k := key{r.RowCount, r.PlanType}
+		s := stats[k]
+		if s == nil {
+			s = &latencies{}
+			stats[k] = s
+			keys = append(keys, k)
+		}
+		if r.CoprCacheHitRatio > 0 {
+			s.hits++
+			s.hitTime += r.Plan.ExecutionTime
+			s.hitRatio += r.CoprCacheHitRatio
+		} else {
+			s.misses++
+			s.missTime += r.Plan.ExecutionTime
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].rowCount != keys[j].rowCount {
+			return keys[i].rowCount < keys[j].rowCount
+		}
+		return keys[i].planType < keys[j].planType
+	})
+	fmt.Println("\n🗄️  Coprocessor Cache")
+	fmt.Println("====================")
+	fmt.Printf("Table_size\tPlan\tHits\tMisses\thit_ratio_avg\thit_ms_avg\tmiss_ms_avg\n")
+	hits := 0
+	for _, k := range keys {
+		s := stats[k]
+		if s.hits == 0 {
+			continue
+		}
+		hits += s.hits
+		missAvg := "n/a"
+		if s.misses > 0 {
+			missAvg = fmt.Sprintf("%.3f", float64(s.missTime.Microseconds())/1000.0/float64(s.misses))
+		}
+		fmt.Printf("%s\t%s\t%d\t%d\t%.2f\t%.3f\t%s\n", formatRowCountName(k.rowCount), k.planType, s.hits, s.misses,
+			s.hitRatio/float64(s.hits), float64(s.hitTime.Microseconds())/1000.0/float64(s.hits), missAvg)
+	}
+	if hits == 0 {
+		fmt.Println("No executions were served from the coprocessor cache")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestCoprCacheHitRatio(t *testing.T) {
+	plan := &ExecutionPlan{ID: "IndexLookUp_10", ExecutionInfo: "time:3.1ms, loops:2",
+		Next: &ExecutionPlan{ID: "├─IndexRangeScan_8(Build)", ExecutionInfo: "time:1.1ms, loops:3, cop_task: {num: 1, max: 1.0ms, proc_keys: 100, copr_cache_hit_ratio: 0.00}",
+			Next: &ExecutionPlan{ID: "└─TableRowIDScan_9(Probe)", Exec: parseExecutionInfo("time:2ms, loops:2, cop_task: {num: 4, max: 1ms, copr_cache_hit_ratio: 0.75}")}}}
+	if ratio := coprCacheHitRatio(plan); ratio != 0.75 {
+		t.Errorf("expected a hit ratio of 0.75, got %v", ratio)
+	}
+	if ratio := coprCacheHitRatio(plan.Next); ratio != 0.75 {
+		t.Errorf("expected a hit ratio of 0.75 below the root, got %v", ratio)
+	}
+	plan.Next.Next = nil
+	if ratio := coprCacheHitRatio(plan); ratio != 0 {
+		t.Errorf("expected no hits, got %v", ratio)
+	}
+}
+
+func TestWherePredicate(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 10", "b = 10"},
+		{"SELECT b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 10 AND 20 GROUP BY b", "b BETWEEN 10 AND 20"},
+		{"SELECT * FROM t1K WHERE b = 10 ORDER BY b, id", "b = 10"},
+		{"SELECT t.id, t.b, j.c FROM t1K t JOIN t1K_join j ON j.id = t.id WHERE t.b = 10", "b = 10"},
+		{"UPDATE t1K SET c = REVERSE(c) WHERE b = 10", "b = 10"},
+		{"SELECT * FROM t1K ORDER BY b LIMIT 10", ""},
+	}
+	for _, tt := range tests {
+		if got := wherePredicate(tt.query); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+}
+
+func TestParseCoprCacheMode(t *testing.T) {
+	for _, mode := range []string{"bust", "exclude", "keep"} {
+		if got, err := parseCoprCacheMode(mode); err != nil || got != mode {
+			t.Errorf("%s: got %s, %v", mode, got, err)
+		}
+	}
+	if _, err := parseCoprCacheMode("off"); err == nil {
+		t.Errorf("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// costGapFar and costGapClose are the ratios of the slower to the faster of
+// two plans from which they are far apart, and up to which they are close
+const (
+	costGapFar   = 4.0
+	costGapClose = 1.25
+)
+
+// The disagreements of the estimated and the measured gap of a cell
+const (
+	costGapOverestimated  = "estimated far apart, measured close"
+	costGapUnderestimated = "estimated close, measured far apart"
+)
+
+// CostGap compares the estimated cost gap between the chosen plan and its
+// fastest measured alternative with their measured latency gap, in one cell
+type CostGap struct {
+	Cell        *CellAnalysis
+	Alternative string
+	// EstRatio and ActualRatio are the estimated cost and the average latency
+	// of the alternative over the chosen plan
+	EstRatio    float64
+	ActualRatio float64
+	// Disagreement is set if one gap is far apart and the other close
+	Disagreement string
+}
+
+// spreadRatio returns the ratio of the larger to the smaller of a ratio and its inverse
+func spreadRatio(ratio float64) float64 {
+	return max(ratio, 1/ratio)
+}
+
+// costGaps compares the estimated and the measured gap of the cells whose
+// chosen plan was measured, against the fastest other allowed plan type
+func costGaps(cells []*CellAnalysis) []CostGap {
+	var gaps []CostGap
+	for _, cell := range cells {
+		if !cell.Measured || cell.EstCost[cell.Chosen] <= 0 || cell.AvgTime[cell.Chosen] <= 0 {
+			continue
+		}
+		alternative := ""
+		for pt, t := range cell.AvgTime {
+			if pt == cell.Chosen || planTypeExcluded(cell.excluded, pt) || cell.EstCost[pt] <= 0 {
+				continue
+			}
+			if alternative == "" || t < cell.AvgTime[alternative] || (t == cell.AvgTime[alternative] && pt < alternative) {
+				alternative = pt
+			}
+		}
+		if alternative == "" {
+			continue
+		}
+		gap := CostGap{
+			Cell:        cell,
+			Alternative: alternative,
+			EstRatio:    cell.EstCost[alternative] / cell.EstCost[cell.Chosen],
+			ActualRatio: float64(cell.AvgTime[alternative]) / float64(cell.AvgTime[cell.Chosen]),
+		}
+		est, actual := spreadRatio(gap.EstRatio), spreadRatio(gap.ActualRatio)
+		switch {
+		case est >= costGapFar && actual <= costGapClose:
+			gap.Disagreement = costGapOverestimated
+		case est <= costGapClose && actual >= costGapFar:
+			gap.Disagreement = costGapUnderestimated
+		}
+		gaps = append(gaps, gap)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Cell.ScenarioID < gaps[j].Cell.ScenarioID })
+	return gaps
+}
+
+// outputCostGapReport prints the cells where the estimated cost gap between
+// the chosen plan and its fastest alternative disagrees with the measured gap
+func outputCostGapReport(cells []*CellAnalysis) {
+	fmt.Println("\n💰 Estimated vs Measured Plan Gap")
+	fmt.Println("====================")
+	gaps := costGaps(cells)
+	fmt.Printf("Scenario\tChosen\tAlternative\tEst_cost_chosen\tEst_cost_alternative\tEst_ratio\tms_chosen\tms_alternative\tActual_ratio\tDisagreement\n")
+	disagreements := 0
+	for _, gap := range gaps {
+		if gap.Disagreement == "" {
+			continue
+		}
+		disagreements++
+		cell := gap.Cell
+		fmt.Printf("%s\t%s\t%s\t%.03f\t%.03f\t%.03f\t%.03f\t%.03f\t%.03f\t%s\n", cell.ScenarioID, cell.Chosen, gap.Alternative,
+			cell.EstCost[cell.Chosen], cell.EstCost[gap.Alternative], gap.EstRatio,
+			cell.AvgTime[cell.Chosen].Seconds()*1000.0, cell.AvgTime[gap.Alternative].Seconds()*1000.0, gap.ActualRatio,
+			gap.Disagreement)
+	}
+	fmt.Printf("%d of %d cells disagree: a gap of %gx or more on one side and at most %gx on the other\n",
+		disagreements, len(gaps), costGapFar, costGapClose)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCostGaps(t *testing.T) {
+	withCost := func(r *TestExecutionResult, cost float64) *TestExecutionResult {
+		r.EstCost.Cost = cost
+		return r
+	}
+	results := []*TestExecutionResult{
+		// Estimated 10x apart, measured within 10%
+		newChoiceResult("index_1M_1000", "index_lookup"),
+		withCost(newTestResult("index_1M_1000", "index_lookup", 10), 100),
+		withCost(newTestResult("index_1M_1000", "table_scan", 11), 1000),
+		// Estimated within 10%, measured 5x apart
+		newChoiceResult("index_1M_50000", "index_lookup"),
+		withCost(newTestResult("index_1M_50000", "index_lookup", 50), 1000),
+		withCost(newTestResult("index_1M_50000", "table_scan", 10), 1050),
+		// Both gaps large, in agreement
+		newChoiceResult("index_1M_10", "index_lookup"),
+		withCost(newTestResult("index_1M_10", "index_lookup", 1), 10),
+		withCost(newTestResult("index_1M_10", "table_scan", 100), 1000),
+	}
+	gaps := costGaps(analyzeCells(results, nil))
+	if len(gaps) != 3 {
+		t.Fatalf("expected 3 compared cells, got %d", len(gaps))
+	}
+	// Sorted by scenario ID
+	for i, expected := range []string{"", costGapOverestimated, costGapUnderestimated} {
+		if gaps[i].Disagreement != expected || gaps[i].Alternative != "table_scan" {
+			t.Fatalf("%s: expected %q, got %+v", gaps[i].Cell.ScenarioID, expected, gaps[i])
+		}
+	}
+	if gaps[1].EstRatio != 10 {
+		t.Fatalf("expected an estimated ratio of 10, got %f", gaps[1].EstRatio)
+	}
+}
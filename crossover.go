@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// crossoverMaxWidth is the ratio of the upper to the lower bound of the
+// crossover confidence interval above which it is too wide to act on, like
+// tuning a cost factor
+const crossoverMaxWidth = 4.0
+
+// crossoverPoint is the latency difference of the fastest index plan minus
+// the table scan of one cell, with its standard error from the per-sample
+// variance of both plans
+type crossoverPoint struct {
+	Matching int
+	Diff     float64
+	StdErr   float64
+}
+
+// CrossoverEstimate is the index vs table scan crossover of a family and
+// table size, in matching rows, with its 95% confidence interval. Low and
+// High are -1 if the bound is not within the measured selectivities.
+type CrossoverEstimate struct {
+	Family   string
+	RowCount int
+	Point    float64
+	Low      float64
+	High     float64
+}
+
+// Actionable returns true if both bounds of the crossover are within the
+// measured selectivities, and at most crossoverMaxWidth apart
+func (e CrossoverEstimate) Actionable() bool {
+	return e.Low > 0 && e.High > 0 && e.High/e.Low <= crossoverMaxWidth
+}
+
+// meanVariance returns the mean and sample variance of the execution times in ms
+func meanVariance(results []*TestExecutionResult) (float64, float64) {
+	var sum float64
+	for _, r := range results {
+		sum += float64(r.Plan.ExecutionTime) / float64(time.Millisecond)
+	}
+	mean := sum / float64(len(results))
+	if len(results) < 2 {
+		return mean, 0
+	}
+	var ss float64
+	for _, r := range results {
+		d := float64(r.Plan.ExecutionTime)/float64(time.Millisecond) - mean
+		ss += d * d
+	}
+	return mean, ss / float64(len(results)-1)
+}
+
+// interpolateCrossover returns the matching rows where the difference, offset
+// by k standard errors, first changes from the index plan being faster to the
+// table scan being faster, interpolated on a log scale between the points
+// sorted by matching rows, or -1 if it does not change sign
+func interpolateCrossover(points []crossoverPoint, k float64) float64 {
+	f := func(p crossoverPoint) float64 { return p.Diff + k*p.StdErr }
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		fa, fb := f(a), f(b)
+		if fa >= 0 || fb < 0 {
+			continue
+		}
+		la, lb := math.Log(float64(max(a.Matching, 1))), math.Log(float64(max(b.Matching, 1)))
+		return math.Exp(la + fa/(fa-fb)*(lb-la))
+	}
+	return -1
+}
+
+// crossoverEstimates returns the index vs table scan crossover per family and
+// table size, over the cells measuring both a table scan and an index plan
+func crossoverEstimates(results []*TestExecutionResult, excluded []string) []CrossoverEstimate {
+	points := make(map[string][]crossoverPoint)
+	var keys []string
+	scenarioIDs, groups := groupByScenario(results)
+	for _, scenarioID := range scenarioIDs {
+		parts := strings.Split(scenarioID, "_")
+		if len(parts) != 3 {
+			continue
+		}
+		matching, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		group := groups[scenarioID]
+		scan, ok := group["table_scan"]
+		if !ok {
+			continue
+		}
+		index := ""
+		var indexMean, indexVar float64
+		for _, pt := range sortedPlanTypes(group) {
+			if pt == "table_scan" || planTypeExcluded(excluded, pt) {
+				continue
+			}
+			if mean, variance := meanVariance(group[pt]); index == "" || mean < indexMean {
+				index, indexMean, indexVar = pt, mean, variance
+			}
+		}
+		if index == "" {
+			continue
+		}
+		scanMean, scanVar := meanVariance(scan)
+		key := parts[0] + "_" + strconv.Itoa(scan[0].RowCount)
+		if _, ok := points[key]; !ok {
+			keys = append(keys, key)
+		}
+		points[key] = append(points[key], crossoverPoint{
+			Matching: matching,
+			Diff:     indexMean - scanMean,
+			StdErr:   math.Sqrt(indexVar/float64(len(group[index])) + scanVar/float64(len(scan))),
+		})
+	}
+	estimates := make([]CrossoverEstimate, 0, len(keys))
+	for _, key := range keys {
+		p := points[key]
+		sort.Slice(p, func(i, j int) bool { return p[i].Matching < p[j].Matching })
+		family, size, _ := strings.Cut(key, "_")
+		rowCount, _ := strconv.Atoi(size)
+		// The table scan is possibly faster from where the upper band crosses,
+		// and significantly faster from where the lower band does
+		estimates = append(estimates, CrossoverEstimate{
+			Family:   family,
+			RowCount: rowCount,
+			Point:    interpolateCrossover(p, 0),
+			Low:      interpolateCrossover(p, z95),
+			High:     interpolateCrossover(p, -z95),
+		})
+	}
+	sort.Slice(estimates, func(i, j int) bool {
+		if estimates[i].Family != estimates[j].Family {
+			return estimates[i].Family < estimates[j].Family
+		}
+		return estimates[i].RowCount < estimates[j].RowCount
+	})
+	return estimates
+}
+
+// outputCrossoverReport prints the index vs table scan crossover selectivity
+// per family and table size with its 95% confidence interval, flagging the
+// intervals too wide to act on
+func outputCrossoverReport(results []*TestExecutionResult, excluded []string) {
+	fmt.Println("\n🎯 Index vs Table Scan Crossover")
+	fmt.Println("====================")
+	fmt.Printf("Family\tTable_size\tCrossover_selectivity\tCrossover_95_low\tCrossover_95_high\tActionable\n")
+	wide := 0
+	for _, e := range crossoverEstimates(results, excluded) {
+		n := float64(e.RowCount)
+		formatSel := func(m float64) string {
+			if m < 0 {
+				return "n/a"
+			}
+			return fmt.Sprintf("%.6f", m/n)
+		}
+		actionable := "yes"
+		switch {
+		case e.Point < 0:
+			actionable = "n/a, no crossover measured"
+		case !e.Actionable():
+			actionable = "no, interval too wide"
+			wide++
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n", e.Family, formatRowCountName(e.RowCount),
+			formatSel(e.Point), formatSel(e.Low), formatSel(e.High), actionable)
+	}
+	if wide > 0 {
+		fmt.Printf("⚠️  %d crossover intervals are not within the measured selectivities, or their bounds more than %gx apart: add repetitions or selectivities around them\n",
+			wide, crossoverMaxWidth)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// crossoverResults returns the results of an index lookup getting slower with
+// the matching rows vs a constant table scan, crossing at 100 matching rows
+func crossoverResults(noise float64) []*TestExecutionResult {
+	var results []*TestExecutionResult
+	for _, matching := range []int{10, 50, 200, 1000} {
+		id := fmt.Sprintf("index_1K_%d", matching)
+		for i, sign := range []float64{-1, 0, 1} {
+			index := newTestResult(id, "index_lookup", float64(matching)/10+sign*noise)
+			scan := newTestResult(id, "table_scan", 10-sign*noise*float64(i%2))
+			index.RowCount, scan.RowCount = 1000, 1000
+			results = append(results, index, scan)
+		}
+	}
+	return results
+}
+
+func TestCrossoverEstimates(t *testing.T) {
+	estimates := crossoverEstimates(crossoverResults(0.1), nil)
+	if len(estimates) != 1 || estimates[0].Family != "index" || estimates[0].RowCount != 1000 {
+		t.Fatalf("unexpected estimates %+v", estimates)
+	}
+	e := estimates[0]
+	// Interpolated on a log scale between 50 (5 ms vs 10 ms) and 200 (20 ms vs 10 ms)
+	want := math.Exp(math.Log(50) + 5.0/15.0*(math.Log(200)-math.Log(50)))
+	if math.Abs(e.Point-want) > 1e-6 {
+		t.Fatalf("expected the crossover at %f matching rows, got %f", want, e.Point)
+	}
+	if !(e.Low > 50 && e.Low < e.Point && e.High > e.Point && e.High < 200) || !e.Actionable() {
+		t.Fatalf("expected a narrow interval around the crossover, got %+v", e)
+	}
+
+	e = crossoverEstimates(crossoverResults(15), nil)[0]
+	if e.Actionable() {
+		t.Fatalf("expected a too wide interval with noisy samples, got %+v", e)
+	}
+}
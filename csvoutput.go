@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// detailedCSVHeader is the column order of the detailed results CSV file
+var detailedCSVHeader = []string{
+	"scenario", "variant", "repetition", "start_time", "run_offset_ms", "table_size", "matching_rows", "plan",
+	"est_cost", "ru", "ms", "rc_wait_ms", "during_background_work", "floor_drifted",
+	"plan_from_cache", "plan_from_binding", "cop_tasks", "cop_concurrency",
+	"total_keys", "processed_keys", "warmup", "outlier", "digest", "query",
+}
+
+// aggregatedCSVHeader is the column order of the aggregated results CSV file,
+// new columns are appended so the existing ones keep their position
+var aggregatedCSVHeader = []string{
+	"scenario", "table_size", "matching_rows", "chosen_plan", "plan", "count",
+	"ms_min", "ms_avg", "ms_max", "ru_min", "ru_avg", "ru_max", "tidb_ms_avg", "cop_ms_avg",
+	"ms_stddev", "ms_p50", "ms_p90", "ms_p99", "ru_stddev", "ru_p50", "ru_p90", "ru_p99", "ms_ci95",
+}
+
+// csvOutputPaths returns the detailed and aggregated CSV file names for the -output-csv file name
+func csvOutputPaths(path string) (string, string) {
+	prefix := strings.TrimSuffix(path, ".csv")
+	return prefix + "-detailed.csv", prefix + "-aggregated.csv"
+}
+
+// writeResultsCSV writes the detailed per-run results and the aggregated
+// per-scenario stats as two CSV files, returning their names
+func writeResultsCSV(path string, results []*TestExecutionResult) (string, string, error) {
+	detailedPath, aggregatedPath := csvOutputPaths(path)
+	detailedHeader, detailed := detailedCSVHeader, detailedCSVRecords(results)
+	aggregatedHeader, aggregated := aggregatedCSVHeader, aggregatedCSVRecords(results)
+	// The execution hook metrics are appended after the fixed columns, the
+	// value of each execution and the average of each scenario and plan type
+	if names := hookMetricNames(results); len(names) > 0 {
+		detailedHeader = append(slices.Clone(detailedHeader), names...)
+		i := 0
+		for _, r := range results {
+			if !r.ExplainOnly {
+				detailed[i] = append(detailed[i], hookMetricValues([]*TestExecutionResult{r}, names)...)
+				i++
+			}
+		}
+		aggregatedHeader = slices.Clone(aggregatedHeader)
+		for _, name := range names {
+			aggregatedHeader = append(aggregatedHeader, name+"_avg")
+		}
+		_, groups := groupByScenario(measuredResults(results))
+		for i, record := range aggregated {
+			aggregated[i] = append(record, hookMetricValues(groups[record[0]][record[4]], names)...)
+		}
+	}
+	if !ruReported(results) {
+		detailedHeader, detailed = withoutRUColumns(detailedHeader, detailed)
+		aggregatedHeader, aggregated = withoutRUColumns(aggregatedHeader, aggregated)
+	}
+	if err := writeCSVFile(detailedPath, detailedHeader, detailed); err != nil {
+		return "", "", err
+	}
+	if err := writeCSVFile(aggregatedPath, aggregatedHeader, aggregated); err != nil {
+		return "", "", err
+	}
+	return detailedPath, aggregatedPath, nil
+}
+
+// hookMetricNames returns the sorted names of the execution hook metrics of the results
+func hookMetricNames(results []*TestExecutionResult) []string {
+	var names []string
+	for _, r := range results {
+		for name := range r.Metrics {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hookMetricValues returns the average of each metric over the executions
+// that have it, empty if none has
+func hookMetricValues(runs []*TestExecutionResult, names []string) []string {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		var sum float64
+		n := 0
+		for _, r := range runs {
+			if v, ok := r.Metrics[name]; ok {
+				sum += v
+				n++
+			}
+		}
+		if n == 0 {
+			values = append(values, "")
+			continue
+		}
+		values = append(values, formatCSVFloat(sum/float64(n)))
+	}
+	return values
+}
+
+func writeCSVFile(path string, header []string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(records)
+	if err = w.Error(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func formatCSVMs(d time.Duration) string {
+	return formatCSVFloat(d.Seconds() * 1000.0)
+}
+
+// formatCSVTime formats a time in UTC with microseconds, empty if not recorded
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// detailedCSVRecords returns one record per measured execution, in execution order
+func detailedCSVRecords(results []*TestExecutionResult) [][]string {
+	var records [][]string
+	for _, r := range results {
+		if r.ExplainOnly {
+			continue
+		}
+		records = append(records, []string{
+			r.ScenarioID,
+			r.Variant,
+			strconv.Itoa(r.Repetition),
+			formatCSVTime(r.StartTime),
+			formatCSVMs(r.RunOffset),
+			strconv.Itoa(r.RowCount),
+			strconv.Itoa(r.MatchingRows),
+			r.PlanType,
+			formatCSVFloat(r.EstCost.Cost),
+			formatCSVFloat(getRU(r.Plan)),
+			formatCSVMs(r.Plan.ExecutionTime),
+			formatCSVMs(r.RCWait),
+			strconv.FormatBool(r.DuringBackgroundWork),
+			strconv.FormatBool(r.FloorDrifted),
+			strconv.FormatBool(r.PlanFromCache),
+			strconv.FormatBool(r.PlanFromBinding),
+			strconv.Itoa(r.CopTasks),
+			strconv.Itoa(r.CopConcurrency),
+			strconv.FormatInt(r.TotalKeys, 10),
+			strconv.FormatInt(r.ProcessedKeys, 10),
+			strconv.FormatBool(r.Warmup),
+			strconv.FormatBool(r.Outlier),
+			r.Digest,
+			r.Query,
+		})
+	}
+	return records
+}
+
+// aggregatedCSVRecords returns one record per scenario and measured plan type, sorted by scenario and plan type,
+// without the warm-up executions and the outliers
+func aggregatedCSVRecords(results []*TestExecutionResult) [][]string {
+	chosen := make(map[string]string)
+	for _, r := range results {
+		if r.ExplainOnly {
+			chosen[r.ScenarioID] = r.PlanType
+		}
+	}
+	var records [][]string
+	scenarioIDs, groups := groupByScenario(measuredResults(results))
+	for _, scenarioID := range scenarioIDs {
+		for _, planType := range sortedPlanTypes(groups[scenarioID]) {
+			runs := groups[scenarioID][planType]
+			var sumTime, minTime, maxTime, sumTiDB, sumCop time.Duration
+			var sumRU, minRU, maxRU float64
+			for i, r := range runs {
+				tidbTime, copTime := layerTimes(r.Plan)
+				sumTiDB += tidbTime
+				sumCop += copTime
+				t, ru := r.Plan.ExecutionTime, getRU(r.Plan)
+				if i == 0 || t < minTime {
+					minTime = t
+				}
+				if i == 0 || ru < minRU {
+					minRU = ru
+				}
+				maxTime = max(maxTime, t)
+				maxRU = max(maxRU, ru)
+				sumTime += t
+				sumRU += ru
+			}
+			msSpread, ruSpread := spreadOf(latenciesMs(runs)), spreadOf(requestUnits(runs))
+			records = append(records, []string{
+				scenarioID,
+				strconv.Itoa(runs[0].RowCount),
+				strconv.Itoa(runs[0].MatchingRows),
+				chosen[scenarioID],
+				planType,
+				strconv.Itoa(len(runs)),
+				formatCSVMs(minTime),
+				formatCSVMs(sumTime / time.Duration(len(runs))),
+				formatCSVMs(maxTime),
+				formatCSVFloat(minRU),
+				formatCSVFloat(sumRU / float64(len(runs))),
+				formatCSVFloat(maxRU),
+				formatCSVMs(sumTiDB / time.Duration(len(runs))),
+				formatCSVMs(sumCop / time.Duration(len(runs))),
+				formatCSVFloat(msSpread.stdDev),
+				formatCSVFloat(msSpread.p50),
+				formatCSVFloat(msSpread.p90),
+				formatCSVFloat(msSpread.p99),
+				formatCSVFloat(ruSpread.stdDev),
+				formatCSVFloat(ruSpread.p50),
+				formatCSVFloat(ruSpread.p90),
+				formatCSVFloat(ruSpread.p99),
+				formatCSVFloat(msSpread.ci95),
+			})
+		}
+	}
+	return records
+}
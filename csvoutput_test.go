@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteResultsCSV(t *testing.T) {
+	results := []*TestExecutionResult{
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 1),
+		newTestResult("index_1K_10", "index_lookup", 3),
+		newTestResult("index_1K_10", "table_scan", 4),
+	}
+	results[1].Query = "SELECT * FROM t1K WHERE b = 10, \"quoted\""
+	results[1].Digest = "a5f3c9d1"
+	results[2].Repetition = 1
+	results[2].StartTime = time.Date(2024, 5, 1, 12, 0, 0, 1500, time.FixedZone("CEST", 2*3600))
+	results[2].RunOffset = 2500 * time.Microsecond
+	results[3].RUReported = true
+	results[2].Metrics = map[string]float64{"command.grpc_msg_total": 42}
+	detailedPath, aggregatedPath, err := writeResultsCSV(filepath.Join(t.TempDir(), "results.csv"), results)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	detailed := readTestCSV(t, detailedPath)
+	if len(detailed) != 4 || len(detailed[0]) != len(detailedCSVHeader)+1 {
+		t.Fatalf("unexpected detailed CSV: %v", detailed)
+	}
+	if detailed[1][len(detailedCSVHeader)-1] != results[1].Query {
+		t.Fatalf("query not preserved: %q", detailed[1][len(detailedCSVHeader)-1])
+	}
+	if detailed[1][len(detailedCSVHeader)-2] != results[1].Digest {
+		t.Fatalf("digest not preserved: %q", detailed[1][len(detailedCSVHeader)-2])
+	}
+	// scenario, variant, repetition, start_time, run_offset_ms
+	if got := detailed[2]; got[2] != "1" || got[3] != "2024-05-01T10:00:00.000001Z" || got[4] != "2.500" {
+		t.Fatalf("unexpected sample timing: %v", got)
+	}
+	if got := detailed[1]; got[3] != "" {
+		t.Fatalf("expected no start time if not recorded, got %q", got[3])
+	}
+
+	aggregated := readTestCSV(t, aggregatedPath)
+	if len(aggregated) != 3 {
+		t.Fatalf("expected header and 2 records, got %v", aggregated)
+	}
+	// The hook metrics are appended, empty for the executions without them
+	if header := detailed[0]; header[len(header)-1] != "command.grpc_msg_total" || detailed[2][len(header)-1] != "42.000" || detailed[1][len(header)-1] != "" {
+		t.Fatalf("unexpected hook metric columns %v", detailed)
+	}
+	if header := aggregated[0]; header[len(header)-1] != "command.grpc_msg_total_avg" || aggregated[1][len(header)-1] != "42.000" || aggregated[2][len(header)-1] != "" {
+		t.Fatalf("unexpected aggregated hook metric columns %v", aggregated)
+	}
+	// Columns added later are appended, the earlier ones keep their position
+	if header := aggregated[0]; header[9] != "ru_min" || header[13] != "cop_ms_avg" || header[len(aggregatedCSVHeader)-1] != "ms_ci95" {
+		t.Fatalf("unexpected aggregated header %v", header)
+	}
+	// scenario, table_size, matching_rows, chosen_plan, plan, count, ms_min, ms_avg, ms_max
+	if got := aggregated[1]; got[3] != "index_lookup" || got[4] != "index_lookup" || got[5] != "2" ||
+		got[6] != "1.000" || got[7] != "2.000" || got[8] != "3.000" {
+		t.Fatalf("unexpected aggregated record: %v", got)
+	}
+}
+
+func readTestCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV %s: %v", path, err)
+	}
+	return records
+}
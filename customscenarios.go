@@ -0,0 +1,459 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioFile is a user defined set of tables and query templates, loaded
+// with -scenarios, expanded over the row counts and selectivities of the run,
+// and the repetition overrides of the built-in and custom scenarios
+type ScenarioFile struct {
+	Tables      []CustomTable        `yaml:"tables"`
+	Scenarios   []CustomScenario     `yaml:"scenarios"`
+	Repetitions []RepetitionOverride `yaml:"repetitions"`
+}
+
+// RepetitionOverride overrides -n for the scenarios of a family, a built-in
+// family like range or the id of a custom scenario, of a table size, or both,
+// like few repetitions of the 1G cells and many of the 1K ones. Variant limits
+// it to one variant, ExplainOnly for how often the unhinted query is explained,
+// otherwise it applies to the measured variants.
+type RepetitionOverride struct {
+	Family      string `yaml:"family"`
+	TableSize   string `yaml:"table_size"`
+	Variant     string `yaml:"variant"`
+	Repetitions int    `yaml:"repetitions"`
+
+	rowCount int
+}
+
+// CustomTable is a user defined table, created from DDL with a {table}
+// placeholder for the table name, and populated by column generators in the
+// -gen syntax, like "customer:zipf(1.1)" or "amount=row % 100"
+type CustomTable struct {
+	Name       string   `yaml:"name"`
+	DDL        string   `yaml:"ddl"`
+	Generators []string `yaml:"generators"`
+
+	columns []string
+	gens    []ColumnGenerator
+}
+
+// CustomScenario is a user defined query template on a custom table. Query is
+// the unhinted query, which the optimizer plans freely, and each variant a
+// hinted query forcing a plan to compare with. Repetitions overrides -n for
+// the variants, and ExplainRepetitions how often the query is explained, once
+// by default, to balance the run time of cheap and expensive scenarios.
+type CustomScenario struct {
+	ID                 string          `yaml:"id"`
+	Name               string          `yaml:"name"`
+	Table              string          `yaml:"table"`
+	Query              string          `yaml:"query"`
+	Repetitions        int             `yaml:"repetitions"`
+	ExplainRepetitions int             `yaml:"explain_repetitions"`
+	Variants           []CustomVariant `yaml:"variants"`
+}
+
+// CustomVariant is a hinted query of a custom scenario, Repetitions overrides
+// the repetitions of the scenario
+type CustomVariant struct {
+	Name        string `yaml:"name"`
+	Query       string `yaml:"query"`
+	Repetitions int    `yaml:"repetitions"`
+}
+
+var (
+	tableNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	// scenarioIDRegex has no _, as the reports split the scenario IDs on it
+	scenarioIDRegex   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+	tableRefTmplRegex = regexp.MustCompile(`\{table:(\w+)\}`)
+)
+
+// loadScenarioFile reads and validates a scenario file
+func loadScenarioFile(path string) (*ScenarioFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	var f ScenarioFile
+	if err = dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err = f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// validate checks the names and references of the scenario file, and parses
+// the column generators of the tables
+func (f *ScenarioFile) validate() error {
+	if len(f.Scenarios) == 0 && len(f.Repetitions) == 0 {
+		return fmt.Errorf("no scenarios or repetitions given")
+	}
+	tables := make(map[string]bool)
+	for i := range f.Tables {
+		t := &f.Tables[i]
+		if !tableNameRegex.MatchString(t.Name) {
+			return fmt.Errorf("invalid table name '%s'", t.Name)
+		}
+		if tables[t.Name] {
+			return fmt.Errorf("duplicate table '%s'", t.Name)
+		}
+		tables[t.Name] = true
+		if !strings.Contains(t.DDL, "{table}") {
+			return fmt.Errorf("table %s: the DDL must name the table {table}", t.Name)
+		}
+		schema, err := parseCreateTable(t.DDL)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		if schema.Comment != "" {
+			return fmt.Errorf("table %s: the DDL must not have a table comment, it records the column generators", t.Name)
+		}
+		if len(t.Generators) == 0 {
+			return fmt.Errorf("table %s: no column generators given", t.Name)
+		}
+		t.columns, t.gens = nil, nil
+		for _, def := range t.Generators {
+			column, gen, err := ParseColumnGenerator(def)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", t.Name, err)
+			}
+			t.columns = append(t.columns, column)
+			t.gens = append(t.gens, gen)
+		}
+	}
+	ids := make(map[string]bool)
+	for _, s := range f.Scenarios {
+		if !scenarioIDRegex.MatchString(s.ID) {
+			return fmt.Errorf("invalid scenario id '%s'", s.ID)
+		}
+		if ids[s.ID] {
+			return fmt.Errorf("duplicate scenario id '%s'", s.ID)
+		}
+		ids[s.ID] = true
+		if !tables[s.Table] {
+			return fmt.Errorf("scenario %s: unknown table '%s'", s.ID, s.Table)
+		}
+		if s.Query == "" {
+			return fmt.Errorf("scenario %s: no query given", s.ID)
+		}
+		if len(s.Variants) == 0 {
+			return fmt.Errorf("scenario %s: no hinted variants given", s.ID)
+		}
+		if s.Repetitions < 0 || s.ExplainRepetitions < 0 {
+			return fmt.Errorf("scenario %s: negative repetitions", s.ID)
+		}
+		queries := []string{s.Query}
+		for _, v := range s.Variants {
+			if v.Name == "" || v.Name == "ExplainOnly" {
+				return fmt.Errorf("scenario %s: invalid variant name '%s'", s.ID, v.Name)
+			}
+			if v.Repetitions < 0 {
+				return fmt.Errorf("scenario %s: negative repetitions of variant %s", s.ID, v.Name)
+			}
+			queries = append(queries, v.Query)
+		}
+		for _, q := range queries {
+			for _, m := range tableRefTmplRegex.FindAllStringSubmatch(q, -1) {
+				if !tables[m[1]] {
+					return fmt.Errorf("scenario %s: unknown table '%s'", s.ID, m[1])
+				}
+			}
+		}
+	}
+	for i := range f.Repetitions {
+		o := &f.Repetitions[i]
+		if o.Repetitions <= 0 {
+			return fmt.Errorf("repetitions %d: expected a positive number of repetitions", i+1)
+		}
+		if _, builtin := scenarioFamilies[o.Family]; o.Family != "" && !builtin && !ids[o.Family] {
+			return fmt.Errorf("repetitions %d: unknown family or scenario '%s'", i+1, o.Family)
+		}
+		if o.TableSize != "" {
+			rowCounts, err := parseRowCounts(o.TableSize)
+			if err != nil || len(rowCounts) != 1 {
+				return fmt.Errorf("repetitions %d: invalid table size '%s'", i+1, o.TableSize)
+			}
+			o.rowCount = rowCounts[0]
+		}
+	}
+	return nil
+}
+
+// createStatement returns the CREATE TABLE statement of a custom table, with
+// the column generators in the table comment, so a table populated by other
+// generators is not reused
+func (t CustomTable) createStatement(tableName string) string {
+	defs := make([]string, 0, len(t.gens))
+	for i, gen := range t.gens {
+		defs = append(defs, canonicalGenerator(t.Generators[i], t.columns[i], gen))
+	}
+	comment := strings.ReplaceAll(tableComment("gen="+strings.Join(defs, ";")), "'", "''")
+	return strings.ReplaceAll(t.DDL, "{table}", tableName) + fmt.Sprintf(" COMMENT '%s'", comment)
+}
+
+// columnNames returns the column and index names of the custom tables
+func (f *ScenarioFile) columnNames() []string {
+	var names []string
+	add := func(name string) {
+		if name != "PRIMARY" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, t := range f.Tables {
+		if schema, err := parseCreateTable(t.DDL); err == nil {
+			for _, def := range slices.Concat(schema.Columns, schema.Indexes) {
+				// Like "column amount int" or "unique index idx_customer (customer)"
+				fields := strings.Fields(strings.TrimPrefix(def, "unique "))
+				if len(fields) > 1 {
+					add(fields[1])
+				}
+			}
+		}
+		for _, column := range t.columns {
+			add(column)
+		}
+	}
+	return names
+}
+
+// customTableName returns the name of a custom table of the given size
+func customTableName(name string, rowCount int, suffix string) string {
+	tableName := name + "_" + formatRowCountName(rowCount)
+	if suffix != "" {
+		tableName += "_" + suffix
+	}
+	return tableName
+}
+
+// selectivityDependent returns true if a query template uses the selectivity
+func selectivityDependent(query string) bool {
+	return strings.Contains(query, "{matching}") || strings.Contains(query, "{sel}")
+}
+
+// expandTemplate fills in the placeholders of a query template
+func expandTemplate(query, table string, rowCount int, sel float64, suffix string) string {
+	query = tableRefTmplRegex.ReplaceAllStringFunc(query, func(m string) string {
+		return customTableName(tableRefTmplRegex.FindStringSubmatch(m)[1], rowCount, suffix)
+	})
+	return strings.NewReplacer(
+		"{table}", customTableName(table, rowCount, suffix),
+		"{rows}", strconv.Itoa(rowCount),
+		"{matching}", strconv.Itoa(GetNumRows(rowCount, sel)),
+		"{sel}", strconv.FormatFloat(sel, 'g', -1, 64),
+	).Replace(query)
+}
+
+// applyRepetitions sets the repetitions of the scenarios matching the
+// repetition overrides, in order, so a later override wins over an earlier
+// one and over the repetitions of a custom scenario
+func (f *ScenarioFile) applyRepetitions(scenarios []TestScenario) {
+	for _, o := range f.Repetitions {
+		for i := range scenarios {
+			if o.matches(&scenarios[i]) {
+				scenarios[i].Repetitions = o.Repetitions
+			}
+		}
+	}
+}
+
+// matches returns true if the override applies to the scenario
+func (o RepetitionOverride) matches(s *TestScenario) bool {
+	if o.Family != "" && s.family != o.Family {
+		return false
+	}
+	if o.TableSize != "" && s.RowCount != o.rowCount {
+		return false
+	}
+	if o.Variant != "" {
+		return s.Variant == o.Variant
+	}
+	return !s.ExplainOnly
+}
+
+// Expand generates the test scenarios of the file for each combination of
+// row count and selectivity, once per row count for the scenarios whose
+// queries do not use the selectivity
+func (f *ScenarioFile) Expand(rowCounts []int, selectivities []float64, suffix string) []TestScenario {
+	var scenarios []TestScenario
+	for _, s := range f.Scenarios {
+		dependent := selectivityDependent(s.Query)
+		for _, v := range s.Variants {
+			dependent = dependent || selectivityDependent(v.Query)
+		}
+		for _, rows := range rowCounts {
+			sels := selectivities
+			if !dependent {
+				sels = selectivities[:1]
+			}
+			for _, sel := range sels {
+				name := fmt.Sprintf("%s - %s rows", s.Name, formatRowCountName(rows))
+				matching := 0
+				if dependent {
+					matching = GetNumRows(rows, sel)
+					name += fmt.Sprintf(", %d matching", matching)
+				}
+				// Always <id>_<size>_<matching> like the built-in families
+				id := fmt.Sprintf("%s_%s_%d", s.ID, formatRowCountName(rows), matching)
+				scenario := TestScenario{
+					ID:           id,
+					Variant:      "ExplainOnly",
+					Name:         name,
+					Query:        expandTemplate(s.Query, s.Table, rows, sel, suffix),
+					TableName:    customTableName(s.Table, rows, suffix),
+					RowCount:     rows,
+					MatchingRows: matching,
+					ExplainOnly:  true,
+					Repetitions:  s.ExplainRepetitions,
+					family:       s.ID,
+				}
+				scenarios = append(scenarios, scenario)
+				for _, v := range s.Variants {
+					scenario.Variant = v.Name
+					scenario.Query = expandTemplate(v.Query, s.Table, rows, sel, suffix)
+					scenario.ExplainOnly = false
+					scenario.Repetitions = s.Repetitions
+					if v.Repetitions > 0 {
+						scenario.Repetitions = v.Repetitions
+					}
+					scenarios = append(scenarios, scenario)
+				}
+			}
+		}
+	}
+	return scenarios
+}
+
+// setupCustomTables creates and populates the custom tables for each row
+// count, reusing existing tables with the same schema and row count
+func setupCustomTables(f *ScenarioFile, rowCounts []int, opts SetupOptions) error {
+	c := NewTiDBClient()
+	if err := c.Connect(opts.TiDB); err != nil {
+		return err
+	}
+	defer c.Close()
+	c.limiter = opts.Limiter
+
+	for _, t := range f.Tables {
+		for _, rows := range rowCounts {
+			if err := setupCustomTable(c, t, rows, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setupCustomTable creates and populates a custom table of the given size
+func setupCustomTable(c *TiDBClient, t CustomTable, rowCount int, opts SetupOptions) error {
+	tableName := customTableName(t.Name, rowCount, opts.TableSuffix)
+	fmt.Printf("✅ Checking table %s\n", tableName)
+	createStmt := t.createStatement(tableName)
+	currentRowCount, err := c.GetTableRowCount(tableName)
+	if err == nil {
+		diff, err := checkTableSchema(c, tableName, createStmt)
+		if err != nil {
+			return err
+		}
+		if len(diff) == 0 && currentRowCount == rowCount {
+			return nil
+		}
+		if len(diff) > 0 && !opts.Recreate {
+			return fmt.Errorf("existing table %s does not match the requested schema (use -recreate to drop and recreate it):\n%s",
+				tableName, strings.Join(diff, "\n"))
+		}
+		slog.Warn("Recreating custom table", "table", tableName, "diff", diff, "rows", currentRowCount)
+	}
+	if _, err = c.ExecuteQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+	}
+	if _, err = c.ExecuteQuery(createStmt); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", tableName, err)
+	}
+	if err = generateRandomData(c, tableName, rowCount, t.columns, t.gens, opts); err != nil {
+		return fmt.Errorf("failed to generate data for %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// tableNames returns the names of the custom tables of each row count
+func (f *ScenarioFile) tableNames(rowCounts []int, suffix string) []string {
+	var names []string
+	for _, t := range f.Tables {
+		for _, rows := range rowCounts {
+			names = append(names, customTableName(t.Name, rows, suffix))
+		}
+	}
+	return names
+}
+
+// verifyCustomTables checks that the custom tables of each row count exist
+// with the requested row counts, schema and column generators, for -skip-setup
+func verifyCustomTables(f *ScenarioFile, rowCounts []int, opts SetupOptions) error {
+	c := NewTiDBClient()
+	if err := c.Connect(opts.TiDB); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var problems []string
+	for _, t := range f.Tables {
+		for _, rows := range rowCounts {
+			tableName := customTableName(t.Name, rows, opts.TableSuffix)
+			fmt.Printf("✅ Verifying table %s\n", tableName)
+			problem, err := c.verifyTableRows(tableName, rows)
+			if err != nil {
+				return err
+			}
+			if problem != "" {
+				problems = append(problems, problem)
+				continue
+			}
+			diff, err := checkTableSchema(c, tableName, t.createStatement(tableName))
+			if err != nil {
+				return err
+			}
+			if len(diff) > 0 {
+				problems = append(problems, fmt.Sprintf("%s does not match the requested schema:\n%s", tableName, strings.Join(diff, "\n")))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the custom tables cannot be reused with -skip-setup, run without it to set them up:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// dropCustomTables drops the custom tables of each row count
+func dropCustomTables(f *ScenarioFile, rowCounts []int, suffix string, config *TiDBConfig) error {
+	c := NewTiDBClient()
+	if err := c.Connect(config); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, t := range f.Tables {
+		for _, rows := range rowCounts {
+			tableName := customTableName(t.Name, rows, suffix)
+			if _, err := c.ExecuteQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)); err != nil {
+				return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+			}
+			fmt.Printf("🧹 Dropped table %s\n", tableName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testScenarioFile = `
+tables:
+  - name: orders
+    ddl: CREATE TABLE {table} (id int AUTO_INCREMENT PRIMARY KEY, customer int, amount int, KEY (customer))
+    generators: ["customer:zipf(1.1, seed=1)", "amount=row % 100"]
+  - name: customers
+    ddl: CREATE TABLE {table} (id int PRIMARY KEY)
+    generators: ["id=row"]
+scenarios:
+  - id: orderscust
+    name: Orders of customers
+    table: orders
+    query: SELECT * FROM {table} WHERE customer < {matching}
+    variants:
+      - name: Index
+        query: SELECT /*+ FORCE_INDEX({table}, customer) */ * FROM {table} WHERE customer < {matching}
+      - name: TableScan
+        query: SELECT /*+ IGNORE_INDEX({table}, customer) */ * FROM {table} WHERE customer < {matching}
+        repetitions: 1
+  - id: ordersjoin
+    name: Orders joined to customers
+    table: orders
+    query: SELECT COUNT(*) FROM {table} o JOIN {table:customers} c ON o.customer = c.id
+    repetitions: 2
+    explain_repetitions: 5
+    variants:
+      - name: HashJoin
+        query: SELECT /*+ HASH_JOIN(o, c) */ COUNT(*) FROM {table} o JOIN {table:customers} c ON o.customer = c.id
+`
+
+func writeScenarioFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenarios.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScenarioFileExpand(t *testing.T) {
+	f, err := loadScenarioFile(writeScenarioFile(t, testScenarioFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(f.Tables[0].columns, ","); got != "customer,amount" {
+		t.Fatalf("unexpected generated columns %s", got)
+	}
+	scenarios := f.Expand([]int{1000, 10000}, []float64{10, 0.5}, "")
+	// orderscust: 2 sizes x 2 selectivities x 3 variants, ordersjoin once per size: 2 x 2 variants
+	if len(scenarios) != 16 {
+		t.Fatalf("expected 16 scenarios, got %d", len(scenarios))
+	}
+	s := scenarios[4]
+	if s.ID != "orderscust_1K_500" || s.Variant != "Index" || s.MatchingRows != 500 || s.TableName != "orders_1K" ||
+		s.Query != "SELECT /*+ FORCE_INDEX(orders_1K, customer) */ * FROM orders_1K WHERE customer < 500" {
+		t.Fatalf("unexpected scenario %+v", s)
+	}
+	s = scenarios[14]
+	if s.ID != "ordersjoin_10K_0" || s.Variant != "ExplainOnly" || !s.ExplainOnly ||
+		s.Query != "SELECT COUNT(*) FROM orders_10K o JOIN customers_10K c ON o.customer = c.id" {
+		t.Fatalf("unexpected scenario %+v", s)
+	}
+	if got := f.Expand([]int{1000}, []float64{10}, "run1")[0].TableName; got != "orders_1K_run1" {
+		t.Fatalf("expected the table suffix, got %s", got)
+	}
+	// The generators are in the table comment, so a table of other generators is not reused
+	if got := f.Tables[0].createStatement("orders_1K"); !strings.HasSuffix(got, "KEY (customer)) COMMENT 'calibration v1: gen=customer:zipf(1.1,1000000,seed=1);amount=row % 100'") {
+		t.Fatalf("unexpected create statement %s", got)
+	}
+	if got := strings.Join(f.columnNames(), ","); got != "id,customer,amount" {
+		t.Fatalf("unexpected column names %s", got)
+	}
+
+	// Per cell of orderscust: one explain, 3 Index and 1 TableScan executions,
+	// per size of ordersjoin: 5 explains and 2 HashJoin executions
+	if got := NewSchedule(scenarios, 3).Len(); got != 4*(1+3+1)+2*(5+2) {
+		t.Fatalf("expected %d executions, got %d", 4*(1+3+1)+2*(5+2), got)
+	}
+}
+
+func TestScenarioFileValidate(t *testing.T) {
+	for _, replace := range [][2]string{
+		{"table: orders\n    query: SELECT *", "table: nosuch\n    query: SELECT *"},
+		{"{table:customers}", "{table:nosuch}"},
+		{"id: ordersjoin", "id: orderscust"},
+		{"id: ordersjoin", "id: orders_join"},
+		{`"id=row"`, `"id=row +"`},
+		{"CREATE TABLE {table} (id int PRIMARY KEY)", "CREATE TABLE customers (id int PRIMARY KEY)"},
+		{"name: HashJoin", "name: ExplainOnly"},
+		{"    table: orders\n    query", "    tabel: orders\n    query"},
+		{"explain_repetitions: 5", "explain_repetitions: -1"},
+		{"(id int PRIMARY KEY)", "(id int PRIMARY KEY) COMMENT 'customers'"},
+	} {
+		content := strings.Replace(testScenarioFile, replace[0], replace[1], 1)
+		if content == testScenarioFile {
+			t.Fatalf("replacement %q did not apply", replace[0])
+		}
+		if _, err := loadScenarioFile(writeScenarioFile(t, content)); err == nil {
+			t.Fatalf("expected an error for %q", replace[1])
+		}
+	}
+}
+
+func TestRepetitionOverrides(t *testing.T) {
+	f, err := loadScenarioFile(writeScenarioFile(t, testScenarioFile+`
+repetitions:
+  - family: point
+    table_size: 10K
+    repetitions: 2
+  - family: point
+    variant: ExplainOnly
+    repetitions: 4
+  - table_size: 10K
+    variant: TableScan
+    repetitions: 1
+  - family: ordersjoin
+    table_size: 1K
+    repetitions: 7
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenarios := GetTestScenarios(tableSpecs([]int{1000, 10000}, "", MatrixOptions{}), []float64{10}, MatrixOptions{}, "point")
+	scenarios = append(scenarios, f.Expand([]int{1000, 10000}, []float64{10}, "")...)
+	f.applyRepetitions(scenarios)
+	repetitions := make(map[string]int)
+	for _, s := range scenarios {
+		repetitions[s.ID+"/"+s.Variant] = s.Repetitions
+	}
+	for key, expected := range map[string]int{
+		"index_1K_10/ExplainOnly":  4,
+		"index_1K_10/Index":        0,
+		"index_10K_10/ExplainOnly": 4,
+		"index_10K_10/Index":       2,
+		"index_10K_10/TableScan":   1,
+		// The overrides win over the repetitions of the custom scenario
+		"ordersjoin_1K_0/HashJoin":    7,
+		"ordersjoin_1K_0/ExplainOnly": 5,
+		"ordersjoin_10K_0/HashJoin":   2,
+		"orderscust_10K_10/TableScan": 1,
+		"orderscust_10K_10/Index":     0,
+	} {
+		if got, ok := repetitions[key]; !ok || got != expected {
+			t.Errorf("expected %d repetitions of %s, got %d", expected, key, got)
+		}
+	}
+
+	// Only overrides, for the built-in families
+	if _, err = loadScenarioFile(writeScenarioFile(t, "repetitions:\n  - table_size: 1G\n    repetitions: 2\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, override := range []string{"family: nosuch\n    repetitions: 2", "table_size: 1X\n    repetitions: 2", "table_size: 1G"} {
+		if _, err = loadScenarioFile(writeScenarioFile(t, "repetitions:\n  - "+override+"\n")); err == nil {
+			t.Errorf("expected an error for %q", override)
+		}
+	}
+}
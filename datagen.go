@@ -4,47 +4,431 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/bits"
-	"math/rand"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	IndexVsTableSchemaFmt = "CREATE TABLE %s (id int AUTO_INCREMENT PRIMARY KEY, b int, c varchar(%d), KEY (b))"
+	IndexVsTableSchemaFmt = "CREATE TABLE %s (id int AUTO_INCREMENT PRIMARY KEY %s, %s, KEY (b))"
 )
 
-func CheckAndSetupTables(rowCounts []int, selectivities []float64, fillerSize int) error {
+// SetupOptions holds the settings for creating and populating the test tables
+type SetupOptions struct {
+	FillerSize int
+	// Recreate drops and recreates existing tables whose schema does not match
+	Recreate bool
+	// TableSuffix, if set, is appended to the table names, to keep runs apart
+	TableSuffix string
+	// TiDB is the connection configuration, nil for the default localhost:4000
+	TiDB *TiDBConfig
+	// Limiter caps the load of the setup statements, nil for no limit
+	Limiter *LoadLimiter
+	// JoinTables also sets up the join partner tables of the join family
+	JoinTables bool
+	// IndexMergeTables also sets up the tables with a second index of the indexmerge family
+	IndexMergeTables bool
+	// CompositeTables also sets up the tables with the composite index of the composite family
+	CompositeTables bool
+	// UniquenessTables also sets up the tables with the unique and non-unique index of the unique family
+	UniquenessTables bool
+	// Matrix are the settings of the scenario matrix the tables are set up for
+	Matrix MatrixOptions
+	// TiFlashWait is how long to wait for the TiFlash replicas of Matrix.TiFlash
+	TiFlashWait time.Duration
+	// LoadConcurrency is the number of connections inserting the generated rows,
+	// LoadBatchSize the rows per INSERT statement (default 100000)
+	LoadConcurrency int
+	LoadBatchSize   int
+	// Loader is loaderInsert (default) or loaderLoadData
+	Loader string
+}
+
+// loadBatchSize returns the rows per INSERT statement of the data generation
+func (opts SetupOptions) loadBatchSize() int {
+	if opts.LoadBatchSize > 0 {
+		return opts.LoadBatchSize
+	}
+	return defaultLoadBatchSize
+}
+
+func CheckAndSetupTables(rowCounts []int, selectivities []float64, opts SetupOptions) error {
 	c := NewTiDBClient()
 
-	err := c.Connect(nil)
+	err := c.Connect(opts.TiDB)
 	if err != nil {
 		return err
 	}
 	defer c.Close()
+	c.limiter = opts.Limiter
 
+	var replicated []string
 	// TODO: Try to reuse mjonss/tidb_data_generator for creating the tables faster
 	// TODO: When inserting, try to set the selectivities already there, so it just needs fine tuning later
-	for _, rows := range rowCounts {
-		tableName := fmt.Sprintf("t%s", formatRowCountName(rows))
-		err = generateTestData(c, tableName, rows, selectivities, fillerSize)
+	for _, table := range tableSpecs(rowCounts, opts.TableSuffix, opts.Matrix) {
+		tableName := table.Name()
+		// Statistics left locked by an interrupted -analyze run would skip the analyze of the setup
+		for _, name := range append([]string{tableName}, opts.partnerNames(table)...) {
+			c.unlockStats(name)
+		}
+		err = generateTestData(c, table, selectivities, opts)
 		if err != nil {
 			return err
 		}
-		err = adjustSelectivities(c, tableName, rows, selectivities)
+		err = adjustTableSelectivities(c, table, selectivities)
 		if err != nil {
 			return err
 		}
+		if opts.JoinTables {
+			if err = setupJoinTable(c, table); err != nil {
+				return err
+			}
+		}
+		if opts.IndexMergeTables {
+			if err = setupIndexMergeTable(c, table); err != nil {
+				return err
+			}
+		}
+		if opts.CompositeTables {
+			if err = setupCompositeTable(c, table, opts.FillerSize); err != nil {
+				return err
+			}
+		}
+		if opts.UniquenessTables {
+			if err = setupUniqueTable(c, table); err != nil {
+				return err
+			}
+		}
+		if opts.Matrix.Partitions != nil {
+			if err = setupPartitionedTable(c, table, opts.FillerSize, opts.Matrix.Partitions); err != nil {
+				return err
+			}
+		}
+		if opts.Matrix.TiFlash {
+			replicated = append(replicated, tableName)
+			if opts.IndexMergeTables {
+				replicated = append(replicated, table.IndexMergeName())
+			}
+			if opts.CompositeTables {
+				replicated = append(replicated, table.CompositeName())
+			}
+			if opts.UniquenessTables {
+				replicated = append(replicated, table.UniqueName())
+			}
+			if opts.Matrix.Partitions != nil {
+				replicated = append(replicated, table.PartitionedName(opts.Matrix.Partitions))
+			}
+		}
+	}
+	for _, tableName := range replicated {
+		if err = c.setTiFlashReplica(tableName); err != nil {
+			return err
+		}
+	}
+	if len(replicated) > 0 {
+		return c.waitForTiFlashReplicas(replicated, opts.TiFlashWait)
+	}
+	return nil
+}
+
+// VerifyTables checks that the test tables, and the partner tables of the
+// requested families, were set up by an earlier run with the same generation
+// parameters, columns and row counts. It only reads the schema and counts
+// the rows, for -skip-setup to reuse the tables without any DDL or data
+// generation.
+func VerifyTables(rowCounts []int, opts SetupOptions) error {
+	c := NewTiDBClient()
+	if err := c.Connect(opts.TiDB); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var problems []string
+	for _, table := range tableSpecs(rowCounts, opts.TableSuffix, opts.Matrix) {
+		tableName := table.Name()
+		fmt.Printf("✅ Verifying table %s\n", tableName)
+		problem, err := c.verifyTableRows(tableName, table.RowCount)
+		if err != nil {
+			return err
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		actual, err := c.getTableSchema(tableName)
+		if err != nil {
+			return err
+		}
+		diff, err := verifyTableSchema(table, opts.FillerSize, actual)
+		if err != nil {
+			return err
+		}
+		if len(diff) > 0 {
+			problems = append(problems, fmt.Sprintf("%s does not match the requested schema:\n%s", tableName, strings.Join(diff, "\n")))
+		}
+		for _, partner := range opts.partnerNames(table) {
+			if problem, err = c.verifyTableRows(partner, table.RowCount); err != nil {
+				return err
+			}
+			if problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the tables cannot be reused with -skip-setup, run without it to set them up:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// partnerNames returns the names of the partner tables of a test table set up
+// for the requested families
+func (opts SetupOptions) partnerNames(table TableSpec) []string {
+	var partners []string
+	if opts.JoinTables {
+		partners = append(partners, table.JoinName())
+	}
+	if opts.IndexMergeTables {
+		partners = append(partners, table.IndexMergeName())
+	}
+	if opts.CompositeTables {
+		partners = append(partners, table.CompositeName())
+	}
+	if opts.UniquenessTables {
+		partners = append(partners, table.UniqueName())
+	}
+	if opts.Matrix.Partitions != nil {
+		partners = append(partners, table.PartitionedName(opts.Matrix.Partitions))
+	}
+	return partners
+}
+
+// tableNames returns the names of the test tables and their partner tables
+func (opts SetupOptions) tableNames(rowCounts []int) []string {
+	var names []string
+	for _, table := range tableSpecs(rowCounts, opts.TableSuffix, opts.Matrix) {
+		names = append(names, table.Name())
+		names = append(names, opts.partnerNames(table)...)
+	}
+	return names
+}
+
+// verifyTableSchema returns the differences of an existing test table to the
+// requested one: the generation parameters in the comment, the columns, the
+// clustering and the indexes
+func verifyTableSchema(table TableSpec, fillerSize int, actual *tableSchema) ([]string, error) {
+	expected, err := parseCreateTable(createTableStatement(table, fillerSize))
+	if err != nil {
+		return nil, err
+	}
+	return diffTableSchema(expected, actual), nil
+}
+
+// verifyTableRows returns a problem description if the table does not exist,
+// or its row count differs from rowCount. The rows are counted, as the
+// TABLE_ROWS of the statistics are 0 after DROP STATS and drift with the
+// modifications until the next analyze.
+func (c *TiDBClient) verifyTableRows(tableName string, rowCount int) (string, error) {
+	exists, err := c.tableExists(tableName)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return fmt.Sprintf("%s does not exist", tableName), nil
+	}
+	tableRows, err := c.GetTableRowCount(tableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the row count of %s: %w", tableName, err)
+	}
+	if tableRows != rowCount {
+		return fmt.Sprintf("%s has %d rows, expected %d", tableName, tableRows, rowCount), nil
+	}
+	return "", nil
+}
+
+// tableExists returns true if the table exists in the current database
+func (c *TiDBClient) tableExists(tableName string) (bool, error) {
+	var exists int
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	slog.Debug("Executing query", "query", query, "table", tableName)
+	if err := c.db.QueryRow(query, tableName).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check if table %s exists: %w", tableName, err)
+	}
+	return exists > 0, nil
+}
+
+// setupJoinTable creates the join partner of a test table, a copy of its rows
+// so every row of the test table has exactly one match on id
+func setupJoinTable(c *TiDBClient, table TableSpec) error {
+	return setupPartnerTable(c, table.Name(), table.JoinName(), "", "*")
+}
+
+// setupIndexMergeTable creates the index merge table of a test table, a copy
+// of its rows with a second indexed column d, uniformly distributed like b
+func setupIndexMergeTable(c *TiDBClient, table TableSpec) error {
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN d int, ADD INDEX d (d)", table.IndexMergeName())
+	return setupPartnerTable(c, table.Name(), table.IndexMergeName(), alter,
+		fmt.Sprintf("*, CONV(SUBSTR(MD5(id), 1, 8), 16, 10) %% %d", bValueDomain))
+}
+
+// maxIndexedFillerLength is the longest filler indexed as a whole by the
+// composite index, 4 bytes per utf8mb4 character under the 3072 bytes limit
+// of an index key, including b
+const maxIndexedFillerLength = 512
+
+// compositeIndex returns the definition of the composite index on (b, c) of
+// the composite family, with a prefix of c if the filler column is too long
+// for an index key. The random part of c is in its first characters.
+func compositeIndex(fillerSize int) string {
+	if size := fillerVarcharSize(fillerSize); size > maxIndexedFillerLength {
+		return fmt.Sprintf("INDEX bc (b, c(%d))", maxIndexedFillerLength)
+	}
+	return "INDEX bc (b, c)"
+}
+
+// setupCompositeTable creates the composite index table of a test table, a
+// copy of its rows with a composite index on (b, c)
+func setupCompositeTable(c *TiDBClient, table TableSpec, fillerSize int) error {
+	alter := fmt.Sprintf("ALTER TABLE %s ADD %s", table.CompositeName(), compositeIndex(table.fillerSizeOr(fillerSize)))
+	return setupPartnerTable(c, table.Name(), table.CompositeName(), alter, "*")
+}
+
+// setupUniqueTable creates the uniqueness table of a test table, a copy of its
+// rows with the columns uu and un holding the same unique values, scrambled
+// from id, uu with a unique index and un with a non-unique index
+func setupUniqueTable(c *TiDBClient, table TableSpec) error {
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN uu bigint, ADD COLUMN un bigint, ADD UNIQUE INDEX uu (uu), ADD INDEX un (un)", table.UniqueName())
+	return setupPartnerTable(c, table.Name(), table.UniqueName(), alter,
+		fmt.Sprintf("*, %s, %s", uniqueValueExpr, uniqueValueExpr))
+}
+
+// setupPartnerTable creates a table like the test table, optionally altered,
+// and copies the rows of the test table, selected with the given columns.
+// The copy is reused as long as it has the same rows as the test table.
+func setupPartnerTable(c *TiDBClient, tableName, copyName, alter, columns string) error {
+	create := []string{fmt.Sprintf("CREATE TABLE %s LIKE %s", copyName, tableName)}
+	if alter != "" {
+		create = append(create, alter)
+	}
+	return setupPartnerTableWith(c, tableName, copyName, create, columns)
+}
+
+// setupPartnerTableWith is setupPartnerTable with the statements creating the copy
+func setupPartnerTableWith(c *TiDBClient, tableName, copyName string, create []string, columns string) error {
+	fmt.Printf("✅ Checking table %s\n", copyName)
+	query := fmt.Sprintf("SELECT COUNT(*), COALESCE(MAX(id), 0) FROM %s", tableName)
+	slog.Debug("Executing query", "query", query)
+	var rows, maxID int
+	if err := c.db.QueryRow(query).Scan(&rows, &maxID); err != nil {
+		return fmt.Errorf("failed to count rows: %w", err)
+	}
+	query = fmt.Sprintf("SELECT COUNT(*), COALESCE(MAX(id), 0) FROM %s", copyName)
+	slog.Debug("Executing query", "query", query)
+	var copyRows, copyMaxID int
+	if err := c.db.QueryRow(query).Scan(&copyRows, &copyMaxID); err == nil && copyRows == rows && copyMaxID == maxID {
+		return nil
+	}
+
+	queries := append([]string{fmt.Sprintf("DROP TABLE IF EXISTS %s", copyName)}, create...)
+	for _, query := range queries {
+		if _, err := c.ExecuteQuery(query); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", copyName, err)
+		}
+	}
+	batchSize := 50000
+	for lastID, copied := 0, 0; copied < rows; copied += batchSize {
+		err := c.ExecuteStatement(fmt.Sprintf("INSERT INTO %s SELECT %s FROM %s WHERE id > %d ORDER BY id LIMIT %d",
+			copyName, columns, tableName, lastID, batchSize))
+		if err != nil {
+			return fmt.Errorf("failed to copy rows to table %s: %w", copyName, err)
+		}
+		query = fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", copyName)
+		slog.Debug("Executing query", "query", query)
+		if err = c.db.QueryRow(query).Scan(&lastID); err != nil {
+			return fmt.Errorf("failed to get copied rows: %w", err)
+		}
+		fmt.Printf(".")
+	}
+	fmt.Printf("\n")
+	if _, err := c.ExecuteQuery(fmt.Sprintf("ANALYZE TABLE %s", copyName)); err != nil {
+		return fmt.Errorf("failed to analyze table %s: %w", copyName, err)
+	}
+	fmt.Printf("✅ Copied %d rows to table %s\n", rows, copyName)
+	return nil
+}
+
+// DropTables drops the given test tables, cleaning up after a run
+func DropTables(tables []TableSpec, partitions *PartitionSpec, config *TiDBConfig) error {
+	c := NewTiDBClient()
+	err := c.Connect(config)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for _, table := range tables {
+		_, err = c.ExecuteQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", table.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", table.Name(), err)
+		}
+		fmt.Printf("🧹 Dropped table %s\n", table.Name())
+		if err = c.dropPartnerTables(table, partitions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropPartnerTables drops the existing partner tables of a test table,
+// including its partitioned copy if partitions is set
+func (c *TiDBClient) dropPartnerTables(table TableSpec, partitions *PartitionSpec) error {
+	partners := []string{table.JoinName(), table.IndexMergeName(), table.CompositeName(), table.UniqueName()}
+	if partitions != nil {
+		partners = append(partners, table.PartitionedName(partitions))
+	}
+	for _, partner := range partners {
+		if exists, err := c.tableExists(partner); err != nil || !exists {
+			continue
+		}
+		if _, err := c.ExecuteQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", partner)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", partner, err)
+		}
+		fmt.Printf("🧹 Dropped table %s\n", partner)
 	}
 	return nil
 }
 
 // generateTestData generates test data with varying selectivity patterns
-func generateTestData(c *TiDBClient, tableName string, rowCount int, selectivities []float64, fillerSize int) error {
+func generateTestData(c *TiDBClient, table TableSpec, selectivities []float64, opts SetupOptions) error {
+	tableName, rowCount := table.Name(), table.RowCount
+	fillerSize := table.fillerSizeOr(opts.FillerSize)
 	fmt.Printf("✅ Checking table %s\n", tableName)
+	createStmt := createTableStatement(table, opts.FillerSize)
 	// Check if table exists and has correct number of rows
 	recreateTable := false
 	currentRowCount, err := c.GetTableRowCount(tableName)
 	if err != nil {
 		recreateTable = true
+	} else {
+		// Never silently reuse a table created with other parameters
+		var diff []string
+		if err = migrateTable(c, tableName, createStmt); err != nil {
+			if !opts.Recreate {
+				return err
+			}
+			diff = []string{err.Error()}
+		} else if diff, err = checkTableSchema(c, tableName, createStmt); err != nil {
+			return err
+		}
+		if len(diff) > 0 {
+			if !opts.Recreate {
+				return fmt.Errorf("existing table %s does not match the requested schema (use -recreate to drop and recreate it):\n%s",
+					tableName, strings.Join(diff, "\n"))
+			}
+			slog.Warn("Recreating table with mismatching schema", "table", tableName, "diff", diff)
+			recreateTable = true
+			currentRowCount = 0
+		}
 	}
 
 	if recreateTable {
@@ -53,9 +437,6 @@ func generateTestData(c *TiDBClient, tableName string, rowCount int, selectiviti
 			return fmt.Errorf("failed to clear existing data: %v", err)
 		}
 
-		// Use the next n^2 value af length of varchar()
-		varcharSize := max(255, (1 << (bits.Len64(uint64(fillerSize)) + 1)))
-		createStmt := fmt.Sprintf(IndexVsTableSchemaFmt, tableName, varcharSize)
 		_, err = c.ExecuteQuery(createStmt)
 		if err != nil {
 			return fmt.Errorf("failed to create table %s: %w", tableName, err)
@@ -71,25 +452,36 @@ func generateTestData(c *TiDBClient, tableName string, rowCount int, selectiviti
 		}
 
 		// Generate random data
-		err = generateRandomData(c, tableName, rowCount, selectivities, fillerSize)
+		fillers, fillerGens := table.layout.fillerColumns(fillerSize)
+		err = generateRandomData(c, tableName, rowCount, append([]string{"b"}, fillers...),
+			append([]ColumnGenerator{getColumnGenerator("b", randomIntGenerator{max: bValueDomain})}, fillerGens...), opts)
 		if err != nil {
 			return fmt.Errorf("failed to generate random data: %v", err)
 		}
 	}
 
 	// Adjust selectivities
-	err = adjustSelectivities(c, tableName, rowCount, selectivities)
+	err = adjustTableSelectivities(c, table, selectivities)
 	if err != nil {
 		return fmt.Errorf("failed to adjust selectivities: %v", err)
 	}
+	if len(table.nulls) > 0 {
+		// Analyzed by the following adjustTableSelectivities of CheckAndSetupTables
+		if err = adjustNulls(c, tableName, rowCount, table.nulls, selectivities, fillerSize); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-// generateRandomData generates random data for the table
-func generateRandomData(c *TiDBClient, tableName string, rowCount int, _ []float64, fillerSize int) error {
-	batchSize := min(100000, rowCount)
-	fmt.Printf("📊 Generating %d rows of random data... (one dot = %d rows)\n", rowCount, batchSize)
+// generateRandomData generates random data for the table, the values of the
+// given columns computed by their generators, inserting batches of rows over
+// opts.LoadConcurrency connections
+func generateRandomData(c *TiDBClient, tableName string, rowCount int, columns []string, gens []ColumnGenerator, opts SetupOptions) error {
+	batchSize := max(min(opts.loadBatchSize(), rowCount), 1)
+	concurrency := max(opts.LoadConcurrency, 1)
+	fmt.Printf("📊 Generating %d rows of random data... (%d rows per batch, %d connections)\n", rowCount, batchSize, concurrency)
 
 	_, err := c.ExecuteQuery(fmt.Sprintf("drop table if exists tmp_%s", tableName))
 	if err != nil {
@@ -105,48 +497,78 @@ func generateRandomData(c *TiDBClient, tableName string, rowCount int, _ []float
 		return fmt.Errorf("failed to insert random data batch: %v", err)
 	}
 	tmpTbls := fmt.Sprintf("tmp_%s", tableName)
+	// Each row of the cross join gets a distinct row number within the batch
+	batchRowNum := fmt.Sprintf("(tmp_%s.a - 1)", tableName)
+	multiplier := 10
 	for i := 10; i <= batchSize; i++ {
 		tmpTbls += fmt.Sprintf(", tmp_%s tt%d", tableName, i)
+		batchRowNum += fmt.Sprintf(" + %d * (tt%d.a - 1)", multiplier, i)
+		multiplier *= 10
 		i *= 10
 	}
-	// Keep inserting until we have enough rows
-	remainingRows := rowCount
-	for {
-		// Check current row count
-		currentBatchSize := batchSize
-
-		if remainingRows <= 0 {
-			var currentRowCount int
-			slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName))
-			err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)).Scan(&currentRowCount)
-			if err != nil {
-				return fmt.Errorf("failed to count current rows: %v", err)
+	// TODO: Generate b values conforming to the seletivities
+	// TODO: Maybe use the mjonss/tidb_data_generator here, instead to speed it up
+	// Generate batch insert with random ID and values using INSERT IGNORE
+	insertBatch := func(start, size int) error {
+		rowNum := fmt.Sprintf("%d + %s", start, batchRowNum)
+		exprs := make([]string, 0, len(gens))
+		for _, gen := range gens {
+			exprs = append(exprs, gen.SQLExpr(rowNum))
+		}
+		query := fmt.Sprintf("INSERT IGNORE INTO %s (%s) SELECT %s FROM %s WHERE %s < %d",
+			tableName, strings.Join(columns, ","), strings.Join(exprs, ", "), tmpTbls, batchRowNum, size)
+		return c.ExecuteStatement(query)
+	}
+	progress := newLoadProgress(tableName, rowCount)
+	loaded := false
+	if opts.Loader == loaderLoadData {
+		err = loadDataRows(c, tableName, rowCount, columns, gens, batchSize, concurrency, progress)
+		switch {
+		case err == nil:
+			loaded = true
+		case errors.Is(err, errLoadDataUnsupported) || isLoadDataRefused(err):
+			slog.Warn("Falling back to INSERT statements", "table", tableName, "error", err)
+			if _, err = c.ExecuteQuery(fmt.Sprintf("TRUNCATE TABLE %s", tableName)); err != nil {
+				return fmt.Errorf("failed to clear existing data: %v", err)
 			}
-			slog.Debug("Current row count", "currentRowCount", currentRowCount)
-
-			if currentRowCount >= rowCount {
-				if currentRowCount > rowCount {
-					slog.Warn("Too many rows in table", "currentRowCount", currentRowCount, "rowCount", rowCount)
-				}
-				break
+			progress = newLoadProgress(tableName, rowCount)
+		default:
+			return fmt.Errorf("failed to load random data batch: %v", err)
+		}
+	}
+	if !loaded {
+		err = loadBatches(rowCount, batchSize, concurrency, func(start, size int) error {
+			if err := insertBatch(start, size); err != nil {
+				return err
 			}
-
-			remainingRows = rowCount - currentRowCount
-			currentBatchSize = min(batchSize, remainingRows)
+			progress.add(size)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert random data batch: %v", err)
 		}
+	}
 
-		// TODO: Generate b values conforming to the seletivities
-		// TODO: Maybe use the mjonss/tidb_data_generator here, instead to speed it up
-		// Generate batch insert with random ID and values using INSERT IGNORE
-		query := fmt.Sprintf("INSERT IGNORE INTO %s (b,c) SELECT FLOOR(RAND() * 1000000), repeat(rand(),%d/18) FROM %s LIMIT %d", tableName, fillerSize, tmpTbls, currentBatchSize)
-		_, err = c.ExecuteQuery(query)
+	// Keep inserting until we have enough rows, INSERT IGNORE may have skipped some
+	for {
+		var currentRowCount int
+		slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName))
+		err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)).Scan(&currentRowCount)
 		if err != nil {
+			return fmt.Errorf("failed to count current rows: %v", err)
+		}
+		slog.Debug("Current row count", "currentRowCount", currentRowCount)
+
+		if currentRowCount >= rowCount {
+			if currentRowCount > rowCount {
+				slog.Warn("Too many rows in table", "currentRowCount", currentRowCount, "rowCount", rowCount)
+			}
+			break
+		}
+		if err = insertBatch(currentRowCount, min(batchSize, rowCount-currentRowCount)); err != nil {
 			return fmt.Errorf("failed to insert random data batch: %v", err)
 		}
-		remainingRows -= currentBatchSize
-		fmt.Printf(".")
 	}
-	fmt.Printf("\n")
 	_, err = c.ExecuteQuery(fmt.Sprintf("drop table tmp_%s", tableName))
 	if err != nil {
 		return fmt.Errorf("failed to drop tmp table: %v", err)
@@ -172,9 +594,77 @@ func generateRandomData(c *TiDBClient, tableName string, rowCount int, _ []float
 	return nil
 }
 
+// defaultLoadBatchSize is the default number of rows per INSERT statement of the data generation
+const defaultLoadBatchSize = 100000
+
+// loadBatches calls insert for the batches of rows [start, start+size)
+// covering rowCount rows, from concurrency goroutines, and returns the first
+// error, after which no more batches are started
+func loadBatches(rowCount, batchSize, concurrency int, insert func(start, size int) error) error {
+	starts := make(chan int)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				if err := insert(start, min(batchSize, rowCount-start)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+feed:
+	for start := 0; start < rowCount; start += batchSize {
+		select {
+		case starts <- start:
+		case err = <-errs:
+			break feed
+		}
+	}
+	close(starts)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return err
+}
+
+// loadProgress reports the progress of the data generation of a table, every
+// tenth of the rows, with the insert rate so far
+type loadProgress struct {
+	mu        sync.Mutex
+	tableName string
+	total     int
+	done      int
+	start     time.Time
+}
+
+func newLoadProgress(tableName string, total int) *loadProgress {
+	return &loadProgress{tableName: tableName, total: total, start: time.Now()}
+}
+
+// add accounts the inserted rows of a batch
+func (p *loadProgress) add(rows int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	before := p.done * 10 / p.total
+	p.done += rows
+	if p.done*10/p.total > before {
+		fmt.Printf("⏳ %s: %d/%d rows (%d%%), %.0f rows/s\n", p.tableName, p.done, p.total,
+			p.done*100/p.total, float64(p.done)/time.Since(p.start).Seconds())
+	}
+}
+
 func getRandomNotInList(l []int) int {
 	for {
-		ret := rand.Intn(1000000) + 1
+		ret := dataRng.Intn(bValueDomain) + 1
 		for i := range l {
 			if i == ret {
 				continue
@@ -184,34 +674,66 @@ func getRandomNotInList(l []int) int {
 	}
 }
 
-// adjustSelectivities adjusts the data to have specific selectivity patterns
-func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectivities []float64) error {
+// adjustTableSelectivities adjusts b of a test table to the selectivities,
+// except on the tables of a distribution, which keep it and are only
+// analyzed, the matching rows of their cells are counted before the measurements
+func adjustTableSelectivities(c *TiDBClient, table TableSpec, selectivities []float64) error {
+	if table.Distribution == "" {
+		return adjustSelectivities(c, table.Name(), table.RowCount, selectivities, table.PredicateValues)
+	}
+	fmt.Printf("🎯 Keeping the %s distribution of b\n", table.Distribution)
+	if _, err := c.ExecuteQuery(fmt.Sprintf("ANALYZE TABLE %s", table.Name())); err != nil {
+		return fmt.Errorf("failed to analyze table %s: %v", table.Name(), err)
+	}
+	return nil
+}
+
+// adjustSelectivities adjusts the data to have specific selectivity patterns,
+// with the given number of b values of the same number of rows per selectivity
+func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectivities []float64, values int) error {
 	batchSize := 50000
 	fmt.Printf("🎯 Adjusting selectivities... (one c/+/- is up to %d rows updated)\n", batchSize)
 
 	if len(selectivities) == 0 {
 		return errors.New("no selectivities given")
 	}
+	if rowCount == 0 {
+		fmt.Printf("✅ Table is empty, no selectivities to adjust\n")
+		return nil
+	}
+	selectivities = fitSelectivities(rowCount, selectivities)
 	rowsForSelectivities := make([]int, 0, len(selectivities))
 
+	// Each selectivity has -predicate-values values of the same number of rows
+	type selectivityValue struct {
+		sel   float64
+		rows  int
+		value int
+	}
+	var targets []selectivityValue
 	notIn := ""
 	numberOfRowsToUpdate := 0
 	for _, sel := range selectivities {
 		// Calculate number of rows for this selectivity
 		rowsForThisSelectivity := GetNumRows(rowCount, sel)
 		rowsForSelectivities = append(rowsForSelectivities, rowsForThisSelectivity)
-		numberOfRowsToUpdate += rowsForThisSelectivity
-		if len(selectivities) > 1 {
+		for i := range max(values, 1) {
+			value := predicateValue(rowsForThisSelectivity, i)
+			targets = append(targets, selectivityValue{sel, rowsForThisSelectivity, value})
+			numberOfRowsToUpdate += rowsForThisSelectivity
 			if notIn != "" {
 				notIn += ","
 			}
-			notIn += fmt.Sprintf("%d", rowsForThisSelectivity)
+			notIn += fmt.Sprintf("%d", value)
 		}
 	}
-	if len(selectivities) > 1 {
+	if len(targets) > 1 {
 		notIn = "WHERE b NOT IN (" + notIn + ")"
+	} else {
+		// Keep the NULL values of -null-ratio
+		notIn = "WHERE b IS NOT NULL"
 	}
-	if numberOfRowsToUpdate >= rowCount {
+	if numberOfRowsToUpdate > rowCount {
 		return errors.New("Total selectivity > 100%")
 	}
 
@@ -226,7 +748,7 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 			break
 		}
 		b := getRandomNotInList(rowsForSelectivities)
-		_, err = c.ExecuteQuery(fmt.Sprintf("UPDATE %s SET b = %d WHERE b <= 0 LIMIT %d", tableName, b, batchSize))
+		err = c.ExecuteStatement(fmt.Sprintf("UPDATE %s SET b = %d WHERE b <= 0 LIMIT %d", tableName, b, batchSize))
 		if err != nil {
 			return fmt.Errorf("failed to clean up negative b's: %v", err)
 		}
@@ -234,9 +756,8 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 
 	}
 
-	for i, sel := range selectivities {
-		// Calculate number of rows for this selectivity
-		rowsForThisSelectivity := rowsForSelectivities[i]
+	for _, target := range targets {
+		sel, rowsForThisSelectivity, value := target.sel, target.rows, target.value
 
 		if rowsForThisSelectivity <= 0 {
 			fmt.Printf("  - %d: Skipping (rowsForThisSelectivity <= 0)\n", int(sel))
@@ -244,8 +765,8 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 		}
 
 		// If already have the correct number of rows, skip
-		slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity))
-		err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity)).Scan(&actualRowCount)
+		slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value))
+		err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value)).Scan(&actualRowCount)
 		if err != nil {
 			return fmt.Errorf("failed to count rows: %v", err)
 		}
@@ -258,13 +779,13 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 		for actualRowCount > rowsForThisSelectivity {
 			b := getRandomNotInList(rowsForSelectivities)
 			limit := min(actualRowCount-rowsForThisSelectivity, batchSize)
-			_, err = c.ExecuteQuery(fmt.Sprintf(
-				"UPDATE %s SET b = %d WHERE b = %d ORDER BY RAND() LIMIT %d", tableName, b, rowsForThisSelectivity, limit))
+			err = c.ExecuteStatement(fmt.Sprintf(
+				"UPDATE %s SET b = %d WHERE b = %d ORDER BY %s LIMIT %d", tableName, b, value, sqlRandomOrder(), limit))
 			if err != nil {
 				return fmt.Errorf("failed to decrease matching rows: %v", err)
 			}
-			slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity))
-			err = c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity)).Scan(&actualRowCount)
+			slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value))
+			err = c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value)).Scan(&actualRowCount)
 			if err != nil {
 				return fmt.Errorf("failed to count rows: %v", err)
 			}
@@ -274,14 +795,14 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 		// Then update the required number of rows to the target value
 		for actualRowCount < rowsForThisSelectivity {
 			limit := min(rowsForThisSelectivity-actualRowCount, batchSize)
-			_, err = c.ExecuteQuery(fmt.Sprintf(
-				"UPDATE %s SET b = %d %s ORDER BY RAND() LIMIT %d",
-				tableName, rowsForThisSelectivity, notIn, limit))
+			err = c.ExecuteStatement(fmt.Sprintf(
+				"UPDATE %s SET b = %d %s ORDER BY %s LIMIT %d",
+				tableName, value, notIn, sqlRandomOrder(), limit))
 			if err != nil {
 				return fmt.Errorf("failed to set selectivity %d: %v", int(sel), err)
 			}
-			slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity))
-			err = c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, rowsForThisSelectivity)).Scan(&actualRowCount)
+			slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value))
+			err = c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s where b = %d", tableName, value)).Scan(&actualRowCount)
 			if err != nil {
 				return fmt.Errorf("failed to count rows: %v", err)
 			}
@@ -290,21 +811,21 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 	}
 	fmt.Printf("\n")
 
-	for i, sel := range selectivities {
+	for _, target := range targets {
 		// Verify the update was successful by counting rows with the target value
-		rowsForThisSelectivity := rowsForSelectivities[i]
+		sel, rowsForThisSelectivity, value := target.sel, target.rows, target.value
 		var actualRowsWithValue int
-		slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE b = %d", tableName, rowsForThisSelectivity))
-		err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE b = %d", tableName, rowsForThisSelectivity)).Scan(&actualRowsWithValue)
+		slog.Debug("Executing query", "query", fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE b = %d", tableName, value))
+		err := c.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE b = %d", tableName, value)).Scan(&actualRowsWithValue)
 		if err != nil {
 			return fmt.Errorf("failed to verify selectivity %f: %v", sel, err)
 		}
 
 		if actualRowsWithValue != rowsForThisSelectivity {
 			fmt.Printf("  - %f: Warning - Expected %d rows with value %d, got %d\n",
-				sel, rowsForThisSelectivity, rowsForThisSelectivity, actualRowsWithValue)
+				sel, rowsForThisSelectivity, value, actualRowsWithValue)
 		} else {
-			fmt.Printf("  - %f: ✅ %d rows set to value %d (verified)\n", sel, rowsForThisSelectivity, rowsForThisSelectivity)
+			fmt.Printf("  - %f: ✅ %d rows set to value %d (verified)\n", sel, rowsForThisSelectivity, value)
 		}
 	}
 
@@ -317,9 +838,10 @@ func adjustSelectivities(c *TiDBClient, tableName string, rowCount int, selectiv
 }
 
 // setupTableWithData creates a table with the standard schema and populates it with data
-func setupTableWithData(c *TiDBClient, tableName string, rowCount int, selectivities []float64) error {
+func setupTableWithData(c *TiDBClient, table TableSpec, selectivities []float64) error {
+	tableName, rowCount := table.Name(), table.RowCount
 	// Check if table already exists with correct row count
-	err := generateTestData(c, tableName, rowCount, selectivities, 500)
+	err := generateTestData(c, table, selectivities, SetupOptions{FillerSize: 500})
 	if err != nil {
 		return fmt.Errorf("failed to populate table %s: %w", tableName, err)
 	}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestLoadBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][2]int
+	err := loadBatches(250, 100, 4, func(start, size int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, [2]int{start, size})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i][0] < batches[j][0] })
+	if len(batches) != 3 || batches[0] != [2]int{0, 100} || batches[1] != [2]int{100, 100} || batches[2] != [2]int{200, 50} {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+
+	failed := errors.New("insert failed")
+	calls := 0
+	err = loadBatches(1000, 10, 1, func(start, size int) error {
+		calls++
+		if start == 30 {
+			return failed
+		}
+		return nil
+	})
+	if !errors.Is(err, failed) || calls != 4 {
+		t.Fatalf("expected the insert error after 4 batches, got %v after %d", err, calls)
+	}
+}
+
+func TestVerifyTableSchema(t *testing.T) {
+	table := TableSpec{RowCount: 1000}
+	actual := &tableSchema{
+		Comment: "calibration v1: filler=100",
+		PKType:  "CLUSTERED",
+		Columns: []string{"column id int NOT NULL", "column b int", "column c varchar(256)"},
+		Indexes: []string{"index b (b)", "unique index PRIMARY (id)"},
+	}
+	diff, err := verifyTableSchema(table, 100, actual)
+	if err != nil || len(diff) != 0 {
+		t.Fatalf("expected no diff, got %v, %v", diff, err)
+	}
+	diff, _ = verifyTableSchema(table, 1000, actual)
+	want := []string{"- comment 'calibration v1: filler=1000'", "+ comment 'calibration v1: filler=100'", "- column c varchar(2048)", "+ column c varchar(256)"}
+	if !slices.Equal(diff, want) {
+		t.Fatalf("expected %v, got %v", want, diff)
+	}
+	table.Nonclustered = true
+	actual.Indexes = actual.Indexes[1:]
+	actual.Columns = actual.Columns[:2]
+	diff, _ = verifyTableSchema(table, 100, actual)
+	want = []string{"- primary key NONCLUSTERED", "+ primary key CLUSTERED", "- column c varchar(256)", "- index b (b)"}
+	if !slices.Equal(diff, want) {
+		t.Fatalf("expected %v, got %v", want, diff)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DoubleRead is the double read of an IndexLookUp: the index rows scanned on
+// the build side, the handles looked up in the table on the probe side, and
+// the wall time of the probe side
+type DoubleRead struct {
+	IndexRows int64
+	Lookups   int64
+	ProbeTime time.Duration
+}
+
+// Ratio returns the handle lookups per scanned index row, below 1 if an index
+// side filter drops rows before the lookups
+func (d DoubleRead) Ratio() float64 {
+	if d.IndexRows == 0 {
+		return 0
+	}
+	return float64(d.Lookups) / float64(d.IndexRows)
+}
+
+// PerLookup returns the measured probe side time per handle lookup
+func (d DoubleRead) PerLookup() time.Duration {
+	if d.Lookups == 0 {
+		return 0
+	}
+	return d.ProbeTime / time.Duration(d.Lookups)
+}
+
+// doubleRead returns the double read of the IndexLookUp operators of an
+// executed plan, false if it has none with execution info
+func doubleRead(plan *ExecutionPlan) (DoubleRead, bool) {
+	var d DoubleRead
+	found := false
+	lookupDepth := -1
+	build := false
+	for p := plan; p != nil; p = p.Next {
+		depth := planDepth(p.ID)
+		name := strings.TrimLeft(p.ID, " │├└─")
+		if lookupDepth >= 0 && depth <= lookupDepth {
+			lookupDepth = -1
+		}
+		if strings.HasPrefix(name, "IndexLookUp") {
+			lookupDepth = depth
+			continue
+		}
+		if lookupDepth < 0 {
+			continue
+		}
+		if depth == lookupDepth+1 {
+			build = strings.Contains(name, "(Build)")
+			if strings.Contains(name, "(Probe)") {
+				if t, ok := operatorTime(p); ok {
+					d.ProbeTime += t
+					found = true
+				}
+			}
+		}
+		switch {
+		case build && strings.HasPrefix(name, "Index") && strings.Contains(name, "Scan"):
+			d.IndexRows += p.ActRows
+		case !build && strings.HasPrefix(name, "TableRowIDScan"):
+			d.Lookups += p.ActRows
+		}
+	}
+	return d, found
+}
+
+// outputDoubleReadReport reports per scenario the handle lookups per scanned
+// index row of the IndexLookUp plans and the measured time per lookup, and
+// over all scenarios the time per lookup, the empirical constant that sets
+// the index lookup vs table scan crossover
+func outputDoubleReadReport(results []*TestExecutionResult) {
+	fmt.Println("\n🔁 Index Lookup Double Read")
+	fmt.Println("====================")
+	scenarioIDs, groups := groupByScenario(results)
+	var total DoubleRead
+	header := false
+	for _, scenarioID := range scenarioIDs {
+		for _, pt := range sortedPlanTypes(groups[scenarioID]) {
+			if !strings.Contains(pt, "index_lookup") {
+				continue
+			}
+			var sum DoubleRead
+			n := 0
+			for _, r := range groups[scenarioID][pt] {
+				d, ok := doubleRead(r.Plan)
+				if !ok {
+					continue
+				}
+				n++
+				sum.IndexRows += d.IndexRows
+				sum.Lookups += d.Lookups
+				sum.ProbeTime += d.ProbeTime
+			}
+			if n == 0 {
+				continue
+			}
+			if !header {
+				fmt.Printf("Scenario\tPlan\tSamples\tindex_rows-avg\tlookups-avg\tlookup_ratio\tprobe_ms-avg\tus_per_lookup\n")
+				header = true
+			}
+			fmt.Printf("%s\t%s\t%d\t%.01f\t%.01f\t%.03f\t%.03f\t%.02f\n", scenarioID, pt, n,
+				float64(sum.IndexRows)/float64(n), float64(sum.Lookups)/float64(n), sum.Ratio(),
+				float64(sum.ProbeTime.Microseconds())/1000/float64(n), float64(sum.PerLookup().Nanoseconds())/1000)
+			total.IndexRows += sum.IndexRows
+			total.Lookups += sum.Lookups
+			total.ProbeTime += sum.ProbeTime
+		}
+	}
+	if !header {
+		fmt.Println("No executed IndexLookUp plans with execution info")
+		return
+	}
+	fmt.Printf("Overall: %.03f lookups per index row, %.02f us per lookup\n",
+		total.Ratio(), float64(total.PerLookup().Nanoseconds())/1000)
+}
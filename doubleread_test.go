@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDoubleRead(t *testing.T) {
+	plan := &ExecutionPlan{ID: "IndexLookUp_10", Task: "root", ExecutionInfo: "time:3.1ms, loops:2, index_task: {total_time: 1.3ms}",
+		Next: &ExecutionPlan{ID: "├─Selection_9(Build)", Task: "cop[tikv]", ActRows: 50, ExecutionInfo: "time:1.2ms, loops:3, cop_task: {num: 1}",
+			Next: &ExecutionPlan{ID: "│ └─IndexRangeScan_8", Task: "cop[tikv]", ActRows: 200, ExecutionInfo: "tikv_task:{time:0s, loops:3}",
+				Next: &ExecutionPlan{ID: "└─TableRowIDScan_7(Probe)", Task: "cop[tikv]", ActRows: 50, ExecutionInfo: "time:2ms, loops:2, cop_task: {num: 3}"}}}}
+	d, ok := doubleRead(plan)
+	if !ok {
+		t.Fatalf("expected a double read")
+	}
+	if d.IndexRows != 200 || d.Lookups != 50 || d.ProbeTime != 2*time.Millisecond {
+		t.Fatalf("unexpected double read %+v", d)
+	}
+	if d.Ratio() != 0.25 || d.PerLookup() != 40*time.Microsecond {
+		t.Fatalf("unexpected ratio %f or time per lookup %v", d.Ratio(), d.PerLookup())
+	}
+	scan := &ExecutionPlan{ID: "TableReader_7", ExecutionInfo: "time:1ms, cop_task: {num: 1}",
+		Next: &ExecutionPlan{ID: "└─TableFullScan_6", ActRows: 1000}}
+	if _, ok = doubleRead(scan); ok {
+		t.Fatalf("expected no double read for a table scan")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecInfo is the decoded execution info of an executed operator. The root
+// side operators report their time and loops, the readers their cop tasks and
+// RPCs, and the cop side operators their tikv_task and scan_detail.
+type ExecInfo struct {
+	Time  time.Duration `json:"time,omitempty"`
+	Loops int           `json:"loops,omitempty"`
+	// CopTasks is the number of cop tasks, with the time of the slowest, and
+	// CopProcKeys the keys they processed
+	CopTasks    int           `json:"cop_tasks,omitempty"`
+	CopMaxTime  time.Duration `json:"cop_max_time,omitempty"`
+	CopProcKeys int64         `json:"cop_proc_keys,omitempty"`
+	// CoprCacheHitRatio is the ratio of the cop tasks served from the coprocessor cache
+	CoprCacheHitRatio float64 `json:"copr_cache_hit_ratio,omitempty"`
+	// RPCs is the number of cop requests sent, and RPCTime their total time
+	RPCs    int           `json:"rpcs,omitempty"`
+	RPCTime time.Duration `json:"rpc_time,omitempty"`
+	// TiKVTime and TiKVLoops are the time and loops of the operator in TiKV
+	TiKVTime  time.Duration `json:"tikv_time,omitempty"`
+	TiKVLoops int           `json:"tikv_loops,omitempty"`
+	// TotalKeys are the keys scanned by TiKV, including the deleted versions
+	// skipped, ProcessedKeys the live keys of them, of ProcessedKeysSize bytes
+	TotalKeys         int64 `json:"total_keys,omitempty"`
+	ProcessedKeys     int64 `json:"processed_keys,omitempty"`
+	ProcessedKeysSize int64 `json:"processed_keys_size,omitempty"`
+	// Backoff is the time TiDB backed off retrying the cop requests, like on a
+	// region miss, included in the operator time
+	Backoff time.Duration `json:"backoff,omitempty"`
+	// CopConcurrency is the most cop tasks the reader sent concurrently, its max_distsql_concurrency
+	CopConcurrency int `json:"cop_concurrency,omitempty"`
+}
+
+// execInfo returns the decoded execution info of the operator, decoding it
+// on first use for the plans read from a result file, nil if it has none
+func (p *ExecutionPlan) execInfo() *ExecInfo {
+	if p.Exec == nil {
+		p.Exec = parseExecutionInfo(p.ExecutionInfo)
+	}
+	return p.Exec
+}
+
+// flattenExecutionInfo returns the values of an execution info string, like
+// "time:1ms, loops:2, cop_task: {num: 1, max: 1ms}", by their dotted path,
+// like time, loops, cop_task.num and cop_task.max. The first of repeated keys wins.
+func flattenExecutionInfo(s string) map[string]string {
+	values := make(map[string]string)
+	flattenExecutionInfoAt(s, 0, "", values)
+	return values
+}
+
+// flattenExecutionInfoAt adds the key/value pairs of s from position i, up to
+// the closing brace of the current level, with the prefix to their keys, and
+// returns the position after them
+func flattenExecutionInfoAt(s string, i int, prefix string, values map[string]string) int {
+	for i < len(s) {
+		switch s[i] {
+		case ' ', ',':
+			i++
+			continue
+		case '}':
+			return i + 1
+		}
+		end := strings.IndexAny(s[i:], ":,{}")
+		if end < 0 {
+			return len(s)
+		}
+		key := strings.TrimSpace(s[i : i+end])
+		i += end
+		if s[i] != ':' {
+			// A key without a colon has no value, only nested values
+			if s[i] == '{' {
+				i = flattenExecutionInfoAt(s, i+1, prefix+key+".", values)
+			}
+			continue
+		}
+		i++
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i < len(s) && s[i] == '{' {
+			i = flattenExecutionInfoAt(s, i+1, prefix+key+".", values)
+			continue
+		}
+		end = strings.IndexAny(s[i:], ",}")
+		if end < 0 {
+			end = len(s) - i
+		}
+		if _, ok := values[prefix+key]; !ok {
+			values[prefix+key] = strings.TrimSpace(s[i : i+end])
+		}
+		i += end
+	}
+	return i
+}
+
+// parseExecutionInfo decodes the execution info of an operator, nil if it has none
+func parseExecutionInfo(s string) *ExecInfo {
+	if s == "" {
+		return nil
+	}
+	values := flattenExecutionInfo(s)
+	duration := func(keys ...string) time.Duration {
+		for _, key := range keys {
+			if d, err := time.ParseDuration(values[key]); err == nil {
+				return d
+			}
+		}
+		return 0
+	}
+	integer := func(keys ...string) int64 {
+		for _, key := range keys {
+			if n, err := strconv.ParseInt(values[key], 10, 64); err == nil {
+				return n
+			}
+		}
+		return 0
+	}
+	ratio, _ := strconv.ParseFloat(values["cop_task.copr_cache_hit_ratio"], 64)
+	// The backoffs are listed per kind, like backoff{regionMiss: 2ms, tikvRPC: 5ms}
+	var backoff time.Duration
+	for key, value := range values {
+		if !strings.HasPrefix(key, "backoff.") && !strings.Contains(key, ".backoff.") || strings.HasSuffix(key, ".total") {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil {
+			backoff += d
+		}
+	}
+	return &ExecInfo{
+		Time:              duration("time"),
+		Loops:             int(integer("loops")),
+		CopTasks:          int(integer("cop_task.num")),
+		CopMaxTime:        duration("cop_task.max"),
+		CopProcKeys:       integer("cop_task.proc_keys", "cop_task.max_proc_keys"),
+		CoprCacheHitRatio: ratio,
+		// Older versions report the RPCs in cop_task, newer ones in rpc_info
+		RPCs:              int(integer("rpc_info.Cop.num_rpc", "cop_task.rpc_num")),
+		RPCTime:           duration("rpc_info.Cop.total_time", "cop_task.rpc_time"),
+		TiKVTime:          duration("tikv_task.time", "tikv_task.proc max"),
+		TiKVLoops:         int(integer("tikv_task.loops", "tikv_task.iters")),
+		TotalKeys:         integer("scan_detail.total_keys"),
+		ProcessedKeys:     integer("scan_detail.total_process_keys"),
+		ProcessedKeysSize: integer("scan_detail.total_process_keys_size"),
+		Backoff:           backoff,
+		CopConcurrency:    int(integer("cop_task.max_distsql_concurrency")),
+	}
+}
+
+// copRetries returns the cop requests TiDB sent again in an executed plan,
+// the RPCs beyond one per cop task, summed over its operators. A backoff
+// alone, like waiting for a lock to be resolved, is not a retry.
+func copRetries(plan *ExecutionPlan) int {
+	var retries int
+	for p := plan; p != nil; p = p.Next {
+		if exec := p.execInfo(); exec != nil && exec.RPCs > exec.CopTasks {
+			retries += exec.RPCs - exec.CopTasks
+		}
+	}
+	return retries
+}
+
+// scannedKeys returns the keys scanned and processed by TiKV for an executed
+// plan, summed over its operators
+func scannedKeys(plan *ExecutionPlan) (total, processed int64) {
+	for p := plan; p != nil; p = p.Next {
+		exec := p.execInfo()
+		if exec == nil {
+			continue
+		}
+		total += exec.TotalKeys
+		processed += exec.ProcessedKeys
+	}
+	return total, processed
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecutionInfo(t *testing.T) {
+	reader := parseExecutionInfo("time:1.5ms, loops:2, cop_task: {num: 3, max: 1.1ms, proc_keys: 100, tot_proc: 200µs, copr_cache_hit_ratio: 0.00, max_distsql_concurrency: 3}, rpc_info:{Cop:{num_rpc:3, total_time:3ms}}")
+	want := ExecInfo{Time: 1500 * time.Microsecond, Loops: 2, CopTasks: 3, CopMaxTime: 1100 * time.Microsecond,
+		CopProcKeys: 100, RPCs: 3, RPCTime: 3 * time.Millisecond, CopConcurrency: 3}
+	if *reader != want {
+		t.Errorf("reader: got %+v, want %+v", *reader, want)
+	}
+	scan := parseExecutionInfo("tikv_task:{time:4ms, loops:5}, scan_detail: {total_process_keys: 1000, total_process_keys_size: 131072, total_keys: 1201, get_snapshot_time: 10µs, rocksdb: {key_skipped_count: 1200, block: {cache_hit_count: 3}}}")
+	want = ExecInfo{TiKVTime: 4 * time.Millisecond, TiKVLoops: 5, TotalKeys: 1201, ProcessedKeys: 1000, ProcessedKeysSize: 131072}
+	if *scan != want {
+		t.Errorf("scan: got %+v, want %+v", *scan, want)
+	}
+	old := parseExecutionInfo("time:10ms, loops:2, cop_task: {num: 2, max: 5ms, proc_keys: 1000, rpc_num: 2, rpc_time: 9ms}")
+	if old.RPCs != 2 || old.RPCTime != 9*time.Millisecond {
+		t.Errorf("expected the RPCs of cop_task, got %+v", *old)
+	}
+	retried := parseExecutionInfo("time:12ms, loops:2, cop_task: {num: 2, max: 10ms, proc_keys: 100}, backoff{regionMiss: 2ms, tikvRPC: 5ms}, rpc_info:{Cop:{num_rpc:3, total_time:9ms}}")
+	if retried.Backoff != 7*time.Millisecond || retried.RPCs != 3 {
+		t.Errorf("expected 7ms of backoff, got %+v", *retried)
+	}
+	if got := copRetries(&ExecutionPlan{Exec: retried, Next: &ExecutionPlan{Exec: reader}}); got != 1 {
+		t.Errorf("expected 1 retried cop request, got %d", got)
+	}
+	waited := parseExecutionInfo("time:12ms, loops:2, cop_task: {num: 2, max: 10ms}, backoff{txnLockFast: 5ms}, rpc_info:{Cop:{num_rpc:2, total_time:9ms}}")
+	if got := copRetries(&ExecutionPlan{Exec: waited}); got != 0 {
+		t.Errorf("expected a backoff without a resent request not to be a retry, got %d", got)
+	}
+	if parseExecutionInfo("") != nil {
+		t.Errorf("expected no execution info")
+	}
+}
+
+func TestScannedKeys(t *testing.T) {
+	plan := &ExecutionPlan{ID: "IndexLookUp_10", Exec: parseExecutionInfo("time:3.1ms, loops:2"),
+		Next: &ExecutionPlan{ID: "├─IndexRangeScan_8(Build)", Exec: parseExecutionInfo("tikv_task:{time:0s, loops:3}, scan_detail: {total_process_keys: 100, total_keys: 101}"),
+			Next: &ExecutionPlan{ID: "└─TableRowIDScan_9(Probe)", Exec: parseExecutionInfo("tikv_task:{time:0s, loops:3}, scan_detail: {total_process_keys: 100, total_keys: 150}")}}}
+	if total, processed := scannedKeys(plan); total != 251 || processed != 200 {
+		t.Errorf("got %d total and %d processed keys", total, processed)
+	}
+}
+
+func TestExecInfoNotStored(t *testing.T) {
+	info := "time:3.1ms, loops:2, cop_task: {num: 4, max: 1ms}"
+	data, err := json.Marshal(&ExecutionPlan{ID: "TableReader_5", ExecutionInfo: info, Exec: parseExecutionInfo(info)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan ExecutionPlan
+	if err = json.Unmarshal(data, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Exec != nil || strings.Contains(string(data), `"exec"`) {
+		t.Fatalf("expected only the raw execution info in the JSON, got %s", data)
+	}
+	// Decoded from the raw execution info when read back
+	if tasks, _ := copParallelism(&plan); tasks != 4 || plan.execInfo().Time != 3100*time.Microsecond {
+		t.Errorf("expected the execution info decoded, got %+v", plan.Exec)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// runExportData implements the export-data command, writing the DDL and a
+// sampled CSV of a test table, so a scenario can be reproduced on another
+// cluster without running the full data generator
+func runExportData(args []string) error {
+	fs := flag.NewFlagSet("export-data", flag.ExitOnError)
+	var logLevel = fs.String("l", "info", "Log level: debug, info, warn, error")
+	var table = fs.String("table", "", "Table to export (e.g. t1M)")
+	var sample = fs.String("sample", "1%", "Fraction of rows to export, as percentage (1%) or ratio (0.01)")
+	var output = fs.String("o", "", "Output file prefix, writes <prefix>.sql and <prefix>.csv (default: table name)")
+	var anonymize = fs.Bool("anonymize", false, "Hash the table, column and index names in the DDL and CSV header")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupLogging(*logLevel)
+	config, err := connectionConfig()
+	if err != nil {
+		return err
+	}
+
+	if *table == "" {
+		return fmt.Errorf("-table is required")
+	}
+	ratio, err := parseSampleRatio(*sample)
+	if err != nil {
+		return err
+	}
+	prefix := *output
+	if prefix == "" {
+		prefix = *table
+		if *anonymize {
+			prefix = anonymizedName("t_", *table)
+		}
+	}
+
+	c := NewTiDBClient()
+	if err = c.Connect(config); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var name, ddl string
+	slog.Debug("Executing query", "query", "SHOW CREATE TABLE "+*table)
+	if err = c.db.QueryRow("SHOW CREATE TABLE "+*table).Scan(&name, &ddl); err != nil {
+		return fmt.Errorf("failed to get DDL for %s: %w", *table, err)
+	}
+	if *anonymize {
+		ddl = anonymizeDDL(ddl, *table)
+	}
+	if err = os.WriteFile(prefix+".sql", []byte(ddl+";\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write DDL: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s", *table)
+	if ratio < 1.0 {
+		query += fmt.Sprintf(" WHERE RAND() < %f", ratio)
+	}
+	rows, err := c.ExecuteQuery(query)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *table, err)
+	}
+	defer rows.Close()
+
+	f, err := os.Create(prefix + ".csv")
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer f.Close()
+	count, err := writeRowsAsCSV(f, rows, *anonymize)
+	if err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	fmt.Printf("✅ Exported DDL to %s.sql and %d sampled rows (%.4g%%) to %s.csv\n", prefix, count, ratio*100, prefix)
+	return nil
+}
+
+// parseSampleRatio parses a sample size given as percentage ("1%") or ratio ("0.01")
+func parseSampleRatio(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	divisor := 1.0
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSuffix(s, "%")
+		divisor = 100.0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample size '%s': %w", s, err)
+	}
+	ratio := v / divisor
+	if ratio <= 0.0 || ratio > 1.0 {
+		return 0, fmt.Errorf("sample size must be between 0 and 100%%, got %s", s)
+	}
+	return ratio, nil
+}
+
+// writeRowsAsCSV writes all rows, with a header of the column names, returning the number of rows written.
+// With anonymize the header has the hashed column names.
+func writeRowsAsCSV(f *os.File, rows *sql.Rows, anonymize bool) (int, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	header := slices.Clone(columns)
+	if anonymize {
+		for i, column := range header {
+			header[i] = anonymizedName("col_", column)
+		}
+	}
+	w := csv.NewWriter(f)
+	if err = w.Write(header); err != nil {
+		return 0, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	count := 0
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return count, err
+		}
+		for i, v := range values {
+			// Use the LOAD DATA convention for NULL
+			record[i] = `\N`
+			if v.Valid {
+				record[i] = v.String
+			}
+		}
+		if err = w.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return count, err
+	}
+	w.Flush()
+	return count, w.Error()
+}
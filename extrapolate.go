@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// z95 is the two-sided 95% normal quantile used for the confidence bands
+const z95 = 1.96
+
+// costModel is a linear model of the latency (ms) of one plan type:
+// latency = b0 + b1 * tableRows + b2 * matchingRows, fitted by least squares
+type costModel struct {
+	PlanType string
+	Samples  int
+	Coef     [3]float64
+	// cov is the covariance matrix of the coefficients
+	cov [3][3]float64
+}
+
+// fitCostModel fits the latency model of one plan type from its samples
+func fitCostModel(planType string, results []*TestExecutionResult) (*costModel, error) {
+	if len(results) < 4 {
+		return nil, fmt.Errorf("too few samples (%d) for %s", len(results), planType)
+	}
+	var xtx [3][3]float64
+	var xty [3]float64
+	for _, r := range results {
+		x := [3]float64{1, float64(r.RowCount), float64(r.MatchingRows)}
+		y := float64(r.Plan.ExecutionTime) / float64(time.Millisecond)
+		for i := range 3 {
+			for j := range 3 {
+				xtx[i][j] += x[i] * x[j]
+			}
+			xty[i] += x[i] * y
+		}
+	}
+	inv, ok := invert3(xtx)
+	if !ok {
+		return nil, fmt.Errorf("cannot fit %s, the samples need at least two table sizes and two selectivities", planType)
+	}
+	m := &costModel{PlanType: planType, Samples: len(results)}
+	for i := range 3 {
+		for j := range 3 {
+			m.Coef[i] += inv[i][j] * xty[j]
+		}
+	}
+	var rss float64
+	for _, r := range results {
+		y := float64(r.Plan.ExecutionTime) / float64(time.Millisecond)
+		residual := y - m.predict(float64(r.RowCount), float64(r.MatchingRows))
+		rss += residual * residual
+	}
+	variance := rss / float64(len(results)-3)
+	for i := range 3 {
+		for j := range 3 {
+			m.cov[i][j] = inv[i][j] * variance
+		}
+	}
+	return m, nil
+}
+
+// predict returns the modelled latency in ms
+func (m *costModel) predict(tableRows, matchingRows float64) float64 {
+	return m.Coef[0] + m.Coef[1]*tableRows + m.Coef[2]*matchingRows
+}
+
+// stdErr returns the standard error of the predicted mean latency
+func (m *costModel) stdErr(tableRows, matchingRows float64) float64 {
+	x := [3]float64{1, tableRows, matchingRows}
+	var v float64
+	for i := range 3 {
+		for j := range 3 {
+			v += x[i] * m.cov[i][j] * x[j]
+		}
+	}
+	return math.Sqrt(max(v, 0))
+}
+
+// invert3 inverts a 3x3 matrix, returning false if it is (nearly) singular
+func invert3(a [3][3]float64) ([3][3]float64, bool) {
+	var inv [3][3]float64
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+	scale := math.Abs(a[0][0] * a[1][1] * a[2][2])
+	if det == 0 || math.Abs(det) < 1e-12*scale {
+		return inv, false
+	}
+	inv[0][0] = (a[1][1]*a[2][2] - a[1][2]*a[2][1]) / det
+	inv[0][1] = (a[0][2]*a[2][1] - a[0][1]*a[2][2]) / det
+	inv[0][2] = (a[0][1]*a[1][2] - a[0][2]*a[1][1]) / det
+	inv[1][0] = (a[1][2]*a[2][0] - a[1][0]*a[2][2]) / det
+	inv[1][1] = (a[0][0]*a[2][2] - a[0][2]*a[2][0]) / det
+	inv[1][2] = (a[0][2]*a[1][0] - a[0][0]*a[1][2]) / det
+	inv[2][0] = (a[1][0]*a[2][1] - a[1][1]*a[2][0]) / det
+	inv[2][1] = (a[0][1]*a[2][0] - a[0][0]*a[2][1]) / det
+	inv[2][2] = (a[0][0]*a[1][1] - a[0][1]*a[1][0]) / det
+	return inv, true
+}
+
+// crossover finds the number of matching rows at which the latency difference
+// a - b, offset by k standard errors, changes sign, by bisection over [1, tableRows].
+// Returns -1 if there is no sign change in the range.
+func crossover(a, b *costModel, tableRows, k float64) float64 {
+	diff := func(m float64) float64 {
+		se := math.Sqrt(math.Pow(a.stdErr(tableRows, m), 2) + math.Pow(b.stdErr(tableRows, m), 2))
+		return a.predict(tableRows, m) - b.predict(tableRows, m) + k*se
+	}
+	lo, hi := 1.0, tableRows
+	dLo, dHi := diff(lo), diff(hi)
+	if dLo*dHi > 0 {
+		return -1
+	}
+	for range 100 {
+		mid := math.Sqrt(lo * hi)
+		dMid := diff(mid)
+		if dMid*dLo > 0 {
+			lo, dLo = mid, dMid
+		} else {
+			hi = mid
+		}
+	}
+	return math.Sqrt(lo * hi)
+}
+
+// outputExtrapolation fits a latency model per plan type over the measured
+// table sizes, and extrapolates it to larger, not loaded, table sizes
+func outputExtrapolation(results []*TestExecutionResult, targetRowCounts []int, selectivities []float64) {
+	fmt.Println("\n🔭 Extrapolation To Larger Tables")
+	fmt.Println("====================")
+
+	samples := make(map[string][]*TestExecutionResult)
+	maxRows := 0
+	for _, r := range results {
+		if r.ExplainOnly || r.Plan == nil {
+			continue
+		}
+		samples[r.PlanType] = append(samples[r.PlanType], r)
+		maxRows = max(maxRows, r.RowCount)
+	}
+	planTypes := make([]string, 0, len(samples))
+	for pt := range samples {
+		planTypes = append(planTypes, pt)
+	}
+	sort.Strings(planTypes)
+
+	models := make(map[string]*costModel)
+	fmt.Printf("Plan\tSamples\tms_fixed\tms_per_1M_table_rows\tms_per_1K_matching_rows\n")
+	for _, pt := range planTypes {
+		m, err := fitCostModel(pt, samples[pt])
+		if err != nil {
+			fmt.Printf("%s\t%d\t(%v)\n", pt, len(samples[pt]), err)
+			continue
+		}
+		models[pt] = m
+		fmt.Printf("%s\t%d\t%.03f\t%.03f\t%.03f\n", pt, m.Samples, m.Coef[0], m.Coef[1]*1e6, m.Coef[2]*1e3)
+	}
+
+	fmt.Printf("\nTable_size\tCardinality\tPlan\tms_predicted\tms_95_low\tms_95_high\n")
+	for _, rows := range targetRowCounts {
+		for _, sel := range selectivities {
+			matching := float64(GetNumRows(rows, sel))
+			for _, pt := range planTypes {
+				m, ok := models[pt]
+				if !ok {
+					continue
+				}
+				pred, se := m.predict(float64(rows), matching), m.stdErr(float64(rows), matching)
+				fmt.Printf("%s\t%d\t%s\t%.03f\t%.03f\t%.03f\n", formatRowCountName(rows), int(matching), pt,
+					pred, max(pred-z95*se, 0), pred+z95*se)
+			}
+		}
+	}
+
+	index, scan := models["index_lookup"], models["table_scan"]
+	if index != nil && scan != nil {
+		fmt.Printf("\nTable_size\tCrossover_selectivity\tCrossover_95_low\tCrossover_95_high\n")
+		for _, rows := range targetRowCounts {
+			n := float64(rows)
+			formatSel := func(m float64) string {
+				if m < 0 {
+					return "n/a"
+				}
+				return fmt.Sprintf("%.6f", m/n)
+			}
+			// The band edges are where one plan becomes significantly faster than the other
+			fmt.Printf("%s\t%s\t%s\t%s\n", formatRowCountName(rows), formatSel(crossover(index, scan, n, 0)),
+				formatSel(crossover(index, scan, n, z95)), formatSel(crossover(index, scan, n, -z95)))
+		}
+	}
+
+	fmt.Printf("\n⚠️  Disclaimer: extrapolated beyond the largest measured table (%s rows), assuming latency\n", formatRowCountName(maxRows))
+	fmt.Println("   scales linearly with table and result size on a cluster of the same size and topology.")
+	fmt.Println("   Larger tables span more regions and stores, which changes scan parallelism and cache")
+	fmt.Println("   hit rates; treat the predictions as rough estimates, not measurements.")
+}
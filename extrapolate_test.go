@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitCostModelAndCrossover(t *testing.T) {
+	var index, scan []*TestExecutionResult
+	// index: 0.5ms + 0.01ms per matching row, scan: 1ms + 0.001ms per table row
+	for _, rows := range []int{1000, 10000, 100000} {
+		for _, matching := range []int{10, 100, 1000} {
+			r := newTestResult("x", "index_lookup", 0.5+0.01*float64(matching))
+			r.RowCount, r.MatchingRows = rows, matching
+			index = append(index, r)
+			r = newTestResult("x", "table_scan", 1+0.001*float64(rows))
+			r.RowCount, r.MatchingRows = rows, matching
+			scan = append(scan, r)
+		}
+	}
+	indexModel, err := fitCostModel("index_lookup", index)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scanModel, err := fitCostModel("table_scan", scan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(indexModel.Coef[2]-0.01) > 1e-6 || math.Abs(scanModel.Coef[1]-0.001) > 1e-6 {
+		t.Fatalf("unexpected coefficients: %v %v", indexModel.Coef, scanModel.Coef)
+	}
+	// At 1M rows the scan takes 1001ms, the index lookup is as slow at 100050 matching rows
+	m := crossover(indexModel, scanModel, 1e6, 0)
+	if math.Abs(m-100050) > 1 {
+		t.Fatalf("expected crossover at 100050 matching rows, got %f", m)
+	}
+
+	if _, err = fitCostModel("index_lookup", index[:3]); err == nil {
+		t.Fatalf("expected error for a single table size")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	// pingsPerProbe is the number of SELECT 1 round trips per latency floor probe
+	pingsPerProbe = 5
+	// minFloorDrift ignores drift below this, to not react to sub-millisecond noise
+	minFloorDrift = 200 * time.Microsecond
+)
+
+// FloorSample is one measurement of the latency floor
+type FloorSample struct {
+	Time    time.Time     `json:"time"`
+	Latency time.Duration `json:"latency"`
+}
+
+// LatencyFloor periodically measures the round trip latency floor with a
+// trivial SELECT 1 ping-pong between samples. If the floor drifts from its
+// baseline (network or VM noise), the samples taken meanwhile are annotated.
+type LatencyFloor struct {
+	// Every is the number of executions between probes
+	Every int
+	// DriftFactor is how much above the baseline the floor may be before it is considered drifted
+	DriftFactor float64
+
+	baseline   time.Duration
+	current    time.Duration
+	executions int
+}
+
+// MaybeProbe measures the floor if it is due, and returns the current floor
+// and whether it has drifted from the baseline
+func (f *LatencyFloor) MaybeProbe(c *TiDBClient, meta *RunMetadata) (time.Duration, bool) {
+	if f.executions%f.Every == 0 {
+		latency, err := c.PingLatency(pingsPerProbe)
+		if err != nil {
+			slog.Warn("Failed to probe latency floor", "error", err)
+		} else {
+			f.current = latency
+			if f.baseline == 0 || latency < f.baseline {
+				f.baseline = latency
+			}
+			if meta != nil {
+				meta.LatencyFloor = append(meta.LatencyFloor, FloorSample{Time: time.Now(), Latency: latency})
+			}
+			slog.Debug("Latency floor", "current", f.current, "baseline", f.baseline)
+		}
+	}
+	f.executions++
+	return f.current, f.Drifted()
+}
+
+// Drifted returns true if the last measured floor is too far above the baseline
+func (f *LatencyFloor) Drifted() bool {
+	return f.current-f.baseline > minFloorDrift && float64(f.current) > float64(f.baseline)*f.DriftFactor
+}
+
+// PingLatency returns the median latency of n SELECT 1 round trips on the query connection
+func (c *TiDBClient) PingLatency(n int) (time.Duration, error) {
+	latencies, err := c.PingLatencies(n)
+	if err != nil {
+		return 0, err
+	}
+	slices.Sort(latencies)
+	return latencies[len(latencies)/2], nil
+}
+
+// PingLatencies returns the latencies of n SELECT 1 round trips on the query connection
+func (c *TiDBClient) PingLatencies(n int) ([]time.Duration, error) {
+	if c.db == nil {
+		return nil, fmt.Errorf("database connection not established")
+	}
+	latencies := make([]time.Duration, 0, n)
+	var one int
+	for range n {
+		start := time.Now()
+		if err := c.db.QueryRow("SELECT 1").Scan(&one); err != nil {
+			return nil, fmt.Errorf("failed to ping: %w", err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	return latencies, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// QueryFrequencies are the production execution rates of query shapes, like
+// an export of the statements summary, to weight the calibration by how often
+// each query shape actually runs. The rates are looked up by the statement
+// digest, or by the query shape of the digest text for queries on other
+// tables, whose column names are mapped to the ones of the test tables.
+type QueryFrequencies struct {
+	byDigest map[string]float64
+	byShape  map[string]float64
+}
+
+// columnNameRegex matches the column names of a -query-frequency-columns mapping
+var columnNameRegex = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// frequencyRateColumns are the accepted names of the rate column, in order of
+// preference, exec_count being relative to the summary window
+var frequencyRateColumns = []string{"qps", "exec_count"}
+
+// frequencyTextColumns are the accepted names of the query text column
+var frequencyTextColumns = []string{"digest_text", "query_sample_text", "query"}
+
+// parseColumnMapping parses the -query-frequency-columns value, a
+// comma-separated list of production=test column names, like customer_id=b
+func parseColumnMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, def := range strings.Split(s, ",") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(def, "=")
+		from, to = strings.ToLower(strings.TrimSpace(from)), strings.ToLower(strings.TrimSpace(to))
+		if !ok || !columnNameRegex.MatchString(from) || !columnNameRegex.MatchString(to) {
+			return nil, fmt.Errorf("invalid column mapping '%s', expected <production column>=<test column>", def)
+		}
+		if _, dup := mapping[from]; dup {
+			return nil, fmt.Errorf("duplicate column mapping of '%s'", from)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
+
+// mapColumns renames the columns of a production query to the test table
+// columns of the mapping, all at once so a mapping like a=b,b=c does not chain
+func mapColumns(query string, mapping map[string]string) string {
+	if len(mapping) == 0 {
+		return query
+	}
+	names := make([]string, 0, len(mapping))
+	for from := range mapping {
+		names = append(names, regexp.QuoteMeta(from))
+	}
+	re := regexp.MustCompile("(?i)`?\\b(" + strings.Join(names, "|") + ")\\b`?")
+	return re.ReplaceAllStringFunc(query, func(name string) string {
+		return mapping[strings.ToLower(strings.Trim(name, "`"))]
+	})
+}
+
+// loadQueryFrequencies reads a CSV file with a header naming the digest
+// column, a rate column (qps or exec_count) and optionally a query text column
+// (digest_text, query_sample_text or query), case insensitive, like
+// SELECT DIGEST, DIGEST_TEXT, EXEC_COUNT FROM information_schema.statements_summary.
+// The columns of the query texts are renamed by the column mapping.
+func loadQueryFrequencies(path string, columns map[string]string) (*QueryFrequencies, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query frequency file %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty query frequency file %s", path)
+	}
+	column := func(names []string) int {
+		for _, name := range names {
+			for i, h := range records[0] {
+				if strings.EqualFold(strings.TrimSpace(h), name) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	digestCol, rateCol, textCol := column([]string{"digest"}), column(frequencyRateColumns), column(frequencyTextColumns)
+	if rateCol < 0 || (digestCol < 0 && textCol < 0) {
+		return nil, fmt.Errorf("query frequency file %s: expected a header with a digest or query text column, and a %s column",
+			path, strings.Join(frequencyRateColumns, " or "))
+	}
+	freqs := &QueryFrequencies{byDigest: make(map[string]float64), byShape: make(map[string]float64)}
+	for line, record := range records[1:] {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[rateCol]), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("query frequency file %s, line %d: invalid rate '%s'", path, line+2, record[rateCol])
+		}
+		// A shape can have several digests, like from different schemas, so the rates add up
+		if digestCol >= 0 && record[digestCol] != "" {
+			freqs.byDigest[strings.TrimSpace(record[digestCol])] += rate
+		}
+		if textCol >= 0 && record[textCol] != "" {
+			freqs.byShape[queryShape(mapColumns(record[textCol], columns))] += rate
+		}
+	}
+	return freqs, nil
+}
+
+// lookup returns the rate of a query, by its digest or else its shape
+func (f *QueryFrequencies) lookup(digest, query string) (float64, bool) {
+	if rate, ok := f.byDigest[digest]; ok && digest != "" {
+		return rate, true
+	}
+	rate, ok := f.byShape[queryShape(query)]
+	return rate, ok
+}
+
+// applyQueryFrequencies sets the production rate of the cells from their
+// unhinted query, and returns how many cells were matched
+func applyQueryFrequencies(cells []*CellAnalysis, results []*TestExecutionResult, f *QueryFrequencies) int {
+	explained := make(map[string]*TestExecutionResult)
+	for _, r := range results {
+		if r.ExplainOnly {
+			explained[r.ScenarioID] = r
+		}
+	}
+	matched := 0
+	for _, cell := range cells {
+		cell.Frequency = 0
+		r := explained[cell.ScenarioID]
+		if r == nil {
+			continue
+		}
+		if rate, ok := f.lookup(r.Digest, r.Query); ok && rate > 0 {
+			cell.Frequency = rate
+			matched++
+		}
+	}
+	return matched
+}
+
+// frequencyMistakesShown is the number of mispredicted cells shown by outputFrequencyMistakes
+const frequencyMistakesShown = 20
+
+// outputFrequencyMistakes ranks the mispredicted cells by the production time
+// they cost, the time lost per execution times the rate of the query shape
+func outputFrequencyMistakes(cells []*CellAnalysis, matched int) {
+	fmt.Println("\n🏭 Plan Mistakes by Production Frequency")
+	fmt.Println("====================")
+	fmt.Printf("%d of %d cells matched a production query shape\n", matched, len(cells))
+	var mistakes []*CellAnalysis
+	for _, cell := range cells {
+		if cell.Frequency > 0 && cell.Measured && cell.Chosen != cell.Best {
+			mistakes = append(mistakes, cell)
+		}
+	}
+	if len(mistakes) == 0 {
+		return
+	}
+	cost := func(cell *CellAnalysis) float64 {
+		return cell.Frequency * cell.TimeLost().Seconds() * 1000.0
+	}
+	slices.SortStableFunc(mistakes, func(a, b *CellAnalysis) int {
+		switch {
+		case cost(a) > cost(b):
+			return -1
+		case cost(a) < cost(b):
+			return 1
+		}
+		return strings.Compare(a.ScenarioID, b.ScenarioID)
+	})
+	fmt.Printf("Scenario\tRate\tChosen\tBest\tRegret\tTime_lost_ms\tLost_ms_per_s\n")
+	for _, cell := range mistakes[:min(len(mistakes), frequencyMistakesShown)] {
+		fmt.Printf("%s\t%.03f\t%s\t%s\t%.03f\t%.03f\t%.03f\n", cell.ScenarioID, cell.Frequency, cell.Chosen, cell.Best,
+			cell.Regret(), cell.TimeLost().Seconds()*1000.0, cost(cell))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryFrequencies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frequencies.csv")
+	content := "DIGEST,DIGEST_TEXT,EXEC_COUNT\n" +
+		"d1,select * from `orders` where `b` = ?,300\n" +
+		"d2,select * from `customers` where `b` between ? and ?,100\n" +
+		"d3,select * from `orders_archive` where `b` = ?,100\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	freqs, err := loadQueryFrequencies(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point := newChoiceResult("index_1M_10", "table_scan")
+	point.Query = "SELECT * FROM t1M WHERE b = 10"
+	scan := newChoiceResult("index_1M_500000", "index_lookup")
+	scan.Query = "SELECT * FROM t1M WHERE b = 500000"
+	scan.Digest = "d2"
+	unknown := newChoiceResult("index_1K_10", "table_scan")
+	unknown.Query = "SELECT COUNT(*) FROM t1K"
+	results := []*TestExecutionResult{
+		// Chosen plan is 2x slower, matched by shape to two digests
+		point,
+		newTestResult("index_1M_10", "index_lookup", 2),
+		newTestResult("index_1M_10", "table_scan", 4),
+		// Chosen plan is 4x slower, matched by digest
+		scan,
+		newTestResult("index_1M_500000", "index_lookup", 400),
+		newTestResult("index_1M_500000", "table_scan", 100),
+		// No production frequency
+		unknown,
+		newTestResult("index_1K_10", "table_scan", 1),
+		newTestResult("index_1K_10", "index_lookup", 2),
+	}
+	cells := analyzeCells(results, nil)
+	if matched := applyQueryFrequencies(cells, results, freqs); matched != 2 {
+		t.Fatalf("expected 2 matched cells, got %d", matched)
+	}
+	score := computeCalibrationScore(cells)
+	if score.FrequencyCells != 2 || score.FrequencyAgreement != 0.0 {
+		t.Fatalf("unexpected frequency weighted score: %+v", score)
+	}
+	// The point lookups run 4 times as often as the scans
+	if expected := math.Exp((400*math.Log(2) + 100*math.Log(4)) / 500); math.Abs(score.FrequencyGeoMeanRegret-expected) > 1e-9 {
+		t.Fatalf("expected frequency weighted regret %f, got %f", expected, score.FrequencyGeoMeanRegret)
+	}
+}
+
+func TestLoadQueryFrequenciesInvalid(t *testing.T) {
+	for _, content := range []string{"", "digest,count\nd1,1\n", "digest,qps\nd1,x\n"} {
+		path := filepath.Join(t.TempDir(), "frequencies.csv")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadQueryFrequencies(path, nil); err == nil {
+			t.Fatalf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestQueryFrequencyColumns(t *testing.T) {
+	mapping, err := parseColumnMapping("customer_id=b, Amount=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapColumns("select * from `orders` where `customer_id` = ? and AMOUNT > ?", mapping); got != "select * from `orders` where b = ? and c > ?" {
+		t.Fatalf("unexpected mapped query %s", got)
+	}
+	// The mapping applies at once, b is not renamed again
+	if got := mapColumns("select b, c from t", map[string]string{"b": "c", "c": "b"}); got != "select c, b from t" {
+		t.Fatalf("unexpected mapped query %s", got)
+	}
+	for _, s := range []string{"customer_id", "a=b,a=c", "a-b=c", "=b"} {
+		if _, err = parseColumnMapping(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "frequencies.csv")
+	if err = os.WriteFile(path, []byte("DIGEST_TEXT,QPS\nselect * from `orders` where `customer_id` = ?,50\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	freqs, err := loadQueryFrequencies(path, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate, ok := freqs.lookup("", "SELECT * FROM t1M WHERE b = 10"); !ok || rate != 50 {
+		t.Fatalf("expected the mapped production shape to match the point cell, got %f, %v", rate, ok)
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ColumnGenerator computes the values of a generated column.
+// The data is generated server side with INSERT ... SELECT, so a generator
+// returns a SQL expression for the value, given a SQL expression that
+// evaluates to the 0-based row number of the generated row.
+type ColumnGenerator interface {
+	SQLExpr(rowNum string) string
+}
+
+// columnGenerators holds user supplied generators, overriding the defaults per column
+var columnGenerators = make(map[string]ColumnGenerator)
+
+// RegisterColumnGenerator overrides how the values of a column are generated
+func RegisterColumnGenerator(column string, gen ColumnGenerator) {
+	columnGenerators[strings.ToLower(column)] = gen
+}
+
+// generatorParams returns the -gen generators of the test table columns as
+// recorded in the table comment, like gen:c=3f2a91c0 with a hash of the SQL
+// of the generator, so a table of other generators is not reused. The
+// distribution of b is recorded by TableSpec.comment.
+func generatorParams() string {
+	var params []string
+	for _, column := range slices.Sorted(maps.Keys(columnGenerators)) {
+		gen := columnGenerators[column]
+		if _, ok := gen.(distGenerator); ok && column == "b" {
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(gen.SQLExpr("row")))
+		params = append(params, fmt.Sprintf("gen:%s=%08x", column, h.Sum32()))
+	}
+	return strings.Join(params, " ")
+}
+
+// getColumnGenerator returns the registered generator for the column, or def if none is registered
+func getColumnGenerator(column string, def ColumnGenerator) ColumnGenerator {
+	if gen, ok := columnGenerators[strings.ToLower(column)]; ok {
+		return gen
+	}
+	return def
+}
+
+// randomIntGenerator generates uniformly distributed integers in [0, max)
+type randomIntGenerator struct {
+	max int
+}
+
+func (g randomIntGenerator) SQLExpr(rowNum string) string {
+	return fmt.Sprintf("FLOOR(%s * %d)", sqlRand(rowNum, randomIntStream), g.max)
+}
+
+// fillerGenerator generates random strings of exactly the filler size, a
+// random number of fillerDigits digits repeated to the size
+type fillerGenerator struct {
+	size int
+}
+
+// fillerDigits is the number of digits of the random number repeated in the filler
+const fillerDigits = 9
+
+func (g fillerGenerator) SQLExpr(rowNum string) string {
+	return fmt.Sprintf("RPAD('', %d, LPAD(FLOOR(%s * 1e%d), %d, '0'))", g.size, sqlRand(rowNum, fillerStream), fillerDigits, fillerDigits)
+}
+
+// exprGenerator generates values from an expression in the generator mini-language
+type exprGenerator struct {
+	tokens []exprToken
+}
+
+func (g exprGenerator) SQLExpr(rowNum string) string {
+	var sb strings.Builder
+	for _, tok := range g.tokens {
+		switch {
+		case tok.kind == tokIdent && tok.text == "row":
+			sb.WriteString("(" + rowNum + ")")
+		case tok.kind == tokIdent:
+			sb.WriteString(exprFunctions[tok.text])
+		default:
+			sb.WriteString(tok.text)
+		}
+		if tok.kind == tokComma {
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+// exprFunctions are the functions allowed in generator expressions, mapped to their SQL names
+var exprFunctions = map[string]string{
+	"abs":      "ABS",
+	"ceil":     "CEIL",
+	"crc32":    "CRC32",
+	"exp":      "EXP",
+	"floor":    "FLOOR",
+	"greatest": "GREATEST",
+	"least":    "LEAST",
+	"ln":       "LN",
+	"mod":      "MOD",
+	"pow":      "POW",
+	"rand":     "RAND",
+	"repeat":   "REPEAT",
+	"round":    "ROUND",
+	"sqrt":     "SQRT",
+}
+
+type exprTokenKind int
+
+const (
+	tokNumber exprTokenKind = iota
+	tokIdent
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+// distGenerator generates values from a named random distribution. The
+// random numbers are a hash of the seed and the row number, instead of RAND(),
+// so the same seed gives the same data on every cluster, whatever the batching.
+type distGenerator struct {
+	dist   string
+	params []float64
+	seed   int64
+}
+
+// distDefaults are the distributions and their parameter defaults, NaN for required parameters
+var distDefaults = map[string][]float64{
+	// uniform(max): integers in [0, max)
+	"uniform": {bValueDomain},
+	// zipf(s, n): integers in [1, n], value k with a probability proportional to 1/k^s
+	"zipf": {math.NaN(), bValueDomain},
+	// normal(mean, stddev): rounded normally distributed values
+	"normal": {math.NaN(), math.NaN()},
+}
+
+// uniformExpr returns a SQL expression for a random number in (0, 1) for the row
+func (g distGenerator) uniformExpr(rowNum string, stream int) string {
+	return fmt.Sprintf("((CONV(SUBSTR(MD5(CONCAT(%d, '-', %d, '-', %s)), 1, 8), 16, 10) + 0.5) / 4294967296)", g.seed, stream, rowNum)
+}
+
+func (g distGenerator) SQLExpr(rowNum string) string {
+	u := g.uniformExpr(rowNum, 0)
+	p := make([]string, 0, len(g.params))
+	for _, v := range g.params {
+		p = append(p, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	switch g.dist {
+	case "zipf":
+		// Inverse of the continuous bounded power law CDF on [1, n]
+		if g.params[0] == 1 {
+			return fmt.Sprintf("FLOOR(POW(%s, %s))", p[1], u)
+		}
+		return fmt.Sprintf("FLOOR(POW(%s * (POW(%s, 1 - %s) - 1) + 1, 1 / (1 - %s)))", u, p[1], p[0], p[0])
+	case "normal":
+		// Box-Muller transform
+		return fmt.Sprintf("ROUND(%s + %s * SQRT(-2 * LN(%s)) * COS(2 * PI() * %s))", p[0], p[1], u, g.uniformExpr(rowNum, 1))
+	default:
+		return fmt.Sprintf("FLOOR(%s * %s)", u, p[0])
+	}
+}
+
+// String returns the canonical definition, with all parameters and the seed
+func (g distGenerator) String() string {
+	params := make([]string, 0, len(g.params)+1)
+	for _, v := range g.params {
+		params = append(params, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	params = append(params, fmt.Sprintf("seed=%d", g.seed))
+	return fmt.Sprintf("%s(%s)", g.dist, strings.Join(params, ","))
+}
+
+// parseDistGenerator parses a distribution like "zipf(1.1, seed=42)", the
+// seed defaults to 1 so the data is reproducible also without a seed
+func parseDistGenerator(def string) (distGenerator, error) {
+	name, args, ok := strings.Cut(strings.TrimSpace(def), "(")
+	name = strings.ToLower(strings.TrimSpace(name))
+	defaults, known := distDefaults[name]
+	if !ok || !known || !strings.HasSuffix(args, ")") {
+		return distGenerator{}, fmt.Errorf("expected one of uniform(max), zipf(s[, n]) or normal(mean, stddev), with an optional seed=N")
+	}
+	g := distGenerator{dist: name, params: append([]float64(nil), defaults...), seed: 1}
+	args = strings.TrimSpace(strings.TrimSuffix(args, ")"))
+	positional := 0
+	for _, arg := range strings.Split(args, ",") {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+		if key, value, named := strings.Cut(arg, "="); named {
+			if strings.TrimSpace(key) != "seed" {
+				return distGenerator{}, fmt.Errorf("unknown parameter '%s'", strings.TrimSpace(key))
+			}
+			seed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return distGenerator{}, fmt.Errorf("invalid seed '%s'", value)
+			}
+			g.seed = seed
+			continue
+		}
+		if positional >= len(g.params) {
+			return distGenerator{}, fmt.Errorf("too many parameters for %s", name)
+		}
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return distGenerator{}, fmt.Errorf("invalid parameter '%s'", arg)
+		}
+		g.params[positional] = v
+		positional++
+	}
+	for _, v := range g.params {
+		if math.IsNaN(v) {
+			return distGenerator{}, fmt.Errorf("missing parameters for %s", name)
+		}
+	}
+	if name == "zipf" && (g.params[0] <= 0 || g.params[1] < 1) {
+		return distGenerator{}, fmt.Errorf("zipf needs s > 0 and n >= 1")
+	}
+	return g, nil
+}
+
+// distributionPresets are the parameters of the distributions given by name only to -distribution
+var distributionPresets = map[string]string{
+	"uniform": "uniform()",
+	"zipf":    "zipf(1.1)",
+	"normal":  fmt.Sprintf("normal(%d, %d)", bValueDomain/2, bValueDomain/8),
+}
+
+// parseDistribution parses the -distribution value, a distribution name
+// like zipf, or a distribution with parameters like "zipf(1.5, seed=7)".
+// The default uniform values give nil.
+func parseDistribution(s string) (*distGenerator, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "uniform" {
+		return nil, nil
+	}
+	if preset, ok := distributionPresets[strings.ToLower(s)]; ok {
+		s = preset
+	}
+	g, err := parseDistGenerator(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid distribution '%s': %w", s, err)
+	}
+	return &g, nil
+}
+
+// name returns the name of the distribution in the table names and scenario
+// IDs: the distribution of a preset, like zipf, or followed by a hash of its
+// parameters and seed, like zipf3fa2c1, so tables of other parameters are not
+// reused for it
+func (g distGenerator) name() string {
+	if preset, err := parseDistGenerator(distributionPresets[g.dist]); err == nil && preset.String() == g.String() {
+		return g.dist
+	}
+	h := fnv.New32a()
+	h.Write([]byte(g.String()))
+	return fmt.Sprintf("%s%06x", g.dist, h.Sum32()&0xffffff)
+}
+
+// isDistributionName reports whether a table name part is the name of a distribution, like zipf or zipf3fa2c1
+func isDistributionName(part string) bool {
+	for dist := range distDefaults {
+		if hash, ok := strings.CutPrefix(part, dist); ok {
+			_, err := strconv.ParseUint(hash, 16, 32)
+			return hash == "" || (len(hash) == 6 && err == nil)
+		}
+	}
+	return false
+}
+
+// canonicalGenerator returns the generator definition to record in the run
+// metadata, distributions with all their parameters and the seed
+func canonicalGenerator(def, column string, gen ColumnGenerator) string {
+	if g, ok := gen.(distGenerator); ok {
+		return column + ":" + g.String()
+	}
+	return def
+}
+
+// ParseColumnGenerator parses a generator definition, either an expression
+// like "b=floor(row / 10) % 1000" or a distribution like "b:zipf(1.1,seed=42)".
+// Expressions may use the row number 'row', numbers, the operators + - * / %,
+// parentheses and the functions in exprFunctions.
+func ParseColumnGenerator(def string) (string, ColumnGenerator, error) {
+	if column, dist, ok := strings.Cut(def, ":"); ok && !strings.Contains(column, "=") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return "", nil, fmt.Errorf("invalid generator '%s': expected column:distribution", def)
+		}
+		g, err := parseDistGenerator(dist)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid generator '%s': %w", def, err)
+		}
+		return column, g, nil
+	}
+	column, expr, ok := strings.Cut(def, "=")
+	column = strings.TrimSpace(column)
+	if !ok || column == "" {
+		return "", nil, fmt.Errorf("invalid generator '%s': expected column=expression", def)
+	}
+	tokens, err := tokenizeExpr(expr)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid generator '%s': %w", def, err)
+	}
+	p := exprParser{tokens: tokens}
+	if err = p.parseExpr(); err == nil && p.pos < len(tokens) {
+		err = fmt.Errorf("unexpected '%s'", tokens[p.pos].text)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid generator '%s': %w", def, err)
+	}
+	return column, exprGenerator{tokens: tokens}, nil
+}
+
+func tokenizeExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if strings.Count(string(runes[start:i]), ".") > 1 {
+				return nil, fmt.Errorf("invalid number '%s'", string(runes[start:i]))
+			}
+			tokens = append(tokens, exprToken{tokNumber, string(runes[start:i])})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			ident := strings.ToLower(string(runes[start:i]))
+			if _, ok := exprFunctions[ident]; !ok && ident != "row" {
+				return nil, fmt.Errorf("unknown identifier '%s'", ident)
+			}
+			tokens = append(tokens, exprToken{tokIdent, ident})
+		case strings.ContainsRune("+-*/%", r):
+			tokens = append(tokens, exprToken{tokOperator, " " + string(r) + " "})
+			i++
+		case r == '(':
+			tokens = append(tokens, exprToken{tokLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, exprToken{tokRParen, ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, exprToken{tokComma, ","})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", r)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return tokens, nil
+}
+
+// exprParser validates the token stream with a recursive descent parser:
+//
+//	expr    = unary { operator unary }
+//	unary   = [ "-" ] primary
+//	primary = number | "row" | function "(" [ expr { "," expr } ] ")" | "(" expr ")"
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *exprParser) expect(kind exprTokenKind, what string) error {
+	tok := p.peek()
+	if tok == nil || tok.kind != kind {
+		return fmt.Errorf("expected %s", what)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) parseExpr() error {
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+	for tok := p.peek(); tok != nil && tok.kind == tokOperator; tok = p.peek() {
+		p.pos++
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) parseUnary() error {
+	if tok := p.peek(); tok != nil && tok.kind == tokOperator && strings.TrimSpace(tok.text) == "-" {
+		p.pos++
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() error {
+	tok := p.peek()
+	if tok == nil {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch {
+	case tok.kind == tokNumber, tok.kind == tokIdent && tok.text == "row":
+		return nil
+	case tok.kind == tokIdent:
+		if err := p.expect(tokLParen, "'(' after "+tok.text); err != nil {
+			return err
+		}
+		if next := p.peek(); next != nil && next.kind == tokRParen {
+			p.pos++
+			return nil
+		}
+		for {
+			if err := p.parseExpr(); err != nil {
+				return err
+			}
+			next := p.peek()
+			if next == nil || next.kind != tokComma {
+				break
+			}
+			p.pos++
+		}
+		return p.expect(tokRParen, "')'")
+	case tok.kind == tokLParen:
+		if err := p.parseExpr(); err != nil {
+			return err
+		}
+		return p.expect(tokRParen, "')'")
+	default:
+		return fmt.Errorf("unexpected '%s'", strings.TrimSpace(tok.text))
+	}
+}
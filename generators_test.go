@@ -0,0 +1,121 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseColumnGenerator(t *testing.T) {
+	column, gen, err := ParseColumnGenerator("b = floor(row / 10) % 1000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if column != "b" {
+		t.Fatalf("expected column b, got %s", column)
+	}
+	expected := "FLOOR((n) / 10) % 1000"
+	if got := gen.SQLExpr("n"); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	_, gen, err = ParseColumnGenerator("c=repeat(crc32(row), 3)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = "REPEAT(CRC32((n)), 3)"
+	if got := gen.SQLExpr("n"); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	for _, def := range []string{"b", "b=", "b=sleep(10)", "b=row;drop table t", "b=(row", "b=row row", "b=floor row", "b='x'"} {
+		if _, _, err = ParseColumnGenerator(def); err == nil {
+			t.Fatalf("expected error for %q", def)
+		}
+	}
+}
+
+func TestParseDistributionGenerator(t *testing.T) {
+	def := "b:zipf(1.1, seed=42)"
+	column, gen, err := ParseColumnGenerator(def)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if column != "b" {
+		t.Fatalf("expected column b, got %s", column)
+	}
+	if got := canonicalGenerator(def, column, gen); got != "b:zipf(1.1,1000000,seed=42)" {
+		t.Fatalf("unexpected canonical definition %s", got)
+	}
+	expected := "FLOOR(POW(((CONV(SUBSTR(MD5(CONCAT(42, '-', 0, '-', n)), 1, 8), 16, 10) + 0.5) / 4294967296) * (POW(1000000, 1 - 1.1) - 1) + 1, 1 / (1 - 1.1)))"
+	if got := gen.SQLExpr("n"); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	_, gen, err = ParseColumnGenerator("b:uniform(1000)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := canonicalGenerator("b:uniform(1000)", "b", gen); got != "b:uniform(1000,seed=1)" {
+		t.Fatalf("expected the default seed to be recorded, got %s", got)
+	}
+	if got := canonicalGenerator("b=row", "b", exprGenerator{}); got != "b=row" {
+		t.Fatalf("expected expressions to be recorded as given, got %s", got)
+	}
+
+	for _, def := range []string{"b:zipf()", "b:zipf(0)", "b:normal(1)", "b:pareto(1)", "b:uniform(1, 2)", "b:uniform(x)", "b:zipf(1.1, seed=x)", "b:zipf(1.1, n=5)", ":uniform(10)"} {
+		if _, _, err = ParseColumnGenerator(def); err == nil {
+			t.Fatalf("expected error for %q", def)
+		}
+	}
+}
+
+func TestParseDistribution(t *testing.T) {
+	if g, err := parseDistribution("uniform"); err != nil || g != nil {
+		t.Fatalf("expected no generator for the default uniform values, got %v, %v", g, err)
+	}
+	g, err := parseDistribution("normal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.String() != "normal(500000,125000,seed=1)" {
+		t.Fatalf("unexpected normal preset %s", g)
+	}
+	if _, err = parseDistribution("pareto"); err == nil {
+		t.Fatalf("expected an error for an unknown distribution")
+	}
+
+	if table := tableSpecs([]int{1000}, "", MatrixOptions{Distribution: g})[0]; table.Name() != "t1K_normal" {
+		t.Fatalf("unexpected table name %s", table.Name())
+	}
+	g, err = parseDistribution("zipf(1.5, seed=7)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other parameters than the preset have a hash in the name
+	table := tableSpecs([]int{1000}, "", MatrixOptions{Distribution: g})[0]
+	if table.Name() != "t1K_zipf3cfa03" {
+		t.Fatalf("unexpected table name %s", table.Name())
+	}
+	if stmt := createTableStatement(table, 100); !strings.HasSuffix(stmt, "COMMENT 'calibration v1: filler=100 b:zipf(1.5,1000000,seed=7)'") {
+		t.Fatalf("expected the distribution in the table comment, got %s", stmt)
+	}
+	// Other -gen generators are recorded as a hash of their SQL
+	_, gen, err := ParseColumnGenerator("c=row % 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(columnGenerators, "c")
+	RegisterColumnGenerator("c", gen)
+	if stmt := createTableStatement(table, 100); !regexp.MustCompile(`b:zipf\(1.5,1000000,seed=7\) gen:c=[0-9a-f]{8}'$`).MatchString(stmt) {
+		t.Fatalf("expected the generator of c in the table comment, got %s", stmt)
+	}
+	scenarios := GetTestScenarios([]TableSpec{table}, []float64{10}, MatrixOptions{})
+	if scenarios[0].ID != "zipf3cfa03index_1K_10" {
+		t.Fatalf("unexpected scenario ID %s", scenarios[0].ID)
+	}
+	// b is not adjusted to the selectivities, the matching rows are counted
+	if scenarios[1].CountQuery != "SELECT /*+ FORCE_INDEX(t1K_zipf3cfa03, b) */ COUNT(*) FROM t1K_zipf3cfa03 WHERE b = 10" {
+		t.Fatalf("unexpected count query %s", scenarios[1].CountQuery)
+	}
+}
@@ -1,5 +1,18 @@
 module github.com/mjonss/tidb-optimizer-calibration
 
-go 1.24
+go 1.24.0
 
-require github.com/go-sql-driver/mysql v1.7.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/go-sql-driver/mysql v1.7.1
+	github.com/zalando/go-keyring v0.2.6
+	golang.org/x/term v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	al.essio.dev/pkg/shellescape v1.5.1 // indirect
+	github.com/danieljoos/wincred v1.2.2 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+)
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// healthWindow is the number of recent executions the error rate is computed over
+	healthWindow = 20
+	// healthPings is the number of SELECT 1 round trips per health check
+	healthPings = 20
+	// healthRecheckInterval is how often an unhealthy cluster is checked while paused
+	healthRecheckInterval = 10 * time.Second
+)
+
+// HealthMonitor watches the cluster health during the run: the error rate of
+// the executions, the p99 of a SELECT 1 probe and the store availability. On
+// degradation the run is paused, and aborted if the cluster does not recover,
+// instead of continuing to hammer a struggling cluster with scans.
+type HealthMonitor struct {
+	// Every is the number of executions between health checks
+	Every int
+	// MaxErrorRate is the fraction of failed recent executions considered unhealthy
+	MaxErrorRate float64
+	// MaxPingP99 is the SELECT 1 p99 latency considered unhealthy (0 disables)
+	MaxPingP99 time.Duration
+	// MaxPause is how long to wait for the cluster to recover before aborting
+	MaxPause time.Duration
+
+	outcomes   []bool
+	executions int
+	// probe returns the problems of the cluster, nil for clusterProblems
+	probe func(c *TiDBClient) []string
+	// recheck is how often an unhealthy cluster is checked while paused, 0 for healthRecheckInterval
+	recheck time.Duration
+}
+
+// Record records the outcome of an execution
+func (h *HealthMonitor) Record(failed bool) {
+	if h == nil {
+		return
+	}
+	h.outcomes = append(h.outcomes, failed)
+	if len(h.outcomes) > healthWindow {
+		h.outcomes = h.outcomes[1:]
+	}
+}
+
+// errorRate returns the fraction of failed recent executions
+func (h *HealthMonitor) errorRate() float64 {
+	if len(h.outcomes) == 0 {
+		return 0
+	}
+	failed := 0
+	for _, f := range h.outcomes {
+		if f {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(h.outcomes))
+}
+
+// problems returns the health problems of the cluster, empty if it is healthy
+func (h *HealthMonitor) problems(c *TiDBClient) []string {
+	var problems []string
+	if len(h.outcomes) >= healthWindow/2 && h.errorRate() > h.MaxErrorRate {
+		problems = append(problems, fmt.Sprintf("%.0f%% of the last %d executions failed", h.errorRate()*100, len(h.outcomes)))
+	}
+	probe := h.probe
+	if probe == nil {
+		probe = h.clusterProblems
+	}
+	return append(problems, probe(c)...)
+}
+
+// clusterProblems probes the cluster: the SELECT 1 p99 and the store availability
+func (h *HealthMonitor) clusterProblems(c *TiDBClient) []string {
+	var problems []string
+	if latencies, err := c.PingLatencies(healthPings); err != nil {
+		problems = append(problems, fmt.Sprintf("SELECT 1 failed: %v", err))
+	} else if h.MaxPingP99 > 0 {
+		values := make([]float64, len(latencies))
+		for i, l := range latencies {
+			values[i] = float64(l)
+		}
+		if p99 := time.Duration(percentile(values, 99)); p99 > h.MaxPingP99 {
+			problems = append(problems, fmt.Sprintf("SELECT 1 p99 %s above %s", p99.Round(time.Microsecond), h.MaxPingP99))
+		}
+	}
+	if down, err := c.UnavailableStores(); err != nil {
+		slog.Warn("Failed to check store availability", "error", err)
+	} else if len(down) > 0 {
+		problems = append(problems, fmt.Sprintf("stores not up: %s", strings.Join(down, ", ")))
+	}
+	return problems
+}
+
+// MaybeCheck checks the cluster health if it is due, or if the error rate
+// is already too high. If the cluster is unhealthy it pauses until it
+// recovers, and returns an error if it did not recover within MaxPause.
+func (h *HealthMonitor) MaybeCheck(c *TiDBClient, meta *RunMetadata) error {
+	if h == nil {
+		return nil
+	}
+	due := h.executions%h.Every == 0
+	h.executions++
+	if !due && h.errorRate() <= h.MaxErrorRate {
+		return nil
+	}
+	problems := h.problems(c)
+	if len(problems) == 0 {
+		return nil
+	}
+	start := time.Now()
+	fmt.Printf("⏸️  Pausing, cluster unhealthy: %s\n", strings.Join(problems, "; "))
+	// The failures before the pause should not count after it
+	h.outcomes = nil
+	for len(problems) > 0 && time.Since(start) < h.MaxPause {
+		time.Sleep(cmp.Or(h.recheck, healthRecheckInterval))
+		problems = h.problems(c)
+	}
+	if len(problems) > 0 {
+		meta.AddEvent("health_abort", time.Since(start), strings.Join(problems, "; "))
+		return fmt.Errorf("cluster still unhealthy after %s: %s", h.MaxPause, strings.Join(problems, "; "))
+	}
+	meta.AddEvent("pause", time.Since(start), "cluster unhealthy")
+	return nil
+}
+
+// UnavailableStores returns the TiKV and TiFlash stores that are not up
+func (c *TiDBClient) UnavailableStores() ([]string, error) {
+	if c.dbPlan == nil {
+		return nil, fmt.Errorf("database connection not established")
+	}
+	query := "SELECT ADDRESS, STORE_STATE_NAME FROM information_schema.tikv_store_status WHERE STORE_STATE_NAME NOT IN ('Up', 'Tombstone')"
+	slog.Debug("Executing query", "query", query)
+	rows, err := c.dbPlan.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store status: %w", err)
+	}
+	defer rows.Close()
+	var down []string
+	for rows.Next() {
+		var address, state string
+		if err = rows.Scan(&address, &state); err != nil {
+			return nil, err
+		}
+		down = append(down, fmt.Sprintf("%s (%s)", address, state))
+	}
+	return down, rows.Err()
+}
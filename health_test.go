@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthErrorRate(t *testing.T) {
+	h := &HealthMonitor{Every: 10, MaxErrorRate: 0.5}
+	for i := range healthWindow * 2 {
+		h.Record(i%4 == 0)
+	}
+	if len(h.outcomes) != healthWindow {
+		t.Fatalf("expected a window of %d outcomes, got %d", healthWindow, len(h.outcomes))
+	}
+	if rate := h.errorRate(); rate != 0.25 {
+		t.Fatalf("expected error rate 0.25, got %v", rate)
+	}
+	// A nil monitor neither records nor checks
+	var none *HealthMonitor
+	none.Record(true)
+	if err := none.MaybeCheck(nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHealthMaybeCheck(t *testing.T) {
+	var unhealthy []string
+	probes := 0
+	h := &HealthMonitor{Every: 3, MaxErrorRate: 0.5, probe: func(*TiDBClient) []string {
+		probes++
+		return unhealthy
+	}}
+	meta := &RunMetadata{}
+	for range 7 {
+		if err := h.MaybeCheck(nil, meta); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The first execution and every third after it
+	if probes != 3 {
+		t.Fatalf("expected 3 health checks in 7 executions, got %d", probes)
+	}
+
+	// Too many failures check right away, even if not due, and abort if they do not recover
+	for range healthWindow / 2 {
+		h.Record(true)
+	}
+	probes = 0
+	if err := h.MaybeCheck(nil, meta); err == nil || probes != 1 {
+		t.Fatalf("expected the failures to abort the run after one check, got %v after %d checks", err, probes)
+	}
+	if len(meta.Timeline) != 1 || meta.Timeline[0].Event != "health_abort" || len(h.outcomes) != 0 {
+		t.Fatalf("unexpected events %+v or outcomes %v", meta.Timeline, h.outcomes)
+	}
+	// Fewer failures than half the window are not enough to judge
+	for range healthWindow/2 - 1 {
+		h.Record(true)
+	}
+	if problems := h.problems(nil); len(problems) != 0 {
+		t.Fatalf("expected no problems from a partial window, got %v", problems)
+	}
+
+	// An unhealthy cluster pauses the run until it recovers
+	h = &HealthMonitor{Every: 1, MaxPause: time.Minute, recheck: time.Millisecond, probe: func(*TiDBClient) []string {
+		probes++
+		if probes < 3 {
+			return []string{"stores not up: tikv-0"}
+		}
+		return nil
+	}}
+	probes = 0
+	meta = &RunMetadata{}
+	if err := h.MaybeCheck(nil, meta); err != nil || probes != 3 {
+		t.Fatalf("expected the run to resume after 3 checks, got %v after %d", err, probes)
+	}
+	if len(meta.Timeline) != 1 || meta.Timeline[0].Event != "pause" {
+		t.Fatalf("unexpected events %+v", meta.Timeline)
+	}
+}
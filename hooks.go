@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/mjonss/tidb-optimizer-calibration/hooks"
+)
+
+// hookExecution returns the execution of a scenario passed to the execution hooks
+func hookExecution(scenario *TestScenario, repetition int) hooks.Execution {
+	return hooks.Execution{
+		ScenarioID: scenario.ID,
+		Variant:    scenario.Variant,
+		Repetition: repetition,
+		Query:      scenario.Query,
+		TableName:  scenario.TableName,
+	}
+}
+
+// runBeforeHooks calls Before of the hooks
+func runBeforeHooks(executionHooks []hooks.Hook, scenario *TestScenario, repetition int) {
+	for _, hook := range executionHooks {
+		if err := hook.Before(hookExecution(scenario, repetition)); err != nil {
+			slog.Warn("Execution hook failed", "hook", hook.Name(), "scenario", scenario.ID, "error", err)
+		}
+	}
+}
+
+// runAfterHooks calls After of the hooks and attaches their metrics to the result
+func runAfterHooks(executionHooks []hooks.Hook, result *TestExecutionResult) {
+	hookResult := hooks.Result{
+		Execution: hooks.Execution{
+			ScenarioID: result.ScenarioID,
+			Variant:    result.Variant,
+			Repetition: result.Repetition,
+			Query:      result.Query,
+			TableName:  result.TableName,
+		},
+		PlanType: result.PlanType,
+	}
+	if result.Plan != nil {
+		hookResult.Duration = result.Plan.ExecutionTime
+	}
+	for _, hook := range executionHooks {
+		metrics, err := hook.After(hookResult)
+		if err != nil {
+			slog.Warn("Execution hook failed", "hook", hook.Name(), "scenario", result.ScenarioID, "error", err)
+			continue
+		}
+		for name, value := range metrics {
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]float64)
+			}
+			result.Metrics[hook.Name()+"."+name] = value
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package hooks defines the execution hooks of the calibration runs: custom
+// metrics collectors called around every execution, like a scraper of the
+// TiKV gRPC metrics or an eBPF probe. A hook implemented in another module
+// registers itself with Register from an init function, and is added to the
+// tool by a blank import of its package.
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Execution is an execution of a scenario of the run
+type Execution struct {
+	ScenarioID string
+	Variant    string
+	Repetition int
+	// Query is the executed query, TableName the table of the scenario
+	Query     string
+	TableName string
+}
+
+// Result is a successful execution, with its plan type and measured time
+type Result struct {
+	Execution
+	PlanType string
+	Duration time.Duration
+}
+
+// Hook is called around every execution of the run. Before is called right
+// before the execution, After right after a successful one, outside of the
+// measured time. The metrics After returns are attached to the result as
+// <name>.<metric>. An error is logged, it does not fail the execution.
+type Hook interface {
+	Name() string
+	Before(execution Execution) error
+	After(result Result) (map[string]float64, error)
+}
+
+var (
+	mu         sync.Mutex
+	registered []Hook
+)
+
+// Register adds a hook to all runs, to be called from the init function of
+// the package implementing it
+func Register(hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, hook)
+}
+
+// Registered returns the registered hooks, in registration order
+func Registered() []Hook {
+	mu.Lock()
+	defer mu.Unlock()
+	return slices.Clone(registered)
+}
+
+// Command returns the hook of an external command, run by the shell as
+// "<command> before <scenario> <variant> <repetition>" and as
+// "<command> after <scenario> <variant> <repetition>", the output of the
+// latter being the metrics, one "<name> <value>" per line. The command may
+// have quoted arguments, like a shell command line.
+func Command(command string) Hook {
+	return &commandHook{command: command}
+}
+
+// commandHook is the hook of Command
+type commandHook struct {
+	command string
+}
+
+func (h *commandHook) Name() string {
+	return "command"
+}
+
+func (h *commandHook) run(phase string, execution Execution) ([]byte, error) {
+	// The arguments are passed as the positional parameters, not quoted into the command line
+	cmd := exec.Command("sh", "-c", h.command+` "$@"`, "sh",
+		phase, execution.ScenarioID, execution.Variant, strconv.Itoa(execution.Repetition))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w: %s", h.command, phase, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (h *commandHook) Before(execution Execution) error {
+	_, err := h.run("before", execution)
+	return err
+}
+
+func (h *commandHook) After(result Result) (map[string]float64, error) {
+	out, err := h.run("after", result.Execution)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetrics(out)
+}
+
+// parseMetrics parses "<name> <value>" lines, skipping empty lines and # comments
+func parseMetrics(out []byte) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid metric line '%s', expected <name> <value>", line)
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of metric %s: %w", fields[0], err)
+		}
+		metrics[fields[0]] = value
+	}
+	return metrics, scanner.Err()
+}
@@ -0,0 +1,48 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommandHook(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "collect.sh")
+	content := "#!/bin/sh\n" +
+		"if [ \"$2\" = before ]; then echo \"$1 $3 $4 $5\" > " + filepath.Join(dir, "before") + "; exit 0; fi\n" +
+		"echo '# grpc metrics'\necho 'grpc_msg_total 42'\necho 'probe_us 1.5'\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A quoted argument is kept as one
+	hook := Command(script + " 'tikv metrics'")
+	if err := hook.Before(Execution{ScenarioID: "index_1K_10", Variant: "Index", Repetition: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "before")); string(args) != "tikv metrics index_1K_10 Index 2\n" {
+		t.Fatalf("unexpected before arguments %q", args)
+	}
+	metrics, err := hook.After(Result{Execution: Execution{ScenarioID: "index_1K_10", Variant: "Index"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 2 || metrics["grpc_msg_total"] != 42 || metrics["probe_us"] != 1.5 {
+		t.Fatalf("unexpected metrics %v", metrics)
+	}
+	if _, err := parseMetrics([]byte("grpc_msg_total\n")); err == nil {
+		t.Fatal("expected an error for a metric without a value")
+	}
+	if err := Command("exit 3").Before(Execution{}); err == nil {
+		t.Fatal("expected an error for a failing command")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() { registered = nil }()
+	hook := Command("true")
+	Register(hook)
+	if got := Registered(); len(got) != 1 || got[0] != hook {
+		t.Fatalf("unexpected registered hooks %v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mjonss/tidb-optimizer-calibration/hooks"
+)
+
+// countingHook counts its calls, and fails After for the scenario fail
+type countingHook struct {
+	before, after int
+	last          hooks.Result
+}
+
+func (h *countingHook) Name() string {
+	return "counting"
+}
+
+func (h *countingHook) Before(execution hooks.Execution) error {
+	h.before++
+	return nil
+}
+
+func (h *countingHook) After(result hooks.Result) (map[string]float64, error) {
+	h.after++
+	h.last = result
+	if result.ScenarioID == "fail" {
+		return nil, errors.New("no metrics")
+	}
+	return map[string]float64{"calls": float64(h.after)}, nil
+}
+
+func TestRunHooks(t *testing.T) {
+	hook := &countingHook{}
+	executionHooks := []hooks.Hook{hook}
+	runBeforeHooks(executionHooks, &TestScenario{ID: "index_1K_10"}, 0)
+	result := &TestExecutionResult{ScenarioID: "index_1K_10", Variant: "Index", Repetition: 2, PlanType: "index_lookup",
+		Plan: &ExecutionPlan{ExecutionTime: 3 * time.Millisecond}}
+	runAfterHooks(executionHooks, result)
+	if hook.before != 1 || hook.after != 1 || result.Metrics["counting.calls"] != 1 {
+		t.Fatalf("unexpected hook calls %+v, metrics %v", hook, result.Metrics)
+	}
+	if hook.last.Variant != "Index" || hook.last.Repetition != 2 || hook.last.PlanType != "index_lookup" || hook.last.Duration != 3*time.Millisecond {
+		t.Fatalf("unexpected result passed to the hook %+v", hook.last)
+	}
+	failed := &TestExecutionResult{ScenarioID: "fail"}
+	runAfterHooks(executionHooks, failed)
+	if failed.Metrics != nil {
+		t.Fatalf("expected no metrics of a failed hook, got %v", failed.Metrics)
+	}
+}
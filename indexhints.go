@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var indexHintRegex = regexp.MustCompile(`(?i)\b(FORCE_INDEX|USE_INDEX|IGNORE_INDEX)\((\w+),\s*(\w+)\)`)
+
+// tableIndexes returns the index names of a table with their columns in
+// index order, from SHOW INDEX, cached per table
+func (c *TiDBClient) tableIndexes(tableName string) (map[string][]string, error) {
+	if indexes, ok := c.indexes[tableName]; ok {
+		return indexes, nil
+	}
+	rows, err := queryNamedRows(c.db, "SHOW INDEX FROM "+tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the indexes of %s: %w", tableName, err)
+	}
+	slices.SortFunc(rows, func(a, b map[string]string) int {
+		x, _ := strconv.Atoi(a["Seq_in_index"])
+		y, _ := strconv.Atoi(b["Seq_in_index"])
+		return x - y
+	})
+	indexes := make(map[string][]string)
+	for _, row := range rows {
+		indexes[row["Key_name"]] = append(indexes[row["Key_name"]], row["Column_name"])
+	}
+	if c.indexes == nil {
+		c.indexes = make(map[string]map[string][]string)
+	}
+	c.indexes[tableName] = indexes
+	return indexes, nil
+}
+
+// rewriteIndexHints replaces index names in the index hints on the table
+// that do not exist, with the index having the hinted name as first column.
+// The test tables name the index after its column, tables created otherwise
+// may not, and TiDB only warns about an unknown index and ignores the hint,
+// so the measured plan would silently not be the hinted one.
+func rewriteIndexHints(query, tableName string, indexes map[string][]string) (string, error) {
+	var err error
+	rewritten := indexHintRegex.ReplaceAllStringFunc(query, func(hint string) string {
+		m := indexHintRegex.FindStringSubmatch(hint)
+		if !strings.EqualFold(m[2], tableName) {
+			return hint
+		}
+		for name := range indexes {
+			if strings.EqualFold(name, m[3]) {
+				return hint
+			}
+		}
+		var candidates []string
+		for name, columns := range indexes {
+			if name != "PRIMARY" && strings.EqualFold(columns[0], m[3]) {
+				candidates = append(candidates, name)
+			}
+		}
+		if len(candidates) == 0 {
+			err = fmt.Errorf("%s: table %s has no index %s, nor an index on column %s", m[1], tableName, m[3], m[3])
+			return hint
+		}
+		// Prefer the index with the fewest columns
+		slices.SortFunc(candidates, func(a, b string) int {
+			if d := len(indexes[a]) - len(indexes[b]); d != 0 {
+				return d
+			}
+			return strings.Compare(a, b)
+		})
+		slog.Warn("Index in hint not found, using the index on the column", "table", tableName, "index", m[3], "using", candidates[0])
+		return fmt.Sprintf("%s(%s, %s)", m[1], m[2], candidates[0])
+	})
+	return rewritten, err
+}
+
+// resolveIndexHints makes sure the index hints on the table name existing indexes
+func (c *TiDBClient) resolveIndexHints(query, tableName string) (string, error) {
+	if tableName == "" || !indexHintRegex.MatchString(query) {
+		return query, nil
+	}
+	indexes, err := c.tableIndexes(tableName)
+	if err != nil {
+		return "", err
+	}
+	return rewriteIndexHints(query, tableName, indexes)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRewriteIndexHints(t *testing.T) {
+	indexes := map[string][]string{"PRIMARY": {"id"}, "idx_b_c": {"b", "c"}, "idx_b": {"b"}}
+	query := "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 1"
+	got, err := rewriteIndexHints(query, "t1K", indexes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "SELECT /*+ FORCE_INDEX(t1K, idx_b) */ * FROM t1K WHERE b = 1"; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+
+	indexes["b"] = []string{"b"}
+	if got, err = rewriteIndexHints(query, "t1K", indexes); err != nil || got != query {
+		t.Fatalf("expected the query unchanged for an existing index, got %s, %v", got, err)
+	}
+	// Hints on other tables or aliases are left alone
+	if got, err = rewriteIndexHints("SELECT /*+ IGNORE_INDEX(x, y) */ * FROM t1K x", "t1K", indexes); err != nil || got != "SELECT /*+ IGNORE_INDEX(x, y) */ * FROM t1K x" {
+		t.Fatalf("expected the query unchanged, got %s, %v", got, err)
+	}
+	if _, err = rewriteIndexHints("SELECT /*+ IGNORE_INDEX(t1K, d) */ * FROM t1K", "t1K", indexes); err == nil {
+		t.Fatalf("expected error for a missing index")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// interruptedByUser is the abort reason of a run stopped with Ctrl-C
+const interruptedByUser = "interrupted by the user"
+
+// exitInterrupted is the exit status of an interrupted run, 128 + SIGINT like
+// the shells use
+const exitInterrupted = 130
+
+// notifyInterrupt catches SIGINT and SIGTERM during the run. The returned
+// interrupted function reports whether one was received, so the run stops
+// after the current execution and reports the partial results; a second
+// Ctrl-C kills the process as usual. stop restores the default handling.
+func notifyInterrupt() (interrupted func() bool, stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	var received atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			received.Store(true)
+			signal.Stop(signals)
+			fmt.Println("\n🛑 Interrupted, stopping after the current execution and reporting the partial results (Ctrl-C again to quit now)")
+		case <-done:
+		}
+	}()
+	return received.Load, func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
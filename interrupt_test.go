@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNotifyInterrupt(t *testing.T) {
+	interrupted, stop := notifyInterrupt()
+	defer stop()
+	if interrupted() {
+		t.Fatal("expected no interrupt yet")
+	}
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = self.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot signal the test process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !interrupted() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the interrupt to be received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// reconnectTimeout is how long a reconnect is retried, long enough for a TiDB restart
+const reconnectTimeout = 5 * time.Minute
+
+// reopen replaces the connections
+func (c *TiDBClient) reopen() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dsn == "" {
+		return fmt.Errorf("database connection not established")
+	}
+	_ = c.Close()
+	return c.open()
+}
+
+// Reconnect replaces broken connections, retrying with backoff up to
+// reconnectTimeout, e.g. while TiDB restarts.
+func (c *TiDBClient) Reconnect() error {
+	deadline := time.Now().Add(reconnectTimeout)
+	backoff := time.Second
+	for {
+		err := c.reopen()
+		if err == nil || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		slog.Warn("Failed to reconnect, retrying", "error", err, "backoff", backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// KeepAlive pings the connections every interval until stop is called, so
+// idle connections are not closed by the server's wait_timeout, proxies or
+// load balancers during long runs, and broken connections are replaced by
+// the pool before the next execution. Pings are skipped while an execution
+// holds the connection.
+func (c *TiDBClient) KeepAlive(every time.Duration) (stop func()) {
+	if every <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if !c.mu.TryLock() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), every)
+			for _, db := range []interface{ PingContext(context.Context) error }{c.db, c.dbPlan} {
+				if err := db.PingContext(ctx); err != nil {
+					slog.Warn("Keep-alive ping failed", "error", err)
+				}
+			}
+			cancel()
+			c.mu.Unlock()
+		}
+	}()
+	return func() { close(done) }
+}
+
+// isConnectionError returns true if the error is caused by a broken connection,
+// rather than by the statement
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
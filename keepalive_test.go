@@ -0,0 +1,29 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestReconnectWithoutConnection(t *testing.T) {
+	c := NewTiDBClient()
+	if err := c.reopen(); err == nil {
+		t.Fatalf("expected an error without a connection")
+	}
+	c.KeepAlive(0)()
+}
+
+func TestIsConnectionError(t *testing.T) {
+	for _, err := range []error{driver.ErrBadConn, mysql.ErrInvalidConn, fmt.Errorf("failed to execute query: %w", mysql.ErrInvalidConn)} {
+		if !isConnectionError(err) {
+			t.Fatalf("expected %v to be a connection error", err)
+		}
+	}
+	if isConnectionError(&mysql.MySQLError{Number: 1105, Message: "unknown error"}) || isConnectionError(errors.New("execution coprocessor cache is used")) {
+		t.Fatalf("expected statement errors not to be connection errors")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import "time"
+
+// operatorTime returns the wall time of an operator from its execution info,
+// false for operators without one, like the cop side operators
+func operatorTime(p *ExecutionPlan) (time.Duration, bool) {
+	exec := p.execInfo()
+	if exec == nil || exec.Time == 0 {
+		return 0, false
+	}
+	return exec.Time, true
+}
+
+// layerTimes splits the root operator time of an executed plan into the time
+// spent in TiDB and the time waiting for cop tasks, the wall time of the
+// operators sending them. The cop time is capped by the root time, since the
+// cop tasks of the two sides of an IndexLookUp overlap.
+func layerTimes(plan *ExecutionPlan) (tidb, cop time.Duration) {
+	if plan == nil {
+		return 0, 0
+	}
+	root, ok := operatorTime(plan)
+	if !ok {
+		return 0, 0
+	}
+	for p := plan; p != nil; p = p.Next {
+		if exec := p.execInfo(); exec == nil || exec.CopTasks == 0 {
+			continue
+		}
+		if t, ok := operatorTime(p); ok {
+			cop += t
+		}
+	}
+	cop = min(cop, root)
+	return root - cop, cop
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLayerTimes(t *testing.T) {
+	plan := &ExecutionPlan{ID: "Projection_4", ExecutionInfo: "time:10ms, loops:2, Concurrency:OFF",
+		Next: &ExecutionPlan{ID: "└─TableReader_7", ExecutionInfo: "time:7.5ms, loops:2, cop_task: {num: 1, max: 7.2ms, proc_keys: 1000, rpc_num: 1, rpc_time: 7.1ms}",
+			Next: &ExecutionPlan{ID: "  └─TableFullScan_6", ExecutionInfo: "tikv_task:{time:6ms, loops:5}"}}}
+	tidb, cop := layerTimes(plan)
+	if tidb != 2500*time.Microsecond || cop != 7500*time.Microsecond {
+		t.Fatalf("expected 2.5ms TiDB and 7.5ms cop time, got %s and %s", tidb, cop)
+	}
+	if tidb, cop = layerTimes(&ExecutionPlan{ID: "Point_Get_1", ExecutionInfo: "time:350µs, loops:2, Get:{num_rpc:1, total_time:300µs}"}); tidb != 350*time.Microsecond || cop != 0 {
+		t.Fatalf("expected all TiDB time for a point get, got %s and %s", tidb, cop)
+	}
+}
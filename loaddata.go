@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// The loaders of the generated rows: INSERT ... SELECT statements generating
+// the rows server side, or CSV generated client side and streamed with
+// LOAD DATA LOCAL INFILE
+const (
+	loaderInsert   = "insert"
+	loaderLoadData = "load-data"
+)
+
+// parseLoader validates the -loader value
+func parseLoader(s string) (string, error) {
+	switch s {
+	case loaderInsert, loaderLoadData:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown loader '%s', expected %s or %s", s, loaderInsert, loaderLoadData)
+}
+
+// valueGenerator is a ColumnGenerator that can also compute its values client
+// side, for the LOAD DATA loader
+type valueGenerator interface {
+	Value(rng *rand.Rand, row int) string
+}
+
+func (g randomIntGenerator) Value(rng *rand.Rand, row int) string {
+	return strconv.Itoa(int(math.Floor(seededRand(rng, row, randomIntStream) * float64(g.max))))
+}
+
+// Value repeats a random number like the SQL expression, a random string of exactly the filler size
+func (g fillerGenerator) Value(rng *rand.Rand, row int) string {
+	digits := fmt.Sprintf("%0*d", fillerDigits, int64(math.Floor(seededRand(rng, row, fillerStream)*math.Pow10(fillerDigits))))
+	return strings.Repeat(digits, g.size/fillerDigits+1)[:g.size]
+}
+
+// errLoadDataUnsupported is returned by loadDataRows if a generator can only generate its values server side
+var errLoadDataUnsupported = errors.New("the column generators are not supported by LOAD DATA")
+
+// isLoadDataRefused returns true for the errors of a server or client that
+// does not allow LOAD DATA LOCAL INFILE
+func isLoadDataRefused(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		// ER_NOT_ALLOWED_COMMAND, ER_NOT_SUPPORTED_YET, ER_CLIENT_LOCAL_FILES_DISABLED
+		return myErr.Number == 1148 || myErr.Number == 1235 || myErr.Number == 3948
+	}
+	return strings.Contains(err.Error(), "LOAD DATA LOCAL INFILE")
+}
+
+// writeCSVRows writes the CSV rows [start, start+size) of the generators
+func writeCSVRows(w io.Writer, gens []valueGenerator, rng *rand.Rand, start, size int) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, len(gens))
+	for row := start; row < start+size; row++ {
+		for i, gen := range gens {
+			record[i] = gen.Value(rng, row)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// loadDataRows generates the rows client side, as batches of CSV in memory,
+// loaded with LOAD DATA LOCAL INFILE over concurrency connections
+func loadDataRows(c *TiDBClient, tableName string, rowCount int, columns []string, gens []ColumnGenerator,
+	batchSize, concurrency int, progress *loadProgress) error {
+	valueGens := make([]valueGenerator, 0, len(gens))
+	for _, gen := range gens {
+		vg, ok := gen.(valueGenerator)
+		if !ok {
+			return errLoadDataUnsupported
+		}
+		valueGens = append(valueGens, vg)
+	}
+	// Only used without -seed, the seeded values are hashes of the row number
+	seed := time.Now().UnixNano()
+	return loadBatches(rowCount, batchSize, concurrency, func(start, size int) error {
+		var buf bytes.Buffer
+		if err := writeCSVRows(&buf, valueGens, rand.New(rand.NewSource(seed+int64(start))), start, size); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s_%d", tableName, start)
+		mysql.RegisterReaderHandler(name, func() io.Reader { return &buf })
+		defer mysql.DeregisterReaderHandler(name)
+		query := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s FIELDS TERMINATED BY ',' ENCLOSED BY '\"' LINES TERMINATED BY '\\n' (%s)",
+			name, tableName, strings.Join(columns, ","))
+		if err := c.ExecuteStatement(query); err != nil {
+			return err
+		}
+		progress.add(size)
+		return nil
+	})
+}
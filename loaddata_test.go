@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestWriteCSVRows(t *testing.T) {
+	var buf bytes.Buffer
+	gens := []valueGenerator{randomIntGenerator{max: 10}, fillerGenerator{size: 36}}
+	if err := writeCSVRows(&buf, gens, rand.New(rand.NewSource(1)), 100, 5); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 rows, got %q", buf.String())
+	}
+	for _, line := range lines {
+		b, filler, ok := strings.Cut(line, ",")
+		if v, err := strconv.Atoi(b); !ok || err != nil || v < 0 || v >= 10 || len(filler) != 36 || filler[:9] != filler[27:] {
+			t.Fatalf("unexpected row %q", line)
+		}
+	}
+}
+
+func TestWriteCSVRowsSeeded(t *testing.T) {
+	defer func(seed int64) { runSeed = seed }(runSeed)
+	runSeed = 42
+	gens := []valueGenerator{randomIntGenerator{max: 1000000}, fillerGenerator{size: 20}}
+	var all, batches bytes.Buffer
+	if err := writeCSVRows(&all, gens, rand.New(rand.NewSource(1)), 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range [][2]int{{0, 4}, {4, 6}} {
+		if err := writeCSVRows(&batches, gens, rand.New(rand.NewSource(int64(batch[0]))), batch[0], batch[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if all.String() != batches.String() {
+		t.Fatalf("expected the same seeded rows whatever the batching, got %q and %q", all.String(), batches.String())
+	}
+	// FLOOR of uniformExpr for the row times 1000000, MD5('42-100-0') starts with 9c684468
+	want := "610965"
+	if b, _, _ := strings.Cut(all.String(), ","); b != want {
+		t.Fatalf("expected the b value of the SQL expression %s, got %s", want, b)
+	}
+}
+
+func TestLoadDataFallback(t *testing.T) {
+	if _, err := parseLoader("import-into"); err == nil {
+		t.Fatalf("expected an error for an unknown loader")
+	}
+	if !isLoadDataRefused(&mysql.MySQLError{Number: 1148, Message: "The used command is not allowed with this TiDB version"}) ||
+		isLoadDataRefused(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}) {
+		t.Fatalf("unexpected LOAD DATA refusal classification")
+	}
+	_, gen, err := ParseColumnGenerator("b:zipf(1.1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loadDataRows(nil, "t1K", 1000, []string{"b"}, []ColumnGenerator{gen}, 1000, 1, nil)
+	if !errors.Is(err, errLoadDataUnsupported) {
+		t.Fatalf("expected the distribution to be unsupported, got %v", err)
+	}
+}
@@ -6,31 +6,188 @@ import (
 	"log/slog"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mjonss/tidb-optimizer-calibration/hooks"
 )
 
 const (
 	ruRegexStr = `(?:"ru_consumption":)(\d+\.\d+)[^\d]`
 )
 
+// defaultSelectivities are the default -c selectivities, halving from 1/2 to 1/512
+const defaultSelectivities = "0.5,0.25,0.125,0.0625,0.03125,0.015625,0.0078125,0.00390625,0.001953125"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export-data":
+			if err := runExportData(os.Args[2:]); err != nil {
+				slog.Error("Failed to export data", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "auto-analyze":
+			if err := runAutoAnalyze(os.Args[2:]); err != nil {
+				slog.Error("Failed to run auto analyze experiment", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "backup":
+			if err := runBackup(os.Args[2:]); err != nil {
+				slog.Error("Failed to back up tables", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "restore":
+			if err := runRestore(os.Args[2:]); err != nil {
+				slog.Error("Failed to restore tables", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "reload":
+			if err := runReload(os.Args[2:]); err != nil {
+				slog.Error("Failed to reload table", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "report":
+			if err := runReport(os.Args[2:]); err != nil {
+				slog.Error("Failed to create report", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "selftest":
+			if err := runSelfTest(os.Args[2:]); err != nil {
+				slog.Error("Self-test failed", "error", err)
+				os.Exit(1)
+			}
+			return
+		case "trend":
+			if err := runTrend(os.Args[2:]); err != nil {
+				slog.Error("Failed to show trend", "error", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Parse command line flags
 	var logLevel = flag.String("l", "info", "Log level: debug, info, warn, error")
 	var rowCounts = flag.String("s", "1K,1M", "Comma-separated list of table sizes to test (e.g., 1,100,10000)")
-	var fillerSize = flag.Int("f", 100, "Filler column size")
-	var selectivities = flag.String("c", "50.0,25.0,12.5,6.25,3.125,1.5625,0.78125,0.390625,0.1953125", "Comma-separated list of selectivity/cardinality values (Selectivity: ratio (0.0-1.0) or Cardinality: row counts. E.g., 0.3,0.1,100,50,25)")
+	var fillerSizesFlag = flag.String("f", "100", "Filler column size, or a comma-separated list of sizes for a row width sweep (e.g. 16,100,1000,4000), with a table per size (t1K_f1000) and the scenario IDs prefixed with the size (f1000index_1K_10)")
+	var uniqueTables = flag.Bool("unique-tables", false, "Suffix the table names with the run ID (e.g. t1M_rx7a), so runs with different data parameters do not clobber each other")
+	var cleanup = flag.Bool("cleanup", false, "Drop the test tables after the run")
+	var loadConcurrency = flag.Int("load-concurrency", 1, "Number of connections inserting the generated rows during table setup; with more than one the id order no longer follows the row numbers of the generators")
+	var loader = flag.String("loader", loaderInsert, "How the generated rows are loaded: insert (INSERT ... SELECT, generated server side) or load-data (CSV generated client side, streamed with LOAD DATA LOCAL INFILE, falling back to insert if the server refuses it or a -gen generator is server side only)")
+	var loadBatchSize = flag.Int("load-batch-size", defaultLoadBatchSize, "Rows per INSERT statement during table setup")
+	var skipSetup = flag.Bool("skip-setup", false, "Reuse the tables of an earlier run without creating or populating any, only verifying their schema and row counts in information_schema")
+	var setupOnly = flag.Bool("setup-only", false, "Create and populate the tables, then exit without running the scenarios")
+	var recreate = flag.Bool("recreate", false, "Drop and recreate existing tables whose schema does not match the requested one")
+	var selectivities = flag.String("c", defaultSelectivities, "Comma-separated list of selectivity/cardinality values (Selectivity: ratio (0.0-1.0) or Cardinality: row counts. E.g., 0.3,0.1,100,50,25)")
+	var familiesFlag = flag.String("families", strings.Join(defaultFamilies, ","), "Comma-separated list of scenario families: point (equality lookups), ordered (keep order index reads vs scan and sort), range (b BETWEEN ranges), join (index vs hash vs merge join), agg (stream vs hash aggregation), topn (ORDER BY b LIMIT n), covering (index reader vs table scan), indexmerge (OR over two indexes), pointget (primary key Point_Get and Batch_Point_Get), inlist (b IN (...) lists, see -in-list-lengths), composite (full, partial and non-leading prefixes of an index on (b, c)), dml (rolled back UPDATE and DELETE), unique (unique vs non-unique index lookups), null (b IS NULL and b IS NOT NULL, see -null-ratio), boundary (b at, just inside and just outside histogram bucket edges, see -boundary-buckets), count (SELECT COUNT(*) of a point lookup and of the whole table, from the index vs the table)")
+	var boundaryBucketsFlag = flag.Int("boundary-buckets", defaultBoundaryBuckets, "Number of histogram buckets per table whose edges the boundary family targets")
+	var tiflash = flag.Bool("tiflash", false, "Create TiFlash replicas of the test tables and add a TiFlash variant to each cell, reporting the TiKV vs TiFlash crossover")
+	var tiflashWait = flag.Duration("tiflash-wait", 10*time.Minute, "How long to wait for the TiFlash replicas to be available")
+	var primaryKeys = flag.String("pk", "clustered", "Primary key of the test tables: clustered, nonclustered (table lookups via the hidden _tidb_rowid, tables named like t1K_nc) or both")
+	var partitions = flag.String("partitions", "", "Also create copies of the test tables partitioned on b, hash:<n> or range:<n>, and run every cell on them, reporting the effect of partition pruning on the index vs table scan crossover")
+	var inLists = flag.String("in-list-lengths", "10", "Comma-separated list of IN-list lengths of the inlist family")
+	var rangeSpan = flag.Int("range-span", 0, "Number of b values covered by the range family predicates (0 for as many rows as the selectivity)")
 	var repetitions = flag.Int("n", 1, "Number of times to repeat each test")
+	var checkpointPath = flag.String("checkpoint", "", "Append every completed execution to this JSON lines file as it finishes, to resume an interrupted run with -resume")
+	var resume = flag.Bool("resume", false, "Resume the interrupted run of the -checkpoint file, skipping the executions it completed and reporting them with the new ones")
+	var checksumTables = flag.Bool("checksum-tables", false, "Record the ADMIN CHECKSUM TABLE of every test table at the start of the run, so report merge, report compare and -resume also tell apart runs on data with the same row counts and table comments. Off by default, since it scans the whole tables, warming the caches right before the measurements")
+	var strict = flag.Bool("strict", false, "Retry-free measurement: drop every execution that was retried (after a reconnect, a coprocessor cache bust or cop requests TiDB sent again) and schedule a clean replacement among the pending runs, so no reported sample includes hidden retry latency")
+	var adaptiveMax = flag.Int("adaptive", 0, "Schedule up to this many extra repetitions of each variant whose cell's two fastest variants have overlapping 95% confidence intervals, in rounds of -n repetitions, until they are significantly different (0 disables)")
+	var warmup = flag.Int("warmup", 0, "Number of warm-up executions of each test right before its first measured one, to warm the region and plan caches. They are recorded in the result files, flagged as warmup, and left out of all aggregates")
+	var outliers = flag.String("outliers", "", "Drop the outlying repetitions of each scenario and plan type from the aggregates: trim:<percent> drops that percent of the fastest and of the slowest executions, mad:<k> the executions more than k median absolute deviations from the median. They are recorded in the result files, flagged as outlier")
 	var detailedOutput = flag.Bool("d", true, "Detailed output, one line per test run")
 	var aggregatedOutput = flag.Bool("a", false, "Aggregated output, per test")
+	var keepAlive = flag.Duration("keepalive", 0, "Interval of the keep-alive pings of the idle connections during the run, like 1m, for long runs through proxies or load balancers closing idle connections, 0 (default) disables them. Broken connections are reconnected and the execution retried either way")
+	var hookCommand = flag.String("hook-command", "", "Custom metrics collector, a shell command line run as '<command> before|after <scenario> <variant> <repetition>' around every execution, the output of 'after' being metrics attached to the result, one '<name> <value>' per line")
+	var analyzeOverheadEvery = flag.Int("analyze-overhead-every", 0, "Also execute every Nth measured query under EXPLAIN ANALYZE and report the instrumentation overhead per plan type (0 disables)")
+	var rcWait = flag.Bool("rc-wait", false, "Capture resource control queueing (RU burst throttling) per execution and report its effect")
+	var assumeYes = flag.Bool("yes", false, "Do not ask for confirmation of long runs")
+	var confirmOver = flag.Duration("confirm-over", 0, "Probe the cluster to estimate the run duration, and ask for confirmation if it is longer than this (0 for no preflight, which would warm the caches of the measured tables)")
+	var explainAnalyze = flag.Bool("explain-analyze", false, "Execute the measured queries under EXPLAIN ANALYZE, taking the plan with the per operator time, actRows, cop tasks and memory from its result and the latency from the root operator, instead of executing them plainly and reading the plan with EXPLAIN FOR CONNECTION")
+	var coprCacheFlag = flag.String("copr-cache", coprCacheBust, "Handling of the executions served from the coprocessor cache: bust invalidates the cached regions and executes again, exclude leaves them out of the results, keep records them with their hit ratio and reports their latency separately")
+	var ignorePlanCache = flag.Bool("ignore-plan-cache", false, "Add the IGNORE_PLAN_CACHE() hint, so every repetition re-optimizes the query")
+	var fillerColumnsFlag = flag.String("fillers", "", "Comma-separated sizes of extra filler columns c2, c3, ... after c (e.g. 200,200), to decouple the row width from the position of b, with the tables named like t1K_fill200x200")
+	var bPositionFlag = flag.String("b-position", "first", "Position of the predicate column b in the row: first, before the filler columns, or last, after them (tables named like t1K_blast), to measure whether the column position affects the scan cost")
+	var generators stringList
+	var seed = flag.Int64("seed", 0, "Seed of the scenario order, the generated data and the rows picked for the selectivities and NULL values, so two runs on the same (recreated) tables are reproducible (0 for a random seed)")
+	var distribution = flag.String("distribution", "uniform", "Distribution of the b values: uniform, zipf (zipf(1.1)) or normal (normal(500000, 125000)), or one with parameters like 'zipf(1.5, seed=7)', a shorthand of -gen 'b:<distribution>'. The table names and scenario IDs of non-uniform distributions include it, like t1K_zipf and zipfindex_1K_10, and b is not adjusted to the selectivities, the matching rows are counted instead")
+	var predicateValuesFlag = flag.Int("predicate-values", 1, "Number of distinct b values with the same number of rows per selectivity, the repetitions of the b = value queries cycling through them instead of reading the same keys and cached regions every time")
+	var nullRatio = flag.String("null-ratio", "", "Ratio of NULL values in b and c, like 'b=0.1,c=0.5' (a ratio without a column is for b), for the null family and the NULL handling of the statistics")
+	flag.Var(&generators, "gen", "Custom column value generator 'column=expression', computed from the 0-based 'row' number, e.g. 'b=floor(row / 10) % 1000', or a seeded distribution 'column:uniform(max)', 'column:zipf(s[, n])' or 'column:normal(mean, stddev)', e.g. 'b:zipf(1.1, seed=42)' (can be repeated)")
+	var prometheusURL = flag.String("prometheus", "", "Prometheus URL of the cluster (e.g. http://127.0.0.1:9090), used to detect TiKV GC and compaction activity")
+	var pauseOnBackground = flag.Bool("pause-on-background", false, "Pause scenario execution while TiKV GC or compaction is heavy (requires -prometheus)")
+	var bgCompaction = flag.Float64("bg-compaction-mbps", 32, "Compaction flow (MB/s) considered heavy background work")
+	var bgGCKeys = flag.Float64("bg-gc-keys", 10000, "GC keys per second considered heavy background work")
+	var pingEvery = flag.Int("ping-every", 0, "Measure the SELECT 1 latency floor every N executions and annotate samples taken while it drifted (0 disables)")
+	var pingDrift = flag.Float64("ping-drift", 1.5, "Factor above the baseline latency floor that is considered drift")
+	var slaTargets stringList
+	flag.Var(&slaTargets, "sla", "SLA target '[scenario-regex:]pNN<duration', e.g. 'p95<50ms' or 'index_1M_.*:p99<200ms' (can be repeated, first match wins)")
+	var extrapolate = flag.String("extrapolate", "", "Comma-separated list of larger table sizes to extrapolate the measured latencies to (e.g. 1G,10G)")
+	var excludePlanTypes = flag.String("exclude-plan-types", "", "Comma-separated plan types, or patterns like tiflash_*, not allowed in production: still measured, but never the best plan of a cell in the misprediction analysis")
+	var parallelismReport = flag.Bool("parallelism", false, "Report the cop tasks and their concurrency per table size and plan type, how the scan parallelism grows with the regions of the tables")
+	var tableSizeRollup = flag.Bool("by-table-size", false, "Report a roll-up per table size, collapsing the selectivities: the optimizer agreement rate, geometric mean regret, and average latency and RU per plan type, and the table size from which the optimizer misbehaves")
+	var costGapReport = flag.Bool("cost-gap", false, "Report the cells where the estimated cost gap between the chosen plan and its fastest alternative disagrees with the measured latency gap: estimated far apart but measured close, or the other way around")
+	var crossoverReport = flag.Bool("crossover", false, "Report the index vs table scan crossover selectivity per family and table size, with its 95% confidence interval from the per-sample variance, flagging the intervals too wide to act on")
+	var doubleReadReport = flag.Bool("double-read", false, "Report the handle lookups per scanned index row of the IndexLookUp plans and the measured time per lookup, per scenario and overall")
+	var ruSplitReport = flag.Bool("ru-split", false, "Report the RU of IndexLookUp plans split into the index scan and table lookup phases")
+	var simulatedRTT = flag.Duration("simulated-rtt", 0, "Simulate a cross-region round trip time: injected client-side per query, and modelled per cop round trip when re-evaluating plan winners (e.g. 30ms)")
+	var outputJSON = flag.String("output-json", "", "Write the run metadata and all results to this JSON result file")
+	var maxLoadQPS = flag.Float64("max-load-qps", 0, "Maximum statements per second sent during table setup and scenario execution, to protect a shared cluster (0 is unlimited)")
+	var maxLoadRU = flag.Float64("max-load-ru-per-sec", 0, "Maximum RU per second consumed during table setup and scenario execution, to protect a shared cluster (0 is unlimited)")
+	var healthEvery = flag.Int("health-check-every", 0, "Check the cluster health (error rate, SELECT 1 p99, store availability) every N executions, pausing and eventually aborting the run if it degrades (0, the default, disables it, as the probes add load during the measurements)")
+	var healthMaxErrorRate = flag.Float64("health-max-error-rate", 0.5, "Fraction of failed recent executions considered unhealthy")
+	var healthMaxPing = flag.Duration("health-max-ping-p99", 500*time.Millisecond, "SELECT 1 p99 latency considered unhealthy (0 disables)")
+	var healthMaxPause = flag.Duration("health-max-pause", 5*time.Minute, "How long to wait for an unhealthy cluster to recover before aborting the run with partial results")
+	var analyzeFlag = flag.String("analyze", "", "Re-analyze the tables before the run, also reused ones, with comma-separated options: full (WITH 1 SAMPLERATE), samplerate=<rate> (WITH <rate> SAMPLERATE), all-columns (ANALYZE TABLE ... ALL COLUMNS), or skip to drop the statistics and measure the plans on pseudo statistics; empty keeps the statistics of the table setup")
+	var lockStats = flag.Bool("lock-stats", false, "Lock the statistics of the test tables during the run (LOCK STATS), so auto analyze and stats loading by the calibration queries cannot change the measured optimizer state")
+	var outputXLSX = flag.String("output-xlsx", "", "Write the aggregated results as an Excel workbook, with a summary sheet and one sheet per table size highlighting the optimizer mismatches")
+	var outputCSV = flag.String("output-csv", "", "Write the detailed and aggregated results as CSV files, <name>-detailed.csv and <name>-aggregated.csv")
+	var anonymize = flag.Bool("anonymize", false, "Hash the table and column names, and strip the store addresses, in the JSON and CSV result files")
+	var filterFlag = flag.String("filter", "", "Only run the scenarios whose ID matches this regular expression as a whole, like 'index_1M_.*', to re-run a subset of the matrix on the existing tables")
+	var excludeFlag = flag.String("exclude", "", "Leave out the scenarios whose ID matches this regular expression as a whole, like '.*_10M_.*'")
+	var shardSpec = flag.String("shard", "", "Only run shard <n>/<count> of the scenario matrix (e.g. 2/4), to split a run over several client machines and merge the result files afterwards")
+	var queryFrequencyColumns = flag.String("query-frequency-columns", "", "Comma-separated production=test column names, like customer_id=b, to rename the columns of the -query-frequencies query texts so their shapes match the test queries")
+	var queryFrequencies = flag.String("query-frequencies", "", "CSV file with the production rate of query shapes, with a header naming a digest or digest_text column and a qps or exec_count column, like an export of information_schema.statements_summary, to weight the calibration score and rank the plan mistakes by how often the query shapes run")
+	var expectations = flag.String("expectations", "", "Comma-separated TiDB planner test suite output files, like planner/core/casetest/testdata/plan_suite_out.json, whose expected plans are lined up with the measured cells of the same query shape")
+	var history = flag.String("history", "", "Append the run metadata and calibration score to this history file, for the trend command")
+	connectionConfig := registerConnectionFlags(flag.CommandLine)
+	var label = flag.String("label", "", "Short label identifying the run (e.g. \"post-upgrade v8.1\")")
+	var description = flag.String("desc", "", "Free-text description of the run, stored in the run metadata")
+	var scenariosFile = flag.String("scenarios", "", "YAML file with user defined tables (DDL and column generators) and query templates with hinted variants, expanded over -s and -c; runs only these unless -families is also given")
+	var configFile = flag.String("config", "", "TOML or YAML file with the run configuration (e.g. calibration.toml), flags given on the command line override it")
 
 	flag.Parse()
 
+	if *configFile != "" {
+		cfg, err := loadConfig(*configFile)
+		if err == nil {
+			err = applyConfig(flag.CommandLine, cfg)
+		}
+		if err != nil {
+			slog.Error("Invalid config file", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// Set up structured logging with slog
 	setupLogging(*logLevel)
 
+	tidbConfig, err := connectionConfig()
+	if err != nil {
+		slog.Error("Invalid connection settings", "error", err)
+		os.Exit(1)
+	}
+
 	// Parse row counts
 	rows, err := parseRowCounts(*rowCounts)
 	if err != nil {
@@ -45,26 +202,552 @@ func main() {
 		os.Exit(1)
 	}
 
+	families, err := parseFamilies(*familiesFlag)
+	if err != nil {
+		slog.Error("Invalid scenario families", "error", err)
+		os.Exit(1)
+	}
+	matrix := MatrixOptions{RangeSpan: *rangeSpan, TiFlash: *tiflash}
+
+	var customScenarios *ScenarioFile
+	if *scenariosFile != "" {
+		customScenarios, err = loadScenarioFile(*scenariosFile)
+		if err != nil {
+			slog.Error("Invalid scenarios", "error", err)
+			os.Exit(1)
+		}
+		// Only the custom scenarios, unless families are asked for too
+		familiesGiven := false
+		flag.Visit(func(f *flag.Flag) { familiesGiven = familiesGiven || f.Name == "families" })
+		if !familiesGiven && len(customScenarios.Scenarios) > 0 {
+			families = nil
+		}
+	}
+
+	if _, err = parseLoader(*loader); err != nil {
+		slog.Error("Invalid loader", "error", err)
+		os.Exit(1)
+	}
+
+	matrix.PrimaryKeys, err = parsePrimaryKeyKinds(*primaryKeys)
+	if err != nil {
+		slog.Error("Invalid primary key", "error", err)
+		os.Exit(1)
+	}
+
+	if *partitions != "" {
+		matrix.Partitions, err = parsePartitionSpec(*partitions)
+		if err != nil {
+			slog.Error("Invalid partitioning", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	excludedPlanTypes, err := parsePlanTypePatterns(*excludePlanTypes)
+	if err != nil {
+		slog.Error("Invalid excluded plan types", "error", err)
+		os.Exit(1)
+	}
+	outlierFilter, err := parseOutlierFilter(*outliers)
+	if err != nil {
+		slog.Error("Invalid -outliers", "error", err)
+		os.Exit(1)
+	}
+	var frequencies *QueryFrequencies
+	frequencyColumns, err := parseColumnMapping(*queryFrequencyColumns)
+	if err != nil {
+		slog.Error("Invalid -query-frequency-columns", "error", err)
+		os.Exit(1)
+	}
+	if *queryFrequencies != "" {
+		if frequencies, err = loadQueryFrequencies(*queryFrequencies, frequencyColumns); err != nil {
+			slog.Error("Invalid -query-frequencies", "error", err)
+			os.Exit(1)
+		}
+	}
+	var fixtureCases []*FixtureCase
+	if *expectations != "" {
+		if fixtureCases, err = readFixtureFiles(strings.Split(*expectations, ",")); err != nil {
+			slog.Error("Invalid -expectations", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var analyzeSpec *AnalyzeSpec
+	if *analyzeFlag != "" {
+		analyzeSpec, err = parseAnalyzeSpec(*analyzeFlag)
+		if err != nil {
+			slog.Error("Invalid analyze options", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if *boundaryBucketsFlag < 1 {
+		slog.Error("Invalid -boundary-buckets, expected at least 1")
+		os.Exit(1)
+	}
+
+	matrix.InListLengths, err = parseInListLengths(*inLists)
+	if err != nil {
+		slog.Error("Invalid IN-list lengths", "error", err)
+		os.Exit(1)
+	}
+
+	for i, def := range generators {
+		column, gen, err := ParseColumnGenerator(def)
+		if err != nil {
+			slog.Error("Invalid column generator", "error", err)
+			os.Exit(1)
+		}
+		RegisterColumnGenerator(column, gen)
+		generators[i] = canonicalGenerator(def, column, gen)
+	}
+
+	fillerSizes, err := parseFillerSizes(*fillerSizesFlag)
+	if err != nil {
+		slog.Error("Invalid filler sizes", "error", err)
+		os.Exit(1)
+	}
+	if len(fillerSizes) > 1 {
+		matrix.FillerSizes = fillerSizes
+	}
+	if matrix.Layout.Sizes, err = parseFillerColumns(*fillerColumnsFlag); err != nil {
+		slog.Error("Invalid -fillers", "error", err)
+		os.Exit(1)
+	}
+	if matrix.Layout.BLast, err = parseBPosition(*bPositionFlag); err != nil {
+		slog.Error("Invalid -b-position", "error", err)
+		os.Exit(1)
+	}
+
+	if *predicateValuesFlag < 1 {
+		slog.Error("Invalid -predicate-values, must be at least 1", "value", *predicateValuesFlag)
+		os.Exit(1)
+	}
+	matrix.PredicateValues = *predicateValuesFlag
+
+	matrix.NullRatios, err = parseNullRatios(*nullRatio)
+	if err != nil {
+		slog.Error("Invalid NULL ratio", "error", err)
+		os.Exit(1)
+	}
+
+	if *seed != 0 {
+		setSeed(*seed)
+	}
+
+	// -distribution is a shorthand of -gen b:<distribution>, both name the tables after the distribution
+	bDistribution, err := parseDistribution(*distribution)
+	if err != nil {
+		slog.Error("Invalid distribution", "error", err)
+		os.Exit(1)
+	}
+	if bDistribution != nil {
+		if _, ok := columnGenerators["b"]; ok {
+			slog.Error("Invalid distribution, b already has a generator given with -gen")
+			os.Exit(1)
+		}
+		RegisterColumnGenerator("b", *bDistribution)
+		generators = append(generators, canonicalGenerator("", "b", *bDistribution))
+	}
+	if dist, ok := columnGenerators["b"].(distGenerator); ok {
+		matrix.Distribution = &dist
+	}
+	if matrix.Distribution != nil && matrix.PredicateValues > 1 {
+		// The b values of a distribution are kept, so the extra values have no rows
+		slog.Error("-predicate-values cannot be combined with a distribution of b")
+		os.Exit(1)
+	}
+
+	shard, shardCount := 1, 1
+	if *shardSpec != "" {
+		shard, shardCount, err = parseShard(*shardSpec)
+		if err != nil {
+			slog.Error("Invalid shard", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	include, err := parseScenarioPattern(*filterFlag)
+	if err != nil {
+		slog.Error("Invalid -filter", "error", err)
+		os.Exit(1)
+	}
+	exclude, err := parseScenarioPattern(*excludeFlag)
+	if err != nil {
+		slog.Error("Invalid -exclude", "error", err)
+		os.Exit(1)
+	}
+
+	var extrapolateRows []int
+	if *extrapolate != "" {
+		extrapolateRows, err = parseRowCounts(*extrapolate)
+		if err != nil {
+			slog.Error("Invalid extrapolation table sizes", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var slas []*SLATarget
+	for _, s := range slaTargets {
+		target, err := parseSLATarget(s)
+		if err != nil {
+			slog.Error("Invalid SLA target", "error", err)
+			os.Exit(1)
+		}
+		slas = append(slas, target)
+	}
+
 	slog.Debug("Row counts to test", "rows", rows)
 	slog.Debug("Selectivity values to test", "selectivities", selValues)
 
-	err = CheckAndSetupTables(rows, selValues, *fillerSize)
+	meta := NewRunMetadata(*label, *description)
+	meta.RowCounts = rows
+	meta.Selectivities = selValues
+	meta.FillerSize = fillerSizes[0]
+	meta.FillerSizes = matrix.FillerSizes
+	meta.FillerLayout = matrix.Layout.params()
+	meta.Repetitions = *repetitions
+	meta.Warmup = *warmup
+	meta.AdaptiveMax = *adaptiveMax
+	meta.Strict = *strict
+	meta.Outliers = outlierFilter.String()
+	meta.QueryFrequencies = *queryFrequencies
+	meta.QueryFrequencyColumns = *queryFrequencyColumns
+	meta.Expectations = *expectations
+	meta.Generators = generators
+	meta.Seed = runSeed
+	meta.Families = families
+	if customScenarios != nil {
+		meta.ScenarioFile = *scenariosFile
+	}
+	meta.PrimaryKeys = matrix.PrimaryKeys
+	meta.ExcludedPlanTypes = excludedPlanTypes
+	meta.ExplainAnalyze = *explainAnalyze
+	coprCache, err := parseCoprCacheMode(*coprCacheFlag)
+	if err != nil {
+		slog.Error("Invalid -copr-cache", "error", err)
+		os.Exit(1)
+	}
+	if coprCache != coprCacheBust {
+		meta.CoprCache = coprCache
+	}
+	if len(matrix.NullRatios) > 0 {
+		meta.NullRatios = matrix.NullRatios
+	}
+	if matrix.PredicateValues > 1 {
+		meta.PredicateValues = matrix.PredicateValues
+	}
+	tableSuffix := ""
+	if *uniqueTables {
+		tableSuffix = meta.RunID
+		meta.TableSuffix = tableSuffix
+	}
+	if customScenarios != nil {
+		meta.CustomTables = customScenarios.tableNames(rows, tableSuffix)
+		meta.CustomColumns = customScenarios.columnNames()
+	}
+	if shardCount > 1 {
+		meta.Shard = *shardSpec
+	}
+	meta.Filter = *filterFlag
+	meta.Exclude = *excludeFlag
+
+	if *skipSetup && (*setupOnly || *recreate) {
+		slog.Error("-skip-setup cannot be combined with -setup-only or -recreate")
+		os.Exit(1)
+	}
+	if *explainAnalyze && *analyzeOverheadEvery > 0 {
+		slog.Error("-analyze-overhead-every compares with plain executions, it cannot be combined with -explain-analyze")
+		os.Exit(1)
+	}
+	if *setupOnly && *cleanup {
+		slog.Error("-setup-only cannot be combined with -cleanup")
+		os.Exit(1)
+	}
+	limiter := NewLoadLimiter(*maxLoadQPS, *maxLoadRU)
+	setupOpts := SetupOptions{
+		FillerSize:       fillerSizes[0],
+		Recreate:         *recreate,
+		TableSuffix:      tableSuffix,
+		TiDB:             tidbConfig,
+		Limiter:          limiter,
+		JoinTables:       slices.Contains(families, "join"),
+		IndexMergeTables: slices.Contains(families, "indexmerge"),
+		CompositeTables:  slices.Contains(families, "composite"),
+		UniquenessTables: slices.Contains(families, "unique"),
+		Matrix:           matrix,
+		TiFlashWait:      *tiflashWait,
+		LoadConcurrency:  *loadConcurrency,
+		LoadBatchSize:    *loadBatchSize,
+		Loader:           *loader,
+	}
+	switch {
+	case len(families) == 0:
+	case *skipSetup:
+		err = VerifyTables(rows, setupOpts)
+	default:
+		err = CheckAndSetupTables(rows, selValues, setupOpts)
+	}
+	if err == nil && customScenarios != nil {
+		if *skipSetup {
+			err = verifyCustomTables(customScenarios, rows, setupOpts)
+		} else {
+			err = setupCustomTables(customScenarios, rows, setupOpts)
+		}
+	}
 	if err != nil {
 		slog.Error("Failed to create all the tables", "error", err)
 		os.Exit(1)
 	}
+	if *setupOnly {
+		fmt.Println("✅ Tables are set up, reuse them with -skip-setup")
+		return
+	}
+	var analyzedTables []string
+	if analyzeSpec != nil {
+		meta.Analyze = analyzeSpec.String()
+		if len(families) > 0 {
+			analyzedTables = setupOpts.tableNames(rows)
+		}
+		if customScenarios != nil {
+			analyzedTables = append(analyzedTables, customScenarios.tableNames(rows, tableSuffix)...)
+		}
+		if err = AnalyzeTables(analyzedTables, analyzeSpec, tidbConfig, meta); err != nil {
+			slog.Error("Failed to analyze the tables", "error", err)
+			os.Exit(1)
+		}
+	}
+	if slices.Contains(families, "boundary") {
+		if matrix.BoundaryPredicates, err = setupBoundaryPredicates(tableSpecs(rows, tableSuffix, matrix), *boundaryBucketsFlag, tidbConfig); err != nil {
+			slog.Error("Failed to read the histogram buckets", "error", err)
+			os.Exit(1)
+		}
+	}
 	// Run comprehensive optimizer tests
-	results := RunOptimizerTests(rows, selValues, *repetitions)
+	runOpts := RunOptions{
+		Repetitions:     *repetitions,
+		Warmup:          *warmup,
+		CaptureRCWait:   *rcWait,
+		IgnorePlanCache: *ignorePlanCache,
+		ExplainAnalyze:  *explainAnalyze,
+		CoprCache:       coprCache,
+		SimulatedRTT:    *simulatedRTT,
+		Shard:           shard,
+		ShardCount:      shardCount,
+		Filter:          include,
+		Exclude:         exclude,
+		Metadata:        meta,
+		TableSuffix:     tableSuffix,
+		TiDB:            tidbConfig,
+		Limiter:         limiter,
+		LockStats:       *lockStats,
+		Families:        families,
+		Matrix:          matrix,
+		KeepAlive:       *keepAlive,
+		CustomScenarios: customScenarios,
+		Hooks:           hooks.Registered(),
+		AdaptiveMax:     *adaptiveMax,
+		Strict:          *strict,
+		ChecksumTables:  *checksumTables,
+
+		AnalyzeOverheadEvery: *analyzeOverheadEvery,
+	}
+	if *hookCommand != "" {
+		runOpts.Hooks = append(runOpts.Hooks, hooks.Command(*hookCommand))
+	}
+	for _, hook := range runOpts.Hooks {
+		meta.Hooks = append(meta.Hooks, hook.Name())
+	}
+	if *confirmOver > 0 {
+		runOpts.Preflight = &Preflight{ConfirmOver: *confirmOver, AssumeYes: *assumeYes}
+	}
+	if *healthEvery > 0 {
+		runOpts.Health = &HealthMonitor{
+			Every:        *healthEvery,
+			MaxErrorRate: *healthMaxErrorRate,
+			MaxPingP99:   *healthMaxPing,
+			MaxPause:     *healthMaxPause,
+		}
+	}
+	if *pingEvery > 0 {
+		runOpts.LatencyFloor = &LatencyFloor{Every: *pingEvery, DriftFactor: *pingDrift}
+	}
+	if *prometheusURL != "" {
+		runOpts.Background = NewBackgroundMonitor(strings.TrimSuffix(*prometheusURL, "/"), *bgCompaction, *bgGCKeys, *pauseOnBackground)
+	} else if *pauseOnBackground {
+		slog.Error("-pause-on-background requires -prometheus")
+		os.Exit(1)
+	}
+	dropTables := func() {
+		if len(families) > 0 {
+			if err := DropTables(tableSpecs(rows, tableSuffix, matrix), matrix.Partitions, tidbConfig); err != nil {
+				slog.Error("Failed to clean up tables", "error", err)
+			}
+		}
+		if customScenarios != nil {
+			if err := dropCustomTables(customScenarios, rows, tableSuffix, tidbConfig); err != nil {
+				slog.Error("Failed to clean up tables", "error", err)
+			}
+		}
+	}
+	if *checkpointPath != "" {
+		// The checkpoint records the cluster, to check it when resuming
+		if err = meta.CollectServerInfo(tidbConfig); err != nil {
+			slog.Warn("Failed to collect server info for the checkpoint", "error", err)
+		}
+		meta.Checkpoint = *checkpointPath
+		if runOpts.Checkpoint, err = openCheckpoint(*checkpointPath, meta, *resume); err != nil {
+			slog.Error("Failed to open the checkpoint", "error", err)
+			os.Exit(1)
+		}
+		defer runOpts.Checkpoint.Close()
+	} else if *resume {
+		slog.Error("-resume requires -checkpoint")
+		os.Exit(1)
+	}
+	allResults := RunOptimizerTests(rows, selValues, runOpts)
+	if len(analyzedTables) > 0 {
+		if err = UnlockTableStats(analyzedTables, tidbConfig); err != nil {
+			slog.Warn("Failed to unlock the statistics of the tables", "error", err)
+		}
+	}
+	meta.OutliersDropped = markOutliers(allResults, outlierFilter)
+	// The warm-up executions and outliers are only exported, flagged, all reports leave them out
+	results := measuredResults(allResults)
+	if meta.Aborted == preflightNotConfirmed {
+		if *cleanup {
+			dropTables()
+		}
+		return
+	}
+	meta.EndTime = time.Now()
+	if limiter != nil {
+		meta.AddEvent("load_throttled", limiter.Waited(), fmt.Sprintf("max %g qps, %g RU/s", *maxLoadQPS, *maxLoadRU))
+	}
+	if err = meta.CollectServerInfo(tidbConfig); err != nil {
+		slog.Warn("Failed to collect server info for run metadata", "error", err)
+	}
+	meta.NoRU = !ruReported(results)
+	if *cleanup {
+		dropTables()
+	}
 
-	if *detailedOutput {
+	// An interrupted run prints everything completed so far
+	interrupted := meta.Aborted == interruptedByUser
+	outputRunMetadata(meta)
+	if *detailedOutput || interrupted {
 		outputDetailedResultsTable(results)
 	}
-	if *aggregatedOutput {
+	if *aggregatedOutput || interrupted {
 		outputAggregatedResultsTable(results)
 	}
+	if *rcWait {
+		outputThrottlingReport(results)
+	}
+	outputPlanCacheWarning(results)
+	if matrix.TiFlash {
+		outputTiFlashCrossover(results)
+	}
+	if slices.Contains(families, "unique") {
+		outputUniquenessComparison(results, excludedPlanTypes)
+	}
+	if matrix.Partitions != nil {
+		outputPartitionCrossover(results, matrix.Partitions, excludedPlanTypes)
+	}
+	if len(slas) > 0 {
+		outputSLAReport(results, slas)
+	}
+	if *ruSplitReport {
+		outputRUSplit(results)
+	}
+	if *doubleReadReport {
+		outputDoubleReadReport(results)
+	}
+	if *parallelismReport {
+		outputParallelismReport(results)
+	}
+	if *crossoverReport {
+		outputCrossoverReport(results, excludedPlanTypes)
+	}
+	if coprCache == coprCacheKeep {
+		outputCoprCacheReport(results)
+	}
+	if *analyzeOverheadEvery > 0 {
+		outputAnalyzeOverhead(results)
+	}
+	if *simulatedRTT > 0 {
+		outputRTTReport(results, *simulatedRTT)
+	}
+	if len(extrapolateRows) > 0 {
+		outputExtrapolation(results, extrapolateRows, selValues)
+	}
+	cells := analyzeCells(results, excludedPlanTypes)
+	outputMispredictionSummary(cells)
+	if *tableSizeRollup {
+		outputTableSizeRollup(cells)
+	}
+	if *costGapReport {
+		outputCostGapReport(cells)
+	}
+	if frequencies != nil {
+		outputFrequencyMistakes(cells, applyQueryFrequencies(cells, results, frequencies))
+	}
+	if fixtureCases != nil {
+		outputFixtureMatches(matchFixtures(fixtureCases, results, excludedPlanTypes), len(fixtureCases))
+	}
+	score := computeCalibrationScore(cells)
+	outputCalibrationScore(score)
+	exportMeta, exportResults := meta, allResults
+	if *anonymize {
+		a := newResultsAnonymizer(allResults, meta)
+		exportMeta, exportResults = a.metadata(meta), a.results(allResults)
+	}
+	if *outputJSON != "" {
+		if err = writeResultSet(*outputJSON, exportMeta, exportResults); err != nil {
+			slog.Error("Failed to write JSON results", "error", err)
+		}
+	}
+	if *outputCSV != "" {
+		if detailedPath, aggregatedPath, err := writeResultsCSV(*outputCSV, exportResults); err != nil {
+			slog.Error("Failed to write CSV results", "error", err)
+		} else {
+			slog.Info("Wrote CSV results", "detailed", detailedPath, "aggregated", aggregatedPath)
+		}
+	}
+	if *outputXLSX != "" {
+		if err = writeResultsXLSX(*outputXLSX, exportResults, excludedPlanTypes); err != nil {
+			slog.Error("Failed to write Excel results", "error", err)
+		} else {
+			slog.Info("Wrote Excel results", "path", *outputXLSX)
+		}
+	}
+	if interrupted {
+		// A partial run is not comparable in the calibration history
+		fmt.Printf("\n🛑 TiDB Optimizer Calibration interrupted, %d executions reported\n", len(allResults))
+		runOpts.Checkpoint.Close()
+		os.Exit(exitInterrupted)
+	}
+	if *history != "" {
+		if err = appendHistory(*history, HistoryEntry{Metadata: meta, Score: score}); err != nil {
+			slog.Error("Failed to append to history", "error", err)
+		}
+	}
 	fmt.Println("\n✅ TiDB Optimizer Calibration completed successfully!")
 }
 
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 // parseRowCounts parses comma-separated row counts from command line
 func parseRowCounts(rowCountsStr string) ([]int, error) {
 	if rowCountsStr == "" {
@@ -99,8 +782,9 @@ func parseRowCounts(rowCountsStr string) ([]int, error) {
 			return nil, fmt.Errorf("invalid row count '%s': %w", part, err)
 		}
 
-		if rowCount <= 0 {
-			return nil, fmt.Errorf("row count must be positive, got %d", rowCount)
+		// Empty and single row tables are valid sizes, for the small table fast paths
+		if rowCount < 0 {
+			return nil, fmt.Errorf("row count must not be negative, got %d", rowCount)
 		}
 
 		rows = append(rows, rowCount*multiplier)
@@ -158,10 +842,11 @@ func parseSelectivities(selectivitiesStr string) ([]float64, error) {
 
 // formatRowCount formats a row count into a human-readable string
 func formatRowCount(count int) string {
+	// Only exact multiples get a suffix, so 1500 rows is not named like 1K
 	switch {
-	case count >= 1000000:
+	case count >= 1000000 && count%1000000 == 0:
 		return fmt.Sprintf("%dM", count/1000000)
-	case count >= 1000:
+	case count >= 1000 && count%1000 == 0:
 		return fmt.Sprintf("%dK", count/1000)
 	default:
 		return fmt.Sprintf("%d", count)
@@ -226,13 +911,104 @@ func setupLogging(level string) {
 	slog.SetDefault(slog.New(handler))
 }
 
+// RunOptions holds the settings for executing the test scenarios
+type RunOptions struct {
+	Repetitions int
+	// Warmup is the number of warm-up executions of each scenario before the measured ones
+	Warmup int
+	// CaptureRCWait records the time each execution was queued by resource control
+	CaptureRCWait bool
+	// Shard (1-based) of ShardCount selects a deterministic subset of the scenario matrix
+	Shard      int
+	ShardCount int
+	// Filter and Exclude select the scenarios by ID, nil for all
+	Filter  *regexp.Regexp
+	Exclude *regexp.Regexp
+	// SimulatedRTT is an artificial delay added to each measured execution
+	SimulatedRTT time.Duration
+	// Background, if set, detects (and optionally pauses for) heavy TiKV background work
+	Background *BackgroundMonitor
+	// LatencyFloor, if set, interleaves SELECT 1 probes to detect latency floor drift
+	LatencyFloor *LatencyFloor
+	// Metadata, if set, gets events like pauses recorded in its timeline
+	Metadata *RunMetadata
+	// TableSuffix, if set, is appended to the table names, to keep runs apart
+	TableSuffix string
+	// TiDB is the connection configuration, nil for the default localhost:4000
+	TiDB *TiDBConfig
+	// Limiter caps the load of the executions, nil for no limit
+	Limiter *LoadLimiter
+	// Health, if set, pauses or aborts the run when the cluster degrades
+	Health *HealthMonitor
+	// Families are the scenario families to run, default point lookups
+	Families []string
+	// Matrix are the settings of the scenario matrix
+	Matrix MatrixOptions
+	// LockStats locks the statistics of the test tables during the run, so the
+	// measurement workload cannot change the optimizer state it is measuring
+	LockStats bool
+	// IgnorePlanCache makes every execution re-optimize, instead of using a cached plan
+	IgnorePlanCache bool
+	// ExplainAnalyze executes the measured queries under EXPLAIN ANALYZE, instead
+	// of plainly followed by EXPLAIN FOR CONNECTION
+	ExplainAnalyze bool
+	// CoprCache is how executions served from the coprocessor cache are handled
+	CoprCache string
+	// Preflight, if set, estimates the run duration and asks for confirmation of long runs
+	Preflight *Preflight
+	// KeepAlive is the interval of the keep-alive pings of the connections, 0 for none
+	KeepAlive time.Duration
+	// AnalyzeOverheadEvery also executes every Nth measured query under EXPLAIN ANALYZE, 0 for none
+	AnalyzeOverheadEvery int
+	// CustomScenarios, if set, are run after the scenarios of Families, which may be empty
+	CustomScenarios *ScenarioFile
+	// Hooks are the custom metrics collectors called around every execution
+	Hooks []hooks.Hook
+	// AdaptiveMax is the most extra repetitions of a variant scheduled while
+	// the fastest variants of its cell overlap, 0 for none
+	AdaptiveMax int
+	// Strict replaces the retried executions by clean ones
+	Strict bool
+	// ChecksumTables records the ADMIN CHECKSUM TABLE of the tables in their snapshots
+	ChecksumTables bool
+	// Checkpoint records the completed executions, and skips the ones of the resumed run
+	Checkpoint *Checkpoint
+}
+
 // RunOptimizerTests runs comprehensive optimizer calibration tests
-func RunOptimizerTests(rowCounts []int, selectivities []float64, repetitions int) []*TestExecutionResult {
+func RunOptimizerTests(rowCounts []int, selectivities []float64, opts RunOptions) []*TestExecutionResult {
 	slog.Info("Running TiDB Optimizer Calibration Tests")
 	slog.Info("======================================")
 
 	// Get comprehensive test scenarios with custom row counts and selectivities
-	scenarios := GetTestScenariosWithRowCountsAndSelectivities(rowCounts, selectivities, repetitions)
+	var scenarios []TestScenario
+	if len(opts.Families) > 0 || opts.CustomScenarios == nil {
+		scenarios = GetTestScenarios(tableSpecs(rowCounts, opts.TableSuffix, opts.Matrix), selectivities, opts.Matrix, opts.Families...)
+	}
+	if opts.CustomScenarios != nil {
+		scenarios = append(scenarios, opts.CustomScenarios.Expand(rowCounts, selectivities, opts.TableSuffix)...)
+		opts.CustomScenarios.applyRepetitions(scenarios)
+	}
+	if opts.Filter != nil || opts.Exclude != nil {
+		scenarios = filterScenarios(scenarios, opts.Filter, opts.Exclude)
+		fmt.Printf("\n🔎 Running the %d scenarios selected by -filter and -exclude\n", len(scenarios))
+	}
+	if opts.ShardCount > 1 {
+		scenarios = filterShard(scenarios, opts.Shard, opts.ShardCount)
+		fmt.Printf("\n🧩 Running shard %d/%d of the scenario matrix\n", opts.Shard, opts.ShardCount)
+	}
+	schedule := NewSchedule(scenarios, opts.Repetitions)
+	if opts.Matrix.PredicateValues > 1 {
+		// Explain the query with each of the values, their plans may differ
+		for i, scenario := range scenarios {
+			if scenario.ExplainOnly && scenario.Repetitions == 0 && scenario.MatchingRows > 0 {
+				schedule.Add(i, opts.Matrix.PredicateValues-1)
+			}
+		}
+	}
+	if opts.Warmup > 0 {
+		schedule.AddWarmup(opts.Warmup)
+	}
 
 	fmt.Printf("\n📋 Test Suite Overview: %d comprehensive scenarios\n", len(scenarios))
 	fmt.Println("Focus: Index Lookup vs Table Scan decisions")
@@ -256,56 +1032,234 @@ func RunOptimizerTests(rowCounts []int, selectivities []float64, repetitions int
 	fmt.Println("================================================")
 
 	// Run all test combinations with real execution
-	return runAllTestCombinations(scenarios)
+	return runAllTestCombinations(schedule, opts)
 }
 
 // runAllTestCombinations runs all test combinations against a real TiDB cluster
-func runAllTestCombinations(scenarios []TestScenario) []*TestExecutionResult {
+func runAllTestCombinations(schedule *Schedule, opts RunOptions) []*TestExecutionResult {
 
-	slog.Info("Connecting to TiDB cluster", "scenarios", len(scenarios))
-	fmt.Printf("Connecting to TiDB cluster and executing %d test scenarios...\n", len(scenarios))
+	slog.Info("Connecting to TiDB cluster", "executions", schedule.Len())
+	fmt.Printf("Connecting to TiDB cluster and executing %d test scenarios...\n", schedule.Len())
 	fmt.Println()
 
 	client := NewTiDBClient()
+	client.captureRCWait = opts.CaptureRCWait
+	client.simulatedRTT = opts.SimulatedRTT
+	client.limiter = opts.Limiter
+	client.ignorePlanCache = opts.IgnorePlanCache
+	client.explainAnalyze = opts.ExplainAnalyze
+	client.coprCache = opts.CoprCache
 
-	err := client.Connect(nil)
+	err := client.Connect(opts.TiDB)
 	if err != nil {
+		config := opts.TiDB
+		if config == nil {
+			config = &defaultTiDBConfig
+		}
 		slog.Error("Failed to connect to TiDB", "error", err)
 		fmt.Printf("❌ Failed to connect to TiDB: %v\n", err)
-		fmt.Println("Please ensure TiDB is running on localhost:4000")
+		fmt.Printf("Please ensure TiDB is running on %s:%d\n", config.Host, config.Port)
 		fmt.Println("You can start TiDB with: tiup playground")
 		return nil
 	}
 	defer client.Close()
+	defer client.KeepAlive(opts.KeepAlive)()
 
 	slog.Info("Connected to TiDB cluster successfully")
 	fmt.Println("✅ Connected to TiDB cluster successfully!")
 	fmt.Println()
 
+	if !opts.Preflight.Check(client, schedule) {
+		fmt.Println("🛑 Run cancelled")
+		if opts.Metadata != nil {
+			opts.Metadata.Aborted = preflightNotConfirmed
+		}
+		return nil
+	}
+
+	var tables []string
+	for _, scenario := range schedule.Scenarios() {
+		if !slices.Contains(tables, scenario.TableName) {
+			tables = append(tables, scenario.TableName)
+		}
+	}
+	if opts.LockStats {
+		if err = client.setStatsLocked(tables, true); err != nil {
+			slog.Warn("Failed to lock statistics, they may change during the run", "error", err)
+		} else {
+			defer func() {
+				if err := client.setStatsLocked(tables, false); err != nil {
+					slog.Error("Failed to unlock statistics", "error", err)
+				}
+			}()
+		}
+	}
+	if err = client.countMatchingRows(schedule.Scenarios()); err != nil {
+		slog.Warn("Failed to count the matching rows, reporting the expected ones", "error", err)
+	}
+	statsBefore := client.snapshotStats(tables)
+	if opts.Metadata != nil {
+		opts.Metadata.TableSnapshots = client.snapshotTables(tables, opts.ChecksumTables)
+	}
+	if err = opts.Checkpoint.Start(opts.Metadata); err != nil {
+		slog.Error("Failed to start the checkpoint", "error", err)
+		return nil
+	}
+	runStart := time.Now()
+
 	// Run all scenarios with repetitions and collect results
 	var results []*TestExecutionResult
-	totalScenarios := len(scenarios)
 	completed := 0
+	// measured counts the executions for the EXPLAIN ANALYZE sampling
+	measured := 0
+	coprCacheHits := 0
+	var adaptive *AdaptiveRepetitions
+	if opts.AdaptiveMax > 0 {
+		adaptive = newAdaptiveRepetitions(schedule, opts.AdaptiveMax, opts.Repetitions)
+		schedule.OnDrained(func() { adaptive.round(results) })
+	}
+	var strict *StrictMode
+	if opts.Strict {
+		strict = newStrictMode(schedule)
+	}
+	if resumed := opts.Checkpoint.Resumed(schedule); len(resumed) > 0 {
+		fmt.Printf("⏯️  Resuming the run, %d executions were completed before\n", len(resumed))
+		results = append(results, resumed...)
+		if opts.Metadata != nil {
+			opts.Metadata.Resumed = len(resumed)
+		}
+	}
 
-	for _, scenario := range scenarios {
+	interrupted, stopInterrupt := notifyInterrupt()
+	defer stopInterrupt()
+
+	for run := range schedule.All() {
+		if interrupted() {
+			if opts.Metadata != nil {
+				opts.Metadata.Aborted = interruptedByUser
+			}
+			break
+		}
+		scenario := run.Scenario
 		if completed%10 == 0 {
-			fmt.Printf("Progress: %d/%d scenarios completed\n", completed, totalScenarios)
+			fmt.Printf("Progress: %d/%d scenarios completed\n", completed, schedule.Len())
 		}
 		completed++
+		if opts.Checkpoint.Done(run) {
+			continue
+		}
 
-		slog.Debug("Executing scenario", "id", scenario.ID, "query", scenario.Query)
+		slog.Debug("Executing scenario", "id", scenario.ID, "repetition", run.Repetition, "query", scenario.Query)
+
+		duringBackgroundWork := false
+		if opts.Background != nil && !scenario.ExplainOnly {
+			duringBackgroundWork = opts.Background.WaitForQuiet(opts.Metadata)
+		}
+		var floor time.Duration
+		floorDrifted := false
+		if opts.LatencyFloor != nil && !scenario.ExplainOnly {
+			floor, floorDrifted = opts.LatencyFloor.MaybeProbe(client, opts.Metadata)
+		}
+
+		if err := opts.Health.MaybeCheck(client, opts.Metadata); err != nil {
+			fmt.Printf("🛑 Aborting the run, reporting the partial results: %v\n", err)
+			if opts.Metadata != nil {
+				opts.Metadata.Aborted = err.Error()
+			}
+			break
+		}
 
+		if opts.Matrix.PredicateValues > 1 {
+			valued := withPredicateValue(*scenario, run.Repetition, opts.Matrix.PredicateValues)
+			scenario = &valued
+		}
+		runBeforeHooks(opts.Hooks, scenario, run.Repetition)
 		// Execute real test with actual TiDB and capture actual execution plan
-		result, err := client.ExecuteQueryWithMetrics(scenario)
+		result, err := client.ExecuteQueryWithMetrics(*scenario)
+		if err != nil && isConnectionError(err) {
+			// Replace the broken connection and retry once, instead of failing all the following executions
+			fmt.Printf("🔌 Connection lost running scenario %s, reconnecting: %v\n", scenario.ID, err)
+			start := time.Now()
+			if rerr := client.Reconnect(); rerr != nil {
+				slog.Error("Failed to reconnect", "error", rerr)
+			} else {
+				opts.Metadata.AddEvent("reconnect", time.Since(start), err.Error())
+				result, err = client.ExecuteQueryWithMetrics(*scenario)
+				if err == nil {
+					result.Retried = retriedReconnect
+				}
+			}
+		}
+		opts.Health.Record(err != nil)
 		if err != nil {
 			fmt.Printf("❌ Error running scenario %s: %v\n", scenario.ID, err)
 			continue
 		} else {
 			slog.Debug("Scenario completed", "scenario_id", scenario.ID, "plan_type", result.PlanType)
 		}
+		if opts.CoprCache == coprCacheExclude && result.CoprCacheHitRatio > 0 {
+			slog.Debug("Leaving out an execution served from the coprocessor cache", "scenario", scenario.ID, "hit_ratio", result.CoprCacheHitRatio)
+			coprCacheHits++
+			continue
+		}
+		result.Repetition = run.Repetition
+		result.Warmup = run.Warmup
+		if !strict.accept(run, result) {
+			slog.Debug("Replacing a retried execution", "scenario", scenario.ID, "retried", result.Retried)
+			continue
+		}
+		runAfterHooks(opts.Hooks, result)
+		if opts.AnalyzeOverheadEvery > 0 && !scenario.ExplainOnly && !run.Warmup && !isDML(result.Query) {
+			if measured++; measured%opts.AnalyzeOverheadEvery == 0 {
+				if result.AnalyzePlainTime, result.AnalyzeTime, err = client.ExplainAnalyzeTime(result.Query); err != nil {
+					slog.Warn("Failed to sample EXPLAIN ANALYZE", "scenario", scenario.ID, "error", err)
+				}
+			}
+		}
+		if !result.StartTime.IsZero() {
+			result.RunOffset = result.StartTime.Sub(runStart)
+		}
+		result.DuringBackgroundWork = duringBackgroundWork
+		result.LatencyFloor = floor
+		result.FloorDrifted = floorDrifted
+		if err := opts.Checkpoint.Record(result); err != nil {
+			slog.Warn("Failed to record the execution in the checkpoint", "error", err)
+		}
 		results = append(results, result)
 	}
 
+	if adaptive != nil {
+		adaptive.outputAdaptiveSummary()
+		if opts.Metadata != nil {
+			opts.Metadata.AdaptiveExtra = adaptive.Added
+			opts.Metadata.AdaptiveUnresolved = adaptive.Unresolved
+		}
+	}
+	if strict != nil {
+		strict.outputStrictSummary()
+		if opts.Metadata != nil {
+			opts.Metadata.StrictReplaced = strict.Replaced
+			opts.Metadata.StrictDropped = strict.Dropped
+		}
+	}
+	if coprCacheHits > 0 {
+		fmt.Printf("🗄️  Left out %d executions served from the coprocessor cache\n", coprCacheHits)
+		opts.Metadata.AddEvent("copr_cache", 0, fmt.Sprintf("%d executions served from the coprocessor cache left out", coprCacheHits))
+	}
+	statsAfter := client.snapshotStats(tables)
+	for _, table := range tables {
+		before, after := statsBefore[table], statsAfter[table]
+		if before == nil || after == nil {
+			continue
+		}
+		for _, change := range diffStatsState(before, after) {
+			slog.Warn("Optimizer statistics changed during the run", "change", change)
+			if opts.Metadata != nil {
+				opts.Metadata.StatsChanges = append(opts.Metadata.StatsChanges, change)
+			}
+		}
+	}
+
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].ScenarioID < results[j].ScenarioID
 	})
@@ -315,21 +1269,25 @@ func runAllTestCombinations(scenarios []TestScenario) []*TestExecutionResult {
 }
 
 func getRU(plan *ExecutionPlan) float64 {
-	if plan == nil {
-		return 0.0
-	}
-	if plan.QueryInfo == "" {
-		return 0.0
+	ru, _ := parseRU(plan)
+	return ru
+}
+
+// parseRU returns the RU of the plan, and false if the plan reported none,
+// like on clusters without resource control
+func parseRU(plan *ExecutionPlan) (float64, bool) {
+	if plan == nil || plan.QueryInfo == "" {
+		return 0.0, false
 	}
 	ruRegex := regexp.MustCompile(ruRegexStr)
 	ruMatch := ruRegex.FindStringSubmatch(plan.QueryInfo)
 	if len(ruMatch) == 2 {
 		ru, err := strconv.ParseFloat(ruMatch[1], 64)
 		if err == nil {
-			return ru
+			return ru, true
 		}
 	}
-	return 0.0
+	return 0.0, false
 }
 
 // outputResultsTable outputs results in a formatted table
@@ -338,8 +1296,13 @@ func outputDetailedResultsTable(results []*TestExecutionResult) {
 	fmt.Println("====================")
 
 	planChoosen := make(map[string]int)
-	fmt.Printf("Scenario\tTable_size\tCardinality\tVariant\tPlan\t")
-	fmt.Printf("RU\tms\n")
+	fmt.Printf("Scenario\tTable_size\tCardinality\tVariant\tRep\tPlan\t")
+	fmt.Printf("EstCost\t")
+	withRU := ruReported(results)
+	if withRU {
+		fmt.Printf("RU\t")
+	}
+	fmt.Printf("ms\tOffset_s\n")
 	// Group results by ScenarioID
 	for _, r := range results {
 		if r.ExplainOnly {
@@ -349,9 +1312,14 @@ func outputDetailedResultsTable(results []*TestExecutionResult) {
 		scenParts := strings.Split(r.ScenarioID, "_")
 		fmt.Printf("%s\t", strings.Join(scenParts, "\t"))
 		fmt.Printf("%s\t", r.Variant)
+		fmt.Printf("%d\t", r.Repetition)
 		fmt.Printf("%s\t", r.PlanType)
-		fmt.Printf("%.03f\t", getRU(r.Plan))
-		fmt.Printf("%.03f\n", r.Plan.ExecutionTime.Seconds()*1000.0)
+		fmt.Printf("%.03f\t", r.EstCost.Cost)
+		if withRU {
+			fmt.Printf("%.03f\t", getRU(r.Plan))
+		}
+		fmt.Printf("%.03f\t", r.Plan.ExecutionTime.Seconds()*1000.0)
+		fmt.Printf("%.03f\n", r.RunOffset.Seconds())
 	}
 	fmt.Printf("\nScenario\tTable_size\tCardinality\t")
 	fmt.Printf("Plan\tCount\n")
@@ -366,6 +1334,7 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 	fmt.Println("\n📊 Test Results Table - Grouped by test")
 	fmt.Println("====================")
 
+	withRU := ruReported(results)
 	scenarioMap := make(map[string][]*TestExecutionResult)
 	for _, result := range results {
 		scenarioMap[result.ScenarioID] = append(scenarioMap[result.ScenarioID], result)
@@ -373,6 +1342,11 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 	// For deterministic output, get sorted ScenarioIDs
 	var scenarioIDs []string
 	for scenarioID := range scenarioMap {
+		// The table has a column per part of <family>_<size>_<matching>
+		if len(strings.Split(scenarioID, "_")) != 3 {
+			slog.Warn("Skipping the scenario of an unexpected ID", "scenario", scenarioID)
+			continue
+		}
 		scenarioIDs = append(scenarioIDs, scenarioID)
 	}
 	sort.Strings(scenarioIDs)
@@ -387,13 +1361,17 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 		RUSum := make(map[string]float64)
 		RUMin := make(map[string]float64)
 		RUMax := make(map[string]float64)
+		tidbSum := make(map[string]time.Duration)
+		copSum := make(map[string]time.Duration)
 		planTypeCount := make(map[string]int)
+		planTypeRuns := make(map[string][]*TestExecutionResult)
 		explainOnlyPlanType := ""
 		for _, res := range group {
 			if res.ExplainOnly {
 				explainOnlyPlanType = res.PlanType
 				continue
 			}
+			planTypeRuns[res.PlanType] = append(planTypeRuns[res.PlanType], res)
 			ru := getRU(res.Plan)
 			if minimum, ok := RUMin[res.PlanType]; !ok || minimum > ru {
 				RUMin[res.PlanType] = ru
@@ -410,6 +1388,9 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 			if t > planTypeMax[res.PlanType] {
 				planTypeMax[res.PlanType] = t
 			}
+			tidbTime, copTime := layerTimes(res.Plan)
+			tidbSum[res.PlanType] += tidbTime
+			copSum[res.PlanType] += copTime
 			planTypeCount[res.PlanType]++
 		}
 
@@ -432,6 +1413,12 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 		for pt, times := range planTypeSum {
 			avgTimes[pt] = times.Seconds() / float64(planTypeCount[pt])
 		}
+		timeSpread := make(map[string]spread)
+		ruSpread := make(map[string]spread)
+		for pt, runs := range planTypeRuns {
+			timeSpread[pt] = spreadOf(latenciesMs(runs))
+			ruSpread[pt] = spreadOf(requestUnits(runs))
+		}
 
 		// Print header for this scenario
 		if i == 0 {
@@ -440,12 +1427,25 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 			fmt.Printf("Cardinality\t")
 			fmt.Printf("Choosen\t")
 			for i, pt := range planTypes {
-				fmt.Printf("%s-ru-min\t", pt)
-				fmt.Printf("%s-ru-avg\t", pt)
-				fmt.Printf("%s-ru-max\t", pt)
+				if withRU {
+					fmt.Printf("%s-ru-min\t", pt)
+					fmt.Printf("%s-ru-avg\t", pt)
+					fmt.Printf("%s-ru-max\t", pt)
+					fmt.Printf("%s-ru-stddev\t", pt)
+					fmt.Printf("%s-ru-p50\t", pt)
+					fmt.Printf("%s-ru-p90\t", pt)
+					fmt.Printf("%s-ru-p99\t", pt)
+				}
 				fmt.Printf("%s-min\t", pt)
 				fmt.Printf("%s-avg\t", pt)
-				fmt.Printf("%s-max", pt)
+				fmt.Printf("%s-max\t", pt)
+				fmt.Printf("%s-stddev\t", pt)
+				fmt.Printf("%s-ci95\t", pt)
+				fmt.Printf("%s-p50\t", pt)
+				fmt.Printf("%s-p90\t", pt)
+				fmt.Printf("%s-p99\t", pt)
+				fmt.Printf("%s-tidb-avg\t", pt)
+				fmt.Printf("%s-cop-avg", pt)
 				if i == len(planTypes)-1 {
 					fmt.Printf("\n")
 				} else {
@@ -459,12 +1459,25 @@ func outputAggregatedResultsTable(results []*TestExecutionResult) {
 		fmt.Printf("%s\t", scenParts[2])
 		fmt.Printf("%s\t", explainOnlyPlanType)
 		for i, pt := range planTypes {
-			fmt.Printf("%.03f\t", RUMin[pt])
-			fmt.Printf("%.03f\t", avgRU[pt])
-			fmt.Printf("%.03f\t", RUMax[pt])
+			if withRU {
+				fmt.Printf("%.03f\t", RUMin[pt])
+				fmt.Printf("%.03f\t", avgRU[pt])
+				fmt.Printf("%.03f\t", RUMax[pt])
+				fmt.Printf("%.03f\t", ruSpread[pt].stdDev)
+				fmt.Printf("%.03f\t", ruSpread[pt].p50)
+				fmt.Printf("%.03f\t", ruSpread[pt].p90)
+				fmt.Printf("%.03f\t", ruSpread[pt].p99)
+			}
 			fmt.Printf("%.03f\t", float64(planTypeMin[pt].Microseconds())/1000.0)
 			fmt.Printf("%.03f\t", avgTimes[pt]*1000)
-			fmt.Printf("%.03f", float64(planTypeMax[pt].Microseconds())/1000.0)
+			fmt.Printf("%.03f\t", float64(planTypeMax[pt].Microseconds())/1000.0)
+			fmt.Printf("%.03f\t", timeSpread[pt].stdDev)
+			fmt.Printf("%.03f\t", timeSpread[pt].ci95)
+			fmt.Printf("%.03f\t", timeSpread[pt].p50)
+			fmt.Printf("%.03f\t", timeSpread[pt].p90)
+			fmt.Printf("%.03f\t", timeSpread[pt].p99)
+			fmt.Printf("%.03f\t", tidbSum[pt].Seconds()*1000/float64(planTypeCount[pt]))
+			fmt.Printf("%.03f", copSum[pt].Seconds()*1000/float64(planTypeCount[pt]))
 			if i == len(planTypes)-1 {
 				fmt.Printf("\n")
 			} else {
@@ -11,11 +11,11 @@ func TestSimple(t *testing.T) {
 
 	// Run the optimizer tests
 	setupLogging("debug")
-	err := CheckAndSetupTables(rowCounts, selectivities, 500)
+	err := CheckAndSetupTables(rowCounts, selectivities, SetupOptions{FillerSize: 500})
 	if err != nil {
 		t.Fatalf("CheckAndSetupTables failed: %v", err)
 	}
-	results := RunOptimizerTests(rowCounts, selectivities, 1)
+	results := RunOptimizerTests(rowCounts, selectivities, RunOptions{Repetitions: 1})
 	outputDetailedResultsTable(results)
 	outputAggregatedResultsTable(results)
 }
@@ -26,11 +26,11 @@ func TestMulti(t *testing.T) {
 
 	// Run the optimizer tests
 	setupLogging("debug")
-	err := CheckAndSetupTables(rowCounts, selectivities, 500)
+	err := CheckAndSetupTables(rowCounts, selectivities, SetupOptions{FillerSize: 500})
 	if err != nil {
 		t.Fatalf("CheckAndSetupTables failed: %v", err)
 	}
-	results := RunOptimizerTests(rowCounts, selectivities, 3)
+	results := RunOptimizerTests(rowCounts, selectivities, RunOptions{Repetitions: 3})
 	outputDetailedResultsTable(results)
 	outputAggregatedResultsTable(results)
 }
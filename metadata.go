@@ -0,0 +1,352 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// storageConfigNames are the TiKV settings describing the storage engine
+var storageConfigNames = []string{
+	"storage.engine",
+	"storage.block-cache.capacity",
+	"rocksdb.titan.enabled",
+	"in-memory-engine.enable",
+	"in-memory-engine.capacity",
+	"coprocessor.region-split-size",
+}
+
+// StoreInfo describes the storage engine configuration of a store
+type StoreInfo struct {
+	Instance string            `json:"instance"`
+	Type     string            `json:"type"`
+	Config   map[string]string `json:"config,omitempty"`
+}
+
+// RunMetadata describes a calibration run, so stored result sets remain
+// identifiable long after the run
+type RunMetadata struct {
+	RunID         string    `json:"run_id"`
+	Label         string    `json:"label,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	// Stores holds the storage engine details per store
+	Stores []StoreInfo `json:"stores,omitempty"`
+	// EngineConfig summarizes the distinct storage engine settings of all stores,
+	// for segmenting runs from heterogeneous clusters
+	EngineConfig string `json:"engine_config,omitempty"`
+	// RUCoefficients are the RU model coefficients of the cluster, if exposed
+	RUCoefficients *RUCoefficients `json:"ru_coefficients,omitempty"`
+	// ResourceControl is the tidb_enable_resource_control setting of the cluster
+	ResourceControl string `json:"resource_control,omitempty"`
+	// NoRU is set if no execution reported an RU, the RU columns are then left out of the outputs
+	NoRU bool `json:"no_ru,omitempty"`
+	// Client describes the host running the tool
+	Client        *ClientInfo `json:"client,omitempty"`
+	RowCounts     []int       `json:"row_counts"`
+	Selectivities []float64   `json:"selectivities"`
+	FillerSize    int         `json:"filler_size"`
+	// FillerSizes are the filler sizes of a row width sweep, FillerSize is the first
+	FillerSizes []int `json:"filler_sizes,omitempty"`
+	// FillerLayout is the row layout of -fillers and -b-position, like "fillers=200,200 b=last", empty for the default
+	FillerLayout string `json:"filler_layout,omitempty"`
+	Repetitions  int    `json:"repetitions"`
+	// Warmup is the number of warm-up executions of each scenario, flagged in the results
+	Warmup int `json:"warmup,omitempty"`
+	// AdaptiveMax is the -adaptive cap of extra repetitions, AdaptiveExtra the
+	// extra executions scheduled, and AdaptiveUnresolved the cells still
+	// overlapping at the cap
+	AdaptiveMax        int      `json:"adaptive_max,omitempty"`
+	AdaptiveExtra      int      `json:"adaptive_extra,omitempty"`
+	AdaptiveUnresolved []string `json:"adaptive_unresolved,omitempty"`
+	// Strict is set for -strict, StrictReplaced the retried executions it
+	// replaced, and StrictDropped the ones dropped at the cap
+	Strict         bool `json:"strict,omitempty"`
+	StrictReplaced int  `json:"strict_replaced,omitempty"`
+	StrictDropped  int  `json:"strict_dropped,omitempty"`
+	// Outliers is the -outliers filter, and OutliersDropped the executions it
+	// flagged as outliers, left out of the aggregates
+	Outliers        string `json:"outliers,omitempty"`
+	OutliersDropped int    `json:"outliers_dropped,omitempty"`
+	// Hooks are the names of the execution hooks collecting custom metrics
+	Hooks []string `json:"hooks,omitempty"`
+	// QueryFrequencies is the -query-frequencies file the calibration score is weighted with
+	QueryFrequencies string `json:"query_frequencies,omitempty"`
+	// QueryFrequencyColumns is the -query-frequency-columns mapping of the production column names
+	QueryFrequencyColumns string `json:"query_frequency_columns,omitempty"`
+	// Expectations are the -expectations TiDB test suite files the cells were lined up with
+	Expectations string   `json:"expectations,omitempty"`
+	Generators   []string `json:"generators,omitempty"`
+	// Seed is the -seed of the scenario order and the data generation, 0 if random
+	Seed     int64    `json:"seed,omitempty"`
+	Families []string `json:"families,omitempty"`
+	// ExplainAnalyze is set if the measured queries were executed under EXPLAIN
+	// ANALYZE, with the latency of the root operator
+	ExplainAnalyze bool `json:"explain_analyze,omitempty"`
+	// CoprCache is the -copr-cache handling of the executions served from the
+	// coprocessor cache, empty for bust
+	CoprCache string `json:"copr_cache,omitempty"`
+	// Analyze is the -analyze options the tables were analyzed with before the run, empty for the table setup's
+	Analyze string `json:"analyze,omitempty"`
+	// ScenarioFile is the -scenarios file of user defined tables and queries
+	ScenarioFile string `json:"scenario_file,omitempty"`
+	// CustomTables and CustomColumns are the table names, and the column and
+	// index names, of the -scenarios tables, hashed by -anonymize
+	CustomTables  []string `json:"custom_tables,omitempty"`
+	CustomColumns []string `json:"custom_columns,omitempty"`
+	// NullRatios are the ratios of NULL values per column
+	NullRatios map[string]float64 `json:"null_ratios,omitempty"`
+	// PredicateValues is the number of b values of the same number of rows
+	// per selectivity, cycled through by the repetitions
+	PredicateValues int `json:"predicate_values,omitempty"`
+	// ExcludedPlanTypes are the plan types not considered as the best plan of a cell
+	ExcludedPlanTypes []string `json:"excluded_plan_types,omitempty"`
+	// PrimaryKeys are the primary key kinds of the test tables, clustered and/or nonclustered
+	PrimaryKeys []string `json:"primary_keys,omitempty"`
+	// TableSuffix is appended to the test table names, if unique tables were used
+	TableSuffix string `json:"table_suffix,omitempty"`
+	// Shard is the part of the scenario matrix run, like "2/4", empty if all of it
+	Shard string `json:"shard,omitempty"`
+	// Filter and Exclude are the -filter and -exclude scenario ID patterns
+	Filter  string `json:"filter,omitempty"`
+	Exclude string `json:"exclude,omitempty"`
+	// Timeline holds noteworthy events during the run, like pauses
+	Timeline []TimelineEvent `json:"timeline,omitempty"`
+	// LatencyFloor is the series of SELECT 1 latency floor measurements
+	LatencyFloor []FloorSample `json:"latency_floor,omitempty"`
+	// TableSnapshots are the states the test tables were frozen in for the run
+	TableSnapshots []TableSnapshot `json:"table_snapshots,omitempty"`
+	// StatsChanges are the optimizer statistics changes detected during the run
+	StatsChanges []string `json:"stats_changes,omitempty"`
+	// Checkpoint is the -checkpoint file of the run, and Resumed the
+	// executions read back from it, completed by the interrupted run
+	Checkpoint string `json:"checkpoint,omitempty"`
+	Resumed    int    `json:"resumed,omitempty"`
+	// Aborted is the reason the run was aborted before completing the schedule
+	Aborted string `json:"aborted,omitempty"`
+}
+
+// TimelineEvent is something noteworthy that happened during the run
+type TimelineEvent struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Event    string        `json:"event"`
+	Detail   string        `json:"detail,omitempty"`
+}
+
+// AddEvent records an event in the run timeline, ending now
+func (m *RunMetadata) AddEvent(event string, duration time.Duration, detail string) {
+	if m == nil {
+		return
+	}
+	m.Timeline = append(m.Timeline, TimelineEvent{
+		Time:     time.Now().Add(-duration),
+		Duration: duration,
+		Event:    event,
+		Detail:   detail,
+	})
+}
+
+// NewRunMetadata creates the metadata for a new run, with a fresh run ID
+func NewRunMetadata(label, description string) *RunMetadata {
+	return &RunMetadata{
+		RunID:       newRunID(),
+		Label:       label,
+		Description: description,
+		StartTime:   time.Now(),
+		Client:      newClientInfo(),
+	}
+}
+
+// newRunID returns a short random identifier, like "rx7a"
+func newRunID() string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	id := []byte{'r', 0, 0, 0}
+	for i := 1; i < len(id); i++ {
+		id[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(id)
+}
+
+// CollectServerInfo fills in the metadata that has to be read from the cluster
+func (m *RunMetadata) CollectServerInfo(config *TiDBConfig) error {
+	c := NewTiDBClient()
+	err := c.Connect(config)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	m.ServerVersion, err = c.GetServerVersion()
+	if err != nil {
+		return err
+	}
+	m.Stores, err = c.GetStoreInfo()
+	if err != nil {
+		// Not all deployments allow SHOW CONFIG, the run is still valid without it
+		slog.Warn("Failed to get storage engine details", "error", err)
+	}
+	m.EngineConfig = engineConfigSummary(m.Stores)
+	if m.RUCoefficients, err = c.GetRUCoefficients(); err != nil {
+		// Older versions do not expose them, RU comparisons then assume the defaults
+		slog.Warn("Failed to get the RU coefficients", "error", err)
+	}
+	if err = c.db.QueryRow("SELECT @@global.tidb_enable_resource_control").Scan(&m.ResourceControl); err != nil {
+		// Versions before resource control do not have the variable, and report no RU
+		slog.Warn("Failed to get tidb_enable_resource_control", "error", err)
+	}
+	if m.Client != nil {
+		if config == nil {
+			config = &defaultTiDBConfig
+		}
+		m.Client.TLS = config.TLS
+		if m.Client.RTT, err = c.PingLatency(clientRTTPings); err != nil {
+			slog.Warn("Failed to measure the RTT to the server", "error", err)
+		}
+	}
+	return nil
+}
+
+// engineConfigSummary returns the distinct storage settings over all stores as
+// a stable string, like "rocksdb.titan.enabled=false,storage.engine=raft-kv"
+func engineConfigSummary(stores []StoreInfo) string {
+	var settings []string
+	for _, s := range stores {
+		for name, value := range s.Config {
+			setting := name + "=" + value
+			if !slices.Contains(settings, setting) {
+				settings = append(settings, setting)
+			}
+		}
+	}
+	slices.Sort(settings)
+	return strings.Join(settings, ",")
+}
+
+// outputRunMetadata prints the run metadata as a header for the result tables
+func outputRunMetadata(m *RunMetadata) {
+	fmt.Println("\n🏷️  Run Metadata")
+	fmt.Println("====================")
+	fmt.Printf("Run ID:\t%s\n", m.RunID)
+	if m.Label != "" {
+		fmt.Printf("Label:\t%s\n", m.Label)
+	}
+	if m.Description != "" {
+		fmt.Printf("Description:\t%s\n", strings.ReplaceAll(m.Description, "\n", " "))
+	}
+	if m.Shard != "" {
+		fmt.Printf("Shard:\t%s\n", m.Shard)
+	}
+	if m.Filter != "" || m.Exclude != "" {
+		fmt.Printf("Scenario filter:\t%s, excluding %s\n", cmp.Or(m.Filter, ".*"), cmp.Or(m.Exclude, "none"))
+	}
+	fmt.Printf("Started:\t%s\n", m.StartTime.Format(time.RFC3339))
+	if m.Checkpoint != "" {
+		fmt.Printf("Checkpoint:\t%s, %d executions resumed\n", m.Checkpoint, m.Resumed)
+	}
+	if m.Aborted != "" {
+		fmt.Printf("Aborted:\t%s (partial results)\n", m.Aborted)
+	}
+	if !m.EndTime.IsZero() {
+		fmt.Printf("Duration:\t%s\n", m.EndTime.Sub(m.StartTime).Round(time.Second))
+	}
+	if m.ServerVersion != "" {
+		fmt.Printf("Server version:\t%s\n", m.ServerVersion)
+	}
+	if m.Client != nil {
+		fmt.Printf("Client:\t%s\tRTT %.03f ms\n", m.Client, float64(m.Client.RTT.Microseconds())/1000.0)
+	}
+	if len(m.ExcludedPlanTypes) > 0 {
+		fmt.Printf("Excluded plan types:\t%s\n", strings.Join(m.ExcludedPlanTypes, ","))
+	}
+	if m.ExplainAnalyze {
+		fmt.Printf("Execution:\tEXPLAIN ANALYZE, root operator latency\n")
+	}
+	if m.NoRU {
+		reason := "although resource control is enabled"
+		switch strings.ToUpper(m.ResourceControl) {
+		case "":
+			reason = "resource control is not supported"
+		case "OFF", "0":
+			reason = "resource control is disabled"
+		}
+		fmt.Printf("RU:\tnot reported, %s, the RU columns are left out\n", reason)
+	}
+	if m.PredicateValues > 1 {
+		fmt.Printf("Predicate values:\t%d per selectivity, cycled through by the repetitions\n", m.PredicateValues)
+	}
+	if m.Warmup > 0 {
+		fmt.Printf("Warm-up:\t%d executions per test, not aggregated\n", m.Warmup)
+	}
+	if m.AdaptiveMax > 0 {
+		fmt.Printf("Adaptive repetitions:\t%d extra executions, up to %d per variant, %d cells unresolved\n",
+			m.AdaptiveExtra, m.AdaptiveMax, len(m.AdaptiveUnresolved))
+	}
+	if m.Strict {
+		fmt.Printf("Strict:\t%d retried executions replaced, %d dropped\n", m.StrictReplaced, m.StrictDropped)
+	}
+	if m.Outliers != "" {
+		fmt.Printf("Outliers:\t%s, %d executions not aggregated\n", m.Outliers, m.OutliersDropped)
+	}
+	if len(m.Hooks) > 0 {
+		fmt.Printf("Execution hooks:\t%s\n", strings.Join(m.Hooks, ", "))
+	}
+	if m.QueryFrequencies != "" {
+		fmt.Printf("Query frequencies:\t%s\n", m.QueryFrequencies)
+	}
+	if m.QueryFrequencyColumns != "" {
+		fmt.Printf("Query frequency columns:\t%s\n", m.QueryFrequencyColumns)
+	}
+	if m.Expectations != "" {
+		fmt.Printf("Expectations:\t%s\n", m.Expectations)
+	}
+	if m.Seed != 0 {
+		fmt.Printf("Seed:\t%d\n", m.Seed)
+	}
+	if m.FillerLayout != "" {
+		fmt.Printf("Row layout:\t%s\n", m.FillerLayout)
+	}
+	if m.CoprCache != "" {
+		fmt.Printf("Copr cache hits:\t%s\n", m.CoprCache)
+	}
+	if m.Analyze != "" {
+		fmt.Printf("Analyze:\t%s\n", m.Analyze)
+	}
+	if m.EngineConfig != "" {
+		fmt.Printf("Storage engine:\t%d stores\t%s\n", len(m.Stores), m.EngineConfig)
+	}
+	if m.RUCoefficients != nil && *m.RUCoefficients != defaultRUCoefficients {
+		k := m.RUCoefficients
+		fmt.Printf("RU coefficients:\tread base %g, read per byte %g, read CPU ms %g, write base %g, write per byte %g\n",
+			k.ReadBaseCost, k.ReadCostPerByte, k.ReadCPUMsCost, k.WriteBaseCost, k.WriteCostPerByte)
+	}
+	if len(m.LatencyFloor) > 0 {
+		lowest, highest := m.LatencyFloor[0].Latency, m.LatencyFloor[0].Latency
+		for _, s := range m.LatencyFloor {
+			lowest = min(lowest, s.Latency)
+			highest = max(highest, s.Latency)
+		}
+		fmt.Printf("Latency floor:\t%d probes, min %.03f ms, max %.03f ms\n", len(m.LatencyFloor),
+			float64(lowest.Microseconds())/1000.0, float64(highest.Microseconds())/1000.0)
+	}
+	for _, s := range m.TableSnapshots {
+		fmt.Printf("Table snapshot:\t%s\tTSO %d (%s)\tstats version %d\t%d rows", s.Table, s.TSO,
+			s.Time.Format(time.RFC3339), s.StatsVersion, s.Rows)
+		if s.Checksum != "" {
+			fmt.Printf("\tchecksum %s, %d kvs", s.Checksum, s.KVs)
+		}
+		fmt.Printf("\n")
+	}
+	for _, change := range m.StatsChanges {
+		fmt.Printf("Stats change:\t%s\n", change)
+	}
+	for _, e := range m.Timeline {
+		fmt.Printf("Event:\t%s\t%s\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Event, e.Duration.Round(time.Second), e.Detail)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// optionFileSections are the MySQL option file sections read for the connection settings
+var optionFileSections = []string{"client", "mysql"}
+
+// optionFileFlags maps MySQL option file keys to the connection flags
+var optionFileFlags = map[string]string{
+	"host":     "host",
+	"port":     "port",
+	"user":     "user",
+	"password": "password",
+	"database": "database",
+	"ssl-ca":   "tls-ca",
+	"ssl-cert": "tls-cert",
+	"ssl-key":  "tls-key",
+}
+
+// defaultOptionFile returns the user's ~/.my.cnf, or "" if there is none
+func defaultOptionFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".my.cnf")
+	if _, err = os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// parseOptionFile reads the client settings from a MySQL option file (my.cnf),
+// later sections and lines override earlier ones like in the mysql client
+func parseOptionFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open option file: %w", err)
+	}
+	defer f.Close()
+
+	options := make(map[string]string)
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			// Comments and !include directives
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%s:%d: invalid section header '%s'", path, lineNo, line)
+			}
+			section := strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			inSection = false
+			for _, s := range optionFileSections {
+				if section == s {
+					inSection = true
+				}
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+		options[key] = unquoteOptionValue(strings.TrimSpace(value))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read option file %s: %w", path, err)
+	}
+	return options, nil
+}
+
+// unquoteOptionValue removes quotes and trailing comments from an option file value
+func unquoteOptionValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// applyOptionFile sets the connection flags from a MySQL option file,
+// except the ones already given on the command line or in a config file
+func applyOptionFile(fs *flag.FlagSet, path string) error {
+	options, err := parseOptionFile(path)
+	if err != nil {
+		return err
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for key, value := range options {
+		name, ok := optionFileFlags[key]
+		if !ok || explicit[name] {
+			continue
+		}
+		if err = fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in option file %s: %w", key, path, err)
+		}
+	}
+	slog.Debug("Read connection settings from option file", "path", path)
+	return nil
+}
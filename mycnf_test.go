@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyOptionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my.cnf")
+	content := `# credentials
+[mysqld]
+port = 3306
+
+[client]
+host = tidb.example.com
+port=4001
+user = "calibration"
+password = 'se#cret'
+ssl-mode = REQUIRED
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	connectionConfig := registerConnectionFlags(fs)
+	// The command line overrides the option file
+	if err := fs.Parse([]string{"-defaults-file", path, "-user", "root"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := connectionConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "tidb.example.com" || config.Port != 4001 || config.User != "root" || config.Password != "se#cret" {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+}
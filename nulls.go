@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseNullRatios parses the -null-ratio value, a comma-separated list of
+// column=ratio, like "b=0.1,c=0.5", a ratio without a column is for b
+func parseNullRatios(s string) (map[string]float64, error) {
+	ratios := make(map[string]float64)
+	for _, def := range strings.Split(s, ",") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		column, value, ok := strings.Cut(def, "=")
+		if !ok {
+			column, value = "b", def
+		}
+		column = strings.TrimSpace(column)
+		if column != "b" && column != "c" {
+			return nil, fmt.Errorf("invalid NULL ratio '%s': only b and c can have NULL values", def)
+		}
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || ratio < 0 || ratio >= 1 {
+			return nil, fmt.Errorf("invalid NULL ratio '%s': expected a ratio in [0, 1)", def)
+		}
+		if ratio > 0 {
+			ratios[column] = ratio
+		}
+	}
+	return ratios, nil
+}
+
+// formatNullRatios returns the NULL ratios as recorded in the table comment, like b:0.1,c:0.5
+func formatNullRatios(ratios map[string]float64) string {
+	columns := make([]string, 0, len(ratios))
+	for column := range ratios {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	defs := make([]string, 0, len(columns))
+	for _, column := range columns {
+		defs = append(defs, column+":"+strconv.FormatFloat(ratios[column], 'f', -1, 64))
+	}
+	return strings.Join(defs, ",")
+}
+
+// nullRows returns the number of NULL values of the column in a table of the NULL ratios
+func nullRows(rowCount int, ratios map[string]float64, column string) int {
+	return int(float64(rowCount) * ratios[column])
+}
+
+// adjustNulls sets exactly as many values of b and c to NULL as their NULL
+// ratios. The b values of the selectivities are kept, so the matching rows of
+// the cells do not change.
+func adjustNulls(c *TiDBClient, tableName string, rowCount int, ratios map[string]float64, selectivities []float64, fillerSize int) error {
+	batchSize := 50000
+	var keep []string
+	var keepValues []int
+	for _, sel := range selectivities {
+		rows := GetNumRows(rowCount, sel)
+		keep = append(keep, strconv.Itoa(rows))
+		keepValues = append(keepValues, rows)
+	}
+	values := map[string]func() string{
+		"b": func() string { return strconv.Itoa(getRandomNotInList(keepValues)) },
+		"c": func() string { return fillerGenerator{size: fillerSize}.SQLExpr("id") },
+	}
+	for _, column := range []string{"b", "c"} {
+		target := nullRows(rowCount, ratios, column)
+		exclude := ""
+		if column == "b" && len(keep) > 0 {
+			exclude = fmt.Sprintf(" AND b NOT IN (%s)", strings.Join(keep, ","))
+		}
+		for {
+			var nulls int
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", tableName, column)
+			slog.Debug("Executing query", "query", query)
+			if err := c.db.QueryRow(query).Scan(&nulls); err != nil {
+				return fmt.Errorf("failed to count NULL values: %w", err)
+			}
+			if nulls == target {
+				break
+			}
+			var update string
+			if nulls > target {
+				update = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL LIMIT %d",
+					tableName, column, values[column](), column, min(nulls-target, batchSize))
+			} else {
+				update = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s IS NOT NULL%s ORDER BY %s LIMIT %d",
+					tableName, column, column, exclude, sqlRandomOrder(), min(target-nulls, batchSize))
+			}
+			if err := c.ExecuteStatement(update); err != nil {
+				return fmt.Errorf("failed to set NULL values of %s: %w", column, err)
+			}
+			fmt.Printf("n")
+		}
+	}
+	return nil
+}
+
+// nullScenarios generates the IS NULL and IS NOT NULL cells of a table, index
+// lookup vs table scan. The cells do not depend on the selectivity, the
+// matching rows are set by the NULL ratio of b.
+func nullScenarios(table TableSpec, _ float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	nulls := nullRows(table.RowCount, table.nulls, "b")
+	var scenarios []TestScenario
+	for _, kind := range []struct {
+		name, predicate string
+		matching        int
+	}{
+		{"isnull", "b IS NULL", nulls},
+		{"notnull", "b IS NOT NULL", table.RowCount - nulls},
+	} {
+		base := TestScenario{
+			ID:           fmt.Sprintf("%s_%s_%d", kind.name, tableSizeName, kind.matching),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: kind.matching,
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("%s - %s rows, %d matching", kind.predicate, tableSizeName, kind.matching)
+		explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, kind.predicate)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("Index lookup %s - %s rows, %d matching", kind.predicate, tableSizeName, kind.matching)
+		index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, kind.predicate)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("Table Scan %s - %s rows, %d matching", kind.predicate, tableSizeName, kind.matching)
+		scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, kind.predicate)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNullRatios(t *testing.T) {
+	ratios, err := parseNullRatios("0.25, c=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatNullRatios(ratios); got != "b:0.25,c:0.5" {
+		t.Fatalf("unexpected NULL ratios %s", got)
+	}
+	for _, s := range []string{"id=0.1", "b=1", "b=-0.1", "b=x"} {
+		if _, err = parseNullRatios(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestNullFamily(t *testing.T) {
+	opts := MatrixOptions{NullRatios: map[string]float64{"b": 0.25}}
+	tables := tableSpecs([]int{1000}, "", opts)
+	scenarios := GetTestScenarios(tables, []float64{10, 100}, opts, "null", "point")
+	// The null cells are generated once, not per selectivity
+	if len(scenarios) != 12 {
+		t.Fatalf("expected 12 scenarios, got %d", len(scenarios))
+	}
+	if scenarios[0].ID != "isnull_1K_250" || scenarios[3].ID != "notnull_1K_750" || scenarios[3].MatchingRows != 750 {
+		t.Fatalf("unexpected null scenarios %+v", scenarios[:6])
+	}
+	if scenarios[4].Query != "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b IS NOT NULL" {
+		t.Fatalf("unexpected query %s", scenarios[4].Query)
+	}
+	if stmt := createTableStatement(tables[0], 100); !strings.HasSuffix(stmt, "filler=100 nulls=b:0.25'") {
+		t.Fatalf("expected the NULL ratios in the table comment, got %s", stmt)
+	}
+}
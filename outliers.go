@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OutlierFilter drops the outlying repetitions of each scenario and plan type
+// before aggregating, so one GC pause or region split does not skew the
+// averages: Trim drops the given percent of the fastest and of the slowest
+// executions, MAD the executions more than the given number of median
+// absolute deviations from the median
+type OutlierFilter struct {
+	Trim float64
+	MAD  float64
+}
+
+const (
+	// madScale scales the median absolute deviation to the standard deviation of a normal distribution
+	madScale = 1.4826
+	// trimFallbackMAD is the MAD filter of the scenarios with too few
+	// executions to trim, fewer than 100/Trim, where trimming drops nothing
+	trimFallbackMAD = 3
+)
+
+// parseOutlierFilter parses the -outliers value, like trim:10 or mad:3
+func parseOutlierFilter(s string) (OutlierFilter, error) {
+	if s == "" || s == "none" {
+		return OutlierFilter{}, nil
+	}
+	method, arg, ok := strings.Cut(s, ":")
+	if !ok {
+		return OutlierFilter{}, fmt.Errorf("invalid outlier filter '%s', expected trim:<percent> or mad:<deviations>", s)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+	if err != nil {
+		return OutlierFilter{}, fmt.Errorf("invalid outlier filter '%s': %w", s, err)
+	}
+	switch method {
+	case "trim":
+		if v <= 0 || v >= 50 {
+			return OutlierFilter{}, fmt.Errorf("invalid outlier filter '%s', the trimmed percent must be between 0 and 50", s)
+		}
+		return OutlierFilter{Trim: v}, nil
+	case "mad":
+		if v <= 0 {
+			return OutlierFilter{}, fmt.Errorf("invalid outlier filter '%s', the deviations must be positive", s)
+		}
+		return OutlierFilter{MAD: v}, nil
+	}
+	return OutlierFilter{}, fmt.Errorf("unknown outlier filter method '%s', expected trim or mad", method)
+}
+
+// String returns the filter in the -outliers syntax, empty if it keeps all executions
+func (f OutlierFilter) String() string {
+	switch {
+	case f.Trim > 0:
+		return "trim:" + strconv.FormatFloat(f.Trim, 'g', -1, 64)
+	case f.MAD > 0:
+		return "mad:" + strconv.FormatFloat(f.MAD, 'g', -1, 64)
+	}
+	return ""
+}
+
+// trimTooFew returns true if the filter trims, but there are too few
+// latencies to trim any of them
+func (f OutlierFilter) trimTooFew(n int) bool {
+	return f.Trim > 0 && int(float64(n)*f.Trim/100) == 0
+}
+
+// outliers returns the indexes of the outlying latencies of one scenario and
+// plan type. Too few latencies to trim are filtered by MAD instead.
+func (f OutlierFilter) outliers(latencies []float64) []int {
+	if f.trimTooFew(len(latencies)) {
+		f = OutlierFilter{MAD: trimFallbackMAD}
+	}
+	var drop []int
+	switch {
+	case f.Trim > 0:
+		order := make([]int, len(latencies))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			switch {
+			case latencies[a] < latencies[b]:
+				return -1
+			case latencies[a] > latencies[b]:
+				return 1
+			}
+			return 0
+		})
+		k := int(float64(len(latencies)) * f.Trim / 100)
+		drop = append(drop, order[:k]...)
+		drop = append(drop, order[len(order)-k:]...)
+	case f.MAD > 0:
+		median := percentile(latencies, 50)
+		deviations := make([]float64, len(latencies))
+		for i, v := range latencies {
+			deviations[i] = math.Abs(v - median)
+		}
+		mad := madScale * percentile(deviations, 50)
+		if mad == 0 {
+			// More than half of the executions took the same time, nothing stands out
+			return nil
+		}
+		for i, d := range deviations {
+			if d > f.MAD*mad {
+				drop = append(drop, i)
+			}
+		}
+	}
+	return drop
+}
+
+// markOutliers flags the outlying executions of each scenario and plan type,
+// among the measured ones, and returns how many were flagged
+func markOutliers(results []*TestExecutionResult, f OutlierFilter) int {
+	var measured []*TestExecutionResult
+	for _, r := range results {
+		r.Outlier = false
+		if !r.Warmup {
+			measured = append(measured, r)
+		}
+	}
+	marked, fallbacks := 0, 0
+	_, groups := groupByScenario(measured)
+	for _, group := range groups {
+		for _, runs := range group {
+			if f.trimTooFew(len(runs)) {
+				fallbacks++
+			}
+			for _, i := range f.outliers(latenciesMs(runs)) {
+				runs[i].Outlier = true
+				marked++
+			}
+		}
+	}
+	if fallbacks > 0 {
+		slog.Warn("Too few executions to trim, filtering them by MAD instead",
+			"outliers", f.String(), "min_executions", int(math.Ceil(100/f.Trim)), "plans", fallbacks, "mad", trimFallbackMAD)
+	}
+	return marked
+}
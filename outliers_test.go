@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseOutlierFilter(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		expected OutlierFilter
+	}{
+		{"", OutlierFilter{}}, {"none", OutlierFilter{}}, {"trim:10", OutlierFilter{Trim: 10}},
+		{"trim:5%", OutlierFilter{Trim: 5}}, {"mad:3.5", OutlierFilter{MAD: 3.5}},
+	} {
+		f, err := parseOutlierFilter(tc.s)
+		if err != nil || f != tc.expected {
+			t.Fatalf("%s: expected %+v, got %+v, %v", tc.s, tc.expected, f, err)
+		}
+	}
+	for _, s := range []string{"trim", "trim:50", "trim:0", "mad:-1", "iqr:1.5", "mad:x"} {
+		if _, err := parseOutlierFilter(s); err == nil {
+			t.Fatalf("expected an error for %s", s)
+		}
+	}
+}
+
+func TestMarkOutliers(t *testing.T) {
+	var results []*TestExecutionResult
+	for _, ms := range []float64{10, 11, 9, 10, 12, 10, 11, 9, 10, 95} {
+		results = append(results, newTestResult("index_1K_10", "index_lookup", ms))
+	}
+	warmup := newTestResult("index_1K_10", "index_lookup", 500)
+	warmup.Warmup = true
+	results = append(results, warmup, newChoiceResult("index_1K_10", "index_lookup"))
+
+	// 10% of 10 executions: the fastest and the slowest
+	if got := markOutliers(results, OutlierFilter{Trim: 10}); got != 2 {
+		t.Fatalf("expected 2 trimmed executions, got %d", got)
+	}
+	if !results[9].Outlier || warmup.Outlier || len(measuredResults(results)) != 9 {
+		t.Fatalf("unexpected trimmed executions: %d measured", len(measuredResults(results)))
+	}
+
+	// Only the GC pause is more than 3 deviations from the median
+	if got := markOutliers(results, OutlierFilter{MAD: 3}); got != 1 || !results[9].Outlier {
+		t.Fatalf("expected the slowest execution as the only outlier, got %d", got)
+	}
+	if got := markOutliers(results, OutlierFilter{}); got != 0 || results[9].Outlier {
+		t.Fatalf("expected no outliers without a filter, got %d", got)
+	}
+
+	// 5% of 10 executions trims nothing, the GC pause is filtered by MAD instead
+	if got := markOutliers(results, OutlierFilter{Trim: 5}); got != 1 || !results[9].Outlier {
+		t.Fatalf("expected the MAD fallback to flag the slowest execution, got %d", got)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// copParallelism returns the cop tasks sent by the reader operators of an
+// executed plan, and the most of them a reader sent concurrently. The scan
+// throughput depends on it, and it grows with the regions of the table.
+func copParallelism(plan *ExecutionPlan) (tasks, concurrency int) {
+	for p := plan; p != nil; p = p.Next {
+		if exec := p.execInfo(); exec != nil {
+			tasks += exec.CopTasks
+			concurrency = max(concurrency, exec.CopConcurrency)
+		}
+	}
+	return tasks, concurrency
+}
+
+// outputParallelismReport prints the average cop tasks and concurrency per
+// table size and plan type, how the parallelism of the scans grows with the
+// table size
+func outputParallelismReport(results []*TestExecutionResult) {
+	fmt.Println("\n🧵 Scan Parallelism")
+	fmt.Println("====================")
+	type key struct {
+		rowCount int
+		planType string
+	}
+	type parallelism struct {
+		samples, tasks, maxTasks, concurrency, maxConcurrency int
+	}
+	stats := make(map[key]*parallelism)
+	var keys []key
+	for _, r := range results {
+		if r.ExplainOnly || r.CopTasks == 0 {
+			continue
+		}
+		k := key{r.RowCount, r.PlanType}
+		s := stats[k]
+		if s == nil {
+			s = &parallelism{}
+			stats[k] = s
+			keys = append(keys, k)
+		}
+		s.samples++
+		s.tasks += r.CopTasks
+		s.maxTasks = max(s.maxTasks, r.CopTasks)
+		s.concurrency += r.CopConcurrency
+		s.maxConcurrency = max(s.maxConcurrency, r.CopConcurrency)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].rowCount != keys[j].rowCount {
+			return keys[i].rowCount < keys[j].rowCount
+		}
+		return keys[i].planType < keys[j].planType
+	})
+	fmt.Printf("Table_size\tPlan\tSamples\tcop_tasks_avg\tcop_tasks_max\tconcurrency_avg\tconcurrency_max\n")
+	for _, k := range keys {
+		s := stats[k]
+		n := float64(s.samples)
+		fmt.Printf("%s\t%s\t%d\t%.1f\t%d\t%.1f\t%d\n", formatRowCountName(k.rowCount), k.planType, s.samples,
+			float64(s.tasks)/n, s.maxTasks, float64(s.concurrency)/n, s.maxConcurrency)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestCopParallelism(t *testing.T) {
+	plan := &ExecutionPlan{
+		ID:            "IndexLookUp_7",
+		ExecutionInfo: "time:5.1ms, loops:2, index_task: {total_time: 1.2ms, fetch_handle: 1.1ms, build: 2µs, wait: 10µs}, table_task: {total_time: 3.5ms, num: 1, concurrency: 5}",
+		Next: &ExecutionPlan{
+			ID:            "IndexRangeScan_5(Build)",
+			ExecutionInfo: "time:1.1ms, loops:3, cop_task: {num: 1, max: 1.0ms, proc_keys: 100, tot_proc: 200µs, copr_cache_hit_ratio: 0.00, max_distsql_concurrency: 1}, rpc_info:{Cop:{num_rpc:1, total_time:1ms}}",
+			Next: &ExecutionPlan{
+				ID:            "TableRowIDScan_6(Probe)",
+				ExecutionInfo: "time:3.2ms, loops:2, cop_task: {num: 4, max: 2.1ms, min: 1.0ms, avg: 1.5ms, p95: 2.1ms, max_proc_keys: 40, copr_cache_hit_ratio: 0.00, max_distsql_concurrency: 4}",
+			},
+		},
+	}
+	tasks, concurrency := copParallelism(plan)
+	if tasks != 5 || concurrency != 4 {
+		t.Fatalf("expected 5 cop tasks with concurrency 4, got %d and %d", tasks, concurrency)
+	}
+	if tasks, concurrency = copParallelism(&ExecutionPlan{ID: "Point_Get_1", ExecutionInfo: "time:200µs, loops:1"}); tasks != 0 || concurrency != 0 {
+		t.Fatalf("expected no cop tasks for a point get, got %d and %d", tasks, concurrency)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PartitionSpec is the partitioning of the partitioned copies of the test tables, set by -partitions
+type PartitionSpec struct {
+	// Kind is hash or range, both on b so the b predicates can prune partitions
+	Kind  string
+	Count int
+}
+
+// parsePartitionSpec parses a partitioning like hash:8 or range:4
+func parsePartitionSpec(s string) (*PartitionSpec, error) {
+	kind, count, ok := strings.Cut(s, ":")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 2 || (kind != "hash" && kind != "range") {
+		return nil, fmt.Errorf("invalid partitioning '%s': expected hash:<n> or range:<n>, with n >= 2", s)
+	}
+	return &PartitionSpec{Kind: kind, Count: n}, nil
+}
+
+// Suffix returns the table name suffix of the partitioning, like hash8
+func (p *PartitionSpec) Suffix() string {
+	return fmt.Sprintf("%s%d", p.Kind, p.Count)
+}
+
+// clause returns the PARTITION BY clause, range partitions split the b value domain evenly
+func (p *PartitionSpec) clause() string {
+	if p.Kind == "hash" {
+		return fmt.Sprintf("PARTITION BY HASH (b) PARTITIONS %d", p.Count)
+	}
+	partitions := make([]string, 0, p.Count)
+	for i := 1; i < p.Count; i++ {
+		partitions = append(partitions, fmt.Sprintf("PARTITION p%d VALUES LESS THAN (%d)", i-1, i*bValueDomain/p.Count))
+	}
+	partitions = append(partitions, fmt.Sprintf("PARTITION p%d VALUES LESS THAN (MAXVALUE)", p.Count-1))
+	return fmt.Sprintf("PARTITION BY RANGE (b) (%s)", strings.Join(partitions, ", "))
+}
+
+// partitionedTableStatement returns the CREATE TABLE statement of a
+// partitioned copy. Its primary key is (id, b) instead of the (id) of the test
+// table, as every unique key must include the partitioning column, so the
+// clustered row keys are longer and an id predicate alone is not a point get,
+// outputPartitionCrossover reports the difference with the crossover.
+func partitionedTableStatement(table TableSpec, fillerSize int, spec *PartitionSpec) string {
+	return fmt.Sprintf("CREATE TABLE %s (id int NOT NULL, %s, PRIMARY KEY (id, b) %s, KEY (b)) %s",
+		table.PartitionedName(spec), table.layout.columnDefinitions(table.fillerSizeOr(fillerSize)), table.clustering(), spec.clause())
+}
+
+// setupPartitionedTable creates the partitioned copy of a test table
+func setupPartitionedTable(c *TiDBClient, table TableSpec, fillerSize int, spec *PartitionSpec) error {
+	name := table.PartitionedName(spec)
+	return setupPartnerTableWith(c, table.Name(), name, []string{partitionedTableStatement(table, fillerSize, spec)}, "*")
+}
+
+// withPartitionedCell adds a copy of a cell on a test table, against its
+// partitioned copy, with the family in the scenario ID prefixed by part, like
+// partindex_1K_10. Cells on the partner tables are not copied, joins join the
+// partitioned copy with the unpartitioned join table.
+func withPartitionedCell(cell []TestScenario, table TableSpec, spec *PartitionSpec) []TestScenario {
+	tableRegex := regexp.MustCompile(`\b` + regexp.QuoteMeta(table.Name()) + `\b`)
+	name := table.PartitionedName(spec)
+	var partitioned []TestScenario
+	for _, scenario := range cell {
+		if scenario.TableName != table.Name() {
+			return cell
+		}
+		scenario.ID = "part" + scenario.ID
+		scenario.TableName = name
+		scenario.Name = fmt.Sprintf("%s (%s partitions)", scenario.Name, spec.Suffix())
+		scenario.Query = tableRegex.ReplaceAllString(scenario.Query, name)
+		partitioned = append(partitioned, scenario)
+	}
+	return append(cell, partitioned...)
+}
+
+// indexScanCrossover returns the smallest matching rows from which a table
+// scan is faster than the fastest index plan, over cells sorted by matching
+// rows, -1 if the index plan stays faster
+func indexScanCrossover(cells []*CellAnalysis, matching map[string]int) int {
+	sort.SliceStable(cells, func(i, j int) bool { return matching[cells[i].ScenarioID] < matching[cells[j].ScenarioID] })
+	for _, cell := range cells {
+		scan, ok := cell.AvgTime["table_scan"]
+		if !ok {
+			continue
+		}
+		indexFaster := false
+		for pt, t := range cell.AvgTime {
+			indexFaster = indexFaster || (pt != "table_scan" && t < scan)
+		}
+		if !indexFaster {
+			return matching[cell.ScenarioID]
+		}
+	}
+	return -1
+}
+
+// outputPartitionCrossover reports, per family and table size, the index vs
+// table scan crossover of the test tables and their partitioned copies
+func outputPartitionCrossover(results []*TestExecutionResult, spec *PartitionSpec, excluded []string) {
+	type group struct{ plain, partitioned []*CellAnalysis }
+	groups := make(map[string]*group)
+	matching := make(map[string]int)
+	var keys []string
+	for _, cell := range analyzeCells(results, excluded) {
+		parts := strings.Split(cell.ScenarioID, "_")
+		if len(parts) != 3 {
+			continue
+		}
+		matching[cell.ScenarioID], _ = strconv.Atoi(parts[2])
+		family, partitioned := strings.CutPrefix(parts[0], "part")
+		key := family + "_" + parts[1]
+		if groups[key] == nil {
+			groups[key] = &group{}
+			keys = append(keys, key)
+		}
+		if partitioned {
+			groups[key].partitioned = append(groups[key].partitioned, cell)
+		} else {
+			groups[key].plain = append(groups[key].plain, cell)
+		}
+	}
+	sort.Strings(keys)
+	fmt.Printf("\n🧩 Partition Pruning (%s partitions on b)\n", spec.Suffix())
+	fmt.Println("====================")
+	fmt.Println("Crossover is the smallest number of matching rows where the table scan is faster than the index plans.")
+	fmt.Println("The partitioned copies have PRIMARY KEY (id, b) instead of (id), every unique key must include b,")
+	fmt.Println("so the crossover change also includes the effect of the longer row keys, not only partition pruning.")
+	fmt.Printf("Family\tTable_size\tCrossover\tPartitioned_crossover\tChanged\n")
+	format := func(crossover int) string {
+		if crossover < 0 {
+			return "none"
+		}
+		return strconv.Itoa(crossover)
+	}
+	for _, key := range keys {
+		g := groups[key]
+		if len(g.plain) == 0 || len(g.partitioned) == 0 {
+			continue
+		}
+		plain, partitioned := indexScanCrossover(g.plain, matching), indexScanCrossover(g.partitioned, matching)
+		family, size, _ := strings.Cut(key, "_")
+		fmt.Printf("%s\t%s\t%s\t%s\t%t\n", family, size, format(plain), format(partitioned), plain != partitioned)
+	}
+}
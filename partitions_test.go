@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartitionedCells(t *testing.T) {
+	spec, err := parsePartitionSpec("range:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parsePartitionSpec("list:4"); err == nil {
+		t.Fatalf("expected an error for list partitioning")
+	}
+	stmt := partitionedTableStatement(TableSpec{RowCount: 1000}, 100, spec)
+	if !strings.HasSuffix(stmt, "PARTITION BY RANGE (b) (PARTITION p0 VALUES LESS THAN (250000), PARTITION p1 VALUES LESS THAN (500000), PARTITION p2 VALUES LESS THAN (750000), PARTITION p3 VALUES LESS THAN (MAXVALUE))") {
+		t.Fatalf("unexpected partitioned table statement %s", stmt)
+	}
+
+	scenarios := GetTestScenarios(tableSpecs([]int{1000}, "", MatrixOptions{}), []float64{10}, MatrixOptions{Partitions: spec}, "point", "join")
+	// Both cells are copied, the join against the unpartitioned join table
+	if len(scenarios) != 14 {
+		t.Fatalf("expected 14 scenarios, got %d", len(scenarios))
+	}
+	if scenarios[3].ID != "partindex_1K_10" || scenarios[4].Query != "SELECT /*+ FORCE_INDEX(t1K_range4, b) */ * FROM t1K_range4 WHERE b = 10" {
+		t.Fatalf("unexpected partitioned scenarios: %+v", scenarios[3:6])
+	}
+	if !strings.Contains(scenarios[12].Query, "FROM t1K_range4 t JOIN t1K_join j") {
+		t.Fatalf("unexpected partitioned join scenario: %+v", scenarios[12])
+	}
+}
+
+func TestIndexScanCrossover(t *testing.T) {
+	cell := func(id string, index, scan time.Duration) *CellAnalysis {
+		return &CellAnalysis{ScenarioID: id, AvgTime: map[string]time.Duration{"index_lookup": index, "table_scan": scan}}
+	}
+	matching := map[string]int{"index_1K_10": 10, "index_1K_100": 100, "index_1K_500": 500}
+	cells := []*CellAnalysis{cell("index_1K_500", 9, 5), cell("index_1K_10", 1, 5), cell("index_1K_100", 6, 5)}
+	if got := indexScanCrossover(cells, matching); got != 100 {
+		t.Fatalf("expected the crossover at 100 matching rows, got %d", got)
+	}
+	if got := indexScanCrossover([]*CellAnalysis{cell("index_1K_10", 1, 5)}, matching); got != -1 {
+		t.Fatalf("expected no crossover, got %d", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// predicateValue returns value i of the selectivity of matchingRows rows. The
+// first value of a selectivity is its number of matching rows, like b = 500
+// for 500 rows, the others are above the random b value domain, so they only
+// have the rows set for them.
+func predicateValue(matchingRows, i int) int {
+	return matchingRows + i*bValueDomain
+}
+
+// bEquals matches the b = <value> predicates of the scenario queries
+var bEquals = regexp.MustCompile(`\bb = (\d+)\b`)
+
+// withPredicateValue returns the scenario with its b = MatchingRows predicate
+// replaced by the value of the repetition, cycling through the given number of
+// values of -predicate-values, so the repetitions do not read the same keys
+func withPredicateValue(scenario TestScenario, repetition, values int) TestScenario {
+	if values <= 1 {
+		return scenario
+	}
+	i := repetition % values
+	if i == 0 || scenario.MatchingRows <= 0 {
+		return scenario
+	}
+	value := predicateValue(scenario.MatchingRows, i)
+	replaced := false
+	scenario.Query = bEquals.ReplaceAllStringFunc(scenario.Query, func(m string) string {
+		if m[len("b = "):] != strconv.Itoa(scenario.MatchingRows) {
+			return m
+		}
+		replaced = true
+		return fmt.Sprintf("b = %d", value)
+	})
+	if replaced {
+		scenario.PredicateValue = value
+	}
+	return scenario
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithPredicateValue(t *testing.T) {
+	scenario := TestScenario{
+		ID:           "index_1K_10",
+		MatchingRows: 10,
+		Query:        "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 10 AND e < 100 AND tb = 10",
+	}
+	if got := withPredicateValue(scenario, 3, 3); got.Query != scenario.Query || got.PredicateValue != 0 {
+		t.Fatalf("expected repetition 3 to keep the value, got %q", got.Query)
+	}
+	got := withPredicateValue(scenario, 2, 3)
+	want := "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 2000010 AND e < 100 AND tb = 10"
+	if got.Query != want || got.PredicateValue != 2000010 {
+		t.Fatalf("expected %q, got %q (%d)", want, got.Query, got.PredicateValue)
+	}
+	if scenario.Query == got.Query {
+		t.Fatal("expected the original scenario to be unchanged")
+	}
+	other := TestScenario{MatchingRows: 10, Query: "SELECT * FROM t1K WHERE b = 100"}
+	if got := withPredicateValue(other, 1, 3); got.Query != other.Query || got.PredicateValue != 0 {
+		t.Fatalf("expected a query without b = MatchingRows to be unchanged, got %q", got.Query)
+	}
+	if got := withPredicateValue(scenario, 2, 1); got.Query != scenario.Query {
+		t.Fatalf("expected a single value to keep the query, got %q", got.Query)
+	}
+}
+
+func TestPredicateValuesTables(t *testing.T) {
+	table := tableSpecs([]int{1000}, "", MatrixOptions{PredicateValues: 4})[0]
+	if table.Name() != "t1K_v4" {
+		t.Fatalf("expected the predicate values in the table name, got %s", table.Name())
+	}
+	if !strings.Contains(table.comment(100), " values=4") {
+		t.Fatalf("expected the predicate values in the table comment, got %s", table.comment(100))
+	}
+	if composite := table.CompositeName(); composite != "t1K_v4_composite" {
+		t.Fatalf("expected the partner tables named after the values, got %s", composite)
+	}
+	if table := tableSpecs([]int{1000}, "", MatrixOptions{PredicateValues: 1})[0]; table.Name() != "t1K" {
+		t.Fatalf("expected a single value to keep the table name, got %s", table.Name())
+	}
+}
+
+func TestPredicateValuesChosenPlan(t *testing.T) {
+	value := newChoiceResult("index_1K_10", "table_scan")
+	value.PredicateValue = 1000010
+	results := []*TestExecutionResult{
+		value,
+		newChoiceResult("index_1K_10", "index_lookup"),
+		newTestResult("index_1K_10", "index_lookup", 2),
+		newTestResult("index_1K_10", "table_scan", 4),
+	}
+	cells := analyzeCells(results, nil)
+	if len(cells) != 1 || cells[0].Chosen != "index_lookup" {
+		t.Fatalf("expected the plan of the first value as the chosen one, got %+v", cells)
+	}
+}
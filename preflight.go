@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// preflightNotConfirmed is the abort reason of a run not confirmed after the preflight estimate
+const preflightNotConfirmed = "run not confirmed after the preflight estimate"
+
+// Preflight estimates the run duration before executing the schedule, and
+// asks for confirmation if it is longer than ConfirmOver
+type Preflight struct {
+	ConfirmOver time.Duration
+	// AssumeYes skips the confirmation
+	AssumeYes bool
+}
+
+// probeCells executes every variant of the first cell of each table size
+// once, returning the average time per execution, including the plan
+// and cost collection, per table size
+func probeCells(c *TiDBClient, schedule *Schedule) map[int]time.Duration {
+	probeCell := make(map[int]string)
+	for _, scenario := range schedule.Scenarios() {
+		if _, ok := probeCell[scenario.RowCount]; !ok {
+			probeCell[scenario.RowCount] = scenario.ID
+		}
+	}
+	totals := make(map[int]time.Duration)
+	counts := make(map[int]int)
+	for _, scenario := range schedule.Scenarios() {
+		if probeCell[scenario.RowCount] != scenario.ID {
+			continue
+		}
+		start := time.Now()
+		if _, err := c.ExecuteQueryWithMetrics(scenario); err != nil {
+			continue
+		}
+		totals[scenario.RowCount] += time.Since(start)
+		counts[scenario.RowCount]++
+	}
+	probes := make(map[int]time.Duration)
+	for rowCount, n := range counts {
+		probes[rowCount] = totals[rowCount] / time.Duration(n)
+	}
+	return probes
+}
+
+// estimateRunDuration extrapolates the probe timings per table size to all executions of the schedule
+func estimateRunDuration(schedule *Schedule, probes map[int]time.Duration) time.Duration {
+	var estimate time.Duration
+	for run := range schedule.All() {
+		estimate += probes[run.Scenario.RowCount]
+	}
+	return estimate
+}
+
+// Check probes the cluster and returns false if the run was not confirmed
+func (p *Preflight) Check(c *TiDBClient, schedule *Schedule) bool {
+	if p == nil {
+		return true
+	}
+	fmt.Println("\n⏱️  Preflight Estimate")
+	fmt.Println("====================")
+	probes := probeCells(c, schedule)
+	rowCounts := make([]int, 0, len(probes))
+	for rowCount := range probes {
+		rowCounts = append(rowCounts, rowCount)
+	}
+	slices.Sort(rowCounts)
+	for _, rowCount := range rowCounts {
+		fmt.Printf("%s rows:\t%.03f ms per execution\n", formatRowCount(rowCount), probes[rowCount].Seconds()*1000.0)
+	}
+	estimate := estimateRunDuration(schedule, probes)
+	fmt.Printf("Estimated duration of %d executions: %s (excluding pauses)\n", schedule.Len(), estimate.Round(time.Second))
+	if p.AssumeYes || estimate <= p.ConfirmOver {
+		return true
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		// Nobody to ask, like in scripts, that are expected to know what they run
+		return true
+	}
+	fmt.Printf("The run is estimated to take more than %s, continue? [y/N] ", p.ConfirmOver)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
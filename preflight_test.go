@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateRunDuration(t *testing.T) {
+	scenarios := GetTestScenarios(tableSpecs([]int{1000, 1000000}, "", MatrixOptions{}), []float64{0.1}, MatrixOptions{})
+	// Per table size: 1 ExplainOnly and 2 variants repeated 3 times
+	schedule := NewSchedule(scenarios, 3)
+	probes := map[int]time.Duration{1000: time.Millisecond, 1000000: 100 * time.Millisecond}
+	if got, expected := estimateRunDuration(schedule, probes), 7*time.Millisecond+700*time.Millisecond; got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	var p *Preflight
+	if !p.Check(nil, schedule) {
+		t.Fatalf("expected no preflight to confirm the run")
+	}
+}
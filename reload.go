@@ -0,0 +1,322 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// parseTableSpec returns the spec of an existing test table of rowCount rows
+// from its name, like t1M_zipf_nc, the inverse of TableSpec.Name
+func parseTableSpec(name string, rowCount int) (TableSpec, error) {
+	spec := TableSpec{RowCount: rowCount}
+	prefix := "t" + formatRowCountName(rowCount)
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return spec, fmt.Errorf("table %s with %d rows is not a test table, expected a name like %s", name, rowCount, prefix)
+	}
+	parts := strings.Split(strings.TrimPrefix(rest, "_"), "_")
+	if rest == "" {
+		parts = nil
+	}
+	for i, part := range parts {
+		isDist := isDistributionName(part)
+		size, sizeErr := strconv.Atoi(strings.TrimPrefix(part, "f"))
+		values, valuesErr := strconv.Atoi(strings.TrimPrefix(part, "v"))
+		switch {
+		case i == 0 && isDist:
+			spec.Distribution = part
+		case strings.HasPrefix(part, "f") && sizeErr == nil && spec.FillerSize == 0 && !spec.Nonclustered:
+			spec.FillerSize = size
+		case strings.HasPrefix(part, "fill") && spec.Layout == "" && !spec.Nonclustered:
+			spec.Layout = part
+		case part == "blast" && !strings.HasSuffix(spec.Layout, "blast") && !spec.Nonclustered:
+			spec.Layout = strings.TrimPrefix(spec.Layout+"_blast", "_")
+		case strings.HasPrefix(part, "v") && valuesErr == nil && values > 1 && spec.PredicateValues == 0 && !spec.Nonclustered:
+			spec.PredicateValues = values
+		case part == "nc" && !spec.Nonclustered:
+			spec.Nonclustered = true
+		default:
+			spec.Suffix = strings.Join(parts[i:], "_")
+		}
+		if spec.Suffix != "" {
+			break
+		}
+	}
+	if spec.Name() != name {
+		return spec, fmt.Errorf("table %s is not a test table", name)
+	}
+	return spec, nil
+}
+
+// commentParams are the generation parameters recorded in a table comment
+type commentParams struct {
+	FillerSize   int
+	Distribution string
+	Nulls        map[string]float64
+	Layout       FillerLayout
+	// PredicateValues is the number of b values per selectivity, 0 for one
+	PredicateValues int
+}
+
+// parseCommentParams returns the generation parameters of a table comment
+func parseCommentParams(params string) (*commentParams, error) {
+	p := &commentParams{}
+	var err error
+	for _, param := range strings.Fields(params) {
+		switch {
+		case strings.HasPrefix(param, "filler="):
+			if p.FillerSize, err = strconv.Atoi(strings.TrimPrefix(param, "filler=")); err != nil {
+				return nil, fmt.Errorf("invalid filler size in '%s'", params)
+			}
+		case strings.HasPrefix(param, "b:"):
+			p.Distribution = strings.TrimPrefix(param, "b:")
+		case strings.HasPrefix(param, "nulls="):
+			// Recorded as b:0.1,c:0.2, given as b=0.1,c=0.2
+			if p.Nulls, err = parseNullRatios(strings.ReplaceAll(strings.TrimPrefix(param, "nulls="), ":", "=")); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(param, "fillers="):
+			if p.Layout.Sizes, err = parseFillerColumns(strings.TrimPrefix(param, "fillers=")); err != nil {
+				return nil, err
+			}
+		case param == "b=last":
+			p.Layout.BLast = true
+		case strings.HasPrefix(param, "values="):
+			if p.PredicateValues, err = strconv.Atoi(strings.TrimPrefix(param, "values=")); err != nil {
+				return nil, fmt.Errorf("invalid predicate values in '%s'", params)
+			}
+		}
+	}
+	if p.FillerSize == 0 {
+		return nil, fmt.Errorf("no filler size in '%s'", params)
+	}
+	return p, nil
+}
+
+// reloadOptions are the settings of the reload command
+type reloadOptions struct {
+	tableName    string
+	distribution string
+	dist         *distGenerator
+	sels         []float64
+	setup        SetupOptions
+}
+
+// parseReloadFlags parses the flags of the reload command
+func parseReloadFlags(args []string) (*reloadOptions, error) {
+	fs := flag.NewFlagSet("reload", flag.ExitOnError)
+	var logLevel = fs.String("l", "info", "Log level: debug, info, warn, error")
+	var tableName = fs.String("table", "", "The test table to reload, like t1M")
+	var distribution = fs.String("distribution", "uniform", "The new distribution of the b values, like for the run: uniform, zipf, normal or one with parameters like 'zipf(1.5, seed=7)'")
+	var selectivities = fs.String("c", defaultSelectivities, "Comma-separated list of selectivity/cardinality values to adjust the reloaded b values to, like for the run")
+	var loadConcurrency = fs.Int("load-concurrency", 1, "Number of connections inserting the generated rows")
+	var loadBatchSize = fs.Int("load-batch-size", defaultLoadBatchSize, "Rows per INSERT statement")
+	var loaderFlag = fs.String("loader", loaderInsert, "How the generated rows are loaded: insert or load-data")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	setupLogging(*logLevel)
+	if *tableName == "" {
+		return nil, fmt.Errorf("usage: reload -table <name> -distribution <distribution>")
+	}
+	config, err := connectionConfig()
+	if err != nil {
+		return nil, err
+	}
+	sels, err := parseSelectivities(*selectivities)
+	if err != nil {
+		return nil, err
+	}
+	loader, err := parseLoader(*loaderFlag)
+	if err != nil {
+		return nil, err
+	}
+	dist, err := parseDistribution(*distribution)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadOptions{
+		tableName:    *tableName,
+		distribution: *distribution,
+		dist:         dist,
+		sels:         sels,
+		setup:        SetupOptions{TiDB: config, LoadConcurrency: *loadConcurrency, LoadBatchSize: *loadBatchSize, Loader: loader},
+	}, nil
+}
+
+// runReload implements the reload command: it regenerates the rows of an
+// existing test table with another distribution of b, keeping the table with
+// its indexes and placement, splitting it into as many regions as before,
+// and renames it to the name of the new distribution, for fast iteration on
+// distribution experiments
+func runReload(args []string) error {
+	ro, err := parseReloadFlags(args)
+	if err != nil {
+		return err
+	}
+	dist, sels := ro.dist, ro.sels
+	if dist != nil {
+		RegisterColumnGenerator("b", *dist)
+	}
+
+	c := NewTiDBClient()
+	if err = c.Connect(ro.setup.TiDB); err != nil {
+		return err
+	}
+	defer c.Close()
+	rowCount, err := c.GetTableRowCount(ro.tableName)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", ro.tableName, err)
+	}
+	table, err := parseTableSpec(ro.tableName, rowCount)
+	if err != nil {
+		return err
+	}
+	schema, err := c.getTableSchema(ro.tableName)
+	if err != nil {
+		return err
+	}
+	version, params := parseTableComment(schema.Comment)
+	if version != tableSchemaVersion {
+		return fmt.Errorf("table %s has table schema v%d, reload supports v%d, run the calibration once to migrate or recreate it", ro.tableName, version, tableSchemaVersion)
+	}
+	comment, err := parseCommentParams(params)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", ro.tableName, err)
+	}
+	if comment.Layout.name() != table.Layout {
+		return fmt.Errorf("table %s has the row layout '%s', its name is of the layout '%s'", ro.tableName, comment.Layout.params(), table.Layout)
+	}
+	if comment.PredicateValues != table.PredicateValues {
+		return fmt.Errorf("table %s has %d predicate values, its name is of %d", ro.tableName, max(comment.PredicateValues, 1), max(table.PredicateValues, 1))
+	}
+	fillerSize := comment.FillerSize
+	table.nulls = comment.Nulls
+	table.layout = comment.Layout
+
+	reloaded := table
+	reloaded.Distribution, reloaded.distribution = "", dist
+	if dist != nil {
+		reloaded.Distribution = dist.name()
+	}
+	newName := reloaded.Name()
+	if newName != ro.tableName {
+		exists, err := c.tableExists(newName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("table %s of the new distribution already exists, drop it first", newName)
+		}
+	}
+	from := comment.Distribution
+	if from == "" {
+		from = "uniform"
+	}
+	fmt.Printf("🔁 Reloading %s (%d rows) from %s to %s\n", ro.tableName, rowCount, from, ro.distribution)
+
+	// TRUNCATE keeps the table definition with its indexes and placement
+	// policy, unlike dropping and recreating it, but gives it a new table ID
+	// without the regions of the old one, so it is split alike again
+	split, err := c.getTableSplit(ro.tableName)
+	if err != nil {
+		return err
+	}
+	if _, err = c.ExecuteQuery(fmt.Sprintf("TRUNCATE TABLE %s", ro.tableName)); err != nil {
+		return fmt.Errorf("failed to truncate table %s: %w", ro.tableName, err)
+	}
+	for _, stmt := range split.statements(ro.tableName) {
+		if _, err = c.ExecuteQuery(stmt); err != nil {
+			return fmt.Errorf("failed to split table %s: %w", ro.tableName, err)
+		}
+	}
+	opts := ro.setup
+	opts.FillerSize = fillerSize
+	fillers, fillerGens := table.layout.fillerColumns(fillerSize)
+	err = generateRandomData(c, ro.tableName, rowCount, append([]string{"b"}, fillers...),
+		append([]ColumnGenerator{getColumnGenerator("b", randomIntGenerator{max: bValueDomain})}, fillerGens...), opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate random data: %w", err)
+	}
+	// The values of a distribution are kept, the matching rows of the cells are counted by the run
+	if dist == nil {
+		if err = adjustSelectivities(c, ro.tableName, rowCount, sels, table.PredicateValues); err != nil {
+			return fmt.Errorf("failed to adjust selectivities: %w", err)
+		}
+	}
+	if len(table.nulls) > 0 {
+		if err = adjustNulls(c, ro.tableName, rowCount, table.nulls, sels, fillerSize); err != nil {
+			return err
+		}
+		if _, err = c.ExecuteQuery(fmt.Sprintf("ANALYZE TABLE %s", ro.tableName)); err != nil {
+			return fmt.Errorf("failed to analyze table %s: %w", ro.tableName, err)
+		}
+	}
+	if _, err = c.ExecuteQuery(fmt.Sprintf("ALTER TABLE %s COMMENT = '%s'", ro.tableName, reloaded.comment(fillerSize))); err != nil {
+		return fmt.Errorf("failed to update the comment of %s: %w", ro.tableName, err)
+	}
+	// The partner tables are copies of the old rows, they are recreated by the next run
+	if err = c.dropPartnerTables(table, nil); err != nil {
+		return err
+	}
+	partitioned, err := queryNamedRows(c.db, fmt.Sprintf(
+		"SELECT TABLE_NAME FROM information_schema.tables WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME REGEXP '^%s_(hash|range)[0-9]+$'", ro.tableName))
+	if err != nil {
+		return fmt.Errorf("failed to list the partitioned copies of %s: %w", ro.tableName, err)
+	}
+	for _, row := range partitioned {
+		if _, err = c.ExecuteQuery("DROP TABLE IF EXISTS " + row["TABLE_NAME"]); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", row["TABLE_NAME"], err)
+		}
+		fmt.Printf("🧹 Dropped table %s\n", row["TABLE_NAME"])
+	}
+	if newName != ro.tableName {
+		if _, err = c.ExecuteQuery(fmt.Sprintf("RENAME TABLE %s TO %s", ro.tableName, newName)); err != nil {
+			return fmt.Errorf("failed to rename %s to %s: %w", ro.tableName, newName, err)
+		}
+		slog.Info("Renamed the reloaded table to the name of its distribution", "from", ro.tableName, "to", newName)
+	}
+	fmt.Printf("✅ Reloaded %s, run with -distribution %s to use it\n", newName, ro.distribution)
+	return nil
+}
+
+// tableSplit are the numbers of regions of the rows and of the index b of a
+// table, and the id and b ranges they cover
+type tableSplit struct {
+	RowRegions, IndexRegions int
+	MinID, MaxID, MinB, MaxB int
+}
+
+// getTableSplit returns the regions of the rows and of the index b of a table
+func (c *TiDBClient) getTableSplit(tableName string) (*tableSplit, error) {
+	s := &tableSplit{}
+	query := "SELECT COUNT(DISTINCT IF(IS_INDEX = 0, REGION_ID, NULL)), COUNT(DISTINCT IF(INDEX_NAME = 'b', REGION_ID, NULL)) " +
+		"FROM information_schema.tikv_region_status WHERE DB_NAME = DATABASE() AND TABLE_NAME = ?"
+	slog.Debug("Executing query", "query", query, "table", tableName)
+	if err := c.db.QueryRow(query, tableName).Scan(&s.RowRegions, &s.IndexRegions); err != nil {
+		return nil, fmt.Errorf("failed to get the regions of %s: %w", tableName, err)
+	}
+	query = fmt.Sprintf("SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0), COALESCE(MIN(b), 0), COALESCE(MAX(b), 0) FROM %s", tableName)
+	slog.Debug("Executing query", "query", query)
+	if err := c.db.QueryRow(query).Scan(&s.MinID, &s.MaxID, &s.MinB, &s.MaxB); err != nil {
+		return nil, fmt.Errorf("failed to get the id and b ranges of %s: %w", tableName, err)
+	}
+	return s, nil
+}
+
+// statements returns the SPLIT TABLE statements splitting the truncated
+// table into as many regions as before, evenly over the old id and b ranges
+func (s *tableSplit) statements(tableName string) []string {
+	var stmts []string
+	if s.RowRegions > 1 && s.MaxID > s.MinID {
+		stmts = append(stmts, fmt.Sprintf("SPLIT TABLE %s BETWEEN (%d) AND (%d) REGIONS %d", tableName, s.MinID, s.MaxID+1, s.RowRegions))
+	}
+	if s.IndexRegions > 1 && s.MaxB > s.MinB {
+		stmts = append(stmts, fmt.Sprintf("SPLIT TABLE %s INDEX b BETWEEN (%d) AND (%d) REGIONS %d", tableName, s.MinB, s.MaxB+1, s.IndexRegions))
+	}
+	return stmts
+}
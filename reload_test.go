@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTableSpec(t *testing.T) {
+	for _, name := range []string{"t1M", "t1M_zipf", "t1M_zipf_f1000_nc", "t1M_nc_rx7a", "t1M_f200_custom_x",
+		"t1M_fill200x50_blast", "t1M_blast_nc", "t1M_zipf_fill100", "t1M_zipf3cfa03_nc", "t1M_v4", "t1M_f1000_v4_nc_rx7a"} {
+		spec, err := parseTableSpec(name, 1000000)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if spec.Name() != name {
+			t.Errorf("%s parsed as %+v, named %s", name, spec, spec.Name())
+		}
+	}
+	spec, _ := parseTableSpec("t1M_zipf_f1000_nc", 1000000)
+	if spec.Distribution != "zipf" || spec.FillerSize != 1000 || !spec.Nonclustered {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	if _, err := parseTableSpec("orders", 1000000); err == nil {
+		t.Errorf("expected an error for a table that is not a test table")
+	}
+	if _, err := parseTableSpec("t10K", 1000000); err == nil {
+		t.Errorf("expected an error for a table of another size")
+	}
+}
+
+func TestParseCommentParams(t *testing.T) {
+	p, err := parseCommentParams("filler=500 b:zipf nulls=b:0.1,c:0.2 fillers=200,200 b=last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FillerSize != 500 || p.Distribution != "zipf" || p.Nulls["b"] != 0.1 || p.Nulls["c"] != 0.2 {
+		t.Errorf("unexpected params %+v", p)
+	}
+	if p.Layout.name() != "fill200x200_blast" {
+		t.Errorf("unexpected layout %+v", p.Layout)
+	}
+	if p, err = parseCommentParams("filler=500 values=4"); err != nil || p.PredicateValues != 4 {
+		t.Errorf("expected 4 predicate values, got %+v, %v", p, err)
+	}
+	if _, err := parseCommentParams("b:zipf"); err == nil {
+		t.Errorf("expected an error without a filler size")
+	}
+}
+
+func TestParseReloadFlagsDefaults(t *testing.T) {
+	ro, err := parseReloadFlags([]string{"-table", "t1K"})
+	if err != nil {
+		t.Fatalf("expected the default flags to be valid, got %v", err)
+	}
+	if ro.tableName != "t1K" || ro.dist != nil || len(ro.sels) != 9 || ro.sels[0] != 0.5 || ro.sels[8] != 0.001953125 {
+		t.Errorf("unexpected reload options %+v", ro)
+	}
+	if ro.setup.Loader != loaderInsert || ro.setup.TiDB == nil {
+		t.Errorf("unexpected setup options %+v", ro.setup)
+	}
+	if _, err = parseReloadFlags(nil); err == nil {
+		t.Errorf("expected an error without -table")
+	}
+}
+
+func TestTableSplitStatements(t *testing.T) {
+	s := tableSplit{RowRegions: 4, IndexRegions: 2, MinID: 1, MaxID: 1000, MinB: 0, MaxB: 999999}
+	got := s.statements("t1K")
+	want := []string{"SPLIT TABLE t1K BETWEEN (1) AND (1001) REGIONS 4", "SPLIT TABLE t1K INDEX b BETWEEN (0) AND (1000000) REGIONS 2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	// A table of a single region is not split
+	if got = (&tableSplit{RowRegions: 1, IndexRegions: 1, MaxID: 1000, MaxB: 999}).statements("t1K"); len(got) != 0 {
+		t.Errorf("expected no split, got %v", got)
+	}
+}
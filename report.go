@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// groupByScenario groups the executed (non ExplainOnly) results by scenario ID and plan type,
+// returning the sorted scenario IDs
+func groupByScenario(results []*TestExecutionResult) ([]string, map[string]map[string][]*TestExecutionResult) {
+	groups := make(map[string]map[string][]*TestExecutionResult)
+	for _, r := range results {
+		if r.ExplainOnly {
+			continue
+		}
+		if groups[r.ScenarioID] == nil {
+			groups[r.ScenarioID] = make(map[string][]*TestExecutionResult)
+		}
+		groups[r.ScenarioID][r.PlanType] = append(groups[r.ScenarioID][r.PlanType], r)
+	}
+	scenarioIDs := make([]string, 0, len(groups))
+	for id := range groups {
+		scenarioIDs = append(scenarioIDs, id)
+	}
+	sort.Strings(scenarioIDs)
+	return scenarioIDs, groups
+}
+
+// sortedPlanTypes returns the plan types of a scenario group in sorted order
+func sortedPlanTypes(group map[string][]*TestExecutionResult) []string {
+	planTypes := make([]string, 0, len(group))
+	for pt := range group {
+		planTypes = append(planTypes, pt)
+	}
+	sort.Strings(planTypes)
+	return planTypes
+}
+
+// outputThrottlingReport shows how resource control throttling (RU burst limits)
+// affected the plan-relative performance, by comparing the measured latency with
+// the latency excluding the time queued by resource control
+func outputThrottlingReport(results []*TestExecutionResult) {
+	fmt.Println("\n🚦 Resource Control Throttling")
+	fmt.Println("====================")
+
+	scenarioIDs, groups := groupByScenario(results)
+	fmt.Printf("Scenario\tTable_size\tCardinality\tPlan\tSamples\tThrottled\t")
+	withRU := ruReported(results)
+	if withRU {
+		fmt.Printf("RU-avg\t")
+	}
+	fmt.Printf("ms-avg\twait-ms-avg\tunthrottled-ms-avg\n")
+	winnerChanges := 0
+	for _, scenarioID := range scenarioIDs {
+		group := groups[scenarioID]
+		fastest, fastestUnthrottled := "", ""
+		var fastestTime, fastestUnthrottledTime time.Duration
+		for _, pt := range sortedPlanTypes(group) {
+			var total, wait time.Duration
+			var ru float64
+			throttled := 0
+			for _, r := range group[pt] {
+				total += r.Plan.ExecutionTime
+				wait += r.RCWait
+				ru += getRU(r.Plan)
+				if r.RCWait > 0 {
+					throttled++
+				}
+			}
+			n := len(group[pt])
+			avg := total / time.Duration(n)
+			avgUnthrottled := (total - wait) / time.Duration(n)
+			if fastest == "" || avg < fastestTime {
+				fastest, fastestTime = pt, avg
+			}
+			if fastestUnthrottled == "" || avgUnthrottled < fastestUnthrottledTime {
+				fastestUnthrottled, fastestUnthrottledTime = pt, avgUnthrottled
+			}
+			fmt.Printf("%s\t%s\t%d\t%d\t", strings.Join(strings.Split(scenarioID, "_"), "\t"), pt, n, throttled)
+			if withRU {
+				fmt.Printf("%.03f\t", ru/float64(n))
+			}
+			fmt.Printf("%.03f\t%.03f\t%.03f\n", float64(avg.Microseconds())/1000.0,
+				float64((wait/time.Duration(n)).Microseconds())/1000.0,
+				float64(avgUnthrottled.Microseconds())/1000.0)
+		}
+		if fastest != fastestUnthrottled {
+			winnerChanges++
+			fmt.Printf("  ⚠️  %s: fastest plan is %s with throttling, %s without\n", scenarioID, fastest, fastestUnthrottled)
+		}
+	}
+	fmt.Printf("\nScenarios where throttling changed the fastest plan: %d of %d\n", winnerChanges, len(scenarioIDs))
+}
+
+// outputPlanCacheWarning warns if executions used a cached or bound plan, so
+// the repetitions did not measure what the optimizer would choose for the query
+func outputPlanCacheWarning(results []*TestExecutionResult) {
+	fromCache, fromBinding := 0, 0
+	for _, r := range results {
+		if r.PlanFromCache {
+			fromCache++
+		}
+		if r.PlanFromBinding {
+			fromBinding++
+		}
+	}
+	if fromCache > 0 {
+		fmt.Printf("\n⚠️  %d executions used a plan from the plan cache, use -ignore-plan-cache to re-optimize every repetition\n", fromCache)
+	}
+	if fromBinding > 0 {
+		fmt.Printf("\n⚠️  %d executions used a plan from a SQL binding, the optimizer choice was not measured for them\n", fromBinding)
+	}
+}
+
+// uniqueCellID returns the table variant prefix of a unique index cell of the
+// unique family, like nc of ncunique_1K_10, and the ID of its non-unique index
+// cell, ncnonunique_1K_10, or false if it is not a unique index cell
+func uniqueCellID(id string) (string, string, bool) {
+	family, rest, ok := strings.Cut(id, "_")
+	variant, unique := strings.CutSuffix(family, "unique")
+	if !ok || !unique || strings.HasSuffix(variant, "non") {
+		return "", "", false
+	}
+	return variant, variant + "nonunique_" + rest, true
+}
+
+// outputUniquenessComparison compares the unique and non-unique index cells of
+// the unique family with the same keys: the optimizer choice, and the latency
+// and RU of the fastest index plan of each
+func outputUniquenessComparison(results []*TestExecutionResult, excluded []string) {
+	cells := make(map[string]*CellAnalysis)
+	var uniqueIDs []string
+	for _, cell := range analyzeCells(results, excluded) {
+		cells[cell.ScenarioID] = cell
+		if _, _, ok := uniqueCellID(cell.ScenarioID); ok {
+			uniqueIDs = append(uniqueIDs, cell.ScenarioID)
+		}
+	}
+	if len(uniqueIDs) == 0 {
+		return
+	}
+	withRU := ruReported(results)
+	// fastestIndex returns the fastest measured plan type that is not a table scan
+	fastestIndex := func(cell *CellAnalysis) string {
+		fastest := ""
+		for pt, t := range cell.AvgTime {
+			if !strings.Contains(pt, "table_scan") && (fastest == "" || t < cell.AvgTime[fastest]) {
+				fastest = pt
+			}
+		}
+		return fastest
+	}
+	fmt.Println("\n🔑 Unique vs Non-Unique Index")
+	fmt.Println("====================")
+	fmt.Printf("Table_size\tKeys")
+	for _, prefix := range []string{"Unique", "NonUnique"} {
+		fmt.Printf("\t%s_chosen\t%s_index_plan\t%s_index_ms", prefix, prefix, prefix)
+		if withRU {
+			fmt.Printf("\t%s_index_RU", prefix)
+		}
+	}
+	fmt.Println()
+	for _, id := range uniqueIDs {
+		variant, nonUniqueID, _ := uniqueCellID(id)
+		unique, nonUnique := cells[id], cells[nonUniqueID]
+		if nonUnique == nil {
+			continue
+		}
+		parts := strings.Split(id, "_")
+		size := parts[1]
+		if variant != "" {
+			size += " (" + variant + ")"
+		}
+		fmt.Printf("%s\t%s", size, parts[2])
+		for _, cell := range []*CellAnalysis{unique, nonUnique} {
+			pt := fastestIndex(cell)
+			fmt.Printf("\t%s\t%s\t%.03f", cell.Chosen, pt, cell.AvgTime[pt].Seconds()*1000.0)
+			if withRU {
+				fmt.Printf("\t%.02f", cell.AvgRU[pt])
+			}
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestUniqueCellID(t *testing.T) {
+	for _, tc := range []struct {
+		id, variant, nonUnique string
+		ok                     bool
+	}{
+		{"unique_1K_10", "", "nonunique_1K_10", true},
+		{"ncunique_1K_10", "nc", "ncnonunique_1K_10", true},
+		{"f200unique_1K_10", "f200", "f200nonunique_1K_10", true},
+		{"zipfnonunique_1K_10", "", "", false},
+		{"index_1K_10", "", "", false},
+	} {
+		variant, nonUnique, ok := uniqueCellID(tc.id)
+		if variant != tc.variant || nonUnique != tc.nonUnique || ok != tc.ok {
+			t.Errorf("%s: got %q, %q, %t", tc.id, variant, nonUnique, ok)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// resultSetFormatVersion is the version of the result file format. Bump it
+// when a change cannot be read by older versions, and convert the older
+// formats in readResultSet.
+const resultSetFormatVersion = 1
+
+// checkResultSetVersion returns an error if a result file of the format
+// version cannot be read by this version of the tool
+func checkResultSetVersion(version int, path string) error {
+	switch {
+	case version == 0:
+		return fmt.Errorf("%s is not a calibration result file (no format_version)", path)
+	case version > resultSetFormatVersion:
+		return fmt.Errorf("%s was written by a newer version of this tool (format v%d, this tool supports up to v%d)",
+			path, version, resultSetFormatVersion)
+	}
+	return nil
+}
+
+// ResultSet is a stored calibration run: its metadata and all results
+type ResultSet struct {
+	FormatVersion int                    `json:"format_version"`
+	Metadata      *RunMetadata           `json:"metadata"`
+	Results       []*TestExecutionResult `json:"results"`
+}
+
+// writeResultSet stores the run as a JSON result file
+func writeResultSet(path string, meta *RunMetadata, results []*TestExecutionResult) error {
+	data, err := json.MarshalIndent(ResultSet{
+		FormatVersion: resultSetFormatVersion,
+		Metadata:      meta,
+		Results:       results,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
+
+// readResultSet reads a JSON result file
+func readResultSet(path string) (*ResultSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	rs := &ResultSet{}
+	if err = json.Unmarshal(data, rs); err != nil {
+		return nil, fmt.Errorf("failed to decode results in %s: %w", path, err)
+	}
+	if err = checkResultSetVersion(rs.FormatVersion, path); err != nil {
+		return nil, err
+	}
+	if rs.Metadata == nil {
+		return nil, fmt.Errorf("no run metadata in %s", path)
+	}
+	return rs, nil
+}
+
+// compatibleWith returns an error describing why results from the two runs
+// cannot be merged, or nil if they measured the same data on the same cluster setup
+func (m *RunMetadata) compatibleWith(o *RunMetadata) error {
+	var diffs []string
+	if m.FillerSize != o.FillerSize {
+		diffs = append(diffs, fmt.Sprintf("filler size %d vs %d", m.FillerSize, o.FillerSize))
+	}
+	if !slices.Equal(m.FillerSizes, o.FillerSizes) {
+		diffs = append(diffs, fmt.Sprintf("filler sizes %v vs %v", m.FillerSizes, o.FillerSizes))
+	}
+	if !slices.Equal(m.Generators, o.Generators) {
+		diffs = append(diffs, fmt.Sprintf("generators %v vs %v", m.Generators, o.Generators))
+	}
+	if m.ServerVersion != o.ServerVersion {
+		diffs = append(diffs, fmt.Sprintf("server version %s vs %s", m.ServerVersion, o.ServerVersion))
+	}
+	if runCoefficients(m) != runCoefficients(o) {
+		diffs = append(diffs, "RU coefficients differ, use report compare")
+	}
+	if m.EngineConfig != o.EngineConfig {
+		diffs = append(diffs, fmt.Sprintf("storage engine %s vs %s", m.EngineConfig, o.EngineConfig))
+	}
+	// Different statistics are only warned about, see dataDifferences
+	data, _ := dataDifferences(m, o)
+	diffs = append(diffs, data...)
+	if len(diffs) > 0 {
+		return fmt.Errorf("incompatible runs %s and %s: %s", m.RunID, o.RunID, strings.Join(diffs, ", "))
+	}
+	return nil
+}
+
+// mergeResultSets merges compatible result sets into one, combining the matrix
+// dimensions of the metadata
+func mergeResultSets(sets []*ResultSet) *ResultSet {
+	first := *sets[0].Metadata
+	merged := &ResultSet{FormatVersion: resultSetFormatVersion, Metadata: &first}
+	merged.Metadata.RowCounts = nil
+	merged.Metadata.Selectivities = nil
+	merged.Metadata.Families = nil
+	merged.Metadata.Timeline = nil
+	merged.Metadata.LatencyFloor = nil
+	merged.Metadata.StatsChanges = nil
+	merged.Metadata.TableSnapshots = nil
+	merged.Metadata.Shard = ""
+	var runIDs []string
+	for _, rs := range sets {
+		m := rs.Metadata
+		runIDs = append(runIDs, m.RunID)
+		for _, rows := range m.RowCounts {
+			if !slices.Contains(merged.Metadata.RowCounts, rows) {
+				merged.Metadata.RowCounts = append(merged.Metadata.RowCounts, rows)
+			}
+		}
+		for _, family := range m.Families {
+			if !slices.Contains(merged.Metadata.Families, family) {
+				merged.Metadata.Families = append(merged.Metadata.Families, family)
+			}
+		}
+		for _, sel := range m.Selectivities {
+			if !slices.Contains(merged.Metadata.Selectivities, sel) {
+				merged.Metadata.Selectivities = append(merged.Metadata.Selectivities, sel)
+			}
+		}
+		if m.StartTime.Before(merged.Metadata.StartTime) {
+			merged.Metadata.StartTime = m.StartTime
+		}
+		if m.EndTime.After(merged.Metadata.EndTime) {
+			merged.Metadata.EndTime = m.EndTime
+		}
+		merged.Metadata.Timeline = append(merged.Metadata.Timeline, m.Timeline...)
+		merged.Metadata.LatencyFloor = append(merged.Metadata.LatencyFloor, m.LatencyFloor...)
+		merged.Metadata.StatsChanges = append(merged.Metadata.StatsChanges, m.StatsChanges...)
+		merged.Metadata.TableSnapshots = mergeTableSnapshots(merged.Metadata.TableSnapshots, m.TableSnapshots)
+		merged.Results = append(merged.Results, rs.Results...)
+	}
+	slices.Sort(merged.Metadata.RowCounts)
+	slices.Sort(merged.Metadata.Selectivities)
+	merged.Metadata.RunID = strings.Join(runIDs, "+")
+	sort.SliceStable(merged.Results, func(i, j int) bool {
+		return merged.Results[i].ScenarioID < merged.Results[j].ScenarioID
+	})
+	return merged
+}
+
+// runReport implements the report command
+func runReport(args []string) error {
+	if len(args) > 0 && args[0] == "compare" {
+		return runReportCompare(args[1:])
+	}
+	if len(args) > 0 && args[0] == "expectations" {
+		return runReportExpectations(args[1:])
+	}
+	if len(args) == 0 || args[0] != "merge" {
+		return fmt.Errorf("usage: report merge [flags] <dir> | report compare <baseline.json> <other.json> | report expectations <results.json> <suite_out.json>...")
+	}
+	fs := flag.NewFlagSet("report merge", flag.ExitOnError)
+	var detailedOutput = fs.Bool("d", false, "Detailed output, one line per test run")
+	var aggregatedOutput = fs.Bool("a", true, "Aggregated output, per test")
+	var outputJSON = fs.String("output-json", "", "Write the merged results to this JSON result file")
+	var outputCSV = fs.String("output-csv", "", "Write the merged detailed and aggregated results as CSV files, <name>-detailed.csv and <name>-aggregated.csv")
+	var anonymize = fs.Bool("anonymize", false, "Hash the table and column names, and strip the store addresses, in the merged result files")
+	var excludePlanTypes = fs.String("exclude-plan-types", "", "Comma-separated plan types, or patterns like tiflash_*, never the best plan of a cell in the misprediction analysis")
+	var queryFrequencyColumns = fs.String("query-frequency-columns", "", "Comma-separated production=test column names, like customer_id=b, to rename the columns of the -query-frequencies query texts")
+	var queryFrequencies = fs.String("query-frequencies", "", "CSV file with the production rate of query shapes (digest or digest_text, and qps or exec_count columns), to weight the calibration score and rank the plan mistakes")
+	var tableSizeRollup = fs.Bool("by-table-size", false, "Report a roll-up per table size of the merged results, collapsing the selectivities")
+	var outliers = fs.String("outliers", "", "Drop the outlying repetitions of each scenario and plan type of the merged results from the aggregates, trim:<percent> or mad:<k>")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	excludedPlanTypes, err := parsePlanTypePatterns(*excludePlanTypes)
+	if err != nil {
+		return err
+	}
+	outlierFilter, err := parseOutlierFilter(*outliers)
+	if err != nil {
+		return err
+	}
+	var frequencies *QueryFrequencies
+	frequencyColumns, err := parseColumnMapping(*queryFrequencyColumns)
+	if err != nil {
+		return err
+	}
+	if *queryFrequencies != "" {
+		if frequencies, err = loadQueryFrequencies(*queryFrequencies, frequencyColumns); err != nil {
+			return err
+		}
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: report merge [flags] <dir>")
+	}
+	paths, err := filepath.Glob(filepath.Join(fs.Arg(0), "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	// Group the result sets by compatibility with the first set of each group
+	var groups [][]*ResultSet
+	for _, path := range paths {
+		rs, err := readResultSet(path)
+		if err != nil {
+			return err
+		}
+		placed := false
+		for i, group := range groups {
+			if group[0].Metadata.compatibleWith(rs.Metadata) == nil {
+				_, stats := dataDifferences(group[0].Metadata, rs.Metadata)
+				if len(stats) > 0 {
+					fmt.Printf("⚠️  %s: merging it with a run on different %s\n", path, strings.Join(stats, ", "))
+				}
+				groups[i] = append(group, rs)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			if len(groups) > 0 {
+				fmt.Printf("⚠️  %s: %v, merging it separately\n", path, groups[0][0].Metadata.compatibleWith(rs.Metadata))
+			}
+			groups = append(groups, []*ResultSet{rs})
+		}
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("no result files found in %s", fs.Arg(0))
+	}
+
+	for i, group := range groups {
+		merged := mergeResultSets(group)
+		merged.Metadata.ExcludedPlanTypes = excludedPlanTypes
+		// The outliers of the merged repetitions, not of the single runs
+		merged.Metadata.Outliers = outlierFilter.String()
+		merged.Metadata.QueryFrequencies = *queryFrequencies
+		merged.Metadata.QueryFrequencyColumns = *queryFrequencyColumns
+		merged.Metadata.OutliersDropped = markOutliers(merged.Results, outlierFilter)
+		measured := measuredResults(merged.Results)
+		merged.Metadata.NoRU = !ruReported(measured)
+		fmt.Printf("\n📦 Merged %d result files\n", len(group))
+		outputRunMetadata(merged.Metadata)
+		if *detailedOutput {
+			outputDetailedResultsTable(measured)
+		}
+		if *aggregatedOutput {
+			outputAggregatedResultsTable(measured)
+		}
+		cells := analyzeCells(measured, excludedPlanTypes)
+		outputMispredictionSummary(cells)
+		if *tableSizeRollup {
+			outputTableSizeRollup(cells)
+		}
+		if frequencies != nil {
+			outputFrequencyMistakes(cells, applyQueryFrequencies(cells, measured, frequencies))
+		}
+		outputCalibrationScore(computeCalibrationScore(cells))
+		if *anonymize {
+			a := newResultsAnonymizer(merged.Results, merged.Metadata)
+			merged.Metadata, merged.Results = a.metadata(merged.Metadata), a.results(merged.Results)
+		}
+		if *outputJSON != "" {
+			path := *outputJSON
+			if len(groups) > 1 {
+				path = strings.TrimSuffix(path, ".json") + fmt.Sprintf("_%d.json", i+1)
+			}
+			if err = writeResultSet(path, merged.Metadata, merged.Results); err != nil {
+				return err
+			}
+		}
+		if *outputCSV != "" {
+			path := *outputCSV
+			if len(groups) > 1 {
+				path = strings.TrimSuffix(path, ".csv") + fmt.Sprintf("_%d", i+1)
+			}
+			if _, _, err = writeResultsCSV(path, merged.Results); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
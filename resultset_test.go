@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestResultSetRoundTripAndMerge(t *testing.T) {
+	dir := t.TempDir()
+	meta1 := &RunMetadata{RunID: "ra", FillerSize: 100, RowCounts: []int{1000}, Selectivities: []float64{0.1}}
+	meta2 := &RunMetadata{RunID: "rb", FillerSize: 100, RowCounts: []int{1000000}, Selectivities: []float64{0.1, 0.2}}
+	results1 := []*TestExecutionResult{newChoiceResult("index_1K_100", "index_lookup"), newTestResult("index_1K_100", "index_lookup", 1)}
+	results2 := []*TestExecutionResult{newTestResult("index_1M_100000", "table_scan", 100)}
+	if err := writeResultSet(filepath.Join(dir, "a.json"), meta1, results1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeResultSet(filepath.Join(dir, "b.json"), meta2, results2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rs1, err := readResultSet(filepath.Join(dir, "a.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rs2, err := readResultSet(filepath.Join(dir, "b.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rs1.Results[1].Plan.ExecutionTime != results1[1].Plan.ExecutionTime {
+		t.Fatalf("execution time not preserved")
+	}
+	if err = rs1.Metadata.compatibleWith(rs2.Metadata); err != nil {
+		t.Fatalf("expected compatible runs: %v", err)
+	}
+	merged := mergeResultSets([]*ResultSet{rs1, rs2})
+	if len(merged.Results) != 3 || merged.Metadata.RunID != "ra+rb" {
+		t.Fatalf("unexpected merge: %d results, run %s", len(merged.Results), merged.Metadata.RunID)
+	}
+	if !slices.Equal(merged.Metadata.RowCounts, []int{1000, 1000000}) || !slices.Equal(merged.Metadata.Selectivities, []float64{0.1, 0.2}) {
+		t.Fatalf("unexpected merged matrix: %v %v", merged.Metadata.RowCounts, merged.Metadata.Selectivities)
+	}
+
+	rs2.Metadata.FillerSize = 500
+	if err = rs1.Metadata.compatibleWith(rs2.Metadata); err == nil {
+		t.Fatalf("expected incompatible filler sizes")
+	}
+}
+
+func TestResultSetVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+	}{
+		{"unversioned.json", `{"results": []}`},
+		{"newer.json", `{"format_version": 99, "metadata": {}}`},
+	} {
+		path := filepath.Join(t.TempDir(), tc.name)
+		if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readResultSet(path); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
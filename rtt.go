@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// defaultCopConcurrency is the default tidb_distsql_scan_concurrency, the
+// number of cop tasks a reader sends in parallel
+const defaultCopConcurrency = 15
+
+// copRoundTrips estimates the sequential TiDB to TiKV round trips of an
+// executed plan, from the cop task counts of its reader operators.
+// Each reader needs at least one round trip, and one more per batch of
+// parallel cop tasks, so a double-read IndexLookUp pays at least two.
+func copRoundTrips(plan *ExecutionPlan) int {
+	trips := 0
+	for p := plan; p != nil; p = p.Next {
+		if exec := p.execInfo(); exec != nil && exec.CopTasks > 0 {
+			trips += int(math.Ceil(float64(exec.CopTasks) / defaultCopConcurrency))
+		}
+	}
+	return trips
+}
+
+// outputRTTReport re-evaluates the plan winners with a simulated cross-region
+// round trip time added once for the client to TiDB round trip, and once for
+// each sequential cop round trip between TiDB and TiKV. The measured latency
+// does not include the simulated round trip, the client waits for it before
+// starting the timer.
+func outputRTTReport(results []*TestExecutionResult, rtt time.Duration) {
+	fmt.Printf("\n🌍 Simulated Cross-Region Latency (RTT %s)\n", rtt)
+	fmt.Println("====================")
+	fmt.Println("The client to TiDB round trip is added once per query, it is not part of the measured latency.")
+	fmt.Println("TiDB to TiKV round trips are modelled from the cop task counts; to measure them for real,")
+	fmt.Printf("inject the delay on the TiKV hosts: tc qdisc add dev <iface> root netem delay %s\n", rtt/2)
+
+	scenarioIDs, groups := groupByScenario(results)
+	fmt.Printf("Scenario\tTable_size\tCardinality\tPlan\tms-avg\tclient_rtt_ms\tcop_round_trips-avg\tms-with-rtt-avg\n")
+	rttMs := float64(rtt) / float64(time.Millisecond)
+	winnerChanges := 0
+	for _, scenarioID := range scenarioIDs {
+		group := groups[scenarioID]
+		fastest, fastestRTT := "", ""
+		var fastestTime, fastestRTTTime float64
+		for _, pt := range sortedPlanTypes(group) {
+			var ms, trips float64
+			for _, r := range group[pt] {
+				ms += float64(r.Plan.ExecutionTime) / float64(time.Millisecond)
+				trips += float64(copRoundTrips(r.Plan))
+			}
+			n := float64(len(group[pt]))
+			ms /= n
+			trips /= n
+			withRTT := ms + (1+trips)*rttMs
+			if fastest == "" || ms < fastestTime {
+				fastest, fastestTime = pt, ms
+			}
+			if fastestRTT == "" || withRTT < fastestRTTTime {
+				fastestRTT, fastestRTTTime = pt, withRTT
+			}
+			fmt.Printf("%s\t%s\t%.03f\t%.03f\t%.01f\t%.03f\n", strings.Join(strings.Split(scenarioID, "_"), "\t"), pt, ms, rttMs, trips, withRTT)
+		}
+		if fastest != fastestRTT {
+			winnerChanges++
+			fmt.Printf("  ⚠️  %s: fastest plan is %s, with RTT %s\n", scenarioID, fastest, fastestRTT)
+		}
+	}
+	fmt.Printf("\nScenarios where the simulated RTT changes the fastest plan: %d of %d\n", winnerChanges, len(scenarioIDs))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ruPhase is the storage reads of one phase of an IndexLookUp
+type ruPhase struct {
+	Requests int
+	Bytes    int64
+}
+
+// RU returns the storage read RU of the phase, according to the default RU model
+func (p ruPhase) RU() float64 {
+	return float64(p.Requests)*defaultRUCoefficients.ReadBaseCost + float64(p.Bytes)*defaultRUCoefficients.ReadCostPerByte
+}
+
+// RUSplit attributes the RU of an execution to the index scan and table
+// lookup phases of its IndexLookUp operators. TiDB does not expose the RU per
+// cop task, so the RU of each phase is modelled from its cop requests and read
+// bytes, and the rest of the measured RU (TiDB CPU and other operators) is Other.
+type RUSplit struct {
+	Index ruPhase
+	Table ruPhase
+	Other float64
+}
+
+// planDepth returns the depth of an operator in the plan tree, from the tree
+// prefix of its ID, two runes per level
+func planDepth(id string) int {
+	return (len([]rune(id)) - len([]rune(strings.TrimLeft(id, " │├└─")))) / 2
+}
+
+// copReads returns the cop requests and read bytes reported by an operator
+func copReads(p *ExecutionPlan) ruPhase {
+	exec := p.execInfo()
+	if exec == nil {
+		return ruPhase{}
+	}
+	reads := ruPhase{Requests: exec.RPCs, Bytes: exec.ProcessedKeysSize}
+	if reads.Requests == 0 {
+		reads.Requests = exec.CopTasks
+	}
+	return reads
+}
+
+// ruSplit returns the RU split of an executed plan, or nil if it has no
+// IndexLookUp with cop execution info
+func ruSplit(plan *ExecutionPlan, totalRU float64) *RUSplit {
+	split := &RUSplit{}
+	found := false
+	lookupDepth := -1
+	var phase *ruPhase
+	for p := plan; p != nil; p = p.Next {
+		depth := planDepth(p.ID)
+		name := strings.TrimLeft(p.ID, " │├└─")
+		if lookupDepth >= 0 && depth <= lookupDepth {
+			lookupDepth, phase = -1, nil
+		}
+		if strings.HasPrefix(name, "IndexLookUp") {
+			lookupDepth = depth
+			continue
+		}
+		if lookupDepth < 0 {
+			continue
+		}
+		if depth == lookupDepth+1 {
+			switch {
+			case strings.Contains(name, "(Build)"):
+				phase = &split.Index
+			case strings.Contains(name, "(Probe)"):
+				phase = &split.Table
+			default:
+				phase = nil
+			}
+		}
+		// The cop execution info is on the top cop operator of each side,
+		// the operators below it only report their tikv_task time
+		if reads := copReads(p); phase != nil && (reads.Requests > 0 || reads.Bytes > 0) {
+			phase.Requests += reads.Requests
+			phase.Bytes += reads.Bytes
+			found = true
+			phase = nil
+		}
+	}
+	if !found {
+		return nil
+	}
+	split.Other = max(totalRU-split.Index.RU()-split.Table.RU(), 0)
+	return split
+}
+
+// outputRUSplit reports the RU of the IndexLookUp plans split into the index
+// scan and table lookup phases, averaged per scenario
+func outputRUSplit(results []*TestExecutionResult) {
+	if !ruReported(results) {
+		fmt.Println("\n🧮 RU Attribution: the cluster reported no RU, resource control is disabled")
+		return
+	}
+	scenarioIDs, groups := groupByScenario(results)
+	header := false
+	for _, scenarioID := range scenarioIDs {
+		for _, pt := range sortedPlanTypes(groups[scenarioID]) {
+			if !strings.Contains(pt, "index_lookup") {
+				continue
+			}
+			var ru, indexRU, tableRU, otherRU float64
+			var indexRequests, tableRequests, n int
+			for _, r := range groups[scenarioID][pt] {
+				split := ruSplit(r.Plan, r.RU)
+				if split == nil {
+					continue
+				}
+				n++
+				ru += r.RU
+				indexRU += split.Index.RU()
+				tableRU += split.Table.RU()
+				otherRU += split.Other
+				indexRequests += split.Index.Requests
+				tableRequests += split.Table.Requests
+			}
+			if n == 0 {
+				continue
+			}
+			if !header {
+				fmt.Println("\n🧮 RU Attribution (IndexLookUp index scan vs table lookup)")
+				fmt.Println("====================")
+				fmt.Println("Index and table RU are modelled from the cop requests and read bytes of each phase, other is the rest of the measured RU.")
+				fmt.Printf("Scenario\tPlan\tRU-avg\tindex_RU-avg\ttable_RU-avg\tother_RU-avg\tindex_requests-avg\ttable_requests-avg\n")
+				header = true
+			}
+			avg := func(v float64) float64 { return v / float64(n) }
+			fmt.Printf("%s\t%s\t%.02f\t%.02f\t%.02f\t%.02f\t%.01f\t%.01f\n", scenarioID, pt, avg(ru), avg(indexRU), avg(tableRU),
+				avg(otherRU), avg(float64(indexRequests)), avg(float64(tableRequests)))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRUSplit(t *testing.T) {
+	plan := &ExecutionPlan{ID: "IndexLookUp_10", Task: "root", ExecutionInfo: "time:3.1ms, loops:2, index_task: {total_time: 1.3ms}, table_task: {total_time: 1.6ms, num: 1, concurrency: 5}",
+		Next: &ExecutionPlan{ID: "├─IndexRangeScan_8(Build)", Task: "cop[tikv]",
+			ExecutionInfo: "time:1.2ms, loops:3, cop_task: {num: 1, max: 1.2ms, proc_keys: 100, rpc_num: 1, rpc_time: 1.2ms}, tikv_task:{time:0s, loops:3}, scan_detail: {total_process_keys: 100, total_process_keys_size: 4600, total_keys: 101}",
+			Next: &ExecutionPlan{ID: "└─Selection_9(Probe)", Task: "cop[tikv]",
+				ExecutionInfo: "time:1.5ms, loops:2, cop_task: {num: 3, max: 1.1ms, proc_keys: 100}, rpc_info:{Cop:{num_rpc:3, total_time:3ms}}, tikv_task:{time:0s, loops:3}, scan_detail: {total_process_keys: 100, total_process_keys_size: 65536, total_keys: 100}",
+				Next:          &ExecutionPlan{ID: "  └─TableRowIDScan_7", Task: "cop[tikv]", ExecutionInfo: "tikv_task:{time:0s, loops:3}"}}}}
+	split := ruSplit(plan, 2)
+	if split == nil {
+		t.Fatalf("expected an RU split")
+	}
+	if split.Index != (ruPhase{Requests: 1, Bytes: 4600}) || split.Table != (ruPhase{Requests: 3, Bytes: 65536}) {
+		t.Fatalf("unexpected phases: %+v", split)
+	}
+	if expected := 2 - (0.125 + 4600.0/65536) - (0.375 + 1); math.Abs(split.Other-expected) > 1e-9 {
+		t.Fatalf("expected other RU %f, got %f", expected, split.Other)
+	}
+	scan := &ExecutionPlan{ID: "TableReader_7", ExecutionInfo: "cop_task: {num: 1, rpc_num: 1}"}
+	if ruSplit(scan, 1) != nil {
+		t.Fatalf("expected no RU split for a table scan")
+	}
+}
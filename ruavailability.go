@@ -0,0 +1,53 @@
+package main
+
+import "strings"
+
+// ruReported returns true if any execution of the results reported an RU.
+// Without RU, like on clusters without resource control, the outputs omit
+// their RU columns instead of printing zeros that look like measurements.
+// Result files from before the RUReported flag only have a non-zero RU.
+func ruReported(results []*TestExecutionResult) bool {
+	for _, r := range results {
+		if !r.ExplainOnly && (r.RUReported || r.RU > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// cellsReportRU returns true if the executions of any of the cells reported an RU
+func cellsReportRU(cells []*CellAnalysis) bool {
+	for _, cell := range cells {
+		if cell.RUReported {
+			return true
+		}
+	}
+	return false
+}
+
+// isRUColumn returns true for the RU columns of the CSV files, like ru and ru_avg
+func isRUColumn(name string) bool {
+	return name == "ru" || strings.HasPrefix(name, "ru_")
+}
+
+// withoutRUColumns returns the header and records without the RU columns
+func withoutRUColumns(header []string, records [][]string) ([]string, [][]string) {
+	var keep []int
+	for i, name := range header {
+		if !isRUColumn(name) {
+			keep = append(keep, i)
+		}
+	}
+	project := func(record []string) []string {
+		projected := make([]string, 0, len(keep))
+		for _, i := range keep {
+			projected = append(projected, record[i])
+		}
+		return projected
+	}
+	stripped := make([][]string, 0, len(records))
+	for _, record := range records {
+		stripped = append(stripped, project(record))
+	}
+	return project(header), stripped
+}
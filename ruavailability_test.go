@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWithoutRUColumns(t *testing.T) {
+	header, records := withoutRUColumns([]string{"scenario", "ru", "ms", "ru_min", "run_offset_ms"},
+		[][]string{{"index_1K_10", "0", "1.5", "0", "12"}})
+	if !slices.Equal(header, []string{"scenario", "ms", "run_offset_ms"}) {
+		t.Errorf("unexpected header %v", header)
+	}
+	if len(records) != 1 || !slices.Equal(records[0], []string{"index_1K_10", "1.5", "12"}) {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestRUReported(t *testing.T) {
+	results := []*TestExecutionResult{{ExplainOnly: true, RU: 3}, {RU: 0}}
+	if ruReported(results) {
+		t.Errorf("expected no RU reported by the executions")
+	}
+	results = append(results, &TestExecutionResult{RU: 0.5})
+	if !ruReported(results) {
+		t.Errorf("expected RU reported")
+	}
+	// A zero RU that was reported is a measurement
+	if !ruReported([]*TestExecutionResult{{RUReported: true}}) {
+		t.Errorf("expected the reported zero RU")
+	}
+}
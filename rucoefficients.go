@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RUCoefficients are the request unit model coefficients of the cluster,
+// the controller.request-unit settings of PD
+type RUCoefficients struct {
+	ReadBaseCost     float64 `json:"read_base_cost"`
+	ReadCostPerByte  float64 `json:"read_cost_per_byte"`
+	ReadCPUMsCost    float64 `json:"read_cpu_ms_cost"`
+	WriteBaseCost    float64 `json:"write_base_cost"`
+	WriteCostPerByte float64 `json:"write_cost_per_byte"`
+}
+
+// defaultRUCoefficients are the PD defaults: 8 read requests, 64 KiB read,
+// 3 ms of CPU, 1 write request or 1 KiB written consume 1 RU
+var defaultRUCoefficients = RUCoefficients{
+	ReadBaseCost:     1.0 / 8,
+	ReadCostPerByte:  1.0 / (64 * 1024),
+	ReadCPUMsCost:    1.0 / 3,
+	WriteBaseCost:    1,
+	WriteCostPerByte: 1.0 / 1024,
+}
+
+// GetRUCoefficients reads the RU coefficients from the PD config, the
+// coefficients not exposed by the server keep their default
+func (c *TiDBClient) GetRUCoefficients() (*RUCoefficients, error) {
+	settings, err := queryNamedRows(c.dbPlan, "SHOW CONFIG WHERE type = 'pd' AND name LIKE 'controller.request-unit.%'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the RU coefficients: %w", err)
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("the RU coefficients are not exposed by the server")
+	}
+	coefficients := defaultRUCoefficients
+	fields := map[string]*float64{
+		"controller.request-unit.read-base-cost":      &coefficients.ReadBaseCost,
+		"controller.request-unit.read-cost-per-byte":  &coefficients.ReadCostPerByte,
+		"controller.request-unit.read-cpu-ms-cost":    &coefficients.ReadCPUMsCost,
+		"controller.request-unit.write-base-cost":     &coefficients.WriteBaseCost,
+		"controller.request-unit.write-cost-per-byte": &coefficients.WriteCostPerByte,
+	}
+	for _, setting := range settings {
+		field, ok := fields[setting["Name"]]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(setting["Value"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RU coefficient %s '%s': %w", setting["Name"], setting["Value"], err)
+		}
+		*field = value
+	}
+	return &coefficients, nil
+}
+
+// planReads returns the cop requests and read bytes of all readers of an executed plan
+func planReads(plan *ExecutionPlan) ruPhase {
+	var reads ruPhase
+	for p := plan; p != nil; p = p.Next {
+		r := copReads(p)
+		reads.Requests += r.Requests
+		reads.Bytes += r.Bytes
+	}
+	return reads
+}
+
+// normalizeRU converts the RU of an execution measured with the from
+// coefficients to the RU it would have consumed with the to coefficients.
+// The read requests and bytes come from the cop execution info, the rest of
+// the RU is attributed to CPU.
+func normalizeRU(ru float64, plan *ExecutionPlan, from, to RUCoefficients) float64 {
+	if from == to || ru == 0 {
+		return ru
+	}
+	reads := planReads(plan)
+	requests, bytes := float64(reads.Requests), float64(reads.Bytes)
+	cpu := max(ru-requests*from.ReadBaseCost-bytes*from.ReadCostPerByte, 0)
+	if from.ReadCPUMsCost > 0 {
+		cpu = cpu / from.ReadCPUMsCost * to.ReadCPUMsCost
+	}
+	return requests*to.ReadBaseCost + bytes*to.ReadCostPerByte + cpu
+}
+
+// runCoefficients returns the RU coefficients of a run, the defaults if they were not recorded
+func runCoefficients(m *RunMetadata) RUCoefficients {
+	if m == nil || m.RUCoefficients == nil {
+		return defaultRUCoefficients
+	}
+	return *m.RUCoefficients
+}
+
+// runReportCompare implements the report compare command, comparing the RU
+// and latency per scenario and plan type of two runs, with the RU of the
+// other run normalized to the RU coefficients of the baseline run
+func runReportCompare(args []string) error {
+	fs := flag.NewFlagSet("report compare", flag.ExitOnError)
+	var allowDataChange = fs.Bool("allow-data-change", false, "Compare the runs even if the data of their tables differs, according to the table snapshots")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: report compare <baseline.json> <other.json>")
+	}
+	baseline, err := readResultSet(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	other, err := readResultSet(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	data, stats := dataDifferences(baseline.Metadata, other.Metadata)
+	if len(data) > 0 && !*allowDataChange {
+		return fmt.Errorf("the runs were made on different data, their results are not comparable (use -allow-data-change to compare anyway): %s",
+			strings.Join(data, ", "))
+	}
+	for _, diff := range append(data, stats...) {
+		slog.Warn("The runs were made on a different table state", "difference", diff)
+	}
+	for _, rs := range []*ResultSet{baseline, other} {
+		if rs.Metadata.RUCoefficients == nil {
+			slog.Warn("RU coefficients not recorded, assuming the defaults", "run", rs.Metadata.RunID)
+		}
+	}
+	outputRunComparison(baseline, other)
+	return nil
+}
+
+// outputRunComparison prints the average RU and latency per scenario and plan
+// type present in both runs
+func outputRunComparison(baseline, other *ResultSet) {
+	from, to := runCoefficients(other.Metadata), runCoefficients(baseline.Metadata)
+	fmt.Printf("\n⚖️  Run Comparison: %s (%s) vs %s (%s)\n", baseline.Metadata.RunID, baseline.Metadata.ServerVersion,
+		other.Metadata.RunID, other.Metadata.ServerVersion)
+	fmt.Println("====================")
+	if from != to && ruReported(other.Results) {
+		fmt.Printf("The RU coefficients differ, the RU of %s is normalized to the coefficients of %s\n",
+			other.Metadata.RunID, baseline.Metadata.RunID)
+	}
+	for _, diff := range clientDifferences(baseline.Metadata.Client, other.Metadata.Client) {
+		fmt.Printf("⚠️  The runs were made from different clients, the latencies include client side costs: %s\n", diff)
+	}
+	_, otherGroups := groupByScenario(measuredResults(other.Results))
+	scenarioIDs, baselineGroups := groupByScenario(measuredResults(baseline.Results))
+	withRU := ruReported(baseline.Results) && ruReported(other.Results)
+	if withRU {
+		fmt.Printf("Scenario\tPlan\tRU-baseline\tRU-other\tRU-other-normalized\tRU-ratio\tms-baseline\tms-other\n")
+	} else {
+		fmt.Println("Not both runs reported RU, only the latencies are compared")
+		fmt.Printf("Scenario\tPlan\tms-baseline\tms-other\n")
+	}
+	for _, scenarioID := range scenarioIDs {
+		for _, pt := range sortedPlanTypes(baselineGroups[scenarioID]) {
+			otherRuns := otherGroups[scenarioID][pt]
+			if len(otherRuns) == 0 {
+				continue
+			}
+			var ru, otherRU, normalized float64
+			var ms, otherMs time.Duration
+			for _, r := range baselineGroups[scenarioID][pt] {
+				ru += r.RU
+				ms += r.Plan.ExecutionTime
+			}
+			for _, r := range otherRuns {
+				otherRU += r.RU
+				normalized += normalizeRU(r.RU, r.Plan, from, to)
+				otherMs += r.Plan.ExecutionTime
+			}
+			n, otherN := float64(len(baselineGroups[scenarioID][pt])), float64(len(otherRuns))
+			ratio := 0.0
+			if ru > 0 {
+				ratio = (normalized / otherN) / (ru / n)
+			}
+			if !withRU {
+				fmt.Printf("%s\t%s\t%.03f\t%.03f\n", scenarioID, pt, ms.Seconds()*1000.0/n, otherMs.Seconds()*1000.0/otherN)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%.02f\t%.02f\t%.02f\t%.02f\t%.03f\t%.03f\n", scenarioID, pt, ru/n, otherRU/otherN,
+				normalized/otherN, ratio, ms.Seconds()*1000.0/n, otherMs.Seconds()*1000.0/otherN)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeRU(t *testing.T) {
+	plan := &ExecutionPlan{ID: "TableReader_7", ExecutionInfo: "time:10ms, cop_task: {num: 2, max: 5ms, proc_keys: 1000, rpc_num: 2, rpc_time: 9ms}",
+		Next: &ExecutionPlan{ID: "└─TableFullScan_6", ExecutionInfo: "tikv_task:{time:4ms, loops:5}, scan_detail: {total_process_keys: 1000, total_process_keys_size: 131072}"}}
+	// 2 requests and 128 KiB read, 0.25 + 2 RU, and 1 RU (3 ms) of CPU
+	from := defaultRUCoefficients
+	if got := normalizeRU(3.25, plan, from, from); got != 3.25 {
+		t.Fatalf("expected the RU unchanged for equal coefficients, got %f", got)
+	}
+	to := defaultRUCoefficients
+	to.ReadBaseCost = 0.5
+	to.ReadCPUMsCost = 1.0 / 6
+	if got, expected := normalizeRU(3.25, plan, from, to), 1+2+0.5; math.Abs(got-expected) > 1e-9 {
+		t.Fatalf("expected %f, got %f", expected, got)
+	}
+	m := &RunMetadata{RUCoefficients: &to}
+	if err := m.compatibleWith(&RunMetadata{}); err == nil {
+		t.Fatalf("expected runs with different RU coefficients to be incompatible")
+	}
+}
@@ -2,28 +2,115 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"iter"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // TestScenario represents a test scenario for optimizer validation
 type TestScenario struct {
-	ID          string `json:"id"`
-	Variant     string `json:"variant"`
-	Name        string `json:"name"`
-	Query       string `json:"original_query"`
-	TableName   string `json:"table_name"`
-	RowCount    int    `json:"row_count"`
-	ExplainOnly bool   `json:"explain_only"`
+	ID        string `json:"id"`
+	Variant   string `json:"variant"`
+	Name      string `json:"name"`
+	Query     string `json:"original_query"`
+	TableName string `json:"table_name"`
+	RowCount  int    `json:"row_count"`
+	// MatchingRows is the number of rows the predicate matches
+	MatchingRows int  `json:"matching_rows"`
+	ExplainOnly  bool `json:"explain_only"`
+	// CountQuery, if set, counts the rows the predicate matches, for the
+	// predicates over random values where MatchingRows is only the expected
+	// number of rows until counted, see countMatchingRows
+	CountQuery string `json:"-"`
+	// Groups is the number of groups of a GROUP BY, counted with the matching rows
+	Groups int `json:"groups,omitempty"`
+	// Repetitions overrides the -n repetitions of the scenario, 0 keeps them,
+	// like the repetitions of a custom scenario
+	Repetitions int `json:"repetitions,omitempty"`
+	// PredicateValue is the b value of the query, if another one of the same
+	// number of rows than MatchingRows, see -predicate-values
+	PredicateValue int `json:"predicate_value,omitempty"`
+
+	// family is the family of the scenario, or the id of a custom scenario,
+	// matched by the repetition overrides
+	family string
 }
 
 // TestExecutionResult represents the result of executing a test query
 type TestExecutionResult struct {
-	ScenarioID  string
-	Variant     string
-	Query       string
-	PlanType    string
-	Plan        *ExecutionPlan
-	ExplainOnly bool
+	ScenarioID   string `json:"scenario_id"`
+	ScenarioName string `json:"scenario_name,omitempty"`
+	Variant      string `json:"variant"`
+	Repetition   int    `json:"repetition"`
+	// StartTime is when the execution started, RunOffset the time from the
+	// start of the run on the monotonic clock, for modelling warm-up trends
+	StartTime    time.Time     `json:"start_time,omitzero"`
+	RunOffset    time.Duration `json:"run_offset,omitempty"`
+	TableName    string        `json:"table_name,omitempty"`
+	RowCount     int           `json:"row_count"`
+	MatchingRows int           `json:"matching_rows"`
+	// Groups is the number of groups of a GROUP BY, if counted
+	Groups int    `json:"groups,omitempty"`
+	Query  string `json:"query"`
+	// PredicateValue is the b value of the query, if not MatchingRows
+	PredicateValue int `json:"predicate_value,omitempty"`
+	// Digest is the statement digest of the executed query, as shown in the
+	// statement analysis of TiDB Dashboard
+	Digest   string `json:"digest,omitempty"`
+	PlanType string `json:"plan_type"`
+	// Plan is the executed plan, or the EXPLAIN plan of ExplainOnly results
+	Plan        *ExecutionPlan `json:"plan,omitempty"`
+	ExplainOnly bool           `json:"explain_only"`
+	// RU is the request units consumed by the execution, RUReported is set if
+	// the execution reported one, telling a zero RU from an uncollected one
+	RU         float64 `json:"ru,omitempty"`
+	RUReported bool    `json:"ru_reported,omitempty"`
+	// PlanFromCache and PlanFromBinding are set if the executed plan came from
+	// the plan cache or a SQL binding, instead of being optimized for the query
+	PlanFromCache   bool `json:"plan_from_cache,omitempty"`
+	PlanFromBinding bool `json:"plan_from_binding,omitempty"`
+	// EstCost is the optimizer's estimated cost of the query's plan
+	EstCost EstimatedCost `json:"est_cost"`
+	// CopTasks are the cop tasks sent by the readers of the plan, and
+	// CopConcurrency the most of them a reader sent concurrently
+	CopTasks       int `json:"cop_tasks,omitempty"`
+	CopConcurrency int `json:"cop_concurrency,omitempty"`
+	// TotalKeys are the keys TiKV scanned for the plan, including deleted
+	// versions, and ProcessedKeys the live keys of them
+	TotalKeys     int64 `json:"total_keys,omitempty"`
+	ProcessedKeys int64 `json:"processed_keys,omitempty"`
+	// Warmup is set for the warm-up executions of -warmup, which are left out of all aggregates
+	Warmup bool `json:"warmup,omitempty"`
+	// Outlier is set for the executions dropped by the -outliers filter, also left out of all aggregates
+	Outlier bool `json:"outlier,omitempty"`
+	// Retried is why the execution was retried, hiding the latency of the
+	// failed attempt: a reconnect, a coprocessor cache bust or cop requests
+	// TiDB sent again, see -strict
+	Retried string `json:"retried,omitempty"`
+	// Metrics are the metrics of the execution hooks, by <hook>.<metric>
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// CoprCacheHitRatio is the highest coprocessor cache hit ratio of the
+	// readers, only kept with -copr-cache keep
+	CoprCacheHitRatio float64 `json:"copr_cache_hit_ratio,omitempty"`
+	// RCWait is the time the execution was queued by resource control (RU burst throttling)
+	RCWait         time.Duration `json:"rc_wait,omitempty"`
+	RCWaitCaptured bool          `json:"rc_wait_captured,omitempty"`
+	// AnalyzeTime is the latency of the same query under EXPLAIN ANALYZE, if
+	// sampled, and AnalyzePlainTime of it executed plainly just before, both
+	// warm, after the measured execution
+	AnalyzeTime      time.Duration `json:"analyze_time,omitempty"`
+	AnalyzePlainTime time.Duration `json:"analyze_plain_time,omitempty"`
+	// DuringBackgroundWork is set if heavy TiKV GC or compaction was detected when the sample was taken
+	DuringBackgroundWork bool `json:"during_background_work,omitempty"`
+	// LatencyFloor is the SELECT 1 latency floor last measured before the sample,
+	// FloorDrifted is set if it had drifted from the baseline, so the sample can be down-weighted
+	LatencyFloor time.Duration `json:"latency_floor,omitempty"`
+	FloorDrifted bool          `json:"floor_drifted,omitempty"`
 }
 
 // GetNumRows return number of matching rows from table rows vs selectivity
@@ -36,69 +123,1100 @@ func GetNumRows(rows int, sel float64) int {
 	return int(sel)
 }
 
+// ScheduledRun is a single execution of a scenario
+type ScheduledRun struct {
+	Scenario   *TestScenario
+	Repetition int
+	// Warmup is set for the warm-up executions before the measurement, Repetition counts them separately
+	Warmup bool
+	// index is the index of the scenario in the schedule
+	index int
+}
+
+type scheduledRun struct {
+	scenario   int32
+	repetition int32
+	warmup     bool
+}
+
+// Schedule is the execution plan of a test matrix: which scenario runs when,
+// and as which repetition. Scenarios are stored once, repetitions are only
+// entries in the run order, so the scheduler is free to interleave them and
+// to add more repetitions for a scenario while the run is in progress.
+type Schedule struct {
+	scenarios   []TestScenario
+	repetitions []int32
+	runs        []scheduledRun
+	// started is the number of runs yielded by All, the runs after them are pending
+	started int
+	// drained is called when all scheduled runs were yielded, and may add more
+	drained func()
+}
+
+// NewSchedule creates a schedule running each scenario the given number of
+// times (ExplainOnly scenarios only once), or its own repetitions if it has
+// them, in random order
+func NewSchedule(scenarios []TestScenario, repetitions int) *Schedule {
+	s := &Schedule{
+		scenarios:   scenarios,
+		repetitions: make([]int32, len(scenarios)),
+	}
+	for i := range scenarios {
+		times := repetitions
+		switch {
+		case scenarios[i].Repetitions > 0:
+			times = scenarios[i].Repetitions
+		case scenarios[i].ExplainOnly:
+			times = 1
+		}
+		s.Add(i, times)
+	}
+	// Make sure they are run in random order.
+	orderRng.Shuffle(len(s.runs), func(i, j int) {
+		s.runs[i], s.runs[j] = s.runs[j], s.runs[i]
+	})
+	return s
+}
+
+// Add schedules extra repetitions of a scenario, each at a random position
+// among the pending runs, so they are interleaved with the other pending and
+// added runs instead of running as a block
+func (s *Schedule) Add(scenario int, extra int) {
+	for range extra {
+		run := scheduledRun{
+			scenario:   int32(scenario),
+			repetition: s.repetitions[scenario],
+		}
+		s.repetitions[scenario]++
+		s.runs = slices.Insert(s.runs, s.started+orderRng.Intn(len(s.runs)-s.started+1), run)
+	}
+}
+
+// AddWarmup schedules n warm-up executions of each scenario, except the
+// ExplainOnly ones, right before its first measured execution, so the region
+// cache, plan cache and coprocessor caches are warm when measuring, and not
+// evicted by the executions of the other scenarios in between
+func (s *Schedule) AddWarmup(n int) {
+	warmed := make([]bool, len(s.scenarios))
+	runs := make([]scheduledRun, 0, len(s.runs)+n*len(s.scenarios))
+	for _, run := range s.runs {
+		if !run.warmup && !warmed[run.scenario] && !s.scenarios[run.scenario].ExplainOnly {
+			warmed[run.scenario] = true
+			for repetition := range n {
+				runs = append(runs, scheduledRun{scenario: run.scenario, repetition: int32(repetition), warmup: true})
+			}
+		}
+		runs = append(runs, run)
+	}
+	s.runs = runs
+}
+
+// measuredResults returns the results without the warm-up executions and the
+// outliers, the ones all aggregates are computed from
+func measuredResults(results []*TestExecutionResult) []*TestExecutionResult {
+	measured := make([]*TestExecutionResult, 0, len(results))
+	for _, r := range results {
+		if !r.Warmup && !r.Outlier {
+			measured = append(measured, r)
+		}
+	}
+	return measured
+}
+
+// OnDrained sets a function called when all scheduled runs were yielded by
+// All, which may add more runs for the iteration to continue with
+func (s *Schedule) OnDrained(f func()) {
+	s.drained = f
+}
+
+// Len returns the total number of scheduled executions
+func (s *Schedule) Len() int {
+	return len(s.runs)
+}
+
+// Scenarios returns the distinct scenarios of the schedule
+func (s *Schedule) Scenarios() []TestScenario {
+	return s.scenarios
+}
+
+// All returns an iterator over all scheduled executions, including runs
+// added during the iteration
+func (s *Schedule) All() iter.Seq[ScheduledRun] {
+	return func(yield func(ScheduledRun) bool) {
+		for i := 0; ; i++ {
+			s.started = i
+			if i == len(s.runs) && s.drained != nil {
+				s.drained()
+			}
+			if i >= len(s.runs) {
+				return
+			}
+			run := s.runs[i]
+			s.started = i + 1
+			if !yield(ScheduledRun{Scenario: &s.scenarios[run.scenario], Repetition: int(run.repetition), Warmup: run.warmup, index: int(run.scenario)}) {
+				return
+			}
+		}
+	}
+}
+
 // GetTestScenariosWithRowCountsAndSelectivities converts comprehensive tests to TestScenario format with custom row counts and selectivities
-func GetTestScenariosWithRowCountsAndSelectivities(rowCounts []int, selectivities []float64, repetitions int) []TestScenario {
+func GetTestScenariosWithRowCountsAndSelectivities(rowCounts []int, selectivities []float64) []TestScenario {
+	return GetTestScenarios(tableSpecs(rowCounts, "", MatrixOptions{}), selectivities, MatrixOptions{})
+}
+
+// MatrixOptions are the settings of the scenario matrix given on the command
+// line, passed to the setup of the test tables and to the scenario families.
+// The zero value is the default matrix.
+type MatrixOptions struct {
+	// RangeSpan is the number of b values covered by the range family predicates,
+	// 0 derives it from the selectivity so the range cells match as many rows as the point cells
+	RangeSpan int
+	// TiFlash creates TiFlash replicas of the tables, and adds a TiFlash variant
+	// to the cells, reading the table from its TiFlash replica
+	TiFlash bool
+	// InListLengths are the IN-list lengths of the inlist family, default defaultInListLengths
+	InListLengths []int
+	// Partitions, if set, adds a copy of the cells on the test tables against
+	// their partitioned copies
+	Partitions *PartitionSpec
+	// Distribution is the distribution of b, from -distribution or a -gen
+	// b:<distribution>, nil for the default uniform values. The tables are
+	// named after it, and b keeps it instead of being adjusted to the
+	// selectivities, so the matching rows of the cells are counted.
+	Distribution *distGenerator
+	// NullRatios are the ratios of NULL values per column, b and c, of the
+	// tables, set by -null-ratio
+	NullRatios map[string]float64
+	// FillerSizes are the filler sizes of a row width sweep, one table per
+	// size, set by -f with several sizes, empty for the single -f size
+	FillerSizes []int
+	// Layout is the row layout of the tables, set by -fillers and -b-position,
+	// the zero value for b followed by a single filler c
+	Layout FillerLayout
+	// PredicateValues is the number of distinct b values with the same number
+	// of rows per selectivity, set by -predicate-values, 0 or 1 for one
+	PredicateValues int
+	// PrimaryKeys are the primary key kinds the test tables are created with,
+	// clustered and/or nonclustered, default clustered
+	PrimaryKeys []string
+	// BoundaryPredicates are the predicates of the boundary family per table,
+	// read from the histograms after the tables are analyzed
+	BoundaryPredicates map[string][]boundaryPredicate
+
+	// targets are the b values of the point cells of the table the cells are
+	// generated for, set by GetTestScenarios
+	targets []int
+}
+
+// primaryKeyKinds returns the primary key kinds of the test tables, clustered if not set
+func (o MatrixOptions) primaryKeyKinds() []string {
+	if len(o.PrimaryKeys) == 0 {
+		return []string{"clustered"}
+	}
+	return o.PrimaryKeys
+}
+
+// scenarioFamily generates the scenarios of one cell, a table and selectivity, of a family
+type scenarioFamily func(table TableSpec, sel float64, opts MatrixOptions) []TestScenario
+
+// scenarioFamilies are the scenario families that can be selected with -families
+var scenarioFamilies = map[string]scenarioFamily{
+	"point":      pointScenarios,
+	"ordered":    orderedScenarios,
+	"range":      rangeScenarios,
+	"join":       joinScenarios,
+	"agg":        aggScenarios,
+	"topn":       topNScenarios,
+	"covering":   coveringScenarios,
+	"indexmerge": indexMergeScenarios,
+	"pointget":   pointGetScenarios,
+	"inlist":     inListScenarios,
+	"composite":  compositeScenarios,
+	"dml":        dmlScenarios,
+	"unique":     uniqueScenarios,
+	"null":       nullScenarios,
+	"boundary":   boundaryScenarios,
+	"count":      countScenarios,
+}
+
+// defaultFamilies are the scenario families run if none are given
+var defaultFamilies = []string{"point"}
+
+// parseFamilies parses a comma-separated list of scenario families
+func parseFamilies(s string) ([]string, error) {
+	var families []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := scenarioFamilies[name]; !ok {
+			known := make([]string, 0, len(scenarioFamilies))
+			for family := range scenarioFamilies {
+				known = append(known, family)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown scenario family '%s', expected one of %s", name, strings.Join(known, ", "))
+		}
+		families = append(families, name)
+	}
+	if len(families) == 0 {
+		return nil, fmt.Errorf("no scenario families given")
+	}
+	return families, nil
+}
+
+// GetTestScenarios generates the test scenarios of the given families (default
+// point lookups) for each combination of table and selectivity
+func GetTestScenarios(tables []TableSpec, selectivities []float64, opts MatrixOptions, families ...string) []TestScenario {
+	if len(families) == 0 {
+		families = defaultFamilies
+	}
 	var scenarios []TestScenario
+	// Cells that do not depend on the selectivity are generated once
+	seen := make(map[string]bool)
 
 	// Generate tests for each combination of row count and selectivity
-	for _, rowCount := range rowCounts {
-		tableSizeName := formatRowCountName(rowCount)
-
-		for _, sel := range selectivities {
-			// Calculate the actual value to search for based on selectivity type
-			searchValue := GetNumRows(rowCount, sel)
-
-			// Create index lookup test (without hints)
-			id := fmt.Sprintf("index_%s_%s", tableSizeName, formatSelectivityName(rowCount, sel))
-			indexQuery := fmt.Sprintf("SELECT * FROM t%s WHERE b = %d", tableSizeName, searchValue)
-
-			scenario := TestScenario{
-				ID:          id,
-				Variant:     "ExplainOnly",
-				Name:        fmt.Sprintf("Index Lookup - %s rows, %d selectivity", tableSizeName, int(sel)),
-				Query:       indexQuery,
-				TableName:   fmt.Sprintf("t%s", tableSizeName),
-				RowCount:    rowCount,
-				ExplainOnly: true,
+	for _, table := range tables {
+		fit := fitSelectivities(table.RowCount, selectivities)
+		opts.targets = opts.targets[:0]
+		for _, sel := range fit {
+			opts.targets = append(opts.targets, GetNumRows(table.RowCount, sel))
+		}
+		for _, sel := range fit {
+			for _, family := range families {
+				cell := scenarioFamilies[family](table, sel, opts)
+				if len(cell) == 0 || seen[table.Name()+"/"+family+"/"+cell[0].ID] {
+					continue
+				}
+				seen[table.Name()+"/"+family+"/"+cell[0].ID] = true
+				for i := range cell {
+					cell[i].family = family
+				}
+				if table.Nonclustered {
+					cell = withIDPrefix(cell, "nc", "non-clustered")
+				}
+				if table.FillerSize > 0 {
+					cell = withIDPrefix(cell, fmt.Sprintf("f%d", table.FillerSize), fmt.Sprintf("filler %d", table.FillerSize))
+				}
+				if table.Distribution != "" {
+					cell = withCountQuery(cell, GetNumRows(table.RowCount, sel))
+					cell = withIDPrefix(cell, table.Distribution, table.Distribution+" distribution")
+				}
+				if opts.TiFlash {
+					cell = withTiFlashVariant(cell)
+				}
+				if opts.Partitions != nil {
+					cell = withPartitionedCell(cell, table, opts.Partitions)
+				}
+				scenarios = append(scenarios, cell...)
 			}
-			scenarios = append(scenarios, scenario)
+		}
+	}
+	return scenarios
+}
 
-			query := fmt.Sprintf("SELECT /*+ FORCE_INDEX(t%s, b) */ * FROM t%s WHERE b = %d", tableSizeName, tableSizeName, searchValue)
+// fitSelectivities returns the selectivities that can be set up in a table of
+// rowCount rows: the matching rows of all of them must fit in the table, and
+// each number of matching rows is only used once, since it is also the b value.
+// On small tables many selectivities match the same number of rows (often 0,
+// which is kept as the cell of a predicate matching no rows), or more rows than
+// the table has; these are left out instead of failing the setup.
+func fitSelectivities(rowCount int, selectivities []float64) []float64 {
+	var fit []float64
+	seen := make(map[int]bool)
+	left := rowCount
+	for _, sel := range selectivities {
+		rows := GetNumRows(rowCount, sel)
+		if seen[rows] || rows > left {
+			continue
+		}
+		seen[rows] = true
+		left -= rows
+		fit = append(fit, sel)
+	}
+	return fit
+}
 
-			scenario = TestScenario{
-				ID:        id,
-				Variant:   "Index",
-				Name:      fmt.Sprintf("Index lookup - %s rows, %d selectivity", tableSizeName, int(sel)),
-				Query:     query,
-				TableName: fmt.Sprintf("t%s", tableSizeName),
-				RowCount:  rowCount,
-			}
-			for range repetitions {
-				scenarios = append(scenarios, scenario)
+// withCountQuery sets the count query of the scenarios of a cell on a table of
+// a distribution matching the rows of b = value, expected to match value rows:
+// b keeps the distribution instead of being adjusted to the selectivities, so
+// their matching rows are counted before the measurements
+func withCountQuery(cell []TestScenario, value int) []TestScenario {
+	for i := range cell {
+		if cell[i].CountQuery != "" || cell[i].MatchingRows != value {
+			continue
+		}
+		for _, m := range bEquals.FindAllStringSubmatch(cell[i].Query, -1) {
+			if m[1] == strconv.Itoa(value) {
+				cell[i].CountQuery = countQuery(cell[i].TableName, "b", fmt.Sprintf("b = %d", value))
+				break
 			}
+		}
+	}
+	return cell
+}
+
+// withIDPrefix prefixes the scenario IDs of a cell with the table variant,
+// like ncindex_1K_10 on a table with a non-clustered primary key, to keep
+// them apart from the same cell on the default table
+func withIDPrefix(cell []TestScenario, prefix, note string) []TestScenario {
+	for i := range cell {
+		cell[i].ID = prefix + cell[i].ID
+		cell[i].Name += " (" + note + ")"
+	}
+	return cell
+}
+
+// pointScenarios generates the index lookup vs table scan cell of an equality predicate
+func pointScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	rowCount := table.RowCount
+	tableSizeName := formatRowCountName(rowCount)
+	tableName := table.Name()
+
+	// Calculate the actual value to search for based on selectivity type
+	searchValue := GetNumRows(rowCount, sel)
+
+	// Create index lookup test (without hints)
+	id := fmt.Sprintf("index_%s_%s", tableSizeName, formatSelectivityName(rowCount, sel))
+	indexQuery := fmt.Sprintf("SELECT * FROM %s WHERE b = %d", tableName, searchValue)
+
+	var scenarios []TestScenario
+	scenarios = append(scenarios, TestScenario{
+		ID:           id,
+		Variant:      "ExplainOnly",
+		Name:         fmt.Sprintf("Index Lookup - %s rows, %d selectivity", tableSizeName, int(sel)),
+		Query:        indexQuery,
+		TableName:    tableName,
+		RowCount:     rowCount,
+		MatchingRows: searchValue,
+		ExplainOnly:  true,
+	})
+
+	query := fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE b = %d", tableName, tableName, searchValue)
+	scenarios = append(scenarios, TestScenario{
+		ID:           id,
+		Variant:      "Index",
+		Name:         fmt.Sprintf("Index lookup - %s rows, %d selectivity", tableSizeName, int(sel)),
+		Query:        query,
+		TableName:    tableName,
+		RowCount:     rowCount,
+		MatchingRows: searchValue,
+	})
+
+	query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE b = %d", tableName, tableName, searchValue)
+
+	scenarios = append(scenarios, TestScenario{
+		ID:           id,
+		Variant:      "TableScan",
+		Name:         fmt.Sprintf("Table Scan - %s rows, %d selectivity", tableSizeName, int(sel)),
+		Query:        query,
+		TableName:    tableName,
+		RowCount:     rowCount,
+		MatchingRows: searchValue,
+	})
+	return scenarios
+}
+
+// orderedScenarios generates the keep order cell of an index lookup: the
+// same lookup ordered by the index, where the index read can keep the order
+// (executed serially) instead of reading in parallel and sorting.
+// Since b is constant, the order is b, id: ORDER BY b alone would be eliminated.
+func orderedScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	base := TestScenario{
+		ID:           fmt.Sprintf("ordered_%s_%s", tableSizeName, formatSelectivityName(table.RowCount, sel)),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: searchValue,
+	}
+	explain, index, scan := base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Ordered lookup - %s rows, %d matching", tableSizeName, searchValue)
+	explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE b = %d ORDER BY b, id", tableName, searchValue)
+	explain.ExplainOnly = true
+
+	index.Variant = "IndexKeepOrder"
+	index.Name = fmt.Sprintf("Keep order index lookup - %s rows, %d matching", tableSizeName, searchValue)
+	index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE b = %d ORDER BY b, id", tableName, tableName, searchValue)
+
+	scan.Variant = "TableScanSort"
+	scan.Name = fmt.Sprintf("Table scan and sort - %s rows, %d matching", tableSizeName, searchValue)
+	scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE b = %d ORDER BY b, id", tableName, tableName, searchValue)
+
+	return []TestScenario{explain, index, scan}
+}
+
+// bValueDomain is the range of the random b values, [0, bValueDomain), of the test tables
+const bValueDomain = 1000000
+
+// coveringScenarios generates the covering index cell of a point lookup:
+// only the indexed columns are selected (the primary key is part of the
+// index), so the index read needs no table lookups
+func coveringScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	base := TestScenario{
+		ID:           fmt.Sprintf("covering_%s_%s", tableSizeName, formatSelectivityName(table.RowCount, sel)),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: searchValue,
+	}
+	explain, index, scan := base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Covering lookup - %s rows, %d matching", tableSizeName, searchValue)
+	explain.Query = fmt.Sprintf("SELECT id, b FROM %s WHERE b = %d", tableName, searchValue)
+	explain.ExplainOnly = true
+
+	index.Variant = "IndexReader"
+	index.Name = fmt.Sprintf("Covering index read - %s rows, %d matching", tableSizeName, searchValue)
+	index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ id, b FROM %s WHERE b = %d", tableName, tableName, searchValue)
+
+	scan.Variant = "TableScan"
+	scan.Name = fmt.Sprintf("Table Scan - %s rows, %d matching", tableSizeName, searchValue)
+	scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ id, b FROM %s WHERE b = %d", tableName, tableName, searchValue)
+
+	return []TestScenario{explain, index, scan}
+}
+
+// topNScenarios generates the top-N cell of a table: the first rows by b,
+// as many as the selectivity matches, where an index read in b order can
+// stop after the limit, instead of scanning the whole table into a TopN.
+func topNScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	limit := max(GetNumRows(table.RowCount, sel), 1)
+	base := TestScenario{
+		ID:           fmt.Sprintf("topn_%s_%d", tableSizeName, limit),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: limit,
+	}
+	explain, index, scan := base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Top-N - %s rows, limit %d", tableSizeName, limit)
+	explain.Query = fmt.Sprintf("SELECT * FROM %s ORDER BY b LIMIT %d", tableName, limit)
+	explain.ExplainOnly = true
+
+	index.Variant = "IndexKeepOrder"
+	index.Name = fmt.Sprintf("Keep order index read - %s rows, limit %d", tableSizeName, limit)
+	index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s ORDER BY b LIMIT %d", tableName, tableName, limit)
+
+	scan.Variant = "TableScanTopN"
+	scan.Name = fmt.Sprintf("Table scan and top-N - %s rows, limit %d", tableSizeName, limit)
+	scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s ORDER BY b LIMIT %d", tableName, tableName, limit)
+
+	return []TestScenario{explain, index, scan}
+}
+
+// maxPointGetKeys is the largest IN-list of the pointget family, larger cells are skipped
+const maxPointGetKeys = 10000
+
+// pointGetIDs returns the IN-list of n primary keys spread over the table,
+// relying on the ids being dense from the auto increment
+func pointGetIDs(rowCount, n int) string {
+	ids := make([]string, 0, n)
+	for i := range n {
+		ids = append(ids, strconv.Itoa(1+i*rowCount/n))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// pointGetScenarios generates the primary key cell of a table: a single id
+// (Point_Get) or an IN-list of as many ids as the selectivity matches
+// (Batch_Point_Get), vs a table scan, where id + 0 prevents the key lookup
+func pointGetScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	keys := min(max(GetNumRows(table.RowCount, sel), 1), table.RowCount)
+	if keys > maxPointGetKeys {
+		return nil
+	}
+	base := TestScenario{
+		ID:           fmt.Sprintf("pointget_%s_%d", tableSizeName, keys),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: keys,
+	}
+	explain, get, scan := base, base, base
+
+	predicate := fmt.Sprintf("id = %d", 1+table.RowCount/2)
+	scanPredicate := fmt.Sprintf("id + 0 = %d", 1+table.RowCount/2)
+	get.Variant = "PointGet"
+	if keys > 1 {
+		ids := pointGetIDs(table.RowCount, keys)
+		predicate = fmt.Sprintf("id IN (%s)", ids)
+		scanPredicate = fmt.Sprintf("id + 0 IN (%s)", ids)
+		get.Variant = "BatchPointGet"
+	}
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Primary key lookup - %s rows, %d keys", tableSizeName, keys)
+	explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, predicate)
+	explain.ExplainOnly = true
+
+	// No hint forces a point get, it is the plan of any primary key equality or IN-list
+	get.Name = fmt.Sprintf("Point get - %s rows, %d keys", tableSizeName, keys)
+	get.Query = explain.Query
+
+	scan.Variant = "TableScan"
+	scan.Name = fmt.Sprintf("Table Scan - %s rows, %d keys", tableSizeName, keys)
+	scan.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, scanPredicate)
+
+	return []TestScenario{explain, get, scan}
+}
 
-			query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(t%s, b) */ * FROM t%s WHERE b = %d", tableSizeName, tableSizeName, searchValue)
+// countQuery returns the query counting the rows matching a predicate, read
+// from the given index alone
+func countQuery(tableName, index, predicate string) string {
+	return fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, %s) */ COUNT(*) FROM %s WHERE %s", tableName, index, tableName, predicate)
+}
+
+// rangePredicate returns the b BETWEEN x AND y predicate of a range cell, the
+// number of b values it covers and the expected number of matching rows if
+// the b values are uniformly distributed
+func rangePredicate(table TableSpec, sel float64, rangeSpan int) (string, int, int) {
+	span := rangeSpan
+	if span <= 0 && table.RowCount > 0 {
+		span = int(float64(GetNumRows(table.RowCount, sel)) * bValueDomain / float64(table.RowCount))
+	}
+	span = min(max(span, 1), bValueDomain)
+	high := bValueDomain - 1
+	low := high - span + 1
+	// The expected number of matching rows, the b values are random
+	matchingRows := int(float64(table.RowCount) * float64(span) / bValueDomain)
+	return fmt.Sprintf("b BETWEEN %d AND %d", low, high), span, matchingRows
+}
+
+// rangeScenarios generates the range cell of a table: a b BETWEEN x AND y
+// predicate over the random b values, where the index read is an
+// IndexRangeScan estimated from the histogram instead of the TopN.
+// The range is placed at the top of the value domain, away from the small
+// b values used for the point selectivities. The ID has the number of rows
+// expected if b is uniform, the matching rows are counted before the run.
+func rangeScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	predicate, span, matchingRows := rangePredicate(table, sel, opts.RangeSpan)
+	base := TestScenario{
+		ID:           fmt.Sprintf("range_%s_%d", tableSizeName, matchingRows),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: matchingRows,
+		CountQuery:   countQuery(tableName, "b", predicate),
+	}
+	explain, index, scan := base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Range - %s rows, %d values span", tableSizeName, span)
+	explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, predicate)
+	explain.ExplainOnly = true
+
+	index.Variant = "IndexRange"
+	index.Name = fmt.Sprintf("Index range scan - %s rows, %d values span", tableSizeName, span)
+	index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, predicate)
+
+	scan.Variant = "TableScan"
+	scan.Name = fmt.Sprintf("Table Scan - %s rows, %d values span", tableSizeName, span)
+	scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, predicate)
+
+	return []TestScenario{explain, index, scan}
+}
+
+// defaultInListLengths are the IN-list lengths of the inlist family if none are given
+var defaultInListLengths = []int{10}
+
+// inListLengths returns the IN-list lengths of the inlist family
+func (opts MatrixOptions) inListLengths() []int {
+	if len(opts.InListLengths) == 0 {
+		return defaultInListLengths
+	}
+	return opts.InListLengths
+}
+
+// parseInListLengths parses a comma-separated list of IN-list lengths
+func parseInListLengths(s string) ([]int, error) {
+	var lengths []int
+	for _, part := range strings.Split(s, ",") {
+		length, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("invalid IN-list length '%s': expected a positive integer", part)
+		}
+		lengths = append(lengths, length)
+	}
+	return lengths, nil
+}
 
-			scenario = TestScenario{
-				ID:        id,
-				Variant:   "TableScan",
-				Name:      fmt.Sprintf("Table Scan - %s rows, %d selectivity", tableSizeName, int(sel)),
-				Query:     query,
-				TableName: fmt.Sprintf("t%s", tableSizeName),
-				RowCount:  rowCount,
+// inListScenarios generates the IN-list cells of a table, one per IN-list
+// length: b IN (N, ...) where N matches as many rows as b = N, and the other
+// values are spread over the b value domain, each matching
+// rows / bValueDomain rows on average. The optimizer has to sum the estimates
+// of the frequent value and the rare ones. The other values leave out the b
+// values of the other point cells, and the rows they actually match are
+// counted before the run. The length is part of the family in the scenario
+// ID, like inlist10_1K_100, with the number of rows expected if b is uniform.
+func inListScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	var scenarios []TestScenario
+	for _, length := range opts.inListLengths() {
+		values := []string{strconv.Itoa(searchValue)}
+		step := bValueDomain / (2 * length)
+		for i := 1; i < length; i++ {
+			value := bValueDomain - i*step
+			for slices.Contains(opts.targets, value) {
+				value--
 			}
-			for range repetitions {
-				scenarios = append(scenarios, scenario)
+			values = append(values, strconv.Itoa(value))
+		}
+		matchingRows := searchValue + int(float64(table.RowCount)*float64(length-1)/bValueDomain)
+		predicate := fmt.Sprintf("b IN (%s)", strings.Join(values, ", "))
+		base := TestScenario{
+			ID:           fmt.Sprintf("inlist%d_%s_%d", length, tableSizeName, matchingRows),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: matchingRows,
+			CountQuery:   countQuery(tableName, "b", predicate),
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("IN-list of %d - %s rows, %d matching", length, tableSizeName, matchingRows)
+		explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, predicate)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("IN-list index lookup - %s rows, %d matching", tableSizeName, matchingRows)
+		index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, predicate)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("Table Scan - %s rows, %d matching", tableSizeName, matchingRows)
+		scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b) */ * FROM %s WHERE %s", tableName, tableName, predicate)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
+
+// compositeScenarios generates the composite index cells of a table, on the
+// composite table with an index on (b, c). The filler c is a random number
+// between 0 and 1 as a string, repeated, so c < '0.x' is a range over c:
+//   - compositefull: b = N AND c < '0.5', both index columns, about half of the b = N rows
+//   - compositeprefix: b = N, the leading column only, as many rows as the point cell
+//   - compositenonleading: c < '0.x', only the second column, matching about as many
+//     rows as b = N, where only a full index scan can use the index
+//
+// Each is an index lookup on (b, c) vs a table scan, ignoring the index on b
+// too. The IDs have the expected number of rows, the rows matched by the
+// predicates on c are counted before the run.
+func compositeScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.CompositeName()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	fraction := 0.0
+	if table.RowCount > 0 {
+		fraction = float64(searchValue) / float64(table.RowCount)
+	}
+	cells := []struct {
+		kind, predicate string
+		matchingRows    int
+	}{
+		{"full", fmt.Sprintf("b = %d AND c < '0.5'", searchValue), searchValue / 2},
+		{"prefix", fmt.Sprintf("b = %d", searchValue), searchValue},
+		{"nonleading", fmt.Sprintf("c < '%s'", strconv.FormatFloat(fraction, 'f', -1, 64)), searchValue},
+	}
+	var scenarios []TestScenario
+	for _, cell := range cells {
+		base := TestScenario{
+			ID:           fmt.Sprintf("composite%s_%s_%d", cell.kind, tableSizeName, cell.matchingRows),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: cell.matchingRows,
+		}
+		if cell.kind != "prefix" {
+			base.CountQuery = countQuery(tableName, "bc", cell.predicate)
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("Composite index %s - %s rows, %d matching", cell.kind, tableSizeName, cell.matchingRows)
+		explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, cell.predicate)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("Composite index lookup - %s rows, %d matching", tableSizeName, cell.matchingRows)
+		index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, bc) */ * FROM %s WHERE %s", tableName, tableName, cell.predicate)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("Table Scan - %s rows, %d matching", tableSizeName, cell.matchingRows)
+		scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b, bc) */ * FROM %s WHERE %s", tableName, tableName, cell.predicate)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
+
+// uniqueValueExpr is the SQL of the unique values of the unique family
+// columns, id scrambled by a multiplication with an odd number modulo 2^32,
+// which is a bijection, so the values stay unique. It is computed in unsigned
+// 64-bit arithmetic on id modulo 2^32, where the product of a 32-bit value
+// and the 32-bit multiplier cannot overflow.
+const uniqueValueExpr = "CAST(id AS UNSIGNED) % 4294967296 * 2654435761 % 4294967296"
+
+// uniqueValue returns the unique family column value of a row, like uniqueValueExpr
+func uniqueValue(id int) int64 {
+	return int64(uint64(id) % 4294967296 * 2654435761 % 4294967296)
+}
+
+// uniqueScenarios generates the uniqueness cells of a table: the same lookup
+// of as many keys as the selectivity matches, on the uniqueness table with
+// identical values in uu, with a unique index (unique, eligible for
+// Point_Get and Batch_Point_Get), and un, with a non-unique index (nonunique).
+// Each is an index lookup vs a table scan. Cells over maxPointGetKeys are skipped.
+func uniqueScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.UniqueName()
+	tableSizeName := formatRowCountName(table.RowCount)
+	keys := min(max(GetNumRows(table.RowCount, sel), 1), table.RowCount)
+	if keys > maxPointGetKeys {
+		return nil
+	}
+	values := make([]string, 0, keys)
+	for i := range keys {
+		values = append(values, strconv.FormatInt(uniqueValue(1+i*table.RowCount/keys), 10))
+	}
+	var scenarios []TestScenario
+	for _, column := range []struct{ kind, name string }{{"unique", "uu"}, {"nonunique", "un"}} {
+		predicate := fmt.Sprintf("%s = %s", column.name, values[0])
+		if keys > 1 {
+			predicate = fmt.Sprintf("%s IN (%s)", column.name, strings.Join(values, ", "))
+		}
+		base := TestScenario{
+			ID:           fmt.Sprintf("%s_%s_%d", column.kind, tableSizeName, keys),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: keys,
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("%s index lookup - %s rows, %d keys", column.kind, tableSizeName, keys)
+		explain.Query = fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, predicate)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("%s index lookup - %s rows, %d keys", column.kind, tableSizeName, keys)
+		index.Query = fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, %s) */ * FROM %s WHERE %s", tableName, column.name, tableName, predicate)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("Table Scan - %s rows, %d keys", tableSizeName, keys)
+		scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, %s) */ * FROM %s WHERE %s", tableName, column.name, tableName, predicate)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
+
+// maxDMLRows is the most rows written by a dml cell, larger cells are skipped,
+// so the rolled back transactions stay well below the transaction size limit
+// (txn-total-size-limit, 100MB by default) even with wide rows
+const maxDMLRows = 10000
+
+// dmlScenarios generates the write path cells of a point lookup, an UPDATE
+// (dmlupdate) and a DELETE (dmldelete) of the b = N rows, index lookup vs table
+// scan. They are executed in a transaction that is rolled back. Cells over
+// maxDMLRows are skipped.
+func dmlScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	if searchValue > maxDMLRows {
+		return nil
+	}
+	statements := []struct {
+		kind, format string
+	}{
+		{"update", "UPDATE %s%s SET c = REVERSE(c) WHERE b = %d"},
+		{"delete", "DELETE %sFROM %s WHERE b = %d"},
+	}
+	var scenarios []TestScenario
+	for _, statement := range statements {
+		base := TestScenario{
+			ID:           fmt.Sprintf("dml%s_%s_%d", statement.kind, tableSizeName, searchValue),
+			TableName:    tableName,
+			RowCount:     table.RowCount,
+			MatchingRows: searchValue,
+		}
+		explain, index, scan := base, base, base
+
+		explain.Variant = "ExplainOnly"
+		explain.Name = fmt.Sprintf("%s - %s rows, %d matching", strings.ToUpper(statement.kind), tableSizeName, searchValue)
+		explain.Query = fmt.Sprintf(statement.format, "", tableName, searchValue)
+		explain.ExplainOnly = true
+
+		index.Variant = "Index"
+		index.Name = fmt.Sprintf("%s by index lookup - %s rows, %d matching", strings.ToUpper(statement.kind), tableSizeName, searchValue)
+		index.Query = fmt.Sprintf(statement.format, fmt.Sprintf("/*+ FORCE_INDEX(%s, b) */ ", tableName), tableName, searchValue)
+
+		scan.Variant = "TableScan"
+		scan.Name = fmt.Sprintf("%s by table scan - %s rows, %d matching", strings.ToUpper(statement.kind), tableSizeName, searchValue)
+		scan.Query = fmt.Sprintf(statement.format, fmt.Sprintf("/*+ IGNORE_INDEX(%s, b) */ ", tableName), tableName, searchValue)
+
+		scenarios = append(scenarios, explain, index, scan)
+	}
+	return scenarios
+}
+
+// indexMergeScenarios generates the index merge cell of a table: b = N OR a
+// range on the second indexed column d of the index merge table, matching
+// about as many rows as b = N, where the union of both index reads competes
+// with a full table scan
+func indexMergeScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.IndexMergeName()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	// d is uniformly distributed over the b value domain
+	span := min(max(int(float64(searchValue)*bValueDomain/float64(table.RowCount)), 1), bValueDomain)
+	matchingRows := searchValue + int(float64(table.RowCount)*float64(span)/bValueDomain)
+	predicate := fmt.Sprintf("b = %d OR d < %d", searchValue, span)
+	base := TestScenario{
+		ID:           fmt.Sprintf("indexmerge_%s_%d", tableSizeName, matchingRows),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: matchingRows,
+	}
+	explain, merge, scan := base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("OR over two indexes - %s rows, %d matching", tableSizeName, matchingRows)
+	explain.Query = fmt.Sprintf("SELECT id, b, c FROM %s WHERE %s", tableName, predicate)
+	explain.ExplainOnly = true
+
+	merge.Variant = "IndexMerge"
+	merge.Name = fmt.Sprintf("Index merge - %s rows, %d matching", tableSizeName, matchingRows)
+	merge.Query = fmt.Sprintf("SELECT /*+ USE_INDEX_MERGE(%s, b, d) */ id, b, c FROM %s WHERE %s", tableName, tableName, predicate)
+
+	scan.Variant = "TableScan"
+	scan.Name = fmt.Sprintf("Table Scan - %s rows, %d matching", tableSizeName, matchingRows)
+	scan.Query = fmt.Sprintf("SELECT /*+ IGNORE_INDEX(%s, b, d) */ id, b, c FROM %s WHERE %s", tableName, tableName, predicate)
+
+	cell := []TestScenario{explain, merge, scan}
+	// b keeps the distribution of the table instead of the selectivities
+	if table.Distribution != "" {
+		for i := range cell {
+			cell[i].CountQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", tableName, predicate)
+		}
+	}
+	return cell
+}
+
+// joinScenarios generates the join cell of a table: the matching rows of the
+// test table joined on id to its join partner table, with the join method
+// forced by hints. The join partner is the inner side of the index join.
+func joinScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName, joinName := table.Name(), table.JoinName()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	base := TestScenario{
+		ID:           fmt.Sprintf("join_%s_%s", tableSizeName, formatSelectivityName(table.RowCount, sel)),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: searchValue,
+	}
+	query := func(hint string) string {
+		if hint != "" {
+			hint = "/*+ " + hint + " */ "
+		}
+		return fmt.Sprintf("SELECT %st.id, t.b, j.c FROM %s t JOIN %s j ON j.id = t.id WHERE t.b = %d",
+			hint, tableName, joinName, searchValue)
+	}
+	explain, indexJoin, hashJoin, mergeJoin := base, base, base, base
+
+	explain.Variant = "ExplainOnly"
+	explain.Name = fmt.Sprintf("Join - %s rows, %d matching", tableSizeName, searchValue)
+	explain.Query = query("")
+	explain.ExplainOnly = true
+
+	indexJoin.Variant = "IndexJoin"
+	indexJoin.Name = fmt.Sprintf("Index join - %s rows, %d matching", tableSizeName, searchValue)
+	indexJoin.Query = query("INL_JOIN(j)")
+
+	hashJoin.Variant = "HashJoin"
+	hashJoin.Name = fmt.Sprintf("Hash join - %s rows, %d matching", tableSizeName, searchValue)
+	hashJoin.Query = query("HASH_JOIN(t, j)")
+
+	mergeJoin.Variant = "MergeJoin"
+	mergeJoin.Name = fmt.Sprintf("Merge join - %s rows, %d matching", tableSizeName, searchValue)
+	mergeJoin.Query = query("MERGE_JOIN(t, j)")
+
+	return []TestScenario{explain, indexJoin, hashJoin, mergeJoin}
+}
+
+// aggScenarios generates the aggregation cell of a table: a GROUP BY b over
+// the rows of a range cell, with stream or hash aggregation forced by hints,
+// and the partial aggregation forced to the coprocessor with AGG_TO_COP.
+// Without AGG_TO_COP the optimizer still decides where to aggregate, the
+// plan type records where the aggregation actually ran. Like for the range
+// cell, the matching rows and the groups are counted before the run.
+func aggScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	predicate, span, matchingRows := rangePredicate(table, sel, opts.RangeSpan)
+	base := TestScenario{
+		ID:           fmt.Sprintf("agg_%s_%d", tableSizeName, matchingRows),
+		TableName:    tableName,
+		RowCount:     table.RowCount,
+		MatchingRows: matchingRows,
+		CountQuery:   fmt.Sprintf("SELECT /*+ FORCE_INDEX(%s, b) */ COUNT(*), COUNT(DISTINCT b) FROM %s WHERE %s", tableName, tableName, predicate),
+	}
+	query := func(hint string) string {
+		if hint != "" {
+			hint = "/*+ " + hint + " */ "
+		}
+		return fmt.Sprintf("SELECT %sb, COUNT(*), SUM(id) FROM %s WHERE %s GROUP BY b", hint, tableName, predicate)
+	}
+	var scenarios []TestScenario
+	for _, variant := range []struct{ variant, name, hint string }{
+		{"ExplainOnly", "Aggregation", ""},
+		{"StreamAgg", "Stream aggregation", "STREAM_AGG()"},
+		{"StreamAggPushdown", "Pushed down stream aggregation", "STREAM_AGG() AGG_TO_COP()"},
+		{"HashAgg", "Hash aggregation", "HASH_AGG()"},
+		{"HashAggPushdown", "Pushed down hash aggregation", "HASH_AGG() AGG_TO_COP()"},
+	} {
+		scenario := base
+		scenario.Variant = variant.variant
+		scenario.Name = fmt.Sprintf("%s - %s rows, %d values span", variant.name, tableSizeName, span)
+		scenario.Query = query(variant.hint)
+		scenario.ExplainOnly = variant.hint == ""
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios
+}
+
+// countScenarios generates the COUNT(*) cells of a table: the rows of a point
+// lookup, and the whole table, generated with the first selectivity. The count
+// is answered from the index alone, or from the table, with the partial count
+// pushed down to the coprocessor either way, the fast paths whose costs differ
+// from the generic index and table scans. Their plan types are classified by
+// the access path, like index_reader_stream_agg_pushdown, see scenarioPlanType.
+func countScenarios(table TableSpec, sel float64, opts MatrixOptions) []TestScenario {
+	tableName := table.Name()
+	tableSizeName := formatRowCountName(table.RowCount)
+	searchValue := GetNumRows(table.RowCount, sel)
+	type countCell struct {
+		id, name, where string
+		matchingRows    int
+	}
+	cells := []countCell{{fmt.Sprintf("count_%s_%s", tableSizeName, formatSelectivityName(table.RowCount, sel)),
+		fmt.Sprintf("%d matching", searchValue), fmt.Sprintf(" WHERE b = %d", searchValue), searchValue}}
+	if len(opts.targets) == 0 || opts.targets[0] == searchValue {
+		cells = append(cells, countCell{fmt.Sprintf("countall_%s_%d", tableSizeName, table.RowCount), "whole table", "", table.RowCount})
+	}
+	var scenarios []TestScenario
+	for _, cell := range cells {
+		for _, variant := range []struct{ variant, name, hint string }{
+			{"ExplainOnly", "Count", ""},
+			{"IndexReader", "Count from the index", "FORCE_INDEX(%s, b)"},
+			{"TableScan", "Count from the table", "IGNORE_INDEX(%s, b)"},
+		} {
+			hint := ""
+			if variant.hint != "" {
+				hint = "/*+ " + fmt.Sprintf(variant.hint, tableName) + " */ "
 			}
+			scenarios = append(scenarios, TestScenario{
+				ID:           cell.id,
+				Variant:      variant.variant,
+				Name:         fmt.Sprintf("%s - %s rows, %s", variant.name, tableSizeName, cell.name),
+				Query:        fmt.Sprintf("SELECT %sCOUNT(*) FROM %s%s", hint, tableName, cell.where),
+				TableName:    tableName,
+				RowCount:     table.RowCount,
+				MatchingRows: cell.matchingRows,
+				ExplainOnly:  variant.hint == "",
+			})
 		}
 	}
-	// Make sure they are run in random order.
-	rand.Shuffle(len(scenarios), func(i, j int) {
-		scenarios[i], scenarios[j] = scenarios[j], scenarios[i]
-	})
 	return scenarios
 }
 
+// parseShard parses a shard specification like "2/4" into the 1-based shard
+// number and the number of shards
+func parseShard(s string) (int, int, error) {
+	shardStr, countStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid shard '%s': expected <shard>/<count>, e.g. 2/4", s)
+	}
+	shard, err := strconv.Atoi(strings.TrimSpace(shardStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard number '%s': %w", shardStr, err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard count '%s': %w", countStr, err)
+	}
+	if count < 1 || shard < 1 || shard > count {
+		return 0, 0, fmt.Errorf("invalid shard '%s': shard must be between 1 and the shard count", s)
+	}
+	return shard, count, nil
+}
+
+// filterShard returns the scenarios belonging to the 1-based shard out of count shards.
+// The partitioning hashes the scenario ID, so it is deterministic across machines
+// and all variants of a cell end up in the same shard.
+func filterShard(scenarios []TestScenario, shard, count int) []TestScenario {
+	if count <= 1 {
+		return scenarios
+	}
+	var filtered []TestScenario
+	for _, scenario := range scenarios {
+		h := fnv.New32a()
+		h.Write([]byte(scenario.ID))
+		if int(h.Sum32()%uint32(count)) == shard-1 {
+			filtered = append(filtered, scenario)
+		}
+	}
+	return filtered
+}
+
+// parseScenarioPattern compiles a -filter or -exclude regular expression,
+// matching whole scenario IDs, nil if empty
+func parseScenarioPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario pattern '%s': %w", pattern, err)
+	}
+	return re, nil
+}
+
+// filterScenarios returns the scenarios whose ID matches include, if given,
+// and does not match exclude, if given. All variants of a cell share the ID,
+// so cells are kept or left out as a whole.
+func filterScenarios(scenarios []TestScenario, include, exclude *regexp.Regexp) []TestScenario {
+	if include == nil && exclude == nil {
+		return scenarios
+	}
+	var filtered []TestScenario
+	for _, scenario := range scenarios {
+		if include != nil && !include.MatchString(scenario.ID) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(scenario.ID) {
+			continue
+		}
+		filtered = append(filtered, scenario)
+	}
+	return filtered
+}
+
 // formatSelectivityName formats a selectivity value into a scenario ID format
 func formatSelectivityName(r int, v float64) string {
 	// Convert to a safe format for scenario IDs
@@ -107,10 +1225,11 @@ func formatSelectivityName(r int, v float64) string {
 
 // formatRowCountName formats a row count into a table name format
 func formatRowCountName(count int) string {
+	// Only exact multiples get a suffix, so 1500 rows is not named like 1K
 	switch {
-	case count >= 1000000:
+	case count >= 1000000 && count%1000000 == 0:
 		return fmt.Sprintf("%dM", count/1000000)
-	case count >= 1000:
+	case count >= 1000 && count%1000 == 0:
 		return fmt.Sprintf("%dK", count/1000)
 	default:
 		return fmt.Sprintf("%d", count)
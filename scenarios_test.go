@@ -0,0 +1,561 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleRepetitions(t *testing.T) {
+	rowCounts := []int{1000, 10000}
+	selectivities := []float64{0.1, 50}
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities(rowCounts, selectivities)
+	schedule := NewSchedule(scenarios, 3)
+
+	// One ExplainOnly plus two hinted variants repeated 3 times, per cell
+	cells := len(rowCounts) * len(selectivities)
+	if len(scenarios) != cells*3 {
+		t.Fatalf("expected %d distinct scenarios, got %d", cells*3, len(scenarios))
+	}
+	if schedule.Len() != cells*(1+2*3) {
+		t.Fatalf("expected %d executions, got %d", cells*(1+2*3), schedule.Len())
+	}
+	variants := make(map[string]int)
+	seen := make(map[*TestScenario]map[int]bool)
+	for run := range schedule.All() {
+		variants[run.Scenario.Variant]++
+		if seen[run.Scenario] == nil {
+			seen[run.Scenario] = make(map[int]bool)
+		}
+		if seen[run.Scenario][run.Repetition] {
+			t.Fatalf("repetition %d of %s scheduled twice", run.Repetition, run.Scenario.ID)
+		}
+		seen[run.Scenario][run.Repetition] = true
+	}
+	if variants["ExplainOnly"] != cells || variants["Index"] != cells*3 || variants["TableScan"] != cells*3 {
+		t.Fatalf("unexpected variant counts: %v", variants)
+	}
+}
+
+func TestScheduleAddDuringIteration(t *testing.T) {
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1})
+	schedule := NewSchedule(scenarios, 1)
+	added := false
+	count := 0
+	for run := range schedule.All() {
+		count++
+		if !added && !run.Scenario.ExplainOnly {
+			added = true
+			schedule.Add(0, 2)
+		}
+	}
+	if count != 3+2 {
+		t.Fatalf("expected 5 executions, got %d", count)
+	}
+
+	// Extra runs added during the iteration are interleaved with the pending ones
+	scenarios = GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1, 0.2, 0.3, 0.4})
+	schedule = NewSchedule(scenarios, 10)
+	var extra []bool
+	for run := range schedule.All() {
+		if len(extra) == 0 {
+			schedule.Add(1, 20)
+		}
+		extra = append(extra, run.index == 1 && run.Repetition >= 10)
+	}
+	first, last := slices.Index(extra, true), len(extra)-1
+	for last > first && !extra[last] {
+		last--
+	}
+	if first < 0 || !slices.Contains(extra[first:last+1], false) {
+		t.Fatalf("expected the extra runs interleaved with the others, got %v", extra)
+	}
+}
+
+func TestScheduleWarmup(t *testing.T) {
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1, 50})
+	schedule := NewSchedule(scenarios, 3)
+	schedule.AddWarmup(2)
+
+	// The two hinted variants per cell are warmed up right before their first measured run
+	warmups := 2 * 2 * 2
+	if schedule.Len() != warmups+2*(1+2*3) {
+		t.Fatalf("expected %d executions, got %d", warmups+2*(1+2*3), schedule.Len())
+	}
+	var results []*TestExecutionResult
+	var previous []string
+	measured := make(map[string]bool)
+	for run := range schedule.All() {
+		if run.Warmup && run.Scenario.ExplainOnly {
+			t.Fatalf("warm-up scheduled for the ExplainOnly %s", run.Scenario.ID)
+		}
+		key := run.Scenario.ID + "/" + run.Scenario.Variant
+		if run.Warmup && measured[key] {
+			t.Fatalf("warm-up of %s after its measured runs", key)
+		}
+		if !run.Warmup && !run.Scenario.ExplainOnly && !measured[key] {
+			if n := len(previous); n < 2 || previous[n-1] != key+"/warmup" || previous[n-2] != key+"/warmup" {
+				t.Fatalf("expected the warm-up runs of %s right before it, got %v", key, previous)
+			}
+			measured[key] = true
+		}
+		if run.Warmup {
+			key += "/warmup"
+		}
+		previous = append(previous, key)
+		results = append(results, &TestExecutionResult{Warmup: run.Warmup})
+	}
+	if measured := measuredResults(results); len(measured) != len(results)-warmups {
+		t.Fatalf("expected %d measured results, got %d", len(results)-warmups, len(measured))
+	}
+}
+
+func TestFilterScenarios(t *testing.T) {
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000, 1000000}, []float64{0.1, 0.2})
+	include, err := parseScenarioPattern("index_1M_.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exclude, err := parseScenarioPattern(".*_200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := filterScenarios(scenarios, include, exclude)
+	if len(filtered) == 0 {
+		t.Fatal("expected some scenarios to be selected")
+	}
+	for _, scenario := range filtered {
+		if !strings.HasPrefix(scenario.ID, "index_1M_") || strings.HasSuffix(scenario.ID, "_200") {
+			t.Fatalf("unexpected scenario %s", scenario.ID)
+		}
+	}
+	// The pattern matches the whole ID
+	partial, _ := parseScenarioPattern("1M")
+	if got := filterScenarios(scenarios, partial, nil); len(got) != 0 {
+		t.Fatalf("expected no scenarios for a partial match, got %d", len(got))
+	}
+	if got := filterScenarios(scenarios, nil, nil); len(got) != len(scenarios) {
+		t.Fatalf("expected all scenarios without patterns, got %d", len(got))
+	}
+	if _, err := parseScenarioPattern("index_(1M"); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func TestFilterShard(t *testing.T) {
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000, 10000, 100000}, []float64{0.1, 0.2, 0.3, 0.4})
+	shardOf := make(map[string]int)
+	total := 0
+	for shard := 1; shard <= 4; shard++ {
+		for _, scenario := range filterShard(scenarios, shard, 4) {
+			if s, ok := shardOf[scenario.ID]; ok && s != shard {
+				t.Fatalf("cell %s split over shards %d and %d", scenario.ID, s, shard)
+			}
+			shardOf[scenario.ID] = shard
+			total++
+		}
+	}
+	if total != len(scenarios) {
+		t.Fatalf("expected the shards to cover all %d scenarios, got %d", len(scenarios), total)
+	}
+
+	if _, _, err := parseShard("2/4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []string{"0/4", "5/4", "2", "a/b"} {
+		if _, _, err := parseShard(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestSmallTables(t *testing.T) {
+	rowCounts, err := parseRowCounts("0,1,10,100,1500")
+	if err != nil || !slices.Equal(rowCounts, []int{0, 1, 10, 100, 1500}) {
+		t.Fatalf("unexpected row counts %v: %v", rowCounts, err)
+	}
+	if _, err = parseRowCounts("-1"); err == nil {
+		t.Fatalf("expected negative row counts to be rejected")
+	}
+	names := make([]string, 0, len(rowCounts))
+	for _, table := range tableSpecs(append(rowCounts, 1000), "", MatrixOptions{}) {
+		names = append(names, table.Name())
+	}
+	if !slices.Equal(names, []string{"t0", "t1", "t10", "t100", "t1500", "t1K"}) {
+		t.Fatalf("unexpected table names %v", names)
+	}
+
+	selectivities := []float64{0.5, 0.25, 0.125, 0.0625, 50}
+	for _, tc := range []struct {
+		rowCount int
+		fit      []float64
+	}{
+		{0, []float64{0.5}},
+		{1, []float64{0.5}},
+		{10, []float64{0.5, 0.25, 0.125, 0.0625}},
+		{100, []float64{0.5, 0.25, 0.125, 0.0625}},
+		{1000, selectivities},
+	} {
+		if fit := fitSelectivities(tc.rowCount, selectivities); !slices.Equal(fit, tc.fit) {
+			t.Fatalf("%d rows: expected selectivities %v, got %v", tc.rowCount, tc.fit, fit)
+		}
+	}
+
+	scenarios := GetTestScenarios(tableSpecs([]int{0, 1}, "", MatrixOptions{}), selectivities, MatrixOptions{}, "point", "range")
+	if len(scenarios) != 12 {
+		t.Fatalf("expected 12 scenarios, got %d", len(scenarios))
+	}
+	for _, scenario := range scenarios {
+		if scenario.MatchingRows != 0 {
+			t.Fatalf("expected no matching rows on tiny tables: %+v", scenario)
+		}
+	}
+	if scenarios[0].ID != "index_0_0" || scenarios[3].ID != "range_0_0" || scenarios[6].ID != "index_1_0" {
+		t.Fatalf("unexpected small table scenarios: %s %s %s", scenarios[0].ID, scenarios[3].ID, scenarios[6].ID)
+	}
+}
+
+// scenarioWant is the expected ID, variant, query and matching rows of a generated scenario
+type scenarioWant struct {
+	id, variant, query string
+	matchingRows       int
+}
+
+// checkScenarios compares the generated scenarios with the expected ones, in order
+func checkScenarios(t *testing.T, scenarios []TestScenario, want []scenarioWant) {
+	t.Helper()
+	if len(scenarios) != len(want) {
+		t.Fatalf("expected %d scenarios, got %d: %+v", len(want), len(scenarios), scenarios)
+	}
+	for i, w := range want {
+		s := scenarios[i]
+		if s.ID != w.id || s.Variant != w.variant || s.Query != w.query || s.MatchingRows != w.matchingRows {
+			t.Fatalf("scenario %d: expected %+v, got %s %s %q matching %d", i, w, s.ID, s.Variant, s.Query, s.MatchingRows)
+		}
+		if s.ExplainOnly != (w.variant == "ExplainOnly") {
+			t.Fatalf("scenario %d: unexpected ExplainOnly %v for the %s variant", i, s.ExplainOnly, s.Variant)
+		}
+	}
+}
+
+func TestScenarioFamilies(t *testing.T) {
+	for _, tc := range []struct {
+		family        string
+		rowCount      int
+		suffix        string
+		selectivities []float64
+		opts          MatrixOptions
+		want          []scenarioWant
+	}{
+		{"point", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"index_1K_100", "ExplainOnly", "SELECT * FROM t1K WHERE b = 100", 100},
+			{"index_1K_100", "Index", "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 100", 100},
+			{"index_1K_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ * FROM t1K WHERE b = 100", 100},
+		}},
+		{"ordered", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"ordered_1K_100", "ExplainOnly", "SELECT * FROM t1K WHERE b = 100 ORDER BY b, id", 100},
+			{"ordered_1K_100", "IndexKeepOrder", "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 100 ORDER BY b, id", 100},
+			{"ordered_1K_100", "TableScanSort", "SELECT /*+ IGNORE_INDEX(t1K, b) */ * FROM t1K WHERE b = 100 ORDER BY b, id", 100},
+		}},
+		{"range", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"range_1K_100", "ExplainOnly", "SELECT * FROM t1K WHERE b BETWEEN 900000 AND 999999", 100},
+			{"range_1K_100", "IndexRange", "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b BETWEEN 900000 AND 999999", 100},
+			{"range_1K_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ * FROM t1K WHERE b BETWEEN 900000 AND 999999", 100},
+		}},
+		{"range", 1000000, "", []float64{0.1}, MatrixOptions{RangeSpan: 1000}, []scenarioWant{
+			{"range_1M_1000", "ExplainOnly", "SELECT * FROM t1M WHERE b BETWEEN 999000 AND 999999", 1000},
+			{"range_1M_1000", "IndexRange", "SELECT /*+ FORCE_INDEX(t1M, b) */ * FROM t1M WHERE b BETWEEN 999000 AND 999999", 1000},
+			{"range_1M_1000", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M, b) */ * FROM t1M WHERE b BETWEEN 999000 AND 999999", 1000},
+		}},
+		{"join", 1000, "rx7a", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"join_1K_100", "ExplainOnly", "SELECT t.id, t.b, j.c FROM t1K_rx7a t JOIN t1K_rx7a_join j ON j.id = t.id WHERE t.b = 100", 100},
+			{"join_1K_100", "IndexJoin", "SELECT /*+ INL_JOIN(j) */ t.id, t.b, j.c FROM t1K_rx7a t JOIN t1K_rx7a_join j ON j.id = t.id WHERE t.b = 100", 100},
+			{"join_1K_100", "HashJoin", "SELECT /*+ HASH_JOIN(t, j) */ t.id, t.b, j.c FROM t1K_rx7a t JOIN t1K_rx7a_join j ON j.id = t.id WHERE t.b = 100", 100},
+			{"join_1K_100", "MergeJoin", "SELECT /*+ MERGE_JOIN(t, j) */ t.id, t.b, j.c FROM t1K_rx7a t JOIN t1K_rx7a_join j ON j.id = t.id WHERE t.b = 100", 100},
+		}},
+		{"agg", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"agg_1K_100", "ExplainOnly", "SELECT b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 900000 AND 999999 GROUP BY b", 100},
+			{"agg_1K_100", "StreamAgg", "SELECT /*+ STREAM_AGG() */ b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 900000 AND 999999 GROUP BY b", 100},
+			{"agg_1K_100", "StreamAggPushdown", "SELECT /*+ STREAM_AGG() AGG_TO_COP() */ b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 900000 AND 999999 GROUP BY b", 100},
+			{"agg_1K_100", "HashAgg", "SELECT /*+ HASH_AGG() */ b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 900000 AND 999999 GROUP BY b", 100},
+			{"agg_1K_100", "HashAggPushdown", "SELECT /*+ HASH_AGG() AGG_TO_COP() */ b, COUNT(*), SUM(id) FROM t1K WHERE b BETWEEN 900000 AND 999999 GROUP BY b", 100},
+		}},
+		{"count", 1000, "", []float64{0.1, 0.2}, MatrixOptions{}, []scenarioWant{
+			{"count_1K_100", "ExplainOnly", "SELECT COUNT(*) FROM t1K WHERE b = 100", 100},
+			{"count_1K_100", "IndexReader", "SELECT /*+ FORCE_INDEX(t1K, b) */ COUNT(*) FROM t1K WHERE b = 100", 100},
+			{"count_1K_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ COUNT(*) FROM t1K WHERE b = 100", 100},
+			{"countall_1K_1000", "ExplainOnly", "SELECT COUNT(*) FROM t1K", 1000},
+			{"countall_1K_1000", "IndexReader", "SELECT /*+ FORCE_INDEX(t1K, b) */ COUNT(*) FROM t1K", 1000},
+			{"countall_1K_1000", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ COUNT(*) FROM t1K", 1000},
+			{"count_1K_200", "ExplainOnly", "SELECT COUNT(*) FROM t1K WHERE b = 200", 200},
+			{"count_1K_200", "IndexReader", "SELECT /*+ FORCE_INDEX(t1K, b) */ COUNT(*) FROM t1K WHERE b = 200", 200},
+			{"count_1K_200", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ COUNT(*) FROM t1K WHERE b = 200", 200},
+		}},
+		{"topn", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"topn_1K_100", "ExplainOnly", "SELECT * FROM t1K ORDER BY b LIMIT 100", 100},
+			{"topn_1K_100", "IndexKeepOrder", "SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K ORDER BY b LIMIT 100", 100},
+			{"topn_1K_100", "TableScanTopN", "SELECT /*+ IGNORE_INDEX(t1K, b) */ * FROM t1K ORDER BY b LIMIT 100", 100},
+		}},
+		{"covering", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"covering_1K_100", "ExplainOnly", "SELECT id, b FROM t1K WHERE b = 100", 100},
+			{"covering_1K_100", "IndexReader", "SELECT /*+ FORCE_INDEX(t1K, b) */ id, b FROM t1K WHERE b = 100", 100},
+			{"covering_1K_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K, b) */ id, b FROM t1K WHERE b = 100", 100},
+		}},
+		{"indexmerge", 1000, "", []float64{0.1}, MatrixOptions{}, []scenarioWant{
+			{"indexmerge_1K_200", "ExplainOnly", "SELECT id, b, c FROM t1K_imerge WHERE b = 100 OR d < 100000", 200},
+			{"indexmerge_1K_200", "IndexMerge", "SELECT /*+ USE_INDEX_MERGE(t1K_imerge, b, d) */ id, b, c FROM t1K_imerge WHERE b = 100 OR d < 100000", 200},
+			{"indexmerge_1K_200", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K_imerge, b, d) */ id, b, c FROM t1K_imerge WHERE b = 100 OR d < 100000", 200},
+		}},
+		{"pointget", 1000000, "", []float64{0.5}, MatrixOptions{}, nil},
+		{"pointget", 1000, "", []float64{1, 4}, MatrixOptions{}, []scenarioWant{
+			{"pointget_1K_1", "ExplainOnly", "SELECT * FROM t1K WHERE id = 501", 1},
+			{"pointget_1K_1", "PointGet", "SELECT * FROM t1K WHERE id = 501", 1},
+			{"pointget_1K_1", "TableScan", "SELECT * FROM t1K WHERE id + 0 = 501", 1},
+			{"pointget_1K_4", "ExplainOnly", "SELECT * FROM t1K WHERE id IN (1, 251, 501, 751)", 4},
+			{"pointget_1K_4", "BatchPointGet", "SELECT * FROM t1K WHERE id IN (1, 251, 501, 751)", 4},
+			{"pointget_1K_4", "TableScan", "SELECT * FROM t1K WHERE id + 0 IN (1, 251, 501, 751)", 4},
+		}},
+		{"inlist", 1000000, "", []float64{100}, MatrixOptions{InListLengths: []int{1, 4}}, []scenarioWant{
+			{"inlist1_1M_100", "ExplainOnly", "SELECT * FROM t1M WHERE b IN (100)", 100},
+			{"inlist1_1M_100", "Index", "SELECT /*+ FORCE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (100)", 100},
+			{"inlist1_1M_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (100)", 100},
+			{"inlist4_1M_103", "ExplainOnly", "SELECT * FROM t1M WHERE b IN (100, 875000, 750000, 625000)", 103},
+			{"inlist4_1M_103", "Index", "SELECT /*+ FORCE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (100, 875000, 750000, 625000)", 103},
+			{"inlist4_1M_103", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (100, 875000, 750000, 625000)", 103},
+		}},
+		// The b values of the point cells are left out of the other values
+		{"inlist", 1000000, "", []float64{750000}, MatrixOptions{InListLengths: []int{4}}, []scenarioWant{
+			{"inlist4_1M_750003", "ExplainOnly", "SELECT * FROM t1M WHERE b IN (750000, 875000, 749999, 625000)", 750003},
+			{"inlist4_1M_750003", "Index", "SELECT /*+ FORCE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (750000, 875000, 749999, 625000)", 750003},
+			{"inlist4_1M_750003", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M, b) */ * FROM t1M WHERE b IN (750000, 875000, 749999, 625000)", 750003},
+		}},
+		{"composite", 1000000, "", []float64{100}, MatrixOptions{}, []scenarioWant{
+			{"compositefull_1M_50", "ExplainOnly", "SELECT * FROM t1M_composite WHERE b = 100 AND c < '0.5'", 50},
+			{"compositefull_1M_50", "Index", "SELECT /*+ FORCE_INDEX(t1M_composite, bc) */ * FROM t1M_composite WHERE b = 100 AND c < '0.5'", 50},
+			{"compositefull_1M_50", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M_composite, b, bc) */ * FROM t1M_composite WHERE b = 100 AND c < '0.5'", 50},
+			{"compositeprefix_1M_100", "ExplainOnly", "SELECT * FROM t1M_composite WHERE b = 100", 100},
+			{"compositeprefix_1M_100", "Index", "SELECT /*+ FORCE_INDEX(t1M_composite, bc) */ * FROM t1M_composite WHERE b = 100", 100},
+			{"compositeprefix_1M_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M_composite, b, bc) */ * FROM t1M_composite WHERE b = 100", 100},
+			{"compositenonleading_1M_100", "ExplainOnly", "SELECT * FROM t1M_composite WHERE c < '0.0001'", 100},
+			{"compositenonleading_1M_100", "Index", "SELECT /*+ FORCE_INDEX(t1M_composite, bc) */ * FROM t1M_composite WHERE c < '0.0001'", 100},
+			{"compositenonleading_1M_100", "TableScan", "SELECT /*+ IGNORE_INDEX(t1M_composite, b, bc) */ * FROM t1M_composite WHERE c < '0.0001'", 100},
+		}},
+		{"dml", 1000, "", []float64{10}, MatrixOptions{}, []scenarioWant{
+			{"dmlupdate_1K_10", "ExplainOnly", "UPDATE t1K SET c = REVERSE(c) WHERE b = 10", 10},
+			{"dmlupdate_1K_10", "Index", "UPDATE /*+ FORCE_INDEX(t1K, b) */ t1K SET c = REVERSE(c) WHERE b = 10", 10},
+			{"dmlupdate_1K_10", "TableScan", "UPDATE /*+ IGNORE_INDEX(t1K, b) */ t1K SET c = REVERSE(c) WHERE b = 10", 10},
+			{"dmldelete_1K_10", "ExplainOnly", "DELETE FROM t1K WHERE b = 10", 10},
+			{"dmldelete_1K_10", "Index", "DELETE /*+ FORCE_INDEX(t1K, b) */ FROM t1K WHERE b = 10", 10},
+			{"dmldelete_1K_10", "TableScan", "DELETE /*+ IGNORE_INDEX(t1K, b) */ FROM t1K WHERE b = 10", 10},
+		}},
+		{"dml", 1000000, "", []float64{0.1}, MatrixOptions{}, nil},
+		{"unique", 1000, "", []float64{1, 2}, MatrixOptions{}, []scenarioWant{
+			{"unique_1K_1", "ExplainOnly", "SELECT * FROM t1K_unique WHERE uu = 2654435761", 1},
+			{"unique_1K_1", "Index", "SELECT /*+ FORCE_INDEX(t1K_unique, uu) */ * FROM t1K_unique WHERE uu = 2654435761", 1},
+			{"unique_1K_1", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K_unique, uu) */ * FROM t1K_unique WHERE uu = 2654435761", 1},
+			{"nonunique_1K_1", "ExplainOnly", "SELECT * FROM t1K_unique WHERE un = 2654435761", 1},
+			{"nonunique_1K_1", "Index", "SELECT /*+ FORCE_INDEX(t1K_unique, un) */ * FROM t1K_unique WHERE un = 2654435761", 1},
+			{"nonunique_1K_1", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K_unique, un) */ * FROM t1K_unique WHERE un = 2654435761", 1},
+			{"unique_1K_2", "ExplainOnly", "SELECT * FROM t1K_unique WHERE uu IN (2654435761, 2727421797)", 2},
+			{"unique_1K_2", "Index", "SELECT /*+ FORCE_INDEX(t1K_unique, uu) */ * FROM t1K_unique WHERE uu IN (2654435761, 2727421797)", 2},
+			{"unique_1K_2", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K_unique, uu) */ * FROM t1K_unique WHERE uu IN (2654435761, 2727421797)", 2},
+			{"nonunique_1K_2", "ExplainOnly", "SELECT * FROM t1K_unique WHERE un IN (2654435761, 2727421797)", 2},
+			{"nonunique_1K_2", "Index", "SELECT /*+ FORCE_INDEX(t1K_unique, un) */ * FROM t1K_unique WHERE un IN (2654435761, 2727421797)", 2},
+			{"nonunique_1K_2", "TableScan", "SELECT /*+ IGNORE_INDEX(t1K_unique, un) */ * FROM t1K_unique WHERE un IN (2654435761, 2727421797)", 2},
+		}},
+	} {
+		t.Run(fmt.Sprintf("%s_%d", tc.family, tc.rowCount), func(t *testing.T) {
+			checkScenarios(t, GetTestScenarios(tableSpecs([]int{tc.rowCount}, tc.suffix, tc.opts), tc.selectivities, tc.opts, tc.family), tc.want)
+		})
+	}
+}
+
+func TestCountQueries(t *testing.T) {
+	scenarios := GetTestScenarios(tableSpecs([]int{1000}, "", MatrixOptions{}), []float64{0.1}, MatrixOptions{}, "point", "range", "agg")
+	expected := map[string]string{
+		"index_1K_100": "",
+		"range_1K_100": "SELECT /*+ FORCE_INDEX(t1K, b) */ COUNT(*) FROM t1K WHERE b BETWEEN 900000 AND 999999",
+		"agg_1K_100":   "SELECT /*+ FORCE_INDEX(t1K, b) */ COUNT(*), COUNT(DISTINCT b) FROM t1K WHERE b BETWEEN 900000 AND 999999",
+	}
+	for _, scenario := range scenarios {
+		if scenario.CountQuery != expected[scenario.ID] {
+			t.Fatalf("unexpected count query of %s %s: %s", scenario.ID, scenario.Variant, scenario.CountQuery)
+		}
+	}
+}
+
+func TestAggregatedResultsTableCount(t *testing.T) {
+	var results []*TestExecutionResult
+	for _, scenario := range GetTestScenarios(tableSpecs([]int{1000}, "", MatrixOptions{}), []float64{0.1}, MatrixOptions{}, "count") {
+		if parts := strings.Split(scenario.ID, "_"); len(parts) != 3 {
+			t.Fatalf("expected <family>_<size>_<matching>, got %s", scenario.ID)
+		}
+		results = append(results, &TestExecutionResult{
+			ScenarioID:  scenario.ID,
+			Variant:     scenario.Variant,
+			PlanType:    strings.ToLower(scenario.Variant),
+			ExplainOnly: scenario.ExplainOnly,
+			Plan:        &ExecutionPlan{ExecutionTime: time.Millisecond},
+		})
+	}
+	// Also printed on an interrupt, it must not fail on any scenario ID
+	outputAggregatedResultsTable(results)
+}
+
+func TestDistributionCountQueries(t *testing.T) {
+	g, err := parseDistribution("zipf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := tableSpecs([]int{1000}, "", MatrixOptions{Distribution: g})
+	families := []string{"ordered", "covering", "dml", "join", "indexmerge"}
+	scenarios := GetTestScenarios(tables, []float64{1}, MatrixOptions{}, families...)
+	seen := map[string]bool{}
+	for _, scenario := range scenarios {
+		seen[scenario.family] = true
+		// b keeps the distribution, the matching rows of every family are counted
+		if scenario.CountQuery == "" {
+			t.Fatalf("expected a count query of %s %s", scenario.ID, scenario.Variant)
+		}
+	}
+	for _, family := range families {
+		if !seen[family] {
+			t.Fatalf("expected scenarios of the %s family", family)
+		}
+	}
+}
+
+func TestParseFamilies(t *testing.T) {
+	if families, err := parseFamilies("point, range,"); err != nil || !slices.Equal(families, []string{"point", "range"}) {
+		t.Fatalf("unexpected families %v: %v", families, err)
+	}
+	if _, err := parseFamilies("point,bogus"); err == nil {
+		t.Fatalf("expected error for unknown family")
+	}
+}
+
+func TestWithHintDML(t *testing.T) {
+	if got := withHint("DELETE FROM t1K WHERE b = 10", "IGNORE_PLAN_CACHE()"); got != "DELETE /*+ IGNORE_PLAN_CACHE() */ FROM t1K WHERE b = 10" || !isDML(got) {
+		t.Fatalf("unexpected hinted delete %s", got)
+	}
+}
+
+func TestDeterminePlanTypeKeepOrder(t *testing.T) {
+	rangeScan := &ExecutionPlan{ID: "IndexRangeScan_8", OperatorInfo: "range:[100,100], keep order:false"}
+	plan := &ExecutionPlan{ID: "IndexLookUp_10", Next: rangeScan}
+	if got := determinePlanType(plan); got != "index_lookup" {
+		t.Fatalf("expected index_lookup, got %s", got)
+	}
+	rangeScan.OperatorInfo = "range:[100,100], keep order:true"
+	if got := determinePlanType(plan); got != "index_lookup_keep_order" {
+		t.Fatalf("expected index_lookup_keep_order, got %s", got)
+	}
+	sorted := &ExecutionPlan{ID: "Sort_5", Next: &ExecutionPlan{ID: "TableReader_9", Next: &ExecutionPlan{ID: "TableFullScan_8", OperatorInfo: "keep order:false"}}}
+	if got := determinePlanType(sorted); got != "table_scan" {
+		t.Fatalf("expected table_scan, got %s", got)
+	}
+}
+
+func TestDeterminePlanTypeJoin(t *testing.T) {
+	for id, expected := range map[string]string{
+		"IndexJoin_12":      "index_join",
+		"IndexHashJoin_14":  "index_join",
+		"IndexMergeJoin_16": "index_join",
+		"HashJoin_10":       "hash_join",
+		"MergeJoin_9":       "merge_join",
+	} {
+		plan := &ExecutionPlan{ID: "Projection_7", Next: &ExecutionPlan{ID: "└─" + id, Next: &ExecutionPlan{ID: "  ├─IndexLookUp_20"}}}
+		if got := determinePlanType(plan); got != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, id, got)
+		}
+	}
+}
+
+func TestDeterminePlanTypeScalarAgg(t *testing.T) {
+	plan := &ExecutionPlan{ID: "StreamAgg_17", Task: "root", OperatorInfo: "funcs:count(Column#6)->Column#4",
+		Next: &ExecutionPlan{ID: "└─IndexReader_18", Task: "root",
+			Next: &ExecutionPlan{ID: "  └─StreamAgg_9", Task: "cop[tikv]", OperatorInfo: "funcs:count(1)->Column#6",
+				Next: &ExecutionPlan{ID: "    └─IndexRangeScan_16", Task: "cop[tikv]"}}}}
+	count := TestScenario{family: "count"}
+	if got := scenarioPlanType(count, plan); got != "index_reader_stream_agg_pushdown" {
+		t.Fatalf("expected index_reader_stream_agg_pushdown, got %s", got)
+	}
+	// The boundary and null families keep the names of the aggregation method
+	if got := scenarioPlanType(TestScenario{family: "boundary"}, plan); got != "stream_agg_pushdown" {
+		t.Fatalf("expected stream_agg_pushdown outside the count family, got %s", got)
+	}
+	plan.Next.ID = "└─TableReader_18"
+	plan.Next.Next.Next.ID = "    └─TableFullScan_16"
+	if got := scenarioPlanType(count, plan); got != "table_scan_stream_agg_pushdown" {
+		t.Fatalf("expected table_scan_stream_agg_pushdown, got %s", got)
+	}
+	plan.OperatorInfo = "group by:test.t1K.b, funcs:count(Column#6)->Column#4"
+	if got := scenarioPlanType(count, plan); got != "stream_agg_pushdown" {
+		t.Fatalf("expected stream_agg_pushdown with GROUP BY, got %s", got)
+	}
+}
+
+func TestDeterminePlanTypeAgg(t *testing.T) {
+	scan := &ExecutionPlan{ID: "└─TableFullScan_9", Task: "cop[tikv]"}
+	root := &ExecutionPlan{ID: "HashAgg_6", Task: "root", Next: &ExecutionPlan{ID: "└─TableReader_10", Task: "root", Next: scan}}
+	if got := determinePlanType(root); got != "hash_agg" {
+		t.Fatalf("expected hash_agg, got %s", got)
+	}
+	root.Next.Next = &ExecutionPlan{ID: "  └─HashAgg_8", Task: "cop[tikv]", Next: scan}
+	if got := determinePlanType(root); got != "hash_agg_pushdown" {
+		t.Fatalf("expected hash_agg_pushdown, got %s", got)
+	}
+	root.ID = "StreamAgg_7"
+	if got := determinePlanType(root); got != "stream_agg_pushdown" {
+		t.Fatalf("expected stream_agg_pushdown, got %s", got)
+	}
+}
+
+func TestDeterminePlanTypeIndexReader(t *testing.T) {
+	plan := &ExecutionPlan{ID: "IndexReader_6", Next: &ExecutionPlan{ID: "└─IndexRangeScan_5", OperatorInfo: "range:[100,100], keep order:false"}}
+	if got := determinePlanType(plan); got != "index_reader" {
+		t.Fatalf("expected index_reader, got %s", got)
+	}
+	plan = &ExecutionPlan{ID: "Projection_4", Next: &ExecutionPlan{ID: "└─IndexLookUp_10", Next: &ExecutionPlan{ID: "  ├─IndexRangeScan_8"}}}
+	if got := determinePlanType(plan); got != "index_lookup" {
+		t.Fatalf("expected index_lookup, got %s", got)
+	}
+}
+
+func TestDeterminePlanTypeAccess(t *testing.T) {
+	for expected, plan := range map[string]*ExecutionPlan{
+		"index_merge":     {ID: "IndexMerge_9", Next: &ExecutionPlan{ID: "├─IndexRangeScan_5"}},
+		"point_get":       {ID: "Point_Get_1"},
+		"batch_point_get": {ID: "Batch_Point_Get_1"},
+		"index_lookup":    {ID: "Update_4", Next: &ExecutionPlan{ID: "└─IndexLookUp_11", Next: &ExecutionPlan{ID: "  ├─IndexRangeScan_9(Build)"}}},
+	} {
+		if got := determinePlanType(plan); got != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, plan.ID, got)
+		}
+	}
+}
+
+func TestParseInListLengths(t *testing.T) {
+	if lengths, err := parseInListLengths("1, 4"); err != nil || !slices.Equal(lengths, []int{1, 4}) {
+		t.Fatalf("unexpected IN-list lengths %v: %v", lengths, err)
+	}
+	if _, err := parseInListLengths("10,0"); err == nil {
+		t.Fatalf("expected an error for a zero IN-list length")
+	}
+}
+
+func TestUniqueValue(t *testing.T) {
+	if got := uniqueValue(1); got != 2654435761 {
+		t.Fatalf("expected 2654435761, got %d", got)
+	}
+	// The largest ids wrap around modulo 2^32 instead of overflowing
+	if got := uniqueValue(1<<32 + 1); got != 2654435761 {
+		t.Fatalf("expected the value of id 1 for id 2^32 + 1, got %d", got)
+	}
+	if got := uniqueValue(math.MaxInt32); got < 0 || got >= 1<<32 {
+		t.Fatalf("unique value %d out of range", got)
+	}
+}
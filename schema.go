@@ -0,0 +1,585 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TableSpec describes a generated test table
+type TableSpec struct {
+	RowCount int
+	// Suffix, if set, is appended to the table name, like the run ID in t1M_rx7a
+	Suffix string
+	// Nonclustered creates the table with a non-clustered primary key, its rows
+	// stored under a hidden _tidb_rowid, named like t1M_nc
+	Nonclustered bool
+	// Distribution is the name of the distribution of b, like zipf in t1M_zipf, empty for the default uniform values
+	Distribution string
+	// distribution is the distribution of b, recorded in the table comment
+	distribution *distGenerator
+	// nulls are the NULL ratios of b and c, recorded in the table comment
+	nulls map[string]float64
+	// FillerSize is the filler size of a row width sweep, like 1000 in t1M_f1000, 0 for the -f size of a single width run
+	FillerSize int
+	// Layout is the name of the row layout of -fillers and -b-position, like fill200x50_blast, empty for the default
+	Layout string
+	// layout is the row layout of the table, recorded in the table comment
+	layout FillerLayout
+	// PredicateValues is the number of b values per selectivity of -predicate-values, like 4 in t1M_v4, 0 for one
+	PredicateValues int
+}
+
+// Name returns the table name, like t1M, t1M_zipf, t1M_f1000, t1M_fill200_blast, t1M_v4, t1M_nc or t1M_rx7a
+func (t TableSpec) Name() string {
+	name := "t" + formatRowCountName(t.RowCount)
+	if t.Distribution != "" {
+		name += "_" + t.Distribution
+	}
+	if t.FillerSize > 0 {
+		name += fmt.Sprintf("_f%d", t.FillerSize)
+	}
+	if t.Layout != "" {
+		name += "_" + t.Layout
+	}
+	if t.PredicateValues > 1 {
+		name += fmt.Sprintf("_v%d", t.PredicateValues)
+	}
+	if t.Nonclustered {
+		name += "_nc"
+	}
+	if t.Suffix != "" {
+		name += "_" + t.Suffix
+	}
+	return name
+}
+
+// JoinName returns the name of the join partner table, a copy of the table used by the join family
+func (t TableSpec) JoinName() string {
+	return t.Name() + "_join"
+}
+
+// IndexMergeName returns the name of the copy of the table with a second index, used by the indexmerge family
+func (t TableSpec) IndexMergeName() string {
+	return t.Name() + "_imerge"
+}
+
+// CompositeName returns the name of the copy of the table with a composite index, used by the composite family
+func (t TableSpec) CompositeName() string {
+	return t.Name() + "_composite"
+}
+
+// UniqueName returns the name of the copy of the table with a unique and a non-unique index, used by the unique family
+func (t TableSpec) UniqueName() string {
+	return t.Name() + "_unique"
+}
+
+// PartitionedName returns the name of the partitioned copy of the table, like t1K_hash8
+func (t TableSpec) PartitionedName(spec *PartitionSpec) string {
+	return t.Name() + "_" + spec.Suffix()
+}
+
+// parsePrimaryKeyKinds parses the -pk value, clustered, nonclustered or both
+func parsePrimaryKeyKinds(s string) ([]string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "clustered":
+		return []string{"clustered"}, nil
+	case "nonclustered":
+		return []string{"nonclustered"}, nil
+	case "both":
+		return []string{"clustered", "nonclustered"}, nil
+	}
+	return nil, fmt.Errorf("unknown primary key kind '%s', expected clustered, nonclustered or both", s)
+}
+
+// parseFillerSizes parses the comma-separated -f filler sizes
+func parseFillerSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		size, err := strconv.Atoi(part)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid filler size '%s'", part)
+		}
+		if slices.Contains(sizes, size) {
+			return nil, fmt.Errorf("duplicate filler size %d", size)
+		}
+		sizes = append(sizes, size)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no filler sizes given")
+	}
+	return sizes, nil
+}
+
+// FillerLayout is the row layout of the test tables, set by -fillers and
+// -b-position: extra filler columns c2, c3, ... of the given sizes after c,
+// and b either first, before the fillers, or last, at the end of the row, to
+// measure whether the position of the predicate column affects the scan cost
+type FillerLayout struct {
+	Sizes []int
+	BLast bool
+}
+
+// parseBPosition parses the -b-position value, first or last, returning true for last
+func parseBPosition(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "first":
+		return false, nil
+	case "last":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown b position '%s', expected first or last", s)
+}
+
+// sizes returns the extra filler sizes joined by sep
+func (l FillerLayout) sizes(sep string) string {
+	sizes := make([]string, 0, len(l.Sizes))
+	for _, size := range l.Sizes {
+		sizes = append(sizes, strconv.Itoa(size))
+	}
+	return strings.Join(sizes, sep)
+}
+
+// parseFillerColumns parses the comma-separated -fillers sizes of the extra filler columns
+func parseFillerColumns(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		size, err := strconv.Atoi(part)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid filler column size '%s'", part)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// name returns the table name part of the layout, like fill200x50_blast, empty for the default layout
+func (l FillerLayout) name() string {
+	var parts []string
+	if len(l.Sizes) > 0 {
+		parts = append(parts, "fill"+l.sizes("x"))
+	}
+	if l.BLast {
+		parts = append(parts, "blast")
+	}
+	return strings.Join(parts, "_")
+}
+
+// params returns the generation parameters of the layout recorded in the table comment, like "fillers=200,50 b=last"
+func (l FillerLayout) params() string {
+	var params []string
+	if len(l.Sizes) > 0 {
+		params = append(params, "fillers="+l.sizes(","))
+	}
+	if l.BLast {
+		params = append(params, "b=last")
+	}
+	return strings.Join(params, " ")
+}
+
+// columnDefinitions returns the definitions of the columns after id, in row order
+func (l FillerLayout) columnDefinitions(fillerSize int) string {
+	columns := []string{fmt.Sprintf("c varchar(%d)", fillerVarcharSize(fillerSize))}
+	for i, size := range l.Sizes {
+		columns = append(columns, fmt.Sprintf("c%d varchar(%d)", i+2, fillerVarcharSize(size)))
+	}
+	if l.BLast {
+		columns = append(columns, "b int")
+	} else {
+		columns = append([]string{"b int"}, columns...)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// fillerColumns returns the names and generators of the filler columns, c and the extra ones
+func (l FillerLayout) fillerColumns(fillerSize int) ([]string, []ColumnGenerator) {
+	columns := []string{"c"}
+	gens := []ColumnGenerator{getColumnGenerator("c", fillerGenerator{size: fillerSize})}
+	for i, size := range l.Sizes {
+		column := fmt.Sprintf("c%d", i+2)
+		columns = append(columns, column)
+		gens = append(gens, getColumnGenerator(column, fillerGenerator{size: size}))
+	}
+	return columns, gens
+}
+
+// tableSpecs returns the specs of the tables for the given row counts, one
+// per filler size of a row width sweep and primary key kind of the matrix
+func tableSpecs(rowCounts []int, suffix string, opts MatrixOptions) []TableSpec {
+	primaryKeyKinds := opts.primaryKeyKinds()
+	fillerSizes := opts.FillerSizes
+	if len(fillerSizes) == 0 {
+		fillerSizes = []int{0}
+	}
+	tables := make([]TableSpec, 0, len(rowCounts)*len(fillerSizes)*len(primaryKeyKinds))
+	for _, rows := range rowCounts {
+		for _, fillerSize := range fillerSizes {
+			for _, kind := range primaryKeyKinds {
+				table := TableSpec{RowCount: rows, Suffix: suffix, Nonclustered: kind == "nonclustered", FillerSize: fillerSize}
+				table.nulls = opts.NullRatios
+				if opts.Distribution != nil {
+					table.Distribution, table.distribution = opts.Distribution.name(), opts.Distribution
+				}
+				table.Layout, table.layout = opts.Layout.name(), opts.Layout
+				if opts.PredicateValues > 1 {
+					table.PredicateValues = opts.PredicateValues
+				}
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+// fillerSizeOr returns the filler size of the table in a row width sweep, or the given -f size
+func (t TableSpec) fillerSizeOr(fillerSize int) int {
+	if t.FillerSize > 0 {
+		return t.FillerSize
+	}
+	return fillerSize
+}
+
+// clustering returns the primary key clustering keyword of the table
+func (t TableSpec) clustering() string {
+	if t.Nonclustered {
+		return "NONCLUSTERED"
+	}
+	return "CLUSTERED"
+}
+
+// tableSchema is the part of a table definition that must match for an
+// existing table to be reused
+type tableSchema struct {
+	Comment string
+	// PKType is the TIDB_PK_TYPE, CLUSTERED or NONCLUSTERED
+	PKType  string
+	Columns []string
+	Indexes []string
+}
+
+// createTableStatement returns the CREATE TABLE statement for a test table
+func createTableStatement(table TableSpec, fillerSize int) string {
+	fillerSize = table.fillerSizeOr(fillerSize)
+	createStmt := fmt.Sprintf(IndexVsTableSchemaFmt, table.Name(), table.clustering(), table.layout.columnDefinitions(fillerSize))
+	return createStmt + fmt.Sprintf(" COMMENT '%s'", table.comment(fillerSize))
+}
+
+// comment returns the table comment of a test table, recording the
+// generation parameters not visible in the columns
+func (t TableSpec) comment(fillerSize int) string {
+	params := fmt.Sprintf("filler=%d", t.fillerSizeOr(fillerSize))
+	if t.distribution != nil {
+		params += " b:" + t.distribution.String()
+	}
+	if len(t.nulls) > 0 {
+		params += " nulls=" + formatNullRatios(t.nulls)
+	}
+	if t.Layout != "" {
+		params += " " + t.layout.params()
+	}
+	if t.PredicateValues > 1 {
+		params += fmt.Sprintf(" values=%d", t.PredicateValues)
+	}
+	if gens := generatorParams(); gens != "" {
+		params += " " + gens
+	}
+	return tableComment(params)
+}
+
+// fillerVarcharSize returns the varchar length of the filler column
+func fillerVarcharSize(fillerSize int) int {
+	// Use the next n^2 value af length of varchar()
+	return max(255, (1 << (bits.Len64(uint64(fillerSize)) + 1)))
+}
+
+// tableSchemaVersion is the version of the test table layout and comment.
+// Version 0 tables were created before the generation parameters were
+// recorded, and are adopted if their structure matches.
+const tableSchemaVersion = 1
+
+var tableCommentRegex = regexp.MustCompile(`^calibration v(\d+): (.*)$`)
+
+// tableComment returns the table comment recording the schema version and generation parameters
+func tableComment(params string) string {
+	return fmt.Sprintf("calibration v%d: %s", tableSchemaVersion, params)
+}
+
+// parseTableComment returns the schema version and generation parameters from a table comment
+func parseTableComment(comment string) (int, string) {
+	match := tableCommentRegex.FindStringSubmatch(comment)
+	if match == nil {
+		return 0, ""
+	}
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, ""
+	}
+	return version, match[2]
+}
+
+// migrateTable upgrades an existing table created by an older version of the
+// tool, if possible, or returns an error explaining why it cannot be reused
+func migrateTable(c *TiDBClient, tableName string, createStmt string) error {
+	actual, err := c.getTableSchema(tableName)
+	if err != nil {
+		return err
+	}
+	version, _ := parseTableComment(actual.Comment)
+	switch {
+	case version == tableSchemaVersion:
+		return nil
+	case version > tableSchemaVersion:
+		return fmt.Errorf("table %s was created by a newer version of this tool (table schema v%d, this tool supports up to v%d), upgrade the tool or use -recreate",
+			tableName, version, tableSchemaVersion)
+	}
+	return adoptTable(c, tableName, createStmt, actual)
+}
+
+// adoptTable adopts a table without generation parameters, created by the
+// first versions of this tool, if its columns and indexes are the ones it
+// would be created with, by recording the parameters of this run in its comment
+func adoptTable(c *TiDBClient, tableName string, createStmt string, actual *tableSchema) error {
+	expected, err := parseCreateTable(createStmt)
+	if err != nil {
+		return err
+	}
+	if diff := diffTableStructure(expected, actual); len(diff) > 0 {
+		return fmt.Errorf("table %s was created by an older version of this tool, that did not record the generation parameters, and does not match the requested schema, use -recreate:\n%s",
+			tableName, strings.Join(diff, "\n"))
+	}
+	slog.Info("Adopting table created by an older version of the tool", "table", tableName, "comment", expected.Comment)
+	_, err = c.ExecuteQuery(fmt.Sprintf("ALTER TABLE %s COMMENT = '%s'", tableName, expected.Comment))
+	if err != nil {
+		return fmt.Errorf("failed to migrate table %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// diffTableStructure returns the differences of the columns, indexes and
+// clustering of two schemas, ignoring the comment
+func diffTableStructure(expected, actual *tableSchema) []string {
+	withoutComment := *actual
+	withoutComment.Comment = expected.Comment
+	return diffTableSchema(expected, &withoutComment)
+}
+
+// getTableSchema reads the schema of a table from SHOW CREATE TABLE
+func (c *TiDBClient) getTableSchema(tableName string) (*tableSchema, error) {
+	query := "SHOW CREATE TABLE " + tableName
+	slog.Debug("Executing query", "query", query)
+	var name, createStmt string
+	if err := c.db.QueryRow(query).Scan(&name, &createStmt); err != nil {
+		return nil, fmt.Errorf("failed to get the schema of %s: %w", tableName, err)
+	}
+	return parseCreateTable(createStmt)
+}
+
+var (
+	intDisplayWidthRegex = regexp.MustCompile(`^((?:tiny|small|medium|big)?int)\(\d+\)`)
+	clusteringRegex      = regexp.MustCompile(`(?i)\b(NONCLUSTERED|CLUSTERED)\b`)
+	tableCommentOptRegex = regexp.MustCompile(`(?i)\bCOMMENT\s*=?\s*'((?:[^']|'')*)'`)
+)
+
+// parseCreateTable parses the parts of a CREATE TABLE statement compared by
+// the schema check, from both the statements of this tool and the normalized
+// output of SHOW CREATE TABLE, like
+//
+//	CREATE TABLE `t1K` (
+//	  `id` int NOT NULL AUTO_INCREMENT,
+//	  ...
+//	  PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */,
+//	  KEY `b` (`b`)
+//	) ENGINE=InnoDB ... COMMENT='calibration v1: filler=500'
+func parseCreateTable(createStmt string) (*tableSchema, error) {
+	stmt := strings.ReplaceAll(createStmt, "`", "")
+	open := strings.Index(stmt, "(")
+	if open < 0 {
+		return nil, fmt.Errorf("not a CREATE TABLE statement: %s", createStmt)
+	}
+	depth, end := 0, -1
+	var elements []string
+	last := open + 1
+	for i := open; i < len(stmt) && end < 0; i++ {
+		switch stmt[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+				elements = append(elements, stmt[last:i])
+			}
+		case ',':
+			if depth == 1 {
+				elements = append(elements, stmt[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in CREATE TABLE statement: %s", createStmt)
+	}
+	schema := &tableSchema{}
+	if match := tableCommentOptRegex.FindStringSubmatch(stmt[end:]); match != nil {
+		schema.Comment = strings.ReplaceAll(match[1], "''", "'")
+	}
+	var primary []string
+	for _, element := range elements {
+		element = strings.TrimSpace(element)
+		fields := strings.Fields(element)
+		if len(fields) == 0 {
+			continue
+		}
+		upper := strings.ToUpper(element)
+		keyword := strings.ToUpper(fields[0])
+		switch {
+		case strings.HasPrefix(upper, "PRIMARY KEY"):
+			primary = indexColumns(element)
+			schema.Indexes = append(schema.Indexes, fmt.Sprintf("unique index PRIMARY (%s)", strings.Join(primary, ",")))
+			schema.PKType = pkClustering(element, schema.PKType)
+		case keyword == "KEY" || keyword == "INDEX" || keyword == "UNIQUE":
+			unique := keyword == "UNIQUE"
+			if unique {
+				fields = fields[1:]
+				if len(fields) > 0 && (strings.EqualFold(fields[0], "KEY") || strings.EqualFold(fields[0], "INDEX")) {
+					fields = fields[1:]
+				}
+			} else {
+				fields = fields[1:]
+			}
+			columns := indexColumns(element)
+			// An unnamed index is named after its first column
+			name := columns[0]
+			if len(fields) > 0 && !strings.HasPrefix(fields[0], "(") {
+				name = strings.SplitN(fields[0], "(", 2)[0]
+			}
+			idx := fmt.Sprintf("index %s (%s)", name, strings.Join(columns, ","))
+			if unique {
+				idx = "unique " + idx
+			}
+			schema.Indexes = append(schema.Indexes, idx)
+		case keyword == "CONSTRAINT" || keyword == "FOREIGN" || keyword == "CHECK" || keyword == "FULLTEXT":
+		default:
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid column definition '%s'", strings.TrimSpace(element))
+			}
+			colType := intDisplayWidthRegex.ReplaceAllString(strings.ToLower(fields[1]), "$1")
+			if len(fields) > 2 && strings.EqualFold(fields[2], "unsigned") {
+				colType += " unsigned"
+			}
+			col := fmt.Sprintf("column %s %s", fields[0], colType)
+			if strings.Contains(upper, "PRIMARY KEY") {
+				primary = []string{fields[0]}
+				schema.Indexes = append(schema.Indexes, fmt.Sprintf("unique index PRIMARY (%s)", fields[0]))
+				schema.PKType = pkClustering(element, schema.PKType)
+				col += " NOT NULL"
+			} else if strings.Contains(upper, "NOT NULL") {
+				col += " NOT NULL"
+			}
+			schema.Columns = append(schema.Columns, col)
+		}
+	}
+	// Primary key columns are NOT NULL, whether declared or not
+	for i, col := range schema.Columns {
+		if fields := strings.Fields(col); slices.Contains(primary, fields[1]) && !strings.HasSuffix(col, " NOT NULL") {
+			schema.Columns[i] += " NOT NULL"
+		}
+	}
+	slices.Sort(schema.Indexes)
+	return schema, nil
+}
+
+// indexColumns returns the column names of an index definition, without prefix lengths
+func indexColumns(element string) []string {
+	open := strings.Index(element, "(")
+	if open < 0 {
+		return []string{""}
+	}
+	var columns []string
+	depth, last := 0, open+1
+	for i := open; i < len(element); i++ {
+		switch element[i] {
+		case '(':
+			depth++
+		case ')', ',':
+			if element[i] == ')' {
+				depth--
+			}
+			if depth == 0 || (depth == 1 && element[i] == ',') {
+				column := strings.Fields(element[last:i] + " ")
+				if len(column) > 0 {
+					columns = append(columns, strings.SplitN(column[0], "(", 2)[0])
+				}
+				last = i + 1
+			}
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	return columns
+}
+
+// pkClustering returns the primary key clustering of an element, CLUSTERED or
+// NONCLUSTERED, or the given default if it does not say
+func pkClustering(element, dflt string) string {
+	if match := clusteringRegex.FindStringSubmatch(element); match != nil {
+		return strings.ToUpper(match[1])
+	}
+	return dflt
+}
+
+// diffTableSchema returns the differences between the expected and actual schema,
+// prefixed with '-' for expected only and '+' for actual only
+func diffTableSchema(expected, actual *tableSchema) []string {
+	var diff []string
+	if expected.Comment != actual.Comment {
+		diff = append(diff, fmt.Sprintf("- comment '%s'", expected.Comment), fmt.Sprintf("+ comment '%s'", actual.Comment))
+	}
+	// A primary key without CLUSTERED or NONCLUSTERED gets the default of the cluster
+	if expected.PKType != "" && expected.PKType != actual.PKType {
+		diff = append(diff, fmt.Sprintf("- primary key %s", expected.PKType), fmt.Sprintf("+ primary key %s", actual.PKType))
+	}
+	diffLists := func(expected, actual []string) {
+		for _, e := range expected {
+			if !slices.Contains(actual, e) {
+				diff = append(diff, "- "+e)
+			}
+		}
+		for _, a := range actual {
+			if !slices.Contains(expected, a) {
+				diff = append(diff, "+ "+a)
+			}
+		}
+	}
+	diffLists(expected.Columns, actual.Columns)
+	diffLists(expected.Indexes, actual.Indexes)
+	return diff
+}
+
+// checkTableSchema compares an existing table with the schema it would be
+// created with, the parsed CREATE TABLE statement against SHOW CREATE TABLE.
+// Returns the differences, empty if the table can be reused.
+func checkTableSchema(c *TiDBClient, tableName string, createStmt string) ([]string, error) {
+	expected, err := parseCreateTable(createStmt)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := c.getTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return diffTableSchema(expected, actual), nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffTableSchema(t *testing.T) {
+	expected := &tableSchema{
+		Comment: "calibration v1: filler=100",
+		PKType:  "CLUSTERED",
+		Columns: []string{"column id int NOT NULL", "column b int", "column c varchar(255)"},
+		Indexes: []string{"index b (b)", "unique index PRIMARY (id)"},
+	}
+	if diff := diffTableSchema(expected, expected); len(diff) != 0 {
+		t.Fatalf("expected no diff, got %v", diff)
+	}
+	actual := &tableSchema{
+		Comment: "calibration v1: filler=500",
+		PKType:  "NONCLUSTERED",
+		Columns: []string{"column id int NOT NULL", "column b int", "column c varchar(1024)"},
+		Indexes: []string{"unique index PRIMARY (id)"},
+	}
+	diff := diffTableSchema(expected, actual)
+	want := []string{
+		"- comment 'calibration v1: filler=100'",
+		"+ comment 'calibration v1: filler=500'",
+		"- primary key CLUSTERED",
+		"+ primary key NONCLUSTERED",
+		"- column c varchar(255)",
+		"+ column c varchar(1024)",
+		"- index b (b)",
+	}
+	if !slices.Equal(diff, want) {
+		t.Fatalf("expected %v, got %v", want, diff)
+	}
+}
+
+func TestParseCreateTable(t *testing.T) {
+	createStmt := createTableStatement(TableSpec{RowCount: 1000}, 500)
+	expected, err := parseCreateTable(createStmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &tableSchema{
+		Comment: "calibration v1: filler=500",
+		PKType:  "CLUSTERED",
+		Columns: []string{"column id int NOT NULL", "column b int", "column c varchar(1024)"},
+		Indexes: []string{"index b (b)", "unique index PRIMARY (id)"},
+	}
+	if diff := diffTableSchema(want, expected); len(diff) != 0 {
+		t.Fatalf("unexpected schema of %s: %v", createStmt, diff)
+	}
+
+	shown := "CREATE TABLE `t1K` (\n" +
+		"  `id` int(11) NOT NULL AUTO_INCREMENT,\n" +
+		"  `b` int(11) DEFAULT NULL,\n" +
+		"  `c` varchar(1024) DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`) /*T![clustered_index] CLUSTERED */,\n" +
+		"  KEY `b` (`b`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin AUTO_INCREMENT=30001 COMMENT='calibration v1: filler=500'"
+	actual, err := parseCreateTable(shown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffTableSchema(expected, actual); len(diff) != 0 {
+		t.Fatalf("expected SHOW CREATE TABLE to match the create statement, got %v", diff)
+	}
+
+	// The tables of the first versions of the tool have no comment, but the same structure
+	baseline := strings.Replace(shown, " COMMENT='calibration v1: filler=500'", "", 1)
+	if actual, err = parseCreateTable(baseline); err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffTableStructure(expected, actual); len(diff) != 0 {
+		t.Fatalf("expected the baseline table to be adoptable, got %v", diff)
+	}
+	nonclustered := strings.Replace(baseline, "/*T![clustered_index] CLUSTERED */", "/*T![clustered_index] NONCLUSTERED */", 1)
+	if actual, err = parseCreateTable(nonclustered); err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffTableStructure(expected, actual); !slices.Equal(diff, []string{"- primary key CLUSTERED", "+ primary key NONCLUSTERED"}) {
+		t.Fatalf("unexpected diff %v", diff)
+	}
+
+	unique, err := parseCreateTable("CREATE TABLE {table} (id bigint unsigned PRIMARY KEY, u int, v varchar(10), UNIQUE KEY uk(u), INDEX (v(4)))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(unique.Indexes, []string{"index v (v)", "unique index PRIMARY (id)", "unique index uk (u)"}) ||
+		unique.Columns[0] != "column id bigint unsigned NOT NULL" || unique.PKType != "" {
+		t.Fatalf("unexpected schema %+v", unique)
+	}
+}
+
+func TestPrimaryKeyKinds(t *testing.T) {
+	if _, err := parsePrimaryKeyKinds("heap"); err == nil {
+		t.Fatalf("expected an error for an unknown primary key kind")
+	}
+	kinds, err := parsePrimaryKeyKinds("both")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := tableSpecs([]int{1000}, "rx7a", MatrixOptions{PrimaryKeys: kinds})
+	if len(tables) != 2 || tables[0].Name() != "t1K_rx7a" || tables[1].Name() != "t1K_nc_rx7a" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	if stmt := createTableStatement(tables[1], 100); !strings.Contains(stmt, "CREATE TABLE t1K_nc_rx7a (id int AUTO_INCREMENT PRIMARY KEY NONCLUSTERED,") {
+		t.Fatalf("unexpected create table statement %s", stmt)
+	}
+	scenarios := GetTestScenarios(tables, []float64{10}, MatrixOptions{})
+	if len(scenarios) != 6 || scenarios[0].ID != "index_1K_10" || scenarios[3].ID != "ncindex_1K_10" || scenarios[3].TableName != "t1K_nc_rx7a" {
+		t.Fatalf("unexpected scenarios %+v", scenarios)
+	}
+}
+
+func TestParseTableComment(t *testing.T) {
+	for _, tc := range []struct {
+		comment string
+		version int
+		params  string
+	}{
+		{tableComment("filler=100"), tableSchemaVersion, "filler=100"},
+		{"calibration: filler=500", 0, ""},
+		{"", 0, ""},
+		{"some user comment", 0, ""},
+		{"calibration v7: filler=100", 7, "filler=100"},
+	} {
+		version, params := parseTableComment(tc.comment)
+		if version != tc.version || params != tc.params {
+			t.Fatalf("%q: expected v%d %q, got v%d %q", tc.comment, tc.version, tc.params, version, params)
+		}
+	}
+}
+
+func TestFillerSizeSweep(t *testing.T) {
+	if _, err := parseFillerSizes("100,x"); err == nil {
+		t.Fatalf("expected an error for an invalid filler size")
+	}
+	if _, err := parseFillerSizes("100,100"); err == nil {
+		t.Fatalf("expected an error for a duplicate filler size")
+	}
+	sizes, err := parseFillerSizes("16, 4000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := tableSpecs([]int{1000}, "", MatrixOptions{FillerSizes: sizes})
+	if len(tables) != 2 || tables[0].Name() != "t1K_f16" || tables[1].Name() != "t1K_f4000" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	if stmt := createTableStatement(tables[1], 100); !strings.Contains(stmt, "c varchar(8192)") || !strings.Contains(stmt, "filler=4000") {
+		t.Fatalf("expected the sweep filler size, got %s", stmt)
+	}
+	scenarios := GetTestScenarios(tables, []float64{10}, MatrixOptions{})
+	if len(scenarios) != 6 || scenarios[0].ID != "f16index_1K_10" || scenarios[3].ID != "f4000index_1K_10" {
+		t.Fatalf("unexpected scenarios %+v", scenarios)
+	}
+}
+
+func TestFillerLayout(t *testing.T) {
+	if got := (FillerLayout{}).columnDefinitions(100); got != "b int, c varchar(256)" {
+		t.Errorf("unexpected default columns %s", got)
+	}
+	layout := FillerLayout{Sizes: []int{200, 1000}, BLast: true}
+	if got := layout.columnDefinitions(100); got != "c varchar(256), c2 varchar(512), c3 varchar(2048), b int" {
+		t.Errorf("unexpected columns %s", got)
+	}
+	if layout.name() != "fill200x1000_blast" || layout.params() != "fillers=200,1000 b=last" {
+		t.Errorf("unexpected name %s or params %s", layout.name(), layout.params())
+	}
+	if columns, gens := layout.fillerColumns(100); len(columns) != 3 || len(gens) != 3 || columns[2] != "c3" {
+		t.Errorf("unexpected filler columns %v", columns)
+	}
+	if (FillerLayout{BLast: true}).name() != "blast" || (FillerLayout{}).name() != "" {
+		t.Errorf("unexpected layout names")
+	}
+	if _, err := parseFillerColumns("200,0"); err == nil {
+		t.Errorf("expected an error for an empty filler column")
+	}
+	if _, err := parseBPosition("middle"); err == nil {
+		t.Errorf("expected an error for an unknown position")
+	}
+	table := tableSpecs([]int{1000}, "", MatrixOptions{Layout: layout})[0]
+	if stmt := createTableStatement(table, 100); table.Name() != "t1K_fill200x1000_blast" ||
+		!strings.Contains(stmt, "c3 varchar(2048), b int") || !strings.HasSuffix(stmt, "filler=100 fillers=200,1000 b=last'") {
+		t.Errorf("unexpected table %s: %s", table.Name(), stmt)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+// keyringService is the OS keyring service the TiDB passwords are stored under
+const keyringService = "tidb-optimizer-calibration"
+
+// keyringAccount returns the keyring account name for a connection, so each cluster and user has its own entry
+func keyringAccount(config *TiDBConfig) string {
+	return fmt.Sprintf("%s@%s:%d", config.User, config.Host, config.Port)
+}
+
+// resolvePassword fills in the connection password from the OS keyring or an
+// interactive prompt. A prompted password is stored in the keyring if it is used.
+func resolvePassword(config *TiDBConfig, askPass, useKeyring bool) error {
+	if useKeyring && !askPass && config.Password == "" {
+		password, err := keyring.Get(keyringService, keyringAccount(config))
+		switch {
+		case err == nil:
+			config.Password = password
+			return nil
+		case errors.Is(err, keyring.ErrNotFound):
+			slog.Info("No password in the keyring", "account", keyringAccount(config))
+		default:
+			return fmt.Errorf("failed to read the password from the keyring: %w", err)
+		}
+	}
+	if !askPass {
+		return nil
+	}
+	password, err := promptPassword(fmt.Sprintf("Password for %s: ", keyringAccount(config)))
+	if err != nil {
+		return err
+	}
+	config.Password = password
+	if useKeyring {
+		if err = keyring.Set(keyringService, keyringAccount(config), password); err != nil {
+			return fmt.Errorf("failed to store the password in the keyring: %w", err)
+		}
+		slog.Info("Stored the password in the keyring", "account", keyringAccount(config))
+	}
+	return nil
+}
+
+// promptPassword reads a password from the terminal without echoing it
+func promptPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("-ask-pass requires an interactive terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// runSeed is the -seed of the run, 0 if the run is not reproducible
+var runSeed int64
+
+// orderRng is the random source of the scenario order, and dataRng the one of
+// the data generation, seeded by -seed, or by the time. They are apart, so a
+// run setting up the tables gives the same scenario order as one reusing them.
+var (
+	orderRng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	dataRng  = rand.New(rand.NewSource(time.Now().UnixNano() + 1))
+)
+
+// The streams of the seeded SQL random numbers, apart from the ones of the
+// distribution generators
+const (
+	randomIntStream = 100
+	fillerStream    = 101
+	// dataStream seeds dataRng
+	dataStream = 102
+)
+
+// setSeed makes the run reproducible: the scenario order, the generated data
+// and the rows picked for the selectivities and NULL values
+func setSeed(seed int64) {
+	runSeed = seed
+	orderRng = rand.New(rand.NewSource(seed))
+	sum := md5.Sum(fmt.Appendf(nil, "%d-%d", seed, dataStream))
+	dataRng = rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+}
+
+// sqlRand returns a SQL expression for a random number in [0, 1) for the
+// row: RAND(), or with -seed a hash of the seed, the stream and the row
+// number, so the same seed gives the same data whatever the batching
+func sqlRand(rowNum string, stream int) string {
+	if runSeed == 0 {
+		return "RAND()"
+	}
+	return distGenerator{seed: runSeed}.uniformExpr(rowNum, stream)
+}
+
+// seededRand returns the random number in (0, 1) of sqlRand for the row,
+// computed client side, so the LOAD DATA loader generates the same rows as
+// the INSERT statements. Without -seed it is taken from rng.
+func seededRand(rng *rand.Rand, row, stream int) float64 {
+	if runSeed == 0 {
+		return rng.Float64()
+	}
+	// The first 8 hex digits of the MD5 of uniformExpr
+	sum := md5.Sum(fmt.Appendf(nil, "%d-%d-%d", runSeed, stream, row))
+	return (float64(binary.BigEndian.Uint32(sum[:4])) + 0.5) / 4294967296
+}
+
+// sqlRandomOrder returns the ORDER BY expression of a random choice of rows:
+// RAND(), or with -seed a hash of the seed and the id
+func sqlRandomOrder() string {
+	if runSeed == 0 {
+		return "RAND()"
+	}
+	return fmt.Sprintf("MD5(CONCAT(%d, '-', id))", runSeed)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// scheduleOrder returns the order of the executions of a schedule
+func scheduleOrder(scenarios []TestScenario) []string {
+	var order []string
+	for run := range NewSchedule(scenarios, 3).All() {
+		order = append(order, run.Scenario.ID+"/"+run.Scenario.Variant)
+	}
+	return order
+}
+
+func TestSeed(t *testing.T) {
+	defer func(seed int64, order, data *rand.Rand) {
+		runSeed, orderRng, dataRng = seed, order, data
+	}(runSeed, orderRng, dataRng)
+	if got := (randomIntGenerator{max: 10}).SQLExpr("row"); got != "FLOOR(RAND() * 10)" {
+		t.Fatalf("unexpected unseeded expression %s", got)
+	}
+
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000, 10000}, []float64{0.1, 0.2})
+	setSeed(42)
+	first := scheduleOrder(scenarios)
+	setSeed(42)
+	// Setting up the tables does not change the scenario order
+	getRandomNotInList(nil)
+	if second := scheduleOrder(scenarios); !slices.Equal(first, second) {
+		t.Fatal("expected the same scenario order for the same seed")
+	}
+	if got := (randomIntGenerator{max: 10}).SQLExpr("row"); strings.Contains(got, "RAND()") || !strings.Contains(got, "CONCAT(42, '-', 100, '-', row)") {
+		t.Fatalf("expected a seeded expression, got %s", got)
+	}
+	if got := sqlRandomOrder(); got != "MD5(CONCAT(42, '-', id))" {
+		t.Fatalf("unexpected random order %s", got)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// selfTest is the tiny matrix of the selftest command
+var (
+	selfTestRows          = []int{1000}
+	selfTestSelectivities = []float64{10, 100, 0.5}
+	selfTestRepetitions   = 2
+)
+
+// SelfTestCheck is the outcome of one check of the selftest command
+type SelfTestCheck struct {
+	Name   string
+	Err    error
+	Detail string
+}
+
+// runSelfTest implements the selftest command: it runs a tiny matrix end to
+// end on a fresh table, and checks connectivity, plan parsing, hint
+// application, RU extraction and the output files, to verify the tool works
+// on a TiDB version before starting long runs
+func runSelfTest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	var logLevel = fs.String("l", "warn", "Log level: debug, info, warn, error")
+	var keep = fs.Bool("keep", false, "Keep the selftest table and output files, instead of removing them")
+	connectionConfig := registerConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	setupLogging(*logLevel)
+	config, err := connectionConfig()
+	if err != nil {
+		return err
+	}
+
+	var checks []SelfTestCheck
+	report := func() error {
+		outputSelfTest(checks)
+		failed := 0
+		for _, check := range checks {
+			if check.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
+		return nil
+	}
+
+	c := NewTiDBClient()
+	err = c.Connect(config)
+	if err == nil {
+		var version string
+		if err = c.db.QueryRow("SELECT VERSION()").Scan(&version); err == nil {
+			checks = append(checks, SelfTestCheck{Name: "connectivity", Detail: version})
+		}
+		c.Close()
+	}
+	if err != nil {
+		checks = append(checks, SelfTestCheck{Name: "connectivity", Err: err})
+		return report()
+	}
+
+	table := TableSpec{RowCount: selfTestRows[0], Suffix: "selftest"}
+	err = CheckAndSetupTables(selfTestRows, selfTestSelectivities, SetupOptions{
+		FillerSize:  100,
+		Recreate:    true,
+		TableSuffix: table.Suffix,
+		TiDB:        config,
+	})
+	if err != nil {
+		checks = append(checks, SelfTestCheck{Name: "table setup", Err: err})
+		return report()
+	}
+	checks = append(checks, SelfTestCheck{Name: "table setup", Detail: table.Name()})
+	if !*keep {
+		defer func() {
+			if err := DropTables([]TableSpec{table}, nil, config); err != nil {
+				fmt.Printf("⚠️  Failed to drop %s: %v\n", table.Name(), err)
+			}
+		}()
+	}
+
+	meta := NewRunMetadata("selftest", "")
+	results := RunOptimizerTests(selfTestRows, selfTestSelectivities, RunOptions{
+		Repetitions: selfTestRepetitions,
+		Metadata:    meta,
+		TableSuffix: table.Suffix,
+		TiDB:        config,
+		Families:    []string{"point"},
+	})
+	scenarios := GetTestScenarios(tableSpecs(selfTestRows, table.Suffix, MatrixOptions{}), selfTestSelectivities, MatrixOptions{}, "point")
+	checks = append(checks, checkSelfTestResults(scenarios, results, selfTestRepetitions)...)
+
+	dir, err := os.MkdirTemp("", "calibration-selftest")
+	if err == nil {
+		if !*keep {
+			defer os.RemoveAll(dir)
+		}
+		err = checkSelfTestOutput(dir, meta, results)
+	}
+	checks = append(checks, SelfTestCheck{Name: "output files", Err: err, Detail: dir})
+	return report()
+}
+
+// checkSelfTestResults checks the results of the selftest matrix: every
+// scheduled execution has a result with a parsed plan, the hinted variants
+// got the plan they force, and the executions report their RU
+func checkSelfTestResults(scenarios []TestScenario, results []*TestExecutionResult, repetitions int) []SelfTestCheck {
+	expected := NewSchedule(scenarios, repetitions).Len()
+	var unparsed, unhinted, noRU []string
+	executed := 0
+	for _, r := range results {
+		if r.Plan == nil || r.PlanType == "" || r.PlanType == "unknown" {
+			unparsed = append(unparsed, r.ScenarioID+"/"+r.Variant)
+		}
+		if r.ExplainOnly {
+			continue
+		}
+		executed++
+		if r.RU <= 0 {
+			noRU = append(noRU, r.ScenarioID+"/"+r.Variant)
+		}
+		switch {
+		case r.Variant == "Index" && !strings.HasPrefix(r.PlanType, "index"),
+			r.Variant == "TableScan" && r.PlanType != "table_scan":
+			unhinted = append(unhinted, fmt.Sprintf("%s/%s: %s", r.ScenarioID, r.Variant, r.PlanType))
+		}
+	}
+	check := func(name string, failed []string, detail string) SelfTestCheck {
+		if len(failed) > 0 {
+			return SelfTestCheck{Name: name, Err: fmt.Errorf("%d of %d: %s", len(failed), len(results), strings.Join(failed, ", "))}
+		}
+		return SelfTestCheck{Name: name, Detail: detail}
+	}
+	executions := SelfTestCheck{Name: "executions", Detail: fmt.Sprintf("%d of %d", len(results), expected)}
+	if len(results) != expected {
+		executions.Err = fmt.Errorf("%d of %d scheduled executions succeeded", len(results), expected)
+	}
+	return []SelfTestCheck{
+		executions,
+		check("plan parsing", unparsed, fmt.Sprintf("%d plans", len(results))),
+		check("hint application", unhinted, fmt.Sprintf("%d hinted executions", executed)),
+		check("RU extraction", noRU, fmt.Sprintf("%d executions", executed)),
+	}
+}
+
+// checkSelfTestOutput writes the JSON and CSV result files into dir, and
+// reads the JSON file back
+func checkSelfTestOutput(dir string, meta *RunMetadata, results []*TestExecutionResult) error {
+	path := filepath.Join(dir, "selftest.json")
+	if err := writeResultSet(path, meta, results); err != nil {
+		return err
+	}
+	rs, err := readResultSet(path)
+	if err != nil {
+		return err
+	}
+	if len(rs.Results) != len(results) {
+		return fmt.Errorf("read %d results back from %s, expected %d", len(rs.Results), path, len(results))
+	}
+	_, _, err = writeResultsCSV(filepath.Join(dir, "selftest"), results)
+	return err
+}
+
+// outputSelfTest prints the outcome of the selftest checks
+func outputSelfTest(checks []SelfTestCheck) {
+	fmt.Println("\n🩺 Self-Test")
+	fmt.Println("====================")
+	for _, check := range checks {
+		if check.Err != nil {
+			fmt.Printf("❌ %s\t%v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("✅ %s\t%s\n", check.Name, check.Detail)
+		}
+	}
+}
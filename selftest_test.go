@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckSelfTestResults(t *testing.T) {
+	scenarios := GetTestScenarios(tableSpecs([]int{1000}, "selftest", MatrixOptions{}), []float64{10}, MatrixOptions{}, "point")
+	plan := &ExecutionPlan{ID: "TableReader_7"}
+	var results []*TestExecutionResult
+	for _, s := range scenarios {
+		reps := 2
+		if s.ExplainOnly {
+			reps = 1
+		}
+		for range reps {
+			planType := "table_scan"
+			if s.Variant == "Index" {
+				planType = "index_lookup"
+			}
+			results = append(results, &TestExecutionResult{ScenarioID: s.ID, Variant: s.Variant, ExplainOnly: s.ExplainOnly,
+				Plan: plan, PlanType: planType, RU: 1})
+		}
+	}
+	for _, check := range checkSelfTestResults(scenarios, results, 2) {
+		if check.Err != nil {
+			t.Fatalf("unexpected failed check %s: %v", check.Name, check.Err)
+		}
+	}
+
+	// The Index variant fell back to a table scan, and an execution failed
+	results[1].PlanType = "table_scan"
+	results = results[:len(results)-1]
+	failed := make(map[string]bool)
+	for _, check := range checkSelfTestResults(scenarios, results, 2) {
+		failed[check.Name] = check.Err != nil
+	}
+	if !failed["executions"] || !failed["hint application"] || failed["plan parsing"] || failed["RU extraction"] {
+		t.Fatalf("unexpected checks %v", failed)
+	}
+}
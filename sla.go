@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLATarget is a latency objective for the scenarios matching Pattern,
+// like "p95 < 50ms"
+type SLATarget struct {
+	Pattern    *regexp.Regexp
+	Percentile float64
+	Limit      time.Duration
+	Text       string
+}
+
+var slaRegex = regexp.MustCompile(`^(?:(.+):)?p(\d+(?:\.\d+)?)\s*<\s*(\S+)$`)
+
+// parseSLATarget parses an SLA target like "p95<50ms" or "index_1M_.*:p99<200ms"
+func parseSLATarget(s string) (*SLATarget, error) {
+	match := slaRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return nil, fmt.Errorf("invalid SLA target '%s': expected [scenario-regex:]pNN<duration", s)
+	}
+	target := &SLATarget{Text: s}
+	pattern := match[1]
+	if pattern == "" {
+		pattern = ".*"
+	}
+	var err error
+	if target.Pattern, err = regexp.Compile("^" + pattern + "$"); err != nil {
+		return nil, fmt.Errorf("invalid SLA scenario pattern '%s': %w", pattern, err)
+	}
+	if target.Percentile, err = strconv.ParseFloat(match[2], 64); err != nil || target.Percentile <= 0 || target.Percentile > 100 {
+		return nil, fmt.Errorf("invalid SLA percentile '%s'", match[2])
+	}
+	if target.Limit, err = time.ParseDuration(match[3]); err != nil {
+		return nil, fmt.Errorf("invalid SLA limit '%s': %w", match[3], err)
+	}
+	return target, nil
+}
+
+// findSLATarget returns the first target matching the scenario, or nil
+func findSLATarget(targets []*SLATarget, scenarioID string) *SLATarget {
+	for _, t := range targets {
+		if t.Pattern.MatchString(scenarioID) {
+			return t
+		}
+	}
+	return nil
+}
+
+// outputSLAReport shows which plans meet the SLA targets, and whether the
+// optimizer's choice does. The plan meeting the SLA with the least RU is not
+// necessarily the fastest one.
+func outputSLAReport(results []*TestExecutionResult, targets []*SLATarget) {
+	fmt.Println("\n⏱️  SLA Report")
+	fmt.Println("====================")
+
+	chosen := make(map[string]string)
+	for _, r := range results {
+		if r.ExplainOnly {
+			chosen[r.ScenarioID] = r.PlanType
+		}
+	}
+	scenarioIDs, groups := groupByScenario(results)
+	withRU := ruReported(results)
+	if withRU {
+		fmt.Printf("Scenario\tTable_size\tCardinality\tSLA\tChoosen\tChoosen_meets_SLA\tFastest\tSLA_least_RU\tPlan\tPercentile_ms\tRU-avg\tMeets_SLA\n")
+	} else {
+		fmt.Printf("Scenario\tTable_size\tCardinality\tSLA\tChoosen\tChoosen_meets_SLA\tFastest\tPlan\tPercentile_ms\tMeets_SLA\n")
+	}
+	cells, chosenMeets, fastestDiffers := 0, 0, 0
+	for _, scenarioID := range scenarioIDs {
+		target := findSLATarget(targets, scenarioID)
+		if target == nil {
+			continue
+		}
+		cells++
+		group := groups[scenarioID]
+		planTypes := sortedPlanTypes(group)
+		pct := make(map[string]float64)
+		avgRU := make(map[string]float64)
+		fastest, leastRU := "", ""
+		limitMs := float64(target.Limit) / float64(time.Millisecond)
+		for _, pt := range planTypes {
+			pct[pt] = percentile(latenciesMs(group[pt]), target.Percentile)
+			for _, r := range group[pt] {
+				avgRU[pt] += getRU(r.Plan)
+			}
+			avgRU[pt] /= float64(len(group[pt]))
+			if fastest == "" || pct[pt] < pct[fastest] {
+				fastest = pt
+			}
+			if pct[pt] < limitMs && (leastRU == "" || avgRU[pt] < avgRU[leastRU]) {
+				leastRU = pt
+			}
+		}
+		choice := chosen[scenarioID]
+		_, measured := pct[choice]
+		meets := measured && pct[choice] < limitMs
+		if meets {
+			chosenMeets++
+		}
+		if leastRU != "" && leastRU != fastest {
+			fastestDiffers++
+		}
+		slaPick := leastRU
+		if slaPick == "" {
+			slaPick = "none"
+		}
+		for _, pt := range planTypes {
+			if !withRU {
+				fmt.Printf("%s\t%s\t%s\t%t\t%s\t%s\t%.03f\t%t\n",
+					strings.Join(strings.Split(scenarioID, "_"), "\t"), target.Text, choice, meets,
+					fastest, pt, pct[pt], pct[pt] < limitMs)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%s\t%t\t%s\t%s\t%s\t%.03f\t%.03f\t%t\n",
+				strings.Join(strings.Split(scenarioID, "_"), "\t"), target.Text, choice, meets,
+				fastest, slaPick, pt, pct[pt], avgRU[pt], pct[pt] < limitMs)
+		}
+	}
+	fmt.Printf("\nChoosen plan meets SLA: %d of %d scenarios\n", chosenMeets, cells)
+	if withRU {
+		fmt.Printf("Scenarios where the SLA plan with least RU is not the fastest: %d\n", fastestDiffers)
+	}
+}
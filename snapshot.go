@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// TableSnapshot records the state a test table was frozen in for the run:
+// the TSO of the run's snapshot, the version (TSO) of its statistics, its row
+// count and comment, and the checksum of its data, so runs on different data
+// are not compared
+type TableSnapshot struct {
+	Table string `json:"table"`
+	// TSO is the timestamp oracle time of the snapshot, Time its physical time
+	TSO  uint64    `json:"tso"`
+	Time time.Time `json:"time"`
+	// StatsVersion is the TSO of the last update of the table statistics
+	StatsVersion uint64 `json:"stats_version,omitempty"`
+	// Rows is the row count of the statistics, Comment the table comment
+	// with the parameters the rows were generated with, always recorded as
+	// a cheap identity of the data
+	Rows    int64  `json:"rows,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// Checksum and KVs are the ADMIN CHECKSUM TABLE of the data and indexes
+	Checksum string `json:"checksum,omitempty"`
+	KVs      int64  `json:"kvs,omitempty"`
+}
+
+// tsoTime returns the physical time of a TSO, its high bits are milliseconds
+func tsoTime(tso uint64) time.Time {
+	return time.UnixMilli(int64(tso >> 18))
+}
+
+// currentTSO returns the TSO of a new read snapshot
+func (c *TiDBClient) currentTSO() (uint64, error) {
+	tx, err := c.dbPlan.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var tso uint64
+	if err = tx.QueryRow("SELECT @@tidb_current_ts").Scan(&tso); err != nil {
+		return 0, fmt.Errorf("failed to get the current TSO: %w", err)
+	}
+	return tso, nil
+}
+
+// GetTableSnapshot returns the snapshot of a table at the TSO, with the
+// checksum of its data if checksum is set, a scan of the whole table
+func (c *TiDBClient) GetTableSnapshot(tableName string, tso uint64, checksum bool) (*TableSnapshot, error) {
+	if c.dbPlan == nil {
+		return nil, fmt.Errorf("database connection not established")
+	}
+	snapshot := &TableSnapshot{Table: tableName, TSO: tso, Time: tsoTime(tso)}
+	query := "SELECT table_comment FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	slog.Debug("Executing query", "query", query)
+	if err := c.dbPlan.QueryRow(query, tableName).Scan(&snapshot.Comment); err != nil {
+		return nil, fmt.Errorf("failed to get the comment of %s: %w", tableName, err)
+	}
+	query = "SELECT m.version, m.count FROM mysql.stats_meta m JOIN information_schema.tables t ON m.table_id = t.tidb_table_id WHERE t.table_schema = DATABASE() AND t.table_name = ?"
+	slog.Debug("Executing query", "query", query)
+	if err := c.dbPlan.QueryRow(query, tableName).Scan(&snapshot.StatsVersion, &snapshot.Rows); err != nil {
+		// Not all users may read mysql.stats_meta, the comment still identifies the data
+		slog.Warn("Failed to get the statistics version", "table", tableName, "error", err)
+	}
+	if !checksum {
+		return snapshot, nil
+	}
+	checksums, err := queryNamedRows(c.dbPlan, "ADMIN CHECKSUM TABLE "+tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum %s: %w", tableName, err)
+	}
+	if len(checksums) > 0 {
+		snapshot.Checksum = checksums[0]["Checksum_crc64_xor"]
+		snapshot.KVs, _ = strconv.ParseInt(checksums[0]["Total_kvs"], 10, 64)
+	}
+	return snapshot, nil
+}
+
+// snapshotTables records the snapshot of the tables of the run, at its start,
+// with their checksums for -checksum-tables
+func (c *TiDBClient) snapshotTables(tables []string, checksum bool) []TableSnapshot {
+	tso, err := c.currentTSO()
+	if err != nil {
+		slog.Warn("Failed to snapshot the tables", "error", err)
+		return nil
+	}
+	var snapshots []TableSnapshot
+	for _, table := range tables {
+		snapshot, err := c.GetTableSnapshot(table, tso, checksum)
+		if err != nil {
+			slog.Warn("Failed to snapshot the table", "table", table, "error", err)
+			continue
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	return snapshots
+}
+
+// dataDifferences returns the tables of both runs whose data, and the ones
+// whose statistics, differ. Runs or tables without snapshots are not
+// compared. The data differs by the table comments and row counts, and by
+// the checksums if both runs have them. The statistics version changes with
+// any DML, like the coprocessor cache invalidation, and auto analyze, so it
+// does not tell that the data differs.
+func dataDifferences(m, o *RunMetadata) (data, stats []string) {
+	for _, a := range m.TableSnapshots {
+		i := slices.IndexFunc(o.TableSnapshots, func(b TableSnapshot) bool { return b.Table == a.Table })
+		if i < 0 {
+			continue
+		}
+		b := o.TableSnapshots[i]
+		switch {
+		case a.Comment != "" && b.Comment != "" && a.Comment != b.Comment:
+			data = append(data, fmt.Sprintf("data of %s (comment %q vs %q)", a.Table, a.Comment, b.Comment))
+		case a.Rows != 0 && b.Rows != 0 && a.Rows != b.Rows:
+			data = append(data, fmt.Sprintf("data of %s (%d vs %d rows)", a.Table, a.Rows, b.Rows))
+		case a.Checksum != "" && b.Checksum != "" && (a.Checksum != b.Checksum || a.KVs != b.KVs):
+			data = append(data, fmt.Sprintf("data of %s (checksum %s vs %s)", a.Table, a.Checksum, b.Checksum))
+		}
+		if a.StatsVersion != 0 && b.StatsVersion != 0 && a.StatsVersion != b.StatsVersion {
+			stats = append(stats, fmt.Sprintf("statistics of %s (version %d vs %d)", a.Table, a.StatsVersion, b.StatsVersion))
+		}
+	}
+	return data, stats
+}
+
+// mergeTableSnapshots adds the snapshots of the tables not in the list yet
+func mergeTableSnapshots(snapshots, more []TableSnapshot) []TableSnapshot {
+	for _, s := range more {
+		if !slices.ContainsFunc(snapshots, func(t TableSnapshot) bool { return t.Table == s.Table }) {
+			snapshots = append(snapshots, s)
+		}
+	}
+	return snapshots
+}
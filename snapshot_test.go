@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTSOTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tso := uint64(want.UnixMilli())<<18 | 42
+	if got := tsoTime(tso); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDataDifferences(t *testing.T) {
+	base := &RunMetadata{TableSnapshots: []TableSnapshot{
+		{Table: "t1K", StatsVersion: 10, Checksum: "111", KVs: 2000},
+		{Table: "t1M", StatsVersion: 20, Checksum: "222", KVs: 2000000},
+	}}
+	same := &RunMetadata{TableSnapshots: []TableSnapshot{
+		{Table: "t1K", StatsVersion: 10, Checksum: "111", KVs: 2000},
+		{Table: "t1G", StatsVersion: 30, Checksum: "333", KVs: 7},
+	}}
+	if data, stats := dataDifferences(base, same); len(data) != 0 || len(stats) != 0 {
+		t.Fatalf("expected no differences, got %v %v", data, stats)
+	}
+	if err := base.compatibleWith(same); err != nil {
+		t.Fatalf("expected compatible runs: %v", err)
+	}
+	if data, stats := dataDifferences(base, &RunMetadata{}); len(data) != 0 || len(stats) != 0 {
+		t.Fatalf("expected runs without snapshots not to be compared, got %v %v", data, stats)
+	}
+
+	other := &RunMetadata{TableSnapshots: []TableSnapshot{
+		{Table: "t1K", StatsVersion: 11, Checksum: "111", KVs: 2000},
+		{Table: "t1M", StatsVersion: 20, Checksum: "999", KVs: 2000000},
+	}}
+	data, stats := dataDifferences(base, other)
+	if len(data) != 1 || len(stats) != 1 {
+		t.Fatalf("expected one data and one stats difference, got %v %v", data, stats)
+	}
+	if err := base.compatibleWith(other); err == nil {
+		t.Fatalf("expected runs on different data to be incompatible")
+	}
+	// Only the statistics differ, like after the coprocessor cache invalidation or an auto analyze
+	analyzed := &RunMetadata{TableSnapshots: []TableSnapshot{{Table: "t1K", StatsVersion: 12, Checksum: "111", KVs: 2000}}}
+	if err := base.compatibleWith(analyzed); err != nil {
+		t.Fatalf("expected runs on different statistics to be compatible: %v", err)
+	}
+
+	// Without checksums, the always recorded comment and row count identify the data
+	plain := &RunMetadata{TableSnapshots: []TableSnapshot{{Table: "t1K", Rows: 1000, Comment: "calibration v1: filler=100"}}}
+	for _, s := range []TableSnapshot{
+		{Table: "t1K", Rows: 1000, Comment: "calibration v1: filler=500"},
+		{Table: "t1K", Rows: 1500, Comment: "calibration v1: filler=100"},
+	} {
+		if err := plain.compatibleWith(&RunMetadata{TableSnapshots: []TableSnapshot{s}}); err == nil {
+			t.Fatalf("expected runs on different data to be incompatible: %v", s)
+		}
+	}
+	// The row count of the statistics is 0 after DROP STATS
+	if err := plain.compatibleWith(&RunMetadata{TableSnapshots: []TableSnapshot{{Table: "t1K", Comment: "calibration v1: filler=100"}}}); err != nil {
+		t.Fatalf("expected runs without a row count to be compatible: %v", err)
+	}
+
+	merged := mergeTableSnapshots(base.TableSnapshots, same.TableSnapshots)
+	if len(merged) != 3 || merged[2].Table != "t1G" {
+		t.Fatalf("unexpected merged snapshots: %v", merged)
+	}
+}
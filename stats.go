@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// percentile returns the p-th percentile (0-100) of the values, using linear
+// interpolation between the closest ranks
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := p / 100.0 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
+
+// mean returns the arithmetic mean of the values, 0 for no values
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stdDev returns the sample standard deviation of the values, 0 for less than two values
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var ss float64
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	return math.Sqrt(ss / float64(len(values)-1))
+}
+
+// t95Table are the two-sided 95% quantiles of Student's t distribution for 1 to 30 degrees of freedom
+var t95Table = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// t95 returns the two-sided 95% quantile of Student's t distribution, the
+// width of the confidence intervals of a few repetitions, where the normal
+// z95 is much too narrow. Fractional degrees of freedom, like the ones of
+// Welch's test, are rounded down, widening the interval.
+func t95(df float64) float64 {
+	if df < 1 {
+		return math.Inf(1)
+	}
+	if df < float64(len(t95Table)) {
+		return t95Table[int(df)-1]
+	}
+	// The Cornish-Fisher expansion of the normal quantile is within 0.001 from 30 degrees of freedom on
+	return z95 + (math.Pow(z95, 3)+z95)/(4*df) + (5*math.Pow(z95, 5)+16*math.Pow(z95, 3)+3*z95)/(96*df*df)
+}
+
+// ci95 returns the half width of the 95% confidence interval of the mean of the values
+func ci95(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	return t95(float64(len(values)-1)) * stdDev(values) / math.Sqrt(float64(len(values)))
+}
+
+// spread is the dispersion of repeated measurements. A single outlier, like
+// an execution during a compaction, makes the max useless, the median and
+// tail percentiles show whether it was one.
+type spread struct {
+	stdDev, ci95, p50, p90, p99 float64
+}
+
+// spreadOf returns the standard deviation and percentiles of the values
+func spreadOf(values []float64) spread {
+	return spread{
+		stdDev: stdDev(values),
+		ci95:   ci95(values),
+		p50:    percentile(values, 50),
+		p90:    percentile(values, 90),
+		p99:    percentile(values, 99),
+	}
+}
+
+// latenciesMs returns the execution times of the results in milliseconds
+func latenciesMs(results []*TestExecutionResult) []float64 {
+	values := make([]float64, 0, len(results))
+	for _, r := range results {
+		values = append(values, float64(r.Plan.ExecutionTime)/float64(time.Millisecond))
+	}
+	return values
+}
+
+// requestUnits returns the RU of the results
+func requestUnits(results []*TestExecutionResult) []float64 {
+	values := make([]float64, 0, len(results))
+	for _, r := range results {
+		values = append(values, getRU(r.Plan))
+	}
+	return values
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	for _, tc := range []struct {
+		p, expected float64
+	}{
+		{0, 1}, {50, 3}, {100, 5}, {25, 2}, {90, 4.6},
+	} {
+		if got := percentile(values, tc.p); got < tc.expected-1e-9 || got > tc.expected+1e-9 {
+			t.Fatalf("p%v: expected %v, got %v", tc.p, tc.expected, got)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Fatalf("expected 0 for no values, got %v", got)
+	}
+}
+
+func TestSpreadOf(t *testing.T) {
+	s := spreadOf([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if s.p50 != 4.5 || s.p90 < 7.6-1e-9 || s.p90 > 7.6+1e-9 {
+		t.Fatalf("unexpected percentiles: %+v", s)
+	}
+	// Sample standard deviation, sqrt(32/7)
+	if s.stdDev < 2.138 || s.stdDev > 2.139 {
+		t.Fatalf("unexpected standard deviation: %v", s.stdDev)
+	}
+	if got := spreadOf([]float64{3}); got.stdDev != 0 || got.p99 != 3 {
+		t.Fatalf("unexpected spread of a single value: %+v", got)
+	}
+	// Student's t of 7 degrees of freedom, 2.365 * sqrt(32/7) / sqrt(8)
+	if s.ci95 < 1.787 || s.ci95 > 1.789 {
+		t.Fatalf("unexpected confidence interval: %v", s.ci95)
+	}
+}
+
+func TestT95(t *testing.T) {
+	for _, tc := range []struct{ df, expected float64 }{{2, 4.303}, {2.9, 4.303}, {30, 2.042}, {60, 2.000}, {1000, 1.962}} {
+		if got := t95(tc.df); math.Abs(got-tc.expected) > 0.001 {
+			t.Errorf("expected t95(%g) = %g, got %g", tc.df, tc.expected, got)
+		}
+	}
+}
+
+func TestParseSLATarget(t *testing.T) {
+	target, err := parseSLATarget("index_1M_.*:p99<200ms")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Percentile != 99 || target.Limit.Milliseconds() != 200 {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	if !target.Pattern.MatchString("index_1M_10") || target.Pattern.MatchString("index_1K_10") {
+		t.Fatalf("unexpected pattern match for %s", target.Pattern)
+	}
+	if _, err = parseSLATarget("p95 < 50ms"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []string{"p95", "avg<10ms", "p0<1ms", "p95<fast"} {
+		if _, err = parseSLATarget(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// TableStatsState is a snapshot of the optimizer statistics of a table, used
+// to detect if the calibration queries themselves changed the optimizer state,
+// e.g. through auto analyze or loading column statistics on first use
+type TableStatsState struct {
+	Table       string
+	MetaUpdated string
+	ModifyCount string
+	RowCount    string
+	// Histograms maps the column or index name to its update time and load status
+	Histograms map[string]string
+}
+
+// queryNamedRows returns the rows of a query as maps from column name to value,
+// for SHOW statements whose columns differ between versions
+func queryNamedRows(db *sql.DB, query string) ([]map[string]string, error) {
+	slog.Debug("Executing query", "query", query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var result []map[string]string
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[column] = values[i].String
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// GetTableStatsState returns a snapshot of the statistics of the table
+func (c *TiDBClient) GetTableStatsState(tableName string) (*TableStatsState, error) {
+	if c.dbPlan == nil {
+		return nil, fmt.Errorf("database connection not established")
+	}
+	state := &TableStatsState{Table: tableName, Histograms: make(map[string]string)}
+	meta, err := queryNamedRows(c.dbPlan, fmt.Sprintf("SHOW STATS_META WHERE Db_name = DATABASE() AND Table_name = '%s'", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats meta of %s: %w", tableName, err)
+	}
+	if len(meta) > 0 {
+		state.MetaUpdated = meta[0]["Update_time"]
+		state.ModifyCount = meta[0]["Modify_count"]
+		state.RowCount = meta[0]["Row_count"]
+	}
+	histograms, err := queryNamedRows(c.dbPlan, fmt.Sprintf("SHOW STATS_HISTOGRAMS WHERE Db_name = DATABASE() AND Table_name = '%s'", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats histograms of %s: %w", tableName, err)
+	}
+	for _, h := range histograms {
+		state.Histograms[h["Column_name"]] = strings.TrimSpace(h["Update_time"] + " " + h["Load_status"])
+	}
+	return state, nil
+}
+
+// diffStatsState describes how the statistics changed between the snapshots
+func diffStatsState(before, after *TableStatsState) []string {
+	var changes []string
+	if before.MetaUpdated != after.MetaUpdated {
+		changes = append(changes, fmt.Sprintf("%s: stats updated (%s -> %s, modify count %s -> %s, row count %s -> %s)",
+			after.Table, before.MetaUpdated, after.MetaUpdated, before.ModifyCount, after.ModifyCount, before.RowCount, after.RowCount))
+	}
+	var columns []string
+	for column := range after.Histograms {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		if old, ok := before.Histograms[column]; !ok || old != after.Histograms[column] {
+			changes = append(changes, fmt.Sprintf("%s.%s: histogram changed (%s -> %s)", after.Table, column, old, after.Histograms[column]))
+		}
+	}
+	return changes
+}
+
+// snapshotStats returns the statistics snapshots of the tables, skipping tables that fail
+func (c *TiDBClient) snapshotStats(tables []string) map[string]*TableStatsState {
+	states := make(map[string]*TableStatsState)
+	for _, table := range tables {
+		state, err := c.GetTableStatsState(table)
+		if err != nil {
+			slog.Warn("Failed to snapshot statistics", "table", table, "error", err)
+			continue
+		}
+		states[table] = state
+	}
+	return states
+}
+
+// setStatsLocked locks or unlocks the statistics of the tables, so neither
+// auto analyze nor other statistics updates change them during the run
+func (c *TiDBClient) setStatsLocked(tables []string, locked bool) error {
+	stmt := "UNLOCK STATS "
+	if locked {
+		stmt = "LOCK STATS "
+	}
+	query := stmt + strings.Join(tables, ", ")
+	slog.Debug("Executing query", "query", query)
+	if _, err := c.dbPlan.Exec(query); err != nil {
+		return fmt.Errorf("failed to %s: %w", strings.ToLower(strings.TrimSpace(stmt)), err)
+	}
+	return nil
+}
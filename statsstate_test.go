@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestDiffStatsState(t *testing.T) {
+	before := &TableStatsState{
+		Table:       "t1K",
+		MetaUpdated: "2025-01-01 10:00:00",
+		Histograms:  map[string]string{"b": "2025-01-01 10:00:00 allEvicted"},
+	}
+	same := &TableStatsState{
+		Table:       "t1K",
+		MetaUpdated: "2025-01-01 10:00:00",
+		Histograms:  map[string]string{"b": "2025-01-01 10:00:00 allEvicted"},
+	}
+	if changes := diffStatsState(before, same); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+	loaded := &TableStatsState{
+		Table:       "t1K",
+		MetaUpdated: "2025-01-01 10:00:00",
+		Histograms:  map[string]string{"b": "2025-01-01 10:00:00 allLoaded", "c": "2025-01-01 10:05:00"},
+	}
+	if changes := diffStatsState(before, loaded); len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	analyzed := &TableStatsState{Table: "t1K", MetaUpdated: "2025-01-01 10:05:00", Histograms: before.Histograms}
+	if changes := diffStatsState(before, analyzed); len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The reasons an execution was retried
+const (
+	retriedReconnect = "reconnect"
+	retriedCoprCache = "copr_cache"
+	retriedBackoff   = "backoff"
+)
+
+// strictMaxReplacements is the most retried executions of a scenario replaced
+// in strict mode, further ones are dropped without a replacement
+const strictMaxReplacements = 10
+
+// StrictMode drops the retried executions of -strict, whose latency may
+// include the failed attempt, and schedules a clean replacement for each,
+// interleaved with the pending runs, so no reported sample includes hidden
+// retry latency
+type StrictMode struct {
+	schedule *Schedule
+	replaced map[int]int
+	// Replaced and Dropped are the retried executions replaced, and dropped
+	// at the cap, Reasons their number per reason
+	Replaced int
+	Dropped  int
+	Reasons  map[string]int
+}
+
+// newStrictMode creates the strict mode of a schedule
+func newStrictMode(schedule *Schedule) *StrictMode {
+	return &StrictMode{
+		schedule: schedule,
+		replaced: make(map[int]int),
+		Reasons:  make(map[string]int),
+	}
+}
+
+// accept returns false for a retried measured execution, replacing it up to
+// strictMaxReplacements times per scenario. Everything is accepted without
+// strict mode.
+func (m *StrictMode) accept(run ScheduledRun, result *TestExecutionResult) bool {
+	if m == nil || result.Retried == "" || run.Warmup || run.Scenario.ExplainOnly {
+		return true
+	}
+	m.Reasons[result.Retried]++
+	if m.replaced[run.index] >= strictMaxReplacements {
+		m.Dropped++
+		return false
+	}
+	m.replaced[run.index]++
+	m.Replaced++
+	m.schedule.Add(run.index, 1)
+	return false
+}
+
+// reasons returns the number of retried executions per reason, like "backoff: 3, reconnect: 1"
+func (m *StrictMode) reasons() string {
+	var parts []string
+	for _, reason := range slices.Sorted(maps.Keys(m.Reasons)) {
+		parts = append(parts, fmt.Sprintf("%s: %d", reason, m.Reasons[reason]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// outputStrictSummary prints the retried executions left out of the results
+func (m *StrictMode) outputStrictSummary() {
+	fmt.Println("\n🧪 Strict Mode")
+	fmt.Println("====================")
+	fmt.Printf("Replaced\tDropped\tReasons\n")
+	fmt.Printf("%d\t%d\t%s\n", m.Replaced, m.Dropped, m.reasons())
+	if m.Dropped > 0 {
+		fmt.Printf("⚠️  %d retried executions were dropped without a replacement, after %d replacements of their scenario\n",
+			m.Dropped, strictMaxReplacements)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestStrictMode(t *testing.T) {
+	scenarios := GetTestScenariosWithRowCountsAndSelectivities([]int{1000}, []float64{0.1})
+	schedule := NewSchedule(scenarios, 2)
+	strict := newStrictMode(schedule)
+	var first ScheduledRun
+	for run := range schedule.All() {
+		if !run.Scenario.ExplainOnly {
+			first = run
+			break
+		}
+	}
+	scheduled := schedule.Len()
+	if !strict.accept(first, &TestExecutionResult{}) {
+		t.Fatal("expected an execution that was not retried to be accepted")
+	}
+	warmup := first
+	warmup.Warmup = true
+	if !strict.accept(warmup, &TestExecutionResult{Retried: retriedBackoff}) {
+		t.Fatal("expected a retried warm-up execution to be accepted")
+	}
+	for range strictMaxReplacements + 2 {
+		if strict.accept(first, &TestExecutionResult{Retried: retriedBackoff}) {
+			t.Fatal("expected a retried execution to be dropped")
+		}
+	}
+	if schedule.Len() != scheduled+strictMaxReplacements || strict.Replaced != strictMaxReplacements || strict.Dropped != 2 {
+		t.Fatalf("expected %d replacements and 2 dropped, got %d scheduled, %+v", strictMaxReplacements, schedule.Len()-scheduled, strict)
+	}
+	// The replacements are interleaved with the pending runs, not appended as a block
+	var positions []int
+	i := 0
+	for run := range schedule.All() {
+		if run.Scenario.ID == first.Scenario.ID && run.Scenario.Variant == first.Scenario.Variant && run.Repetition >= 2 {
+			positions = append(positions, i)
+		}
+		i++
+	}
+	if len(positions) != strictMaxReplacements || positions[0] >= schedule.Len()-strictMaxReplacements {
+		t.Fatalf("expected the replacements interleaved with the pending runs, got positions %v of %d", positions, schedule.Len())
+	}
+	if got := strict.reasons(); got != "backoff: 12" {
+		t.Fatalf("unexpected reasons %q", got)
+	}
+	var none *StrictMode
+	if !none.accept(first, &TestExecutionResult{Retried: retriedReconnect}) {
+		t.Fatal("expected everything to be accepted without strict mode")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// misbehavingRegret is the geometric mean regret from which the optimizer is
+// considered to misbehave at a table size
+const misbehavingRegret = 1.1
+
+// TableSizeRollup collapses the selectivities of one table size: the
+// calibration score of its cells, and the average latency and RU per plan type
+type TableSizeRollup struct {
+	RowCount int
+	Score    CalibrationScore
+	// PlanTypes are the measured plan types, Cells the number of cells each was
+	// measured in, Fastest the ones where it was the fastest allowed plan
+	PlanTypes []string
+	Cells     map[string]int
+	Fastest   map[string]int
+	AvgTime   map[string]time.Duration
+	AvgRU     map[string]float64
+}
+
+// tableSizeRollups rolls the cells up per table size, sorted by size
+func tableSizeRollups(cells []*CellAnalysis) []TableSizeRollup {
+	bySize := make(map[int][]*CellAnalysis)
+	for _, cell := range cells {
+		bySize[cell.RowCount] = append(bySize[cell.RowCount], cell)
+	}
+	sizes := make([]int, 0, len(bySize))
+	for size := range bySize {
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	rollups := make([]TableSizeRollup, 0, len(sizes))
+	for _, size := range sizes {
+		r := TableSizeRollup{
+			RowCount: size,
+			Score:    computeCalibrationScore(bySize[size]),
+			Cells:    make(map[string]int),
+			Fastest:  make(map[string]int),
+			AvgTime:  make(map[string]time.Duration),
+			AvgRU:    make(map[string]float64),
+		}
+		totalTime := make(map[string]time.Duration)
+		for _, cell := range bySize[size] {
+			for pt, t := range cell.AvgTime {
+				if r.Cells[pt] == 0 {
+					r.PlanTypes = append(r.PlanTypes, pt)
+				}
+				r.Cells[pt]++
+				totalTime[pt] += t
+				r.AvgRU[pt] += cell.AvgRU[pt]
+			}
+			r.Fastest[cell.Best]++
+		}
+		slices.Sort(r.PlanTypes)
+		for _, pt := range r.PlanTypes {
+			r.AvgTime[pt] = totalTime[pt] / time.Duration(r.Cells[pt])
+			r.AvgRU[pt] /= float64(r.Cells[pt])
+		}
+		rollups = append(rollups, r)
+	}
+	return rollups
+}
+
+// firstMisbehaving returns the smallest table size from which the geometric
+// mean regret stays at or above misbehavingRegret, 0 if there is none
+func firstMisbehaving(rollups []TableSizeRollup) int {
+	first := 0
+	for _, r := range rollups {
+		switch {
+		case r.Score.Cells == 0:
+		case r.Score.GeoMeanRegret >= misbehavingRegret:
+			if first == 0 {
+				first = r.RowCount
+			}
+		default:
+			first = 0
+		}
+	}
+	return first
+}
+
+// outputTableSizeRollup prints the optimizer agreement, regret, and latency
+// and RU per plan type of each table size, collapsing the selectivities
+func outputTableSizeRollup(cells []*CellAnalysis) {
+	fmt.Println("\n📏 Table Size Roll-up")
+	fmt.Println("====================")
+	rollups := tableSizeRollups(cells)
+	fmt.Printf("Table_size\tCells\tUnmeasured\tAgreement\tGeomean_regret\tScore\n")
+	for _, r := range rollups {
+		fmt.Printf("%s\t%d\t%d\t%.03f\t%.03f\t%.01f\n", formatRowCountName(r.RowCount), r.Score.Cells, r.Score.Unmeasured,
+			r.Score.Agreement, r.Score.GeoMeanRegret, r.Score.Score)
+	}
+	fmt.Println()
+	withRU := cellsReportRU(cells)
+	if withRU {
+		fmt.Printf("Table_size\tPlan_type\tCells\tFastest\tms_avg\tru_avg\n")
+	} else {
+		fmt.Printf("Table_size\tPlan_type\tCells\tFastest\tms_avg\n")
+	}
+	for _, r := range rollups {
+		for _, pt := range r.PlanTypes {
+			if !withRU {
+				fmt.Printf("%s\t%s\t%d\t%d\t%.03f\n", formatRowCountName(r.RowCount), pt, r.Cells[pt], r.Fastest[pt],
+					r.AvgTime[pt].Seconds()*1000.0)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%d\t%d\t%.03f\t%.03f\n", formatRowCountName(r.RowCount), pt, r.Cells[pt], r.Fastest[pt],
+				r.AvgTime[pt].Seconds()*1000.0, r.AvgRU[pt])
+		}
+	}
+	if size := firstMisbehaving(rollups); size > 0 {
+		fmt.Printf("The optimizer misbehaves from %s rows on: a geometric mean regret of %gx or more at every larger size\n",
+			formatRowCountName(size), misbehavingRegret)
+	} else {
+		fmt.Printf("No table size from which the geometric mean regret stays at %gx or more\n", misbehavingRegret)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableSizeRollups(t *testing.T) {
+	cell := func(rowCount int, chosen string, index, scan time.Duration) *CellAnalysis {
+		c := &CellAnalysis{
+			RowCount: rowCount,
+			Chosen:   chosen,
+			Measured: true,
+			AvgTime:  map[string]time.Duration{"index_lookup": index, "table_scan": scan},
+			AvgRU:    map[string]float64{"index_lookup": 2, "table_scan": 4},
+		}
+		c.Best = "index_lookup"
+		if scan < index {
+			c.Best = "table_scan"
+		}
+		return c
+	}
+	cells := []*CellAnalysis{
+		cell(1000, "index_lookup", time.Millisecond, 2*time.Millisecond),
+		cell(1000, "table_scan", 3*time.Millisecond, 2*time.Millisecond),
+		cell(1000000, "table_scan", time.Millisecond, 4*time.Millisecond),
+		cell(1000000, "table_scan", 2*time.Millisecond, 8*time.Millisecond),
+	}
+	rollups := tableSizeRollups(cells)
+	if len(rollups) != 2 || rollups[0].RowCount != 1000 || rollups[1].RowCount != 1000000 {
+		t.Fatalf("unexpected roll-ups %+v", rollups)
+	}
+	small, large := rollups[0], rollups[1]
+	if small.Score.Agreement != 1 || small.AvgTime["index_lookup"] != 2*time.Millisecond || small.Fastest["table_scan"] != 1 {
+		t.Fatalf("unexpected 1K roll-up %+v", small)
+	}
+	if large.Score.Agreement != 0 || large.Score.GeoMeanRegret != 4 || large.Cells["table_scan"] != 2 || large.AvgRU["table_scan"] != 4 {
+		t.Fatalf("unexpected 1M roll-up %+v", large)
+	}
+	if got := firstMisbehaving(rollups); got != 1000000 {
+		t.Fatalf("expected the optimizer to misbehave from 1M rows, got %d", got)
+	}
+	// A size that recovers resets the start of the misbehaviour
+	rollups = append(rollups, TableSizeRollup{RowCount: 10000000, Score: CalibrationScore{Cells: 1, GeoMeanRegret: 1}})
+	if got := firstMisbehaving(rollups); got != 0 {
+		t.Fatalf("expected no misbehaving size, got %d", got)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LoadLimiter caps the load the calibration puts on a shared cluster, both
+// during table setup and scenario execution. The QPS cap spaces the
+// statements, the RU cap delays the next statement until the RU consumed
+// so far fits within the rate. A nil LoadLimiter does not limit anything.
+type LoadLimiter struct {
+	MaxQPS      float64
+	MaxRUPerSec float64
+
+	// mu serializes the concurrent loading connections of the table setup
+	mu sync.Mutex
+
+	nextQuery time.Time
+	nextRU    time.Time
+	waited    time.Duration
+}
+
+// NewLoadLimiter returns a limiter for the given caps, or nil if both are disabled (0)
+func NewLoadLimiter(maxQPS, maxRUPerSec float64) *LoadLimiter {
+	if maxQPS <= 0 && maxRUPerSec <= 0 {
+		return nil
+	}
+	return &LoadLimiter{MaxQPS: maxQPS, MaxRUPerSec: maxRUPerSec}
+}
+
+// delay returns how long to wait before the next statement may be sent at the given time
+func (l *LoadLimiter) delay(now time.Time) time.Duration {
+	next := l.nextQuery
+	if l.nextRU.After(next) {
+		next = l.nextRU
+	}
+	if next.After(now) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// reserve books a statement slot, for a statement sent at the given time
+func (l *LoadLimiter) reserve(now time.Time) {
+	if l.MaxQPS > 0 {
+		l.nextQuery = now.Add(time.Duration(float64(time.Second) / l.MaxQPS))
+	}
+}
+
+// Wait blocks until the next statement may be sent
+func (l *LoadLimiter) Wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if d := l.delay(time.Now()); d > 0 {
+		slog.Debug("Throttling load", "wait", d)
+		time.Sleep(d)
+		l.waited += d
+	}
+	l.reserve(time.Now())
+}
+
+// ConsumeRU accounts the RU consumed by a statement
+func (l *LoadLimiter) ConsumeRU(ru float64) {
+	if l == nil || l.MaxRUPerSec <= 0 || ru <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := time.Now()
+	if l.nextRU.After(start) {
+		start = l.nextRU
+	}
+	l.nextRU = start.Add(time.Duration(ru / l.MaxRUPerSec * float64(time.Second)))
+}
+
+// limitsRU returns true if the RU consumption needs to be tracked
+func (l *LoadLimiter) limitsRU() bool {
+	return l != nil && l.MaxRUPerSec > 0
+}
+
+// Waited returns the total time spent throttling
+func (l *LoadLimiter) Waited() time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waited
+}
+
+// ExecuteStatement executes a statement without result set, like the table
+// setup's INSERT and UPDATE batches, within the load limits
+func (c *TiDBClient) ExecuteStatement(query string) error {
+	if c.db == nil {
+		return fmt.Errorf("database connection not established")
+	}
+	c.limiter.Wait()
+	slog.Debug("Executing query", "query", query)
+	ctx := context.Background()
+	// Use a single connection, for the session's last query info
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if c.limiter.limitsRU() {
+		var queryInfo string
+		if err = conn.QueryRowContext(ctx, "select @@tidb_last_query_info").Scan(&queryInfo); err != nil {
+			return fmt.Errorf("failed to to get last query info: %w", err)
+		}
+		c.limiter.ConsumeRU(getRU(&ExecutionPlan{QueryInfo: queryInfo}))
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadLimiter(t *testing.T) {
+	if NewLoadLimiter(0, 0) != nil {
+		t.Fatalf("expected no limiter without caps")
+	}
+	// A nil limiter never waits
+	var none *LoadLimiter
+	none.Wait()
+	none.ConsumeRU(100)
+
+	now := time.Now()
+	l := NewLoadLimiter(10, 0)
+	l.reserve(now)
+	if d := l.delay(now); d != 100*time.Millisecond {
+		t.Fatalf("expected 100ms between statements at 10 qps, got %v", d)
+	}
+	if d := l.delay(now.Add(time.Second)); d != 0 {
+		t.Fatalf("expected no wait after the interval, got %v", d)
+	}
+
+	l = NewLoadLimiter(0, 100)
+	l.ConsumeRU(50)
+	if d := l.delay(time.Now()); d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Fatalf("expected ~500ms wait after 50 RU at 100 RU/s, got %v", d)
+	}
+}
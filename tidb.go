@@ -1,15 +1,23 @@
 package main
 
 import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log/slog"
-	"regexp"
+	"math"
+	"net"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // TiDBClient represents a TiDB database client
@@ -17,24 +25,62 @@ type TiDBClient struct {
 	db             *sql.DB
 	dbPlan         *sql.DB
 	dbConnectionID int
+	captureRCWait  bool
+	simulatedRTT   time.Duration
+	limiter        *LoadLimiter
+	// ignorePlanCache adds the IGNORE_PLAN_CACHE() hint to the measured queries
+	ignorePlanCache bool
+	// explainAnalyze executes the measured queries under EXPLAIN ANALYZE
+	explainAnalyze bool
+	// coprCache is how executions served from the coprocessor cache are handled,
+	// "" is like coprCacheBust
+	coprCache string
+	estCosts  map[string]EstimatedCost
+	// digests caches the statement digests per query, see GetSQLDigest
+	digests map[string]string
+	// indexes caches the index names and columns per table, see tableIndexes
+	indexes map[string]map[string][]string
+	// mu serializes the measured executions with the keep-alive pings and reconnects
+	mu sync.Mutex
+	// dsn is the DSN the connections are opened with, and reopened with after a reconnect
+	dsn string
 }
 
+// EstimatedCost is the optimizer's estimated cost of a query's plan
+type EstimatedCost struct {
+	Verbose   float64 `json:"verbose"`
+	CostTrace float64 `json:"cost_trace"`
+	// Cost is the canonical estimated cost, reconciled from the sources
+	Cost   float64 `json:"cost"`
+	Source string  `json:"source,omitempty"`
+}
+
+// ExecutionPlan is an operator of a plan, the operators below it are linked through Next.
+// QueryInfo and ExecutionTime are only set on the top operator.
 type ExecutionPlan struct {
-	ID            string
-	Task          string
-	Count         int64
-	EstRows       float64
-	ActRows       int64
-	AccessObject  string
-	OperatorInfo  string
-	ExecutionInfo string
-	Memory        string
-	Disk          string
-	Next          *ExecutionPlan
-	bVal          int
-	rows          int
-	QueryInfo     string
-	ExecutionTime time.Duration
+	ID            string  `json:"id"`
+	Task          string  `json:"task,omitempty"`
+	Count         int64   `json:"count,omitempty"`
+	EstRows       float64 `json:"est_rows"`
+	EstCost       float64 `json:"est_cost,omitempty"`
+	ActRows       int64   `json:"act_rows,omitempty"`
+	AccessObject  string  `json:"access_object,omitempty"`
+	OperatorInfo  string  `json:"operator_info,omitempty"`
+	ExecutionInfo string  `json:"execution_info,omitempty"`
+	// Exec is the decoded ExecutionInfo, nil for plans that were not executed,
+	// not stored in the result files, see execInfo
+	Exec   *ExecInfo      `json:"-"`
+	Memory string         `json:"memory,omitempty"`
+	Disk   string         `json:"disk,omitempty"`
+	Next   *ExecutionPlan `json:"next,omitempty"`
+	rows   int
+	// fromCache and fromBinding are set if the plan came from the plan cache or a SQL binding
+	fromCache   bool
+	fromBinding bool
+	// startTime is when the execution started, with a monotonic clock reading
+	startTime     time.Time
+	QueryInfo     string        `json:"query_info,omitempty"`
+	ExecutionTime time.Duration `json:"execution_time,omitempty"`
 }
 
 // TiDBConfig holds TiDB connection configuration
@@ -45,6 +91,12 @@ type TiDBConfig struct {
 	Password string
 	Database string
 	Timeout  time.Duration
+	// TLS is the driver's tls mode: "" (disabled), "true", "skip-verify" or "preferred".
+	// TLSCA, TLSCert and TLSKey are PEM files for a custom CA and a client certificate.
+	TLS     string
+	TLSCA   string
+	TLSCert string
+	TLSKey  string
 }
 
 // NewTiDBClient creates a new TiDB client
@@ -61,6 +113,125 @@ var defaultTiDBConfig = TiDBConfig{
 	Timeout:  30 * time.Second,
 }
 
+// registerConnectionFlags registers the TiDB connection flags on the flag set.
+// The returned function builds the TiDBConfig once the flags are parsed.
+func registerConnectionFlags(fs *flag.FlagSet) func() (*TiDBConfig, error) {
+	config := defaultTiDBConfig
+	fs.StringVar(&config.Host, "host", config.Host, "TiDB host")
+	fs.IntVar(&config.Port, "port", config.Port, "TiDB port")
+	fs.StringVar(&config.User, "user", config.User, "TiDB user")
+	fs.StringVar(&config.Password, "password", config.Password, "TiDB password")
+	fs.StringVar(&config.Database, "database", config.Database, "Database for the test tables")
+	var dsn = fs.String("dsn", "", "TiDB connection as a Go MySQL driver DSN (user:password@tcp(host:port)/database), overrides the other connection flags")
+	fs.StringVar(&config.TLS, "tls", "", "TLS mode: true, skip-verify (encrypt without verifying the server certificate) or preferred (default disabled, unless -tls-ca is given)")
+	fs.StringVar(&config.TLSCA, "tls-ca", "", "PEM file with the CA certificate to verify the server with")
+	fs.StringVar(&config.TLSCert, "tls-cert", "", "PEM file with the client certificate (requires -tls-key)")
+	fs.StringVar(&config.TLSKey, "tls-key", "", "PEM file with the client certificate key")
+	var askPass = fs.Bool("ask-pass", false, "Prompt for the TiDB password, to keep it out of the shell history")
+	var useKeyring = fs.Bool("keyring", false, "Read the TiDB password from the OS keyring if no password is given, and store the password entered with -ask-pass there")
+	var defaultsFile = fs.String("defaults-file", "", "MySQL option file to read the [client] connection settings from (default ~/.my.cnf if it exists)")
+	return func() (*TiDBConfig, error) {
+		path := *defaultsFile
+		if path == "" {
+			path = defaultOptionFile()
+		}
+		if path != "" {
+			if err := applyOptionFile(fs, path); err != nil {
+				return nil, err
+			}
+		}
+		result := &config
+		if *dsn != "" {
+			var err error
+			if result, err = parseDSN(*dsn); err != nil {
+				return nil, err
+			}
+		}
+		if err := resolvePassword(result, *askPass, *useKeyring); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// parseDSN converts a Go MySQL driver DSN into a TiDBConfig
+func parseDSN(dsn string) (*TiDBConfig, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	config := defaultTiDBConfig
+	config.User = cfg.User
+	config.Password = cfg.Passwd
+	if cfg.DBName != "" {
+		config.Database = cfg.DBName
+	}
+	if cfg.Timeout > 0 {
+		config.Timeout = cfg.Timeout
+	}
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN address '%s': %w", cfg.Addr, err)
+	}
+	config.Host = host
+	config.TLS = cfg.TLSConfig
+	if config.Port, err = strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("invalid DSN port '%s': %w", port, err)
+	}
+	return &config, nil
+}
+
+// tlsConfigName is the name the custom TLS config is registered with in the mysql driver
+const tlsConfigName = "calibration"
+
+// registerTLSConfig registers a custom TLS config with the mysql driver if
+// certificate files are given, and returns the DSN tls parameter value
+func registerTLSConfig(config *TiDBConfig) (string, error) {
+	switch config.TLS {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		return "", fmt.Errorf("invalid TLS mode '%s': expected true, skip-verify or preferred", config.TLS)
+	}
+	if config.TLSCA == "" && config.TLSCert == "" && config.TLSKey == "" {
+		if config.TLS == "false" {
+			return "", nil
+		}
+		return config.TLS, nil
+	}
+	if config.TLS == "false" {
+		return "", fmt.Errorf("TLS certificates given with TLS disabled")
+	}
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return "", fmt.Errorf("the client certificate and key must be given together")
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         config.Host,
+		InsecureSkipVerify: config.TLS == "skip-verify",
+	}
+	if config.TLSCA != "" {
+		pem, err := os.ReadFile(config.TLSCA)
+		if err != nil {
+			return "", fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificates found in %s", config.TLSCA)
+		}
+	}
+	if config.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+		return "", fmt.Errorf("failed to register TLS config: %w", err)
+	}
+	return tlsConfigName, nil
+}
+
 // Connect establishes a connection to TiDB
 func (c *TiDBClient) Connect(config *TiDBConfig) error {
 	if config == nil {
@@ -68,9 +239,21 @@ func (c *TiDBClient) Connect(config *TiDBConfig) error {
 	}
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=%s&parseTime=true",
 		config.User, config.Password, config.Host, config.Port, config.Database, config.Timeout)
-	slog.Debug("TiDB connection config", "host", config.Host, "port", config.Port, "database", config.Database)
+	tlsMode, err := registerTLSConfig(config)
+	if err != nil {
+		return err
+	}
+	if tlsMode != "" {
+		dsn += "&tls=" + tlsMode
+	}
+	slog.Debug("TiDB connection config", "host", config.Host, "port", config.Port, "database", config.Database, "tls", tlsMode)
+	c.dsn = dsn
+	return c.open()
+}
 
-	db, err := sql.Open("mysql", dsn)
+// open opens the query and plan connections
+func (c *TiDBClient) open() error {
+	db, err := sql.Open("mysql", c.dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -88,7 +271,7 @@ func (c *TiDBClient) Connect(config *TiDBConfig) error {
 
 	// Use a separate connection for EXPLAIN FOR CONNECTION,
 	// since it may destroy things like @@tidb_last_query_info
-	db, err = sql.Open("mysql", dsn)
+	db, err = sql.Open("mysql", c.dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -107,6 +290,7 @@ func (c *TiDBClient) ExecuteQuery(query string) (*sql.Rows, error) {
 	if c.db == nil {
 		return nil, fmt.Errorf("database connection not established")
 	}
+	c.limiter.Wait()
 	slog.Debug("Executing query", "query", query)
 
 	return c.db.Query(query)
@@ -114,31 +298,49 @@ func (c *TiDBClient) ExecuteQuery(query string) (*sql.Rows, error) {
 
 // ExecuteQueryWithPlanAndRU executes a SQL query and returns the result
 func (c *TiDBClient) ExecuteQueryGetPlan(query string) (*ExecutionPlan, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.db == nil || c.dbPlan == nil {
 		return nil, fmt.Errorf("database connection not established")
 	}
 	slog.Debug("Executing query", "query", query)
 
-	id, err := c.getConnectionID()
+	var conn queryer = c.db
+	if isDML(query) {
+		// DML runs in a transaction that is rolled back, to keep the table
+		// unchanged, all its statements on the connection of the transaction
+		tx, err := c.db.Begin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			if err := tx.Rollback(); err != nil {
+				slog.Warn("Failed to roll back DML", "query", query, "error", err)
+			}
+		}()
+		conn = tx
+	}
+	id, err := connectionID(conn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get connection id: %w", err)
 	}
 	c.dbConnectionID = id
+	c.limiter.Wait()
+	if c.explainAnalyze {
+		return c.explainAnalyzeGetPlan(conn, query)
+	}
+	if c.simulatedRTT > 0 {
+		// Simulated client to TiDB round trip, paced like a remote client but
+		// outside the measured time, the RTT report adds it as its own term
+		time.Sleep(c.simulatedRTT)
+	}
 	startTime := time.Now()
-	rows, err := c.db.Query(query)
+	rows, err := conn.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
-	var aVal, bVal int
-	var cVal string
 	count := 0
 	for rows.Next() {
-		if count == 0 {
-			if err = rows.Scan(&aVal, &bVal, &cVal); err != nil {
-				_ = rows.Close()
-				return nil, err
-			}
-		}
 		count++
 	}
 	err = rows.Close()
@@ -160,17 +362,143 @@ func (c *TiDBClient) ExecuteQueryGetPlan(query string) (*ExecutionPlan, error) {
 	var s string
 	// TODO: Investigate if it is possible to get this in the OK package
 	//
-	err = c.db.QueryRow("select @@tidb_last_query_info").Scan(&s)
+	// Read together, since the next statement resets the last plan flags
+	err = conn.QueryRow("select @@tidb_last_query_info, @@last_plan_from_cache, @@last_plan_from_binding").Scan(&s, &plan.fromCache, &plan.fromBinding)
 	if err != nil {
 		return nil, fmt.Errorf("failed to to get last query info: %w", err)
 	}
 	plan.ExecutionTime = elapsed
-	plan.bVal = bVal
+	plan.startTime = startTime
 	plan.QueryInfo = s
 	plan.rows = count
+	c.limiter.ConsumeRU(getRU(plan))
 	return plan, nil
 }
 
+// GetServerVersion returns the version string reported by the server
+func (c *TiDBClient) GetServerVersion() (string, error) {
+	if c.db == nil {
+		return "", fmt.Errorf("database connection not established")
+	}
+	var version string
+	slog.Debug("Executing query", "query", "SELECT VERSION()")
+	err := c.db.QueryRow("SELECT VERSION()").Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+	return version, nil
+}
+
+// GetStoreInfo returns the storage engine configuration per TiKV store
+func (c *TiDBClient) GetStoreInfo() ([]StoreInfo, error) {
+	if c.db == nil {
+		return nil, fmt.Errorf("database connection not established")
+	}
+	query := fmt.Sprintf("SHOW CONFIG WHERE type = 'tikv' AND name IN ('%s')", strings.Join(storageConfigNames, "','"))
+	slog.Debug("Executing query", "query", query)
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store config: %w", err)
+	}
+	defer rows.Close()
+	var stores []StoreInfo
+	index := make(map[string]int)
+	for rows.Next() {
+		var storeType, instance, name, value string
+		if err = rows.Scan(&storeType, &instance, &name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan store config: %w", err)
+		}
+		i, ok := index[instance]
+		if !ok {
+			i = len(stores)
+			index[instance] = i
+			stores = append(stores, StoreInfo{Instance: instance, Type: storeType, Config: make(map[string]string)})
+		}
+		stores[i].Config[name] = value
+	}
+	return stores, rows.Err()
+}
+
+// explainCost returns the estimated cost of the root operator for the given EXPLAIN format.
+// Uses the plan connection, to not disturb the session state of the query connection.
+func (c *TiDBClient) explainCost(query, format string) (float64, error) {
+	explainQuery := fmt.Sprintf("EXPLAIN FORMAT='%s' %s", format, query)
+	slog.Debug("Executing query", "query", explainQuery)
+	rows, err := c.dbPlan.Query(explainQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to explain with format %s: %w", format, err)
+	}
+	defer rows.Close()
+	plan, err := parseTabularExecutionPlan(rows)
+	if err != nil {
+		return 0, err
+	}
+	return plan.EstCost, nil
+}
+
+// GetEstimatedCost returns the estimated cost of the query's plan, from both
+// FORMAT='verbose' and the cost trace, reconciled to a single canonical cost.
+// The cost is cached per query, since repetitions get the same estimate.
+func (c *TiDBClient) GetEstimatedCost(query string) (EstimatedCost, error) {
+	if c.dbPlan == nil {
+		return EstimatedCost{}, fmt.Errorf("database connection not established")
+	}
+	if cost, ok := c.estCosts[query]; ok {
+		return cost, nil
+	}
+	var cost EstimatedCost
+	verbose, errVerbose := c.explainCost(query, "verbose")
+	if errVerbose == nil {
+		cost.Verbose = verbose
+	}
+	trace, errTrace := c.explainCost(query, "cost_trace")
+	if errTrace == nil {
+		cost.CostTrace = trace
+	}
+	switch {
+	case errVerbose != nil && errTrace != nil:
+		return cost, errVerbose
+	case errTrace != nil:
+		slog.Debug("Cost trace not available", "error", errTrace)
+		cost.Cost, cost.Source = cost.Verbose, "verbose"
+	case errVerbose != nil:
+		cost.Cost, cost.Source = cost.CostTrace, "cost_trace"
+	case math.Abs(cost.Verbose-cost.CostTrace) <= 0.01*max(math.Abs(cost.Verbose), math.Abs(cost.CostTrace)):
+		cost.Cost, cost.Source = cost.Verbose, "verbose"
+	default:
+		// The verbose format is what EXPLAIN users see, so prefer it, but keep the discrepancy visible
+		slog.Warn("Estimated cost differs between verbose and cost trace", "query", query,
+			"verbose", cost.Verbose, "cost_trace", cost.CostTrace)
+		cost.Cost, cost.Source = cost.Verbose, "verbose (cost_trace differs)"
+	}
+	if c.estCosts == nil {
+		c.estCosts = make(map[string]EstimatedCost)
+	}
+	c.estCosts[query] = cost
+	return cost, nil
+}
+
+// GetSQLDigest returns the digest TiDB records the statement with in the
+// statement summary and slow log, to find the calibration queries in TiDB
+// Dashboard. The digest is of the normalized statement, so it is cached per query.
+func (c *TiDBClient) GetSQLDigest(query string) (string, error) {
+	if c.dbPlan == nil {
+		return "", fmt.Errorf("database connection not established")
+	}
+	if digest, ok := c.digests[query]; ok {
+		return digest, nil
+	}
+	var digest sql.NullString
+	if err := c.dbPlan.QueryRow("SELECT TIDB_ENCODE_SQL_DIGEST(?)", query).Scan(&digest); err != nil {
+		return "", fmt.Errorf("failed to get statement digest: %w", err)
+	}
+	if c.digests == nil {
+		c.digests = make(map[string]string)
+	}
+	c.digests[query] = digest.String
+	return digest.String, nil
+}
+
 // GetTableRowCount returns number of rows in a table, or error if not exists
 func (c *TiDBClient) GetTableRowCount(tableName string) (int, error) {
 	// Get current row count
@@ -184,6 +512,59 @@ func (c *TiDBClient) GetTableRowCount(tableName string) (int, error) {
 	return rowCount, nil
 }
 
+// countMatchingRows sets the MatchingRows of the scenarios with a CountQuery
+// to the rows their predicate matches in the test table, instead of the
+// number expected from the value distribution, and the Groups if the query
+// counts them in a second column. Each query is run once.
+func (c *TiDBClient) countMatchingRows(scenarios []TestScenario) error {
+	counts := make(map[string][2]int)
+	for i := range scenarios {
+		query := scenarios[i].CountQuery
+		if query == "" {
+			continue
+		}
+		count, ok := counts[query]
+		if !ok {
+			var err error
+			if count, err = c.queryCounts(query); err != nil {
+				return fmt.Errorf("failed to count the matching rows of %s: %w", scenarios[i].ID, err)
+			}
+			counts[query] = count
+		}
+		scenarios[i].MatchingRows = count[0]
+		if count[1] > 0 {
+			scenarios[i].Groups = count[1]
+		}
+	}
+	return nil
+}
+
+// queryCounts returns the one or two counts of the single row of a query
+func (c *TiDBClient) queryCounts(query string) ([2]int, error) {
+	var counts [2]int
+	slog.Debug("Executing query", "query", query)
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return counts, err
+	}
+	if len(columns) > len(counts) {
+		return counts, fmt.Errorf("expected at most %d counts, got %d columns", len(counts), len(columns))
+	}
+	dest := []any{&counts[0], &counts[1]}[:len(columns)]
+	if !rows.Next() {
+		return counts, cmp.Or(rows.Err(), sql.ErrNoRows)
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return counts, err
+	}
+	return counts, rows.Err()
+}
+
 // GetExplainPlan returns the execution plan for a query
 func (c *TiDBClient) GetExplainPlan(query string) (*ExecutionPlan, error) {
 	if c.db == nil {
@@ -255,6 +636,7 @@ func getPlanFromExplainAnalyze(rows *sql.Rows) (*ExecutionPlan, error) {
 			ActRows:       int64(actRows),
 			OperatorInfo:  operatorInfo,
 			ExecutionInfo: executionInfo,
+			Exec:          parseExecutionInfo(executionInfo),
 			Memory:        memory,
 			Disk:          disk,
 			AccessObject:  accessObject,
@@ -273,6 +655,100 @@ func getPlanFromExplainAnalyze(rows *sql.Rows) (*ExecutionPlan, error) {
 	return retPlan, nil
 }
 
+// getPlanFromNamedColumns parses the EXPLAIN formats that include estCost,
+// like FORMAT='verbose' (with or without ANALYZE) and FORMAT='cost_trace',
+// mapping the columns by name
+func getPlanFromNamedColumns(rows *sql.Rows, columns []string) (*ExecutionPlan, error) {
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var retPlan, currPlan *ExecutionPlan
+	for {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan execution plan line: %w", err)
+		}
+		plan := &ExecutionPlan{}
+		for i, column := range columns {
+			v := values[i].String
+			switch column {
+			case "id":
+				plan.ID = v
+			case "estRows":
+				plan.EstRows, _ = strconv.ParseFloat(v, 64)
+			case "estCost":
+				plan.EstCost, _ = strconv.ParseFloat(v, 64)
+			case "actRows":
+				plan.ActRows, _ = strconv.ParseInt(v, 10, 64)
+			case "task":
+				plan.Task = v
+			case "access object":
+				plan.AccessObject = v
+			case "execution info":
+				plan.ExecutionInfo = v
+				plan.Exec = parseExecutionInfo(v)
+			case "operator info":
+				plan.OperatorInfo = v
+			case "memory":
+				plan.Memory = v
+			case "disk":
+				plan.Disk = v
+			}
+		}
+		if retPlan == nil {
+			retPlan = plan
+			currPlan = plan
+		} else {
+			currPlan.Next = plan
+			currPlan = plan
+		}
+		if !rows.Next() {
+			break
+		}
+	}
+	return retPlan, nil
+}
+
+// explainAnalyzeGetPlan executes the query under EXPLAIN ANALYZE, the plan
+// with the per operator time, actRows, cop tasks and memory is its result.
+// The latency is the time of the root operator, which leaves out the client
+// round trip and result set transfer, like the simulated round trip.
+// The caller holds c.mu.
+func (c *TiDBClient) explainAnalyzeGetPlan(conn queryer, query string) (*ExecutionPlan, error) {
+	analyzeQuery := "EXPLAIN ANALYZE " + query
+	slog.Debug("Executing query", "query", analyzeQuery)
+	if c.simulatedRTT > 0 {
+		time.Sleep(c.simulatedRTT)
+	}
+	startTime := time.Now()
+	rows, err := conn.Query(analyzeQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	plan, err := parseTabularExecutionPlan(rows)
+	elapsed := time.Since(startTime)
+	if closeErr := rows.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close rows: %w", closeErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse execution plan: %w", err)
+	}
+	err = conn.QueryRow("select @@tidb_last_query_info, @@last_plan_from_cache, @@last_plan_from_binding").Scan(&plan.QueryInfo, &plan.fromCache, &plan.fromBinding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to to get last query info: %w", err)
+	}
+	plan.ExecutionTime = elapsed
+	if root, ok := operatorTime(plan); ok {
+		plan.ExecutionTime = root
+	}
+	plan.startTime = startTime
+	plan.rows = int(plan.ActRows)
+	c.limiter.ConsumeRU(getRU(plan))
+	return plan, nil
+}
+
 // Standard EXPLAIN format: id, estRows, task, access object, operator info
 
 // parseTabularExecutionPlan parses a tabular format execution plan
@@ -292,6 +768,8 @@ func parseTabularExecutionPlan(rows *sql.Rows) (*ExecutionPlan, error) {
 		return getPlanFromSimpleExplain(rows)
 	} else if len(columns) == 9 {
 		return getPlanFromExplainAnalyze(rows)
+	} else if slices.Contains(columns, "estCost") {
+		return getPlanFromNamedColumns(rows, columns)
 	} else {
 		return nil, fmt.Errorf("unsupported EXPLAIN format with %d columns: %v", len(columns), columns)
 	}
@@ -299,13 +777,14 @@ func parseTabularExecutionPlan(rows *sql.Rows) (*ExecutionPlan, error) {
 
 // Close closes the database connection
 func (c *TiDBClient) Close() error {
-	if c.db != nil {
-		return c.db.Close()
-	}
+	var err error
 	if c.dbPlan != nil {
-		return c.dbPlan.Close()
+		err = c.dbPlan.Close()
 	}
-	return nil
+	if c.db != nil {
+		err = c.db.Close()
+	}
+	return err
 }
 
 // ExecuteQueryWithMetrics executes a query and captures performance metrics
@@ -316,12 +795,46 @@ func (c *TiDBClient) ExecuteQueryWithMetrics(testScenario TestScenario) (*TestEx
 // ExecuteQueryWithMetrics executes a query and captures performance metrics
 func (c *TiDBClient) executeQueryWithMetrics(testScenario TestScenario, retry bool) (*TestExecutionResult, error) {
 	res := &TestExecutionResult{
-		ScenarioID:  testScenario.ID,
-		Variant:     testScenario.Variant,
-		Query:       testScenario.Query,
-		ExplainOnly: testScenario.ExplainOnly,
+		ScenarioID:     testScenario.ID,
+		ScenarioName:   testScenario.Name,
+		Variant:        testScenario.Variant,
+		TableName:      testScenario.TableName,
+		Query:          testScenario.Query,
+		ExplainOnly:    testScenario.ExplainOnly,
+		RowCount:       testScenario.RowCount,
+		MatchingRows:   testScenario.MatchingRows,
+		Groups:         testScenario.Groups,
+		PredicateValue: testScenario.PredicateValue,
+	}
+	query, err := c.resolveIndexHints(testScenario.Query, testScenario.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if query != testScenario.Query {
+		res.Query = query
+	}
+	if c.ignorePlanCache {
+		// Make every repetition re-optimize the query
+		query = withHint(query, "IGNORE_PLAN_CACHE()")
+		res.Query = query
+	}
+
+	if cost, err := c.GetEstimatedCost(query); err != nil {
+		slog.Warn("Failed to get estimated cost", "query", query, "error", err)
+	} else {
+		res.EstCost = cost
+	}
+
+	statement := query
+	if c.explainAnalyze && !testScenario.ExplainOnly {
+		// The statement summary records the executed statement, not the query
+		statement = "EXPLAIN ANALYZE " + query
+	}
+	if digest, err := c.GetSQLDigest(statement); err != nil {
+		slog.Warn("Failed to get statement digest", "query", statement, "error", err)
+	} else {
+		res.Digest = digest
 	}
-	query := testScenario.Query
 
 	if testScenario.ExplainOnly {
 		// Get execution plan first
@@ -330,57 +843,219 @@ func (c *TiDBClient) executeQueryWithMetrics(testScenario TestScenario, retry bo
 			return nil, err
 		}
 		// Analyze the execution plan to determine plan type
-		res.PlanType = determinePlanType(plan)
+		res.Plan = plan
+		res.PlanType = scenarioPlanType(testScenario, plan)
 		return res, nil
 	}
 
+	var rcWaitBefore time.Duration
+	if c.captureRCWait {
+		var err error
+		rcWaitBefore, err = c.getQueuedRCTime(query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Execute the query and get the plan
 	plan, err := c.ExecuteQueryGetPlan(query)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.captureRCWait {
+		rcWaitAfter, err := c.getQueuedRCTime(query)
+		if err != nil {
+			return nil, err
+		}
+		res.RCWait = rcWaitAfter - rcWaitBefore
+		res.RCWaitCaptured = true
+	}
+
 	res.Plan = plan
-	res.PlanType = determinePlanType(plan)
+	res.StartTime = plan.startTime
+	res.PlanType = scenarioPlanType(testScenario, plan)
+	res.RU, res.RUReported = parseRU(plan)
+	res.CopTasks, res.CopConcurrency = copParallelism(plan)
+	res.TotalKeys, res.ProcessedKeys = scannedKeys(plan)
+	res.PlanFromCache = plan.fromCache
+	res.PlanFromBinding = plan.fromBinding
 
-	if isCoprCacheUsed(plan) {
+	res.CoprCacheHitRatio = coprCacheHitRatio(plan)
+
+	if res.CoprCacheHitRatio > 0 && (c.coprCache == "" || c.coprCache == coprCacheBust) {
+		slog.Info("Execution served from the coprocessor cache, invalidating it", "scenario", testScenario.ID, "hit_ratio", res.CoprCacheHitRatio)
 		if !retry {
 			return nil, fmt.Errorf("execution coprocessor cache is used")
 		}
-		// cache is used, try to update all b values and then back again, to invalidate the cache
-		var count int
-		b := strconv.Itoa(plan.bVal)
-		_, err = c.ExecuteQuery("UPDATE " + testScenario.TableName + " SET b = -313 where b = " + b + " ORDER BY rand() LIMIT 50000")
-		if err != nil {
+		if err = c.bustCoprCache(testScenario); err != nil {
 			return nil, err
 		}
-		for {
-			_, err = c.ExecuteQuery("UPDATE " + testScenario.TableName + " SET b = " + b + " where b = -313 LIMIT 50000")
-			if err != nil {
-				return nil, err
-			}
-			err = c.db.QueryRow("SELECT COUNT(*) FROM " + testScenario.TableName + " WHERE b = -313").Scan(&count)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get count: %w", err)
-			}
-			if count == 0 {
-				break
-			}
+		res, err = c.executeQueryWithMetrics(testScenario, false)
+		if res != nil && res.Retried == "" {
+			res.Retried = retriedCoprCache
 		}
-		return c.executeQueryWithMetrics(testScenario, false)
+		return res, err
+	}
+	if res.Retried == "" && copRetries(plan) > 0 {
+		res.Retried = retriedBackoff
 	}
 	return res, nil
 }
 
+// withHint adds an optimizer hint to a SELECT, UPDATE or DELETE query, to its existing hint comment if it has one
+func withHint(query, hint string) string {
+	if strings.Contains(query, "/*+ ") {
+		return strings.Replace(query, "/*+ ", "/*+ "+hint+" ", 1)
+	}
+	for _, keyword := range []string{"UPDATE ", "DELETE "} {
+		if strings.HasPrefix(query, keyword) {
+			return keyword + "/*+ " + hint + " */ " + strings.TrimPrefix(query, keyword)
+		}
+	}
+	return strings.Replace(query, "SELECT ", "SELECT /*+ "+hint+" */ ", 1)
+}
+
+// isDML returns true for the UPDATE and DELETE queries of the dml family
+func isDML(query string) bool {
+	return strings.HasPrefix(query, "UPDATE ") || strings.HasPrefix(query, "DELETE ")
+}
+
+// scenarioPlanType classifies the plan of a scenario. The COUNT(*) of the
+// count family, without GROUP BY, is also classified by its access path, like
+// index_reader_stream_agg_pushdown, as that is what differs between its
+// variants, the aggregations of the other families by their method only.
+func scenarioPlanType(scenario TestScenario, plan *ExecutionPlan) string {
+	planType := determinePlanType(plan)
+	if scenario.family != "count" || joinPlanType(plan) != "" || !scalarAgg(plan) {
+		return planType
+	}
+	aggType := aggPlanType(plan)
+	if aggType == "" {
+		return planType
+	}
+	if readsTiFlash(plan) {
+		return "tiflash_" + accessPlanType(plan) + "_" + aggType
+	}
+	return accessPlanType(plan) + "_" + aggType
+}
+
 // determinePlanType analyzes the execution plan to determine if it's index lookup or table scan
 func determinePlanType(plan *ExecutionPlan) string {
+	if readsTiFlash(plan) {
+		return "tiflash_" + tikvPlanType(plan)
+	}
+	return tikvPlanType(plan)
+}
+
+// tikvPlanType classifies the plan by its join, aggregation or access method
+func tikvPlanType(plan *ExecutionPlan) string {
+	if joinType := joinPlanType(plan); joinType != "" {
+		return joinType
+	}
+	if aggType := aggPlanType(plan); aggType != "" {
+		return aggType
+	}
+	planType := accessPlanType(plan)
+	if planType == "index_lookup" && keepsOrder(plan) {
+		// Keep order index reads are executed serially, unlike the parallel unordered reads
+		return "index_lookup_keep_order"
+	}
+	return planType
+}
+
+// joinPlanType returns the join method of the first join operator of the plan, or "" if there is no join
+func joinPlanType(plan *ExecutionPlan) string {
+	for ; plan != nil; plan = plan.Next {
+		id := strings.ToLower(plan.ID)
+		switch {
+		case strings.Contains(id, "indexjoin"), strings.Contains(id, "indexhashjoin"), strings.Contains(id, "indexmergejoin"):
+			return "index_join"
+		case strings.Contains(id, "hashjoin"):
+			return "hash_join"
+		case strings.Contains(id, "mergejoin"):
+			return "merge_join"
+		}
+	}
+	return ""
+}
+
+// aggPlanType returns the aggregation method of the plan, suffixed with
+// _pushdown if (partial) aggregation runs in the coprocessor, or "" if there
+// is no aggregation
+func aggPlanType(plan *ExecutionPlan) string {
+	aggType, pushdown := "", false
+	for ; plan != nil; plan = plan.Next {
+		id := strings.ToLower(plan.ID)
+		var opType string
+		switch {
+		case strings.Contains(id, "streamagg"):
+			opType = "stream_agg"
+		case strings.Contains(id, "hashagg"):
+			opType = "hash_agg"
+		default:
+			continue
+		}
+		if aggType == "" {
+			aggType = opType
+		}
+		if strings.Contains(plan.Task, "cop") {
+			pushdown = true
+		}
+	}
+	if pushdown {
+		return aggType + "_pushdown"
+	}
+	return aggType
+}
+
+// scalarAgg returns true if the aggregation of the plan has no GROUP BY, like
+// a SELECT COUNT(*), according to the operator info of its aggregation operators
+func scalarAgg(plan *ExecutionPlan) bool {
+	funcs := false
+	for ; plan != nil; plan = plan.Next {
+		id := strings.ToLower(plan.ID)
+		if !strings.Contains(id, "streamagg") && !strings.Contains(id, "hashagg") {
+			continue
+		}
+		if strings.Contains(plan.OperatorInfo, "group by:") {
+			return false
+		}
+		funcs = funcs || strings.Contains(plan.OperatorInfo, "funcs:")
+	}
+	return funcs
+}
+
+// keepsOrder returns true if any operator of the plan reads in index order
+func keepsOrder(plan *ExecutionPlan) bool {
+	for ; plan != nil; plan = plan.Next {
+		if strings.Contains(plan.OperatorInfo, "keep order:true") {
+			return true
+		}
+	}
+	return false
+}
+
+// accessPlanType classifies the plan by its access path
+func accessPlanType(plan *ExecutionPlan) string {
 	if plan == nil {
 		return "unknown"
 	}
 
 	// Check the root operator
 	id := strings.ToLower(plan.ID)
-	if strings.Contains(id, "index") && !strings.Contains(id, "table") {
+	if strings.Contains(id, "batch_point_get") {
+		// Primary or unique key lookup of several keys, without a coprocessor read
+		return "batch_point_get"
+	} else if strings.Contains(id, "point_get") {
+		return "point_get"
+	} else if strings.Contains(id, "indexmerge") {
+		// Union (or intersection) of several index reads
+		return "index_merge"
+	} else if strings.Contains(id, "indexreader") {
+		// Covering index read, without table lookups
+		return "index_reader"
+	} else if strings.Contains(id, "index") && !strings.Contains(id, "table") {
 		return "index_lookup"
 	} else if strings.Contains(id, "tablereader") {
 		return "table_scan"
@@ -388,7 +1063,7 @@ func determinePlanType(plan *ExecutionPlan) string {
 
 	// Check children for more specific information
 	for plan = plan.Next; plan != nil; plan = plan.Next {
-		planType := determinePlanType(plan)
+		planType := accessPlanType(plan)
 		if planType != "unknown" {
 			return planType
 		}
@@ -397,23 +1072,21 @@ func determinePlanType(plan *ExecutionPlan) string {
 	return "unknown"
 }
 
-func isCoprCacheUsed(plan *ExecutionPlan) bool {
-	if plan == nil {
-		return false
+// getQueuedRCTime returns the total time executions of the query have been
+// queued by resource control (RU throttling), according to the statements summary.
+// It uses the plan connection, to not disturb the session state of the query connection.
+func (c *TiDBClient) getQueuedRCTime(query string) (time.Duration, error) {
+	if c.dbPlan == nil {
+		return 0, fmt.Errorf("database connection not established")
 	}
-
-	r, err := regexp.Compile(`copr_cache_hit_ratio: ([01]\.[0-9][0-9])`)
+	var queued float64
+	rcQuery := "SELECT IFNULL(SUM(EXEC_COUNT * AVG_QUEUED_RC_TIME), 0) FROM information_schema.cluster_statements_summary WHERE DIGEST = STATEMENT_DIGEST(?)"
+	slog.Debug("Executing query", "query", rcQuery)
+	err := c.dbPlan.QueryRow(rcQuery, query).Scan(&queued)
 	if err != nil {
-		return false
-	}
-	matches := r.FindAllStringSubmatch(plan.ExecutionInfo, -1)
-	for _, match := range matches {
-		if len(match) == 2 && match[1] != "0.00" {
-			slog.Info("copr_cache_hit_ratio match", "match", match)
-			return true
-		}
+		return 0, fmt.Errorf("failed to get resource control queued time: %w", err)
 	}
-	return isCoprCacheUsed(plan.Next)
+	return time.Duration(queued), nil
 }
 
 // getConnectionID returns the current connection ID
@@ -421,10 +1094,21 @@ func (c *TiDBClient) getConnectionID() (int, error) {
 	if c.db == nil {
 		return 0, fmt.Errorf("database connection not established")
 	}
+	return connectionID(c.db)
+}
+
+// queryer is the part of *sql.DB and *sql.Tx the executions use, to run the
+// statements of a DML execution in its transaction
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
 
+// connectionID returns the connection ID of the session the queries run on
+func connectionID(conn queryer) (int, error) {
 	var connectionID int
 	slog.Debug("Executing query", "query", "SELECT CONNECTION_ID()")
-	err := c.db.QueryRow("SELECT CONNECTION_ID()").Scan(&connectionID)
+	err := conn.QueryRow("SELECT CONNECTION_ID()").Scan(&connectionID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get connection ID: %w", err)
 	}
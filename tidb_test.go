@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestParseDSN(t *testing.T) {
+	config, err := parseDSN("calib:secret@tcp(tidb.example.com:4001)/bench?timeout=5s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "tidb.example.com" || config.Port != 4001 || config.User != "calib" ||
+		config.Password != "secret" || config.Database != "bench" || config.Timeout != 5*time.Second {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+	config, err = parseDSN("root@tcp(127.0.0.1:4000)/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Database != defaultTiDBConfig.Database {
+		t.Fatalf("expected default database, got %s", config.Database)
+	}
+}
+
+func TestRegisterTLSConfig(t *testing.T) {
+	for _, tc := range []struct {
+		config   TiDBConfig
+		expected string
+		err      bool
+	}{
+		{TiDBConfig{}, "", false},
+		{TiDBConfig{TLS: "true"}, "true", false},
+		{TiDBConfig{TLS: "skip-verify"}, "skip-verify", false},
+		{TiDBConfig{TLS: "required"}, "", true},
+		{TiDBConfig{TLSCert: "client.pem"}, "", true},
+		{TiDBConfig{TLSCA: "missing-ca.pem"}, "", true},
+	} {
+		mode, err := registerTLSConfig(&tc.config)
+		if (err != nil) != tc.err || mode != tc.expected {
+			t.Fatalf("%+v: expected %q (error %v), got %q (%v)", tc.config, tc.expected, tc.err, mode, err)
+		}
+	}
+}
+
+func TestResolvePasswordKeyring(t *testing.T) {
+	keyring.MockInit()
+	config := &TiDBConfig{Host: "tidb.example.com", Port: 4000, User: "calib"}
+	if err := keyring.Set(keyringService, keyringAccount(config), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := resolvePassword(config, false, true); err != nil || config.Password != "secret" {
+		t.Fatalf("expected password from keyring, got %q (%v)", config.Password, err)
+	}
+	// An explicitly given password is not overridden
+	config.Password = "explicit"
+	if err := resolvePassword(config, false, true); err != nil || config.Password != "explicit" {
+		t.Fatalf("expected explicit password, got %q (%v)", config.Password, err)
+	}
+	other := &TiDBConfig{Host: "other.example.com", Port: 4000, User: "calib"}
+	if err := resolvePassword(other, false, true); err != nil || other.Password != "" {
+		t.Fatalf("expected no password, got %q (%v)", other.Password, err)
+	}
+}
+
+func TestWithHint(t *testing.T) {
+	if got := withHint("SELECT * FROM t1K WHERE b = 1", "IGNORE_PLAN_CACHE()"); got != "SELECT /*+ IGNORE_PLAN_CACHE() */ * FROM t1K WHERE b = 1" {
+		t.Fatalf("unexpected query %s", got)
+	}
+	if got := withHint("SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 1", "IGNORE_PLAN_CACHE()"); got != "SELECT /*+ IGNORE_PLAN_CACHE() FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 1" {
+		t.Fatalf("unexpected query %s", got)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FixtureCase is a query and its expected plan from the testdata of the TiDB
+// planner test suites, like planner/core/casetest/testdata/*_out.json
+type FixtureCase struct {
+	Suite string
+	SQL   string
+	Plan  *ExecutionPlan
+}
+
+// PlanType returns the plan type of the expected plan, classified like the measured plans
+func (fc *FixtureCase) PlanType() string {
+	return determinePlanType(fc.Plan)
+}
+
+// fixtureSQLKeys and fixturePlanKeys are the keys of the SQL and the plan
+// rows used by the different suites for their cases
+var (
+	fixtureSQLKeys  = []string{"SQL", "Sql", "Query"}
+	fixturePlanKeys = []string{"Plan", "Result", "Explain", "Res"}
+)
+
+// readFixtureCases reads the cases with an expected plan from a TiDB test
+// suite output file, a list of suites with a name and their cases
+func readFixtureCases(path string) ([]*FixtureCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suites []struct {
+		Name  string
+		Cases json.RawMessage
+	}
+	if err = json.Unmarshal(data, &suites); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var cases []*FixtureCase
+	for _, suite := range suites {
+		// The input files list the queries as strings, only the output files have plans
+		var suiteCases []map[string]json.RawMessage
+		if err = json.Unmarshal(suite.Cases, &suiteCases); err != nil {
+			continue
+		}
+		for _, c := range suiteCases {
+			sql := fixtureString(c, fixtureSQLKeys)
+			if sql == "" {
+				continue
+			}
+			var lines []string
+			for _, key := range fixturePlanKeys {
+				if err = json.Unmarshal(c[key], &lines); err == nil && len(lines) > 0 {
+					break
+				}
+			}
+			plan := parseFixturePlan(lines)
+			if plan == nil {
+				continue
+			}
+			cases = append(cases, &FixtureCase{Suite: suite.Name, SQL: sql, Plan: plan})
+		}
+	}
+	return cases, nil
+}
+
+// fixtureString returns the first of the keys that is a string
+func fixtureString(c map[string]json.RawMessage, keys []string) string {
+	for _, key := range keys {
+		var s string
+		if err := json.Unmarshal(c[key], &s); err == nil && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// parseFixturePlan parses the plan rows of a test case, the EXPLAIN columns
+// separated by spaces, like "└─IndexRangeScan_8 10.00 cop[tikv] table:t, index:b(b) range:[1,1]".
+// Rows that are not a plan, like query results, give nil.
+func parseFixturePlan(lines []string) *ExecutionPlan {
+	var retPlan, currPlan *ExecutionPlan
+	for _, line := range lines {
+		start := strings.IndexFunc(line, func(r rune) bool { return !strings.ContainsRune("│├└─ ", r) })
+		if start < 0 {
+			continue
+		}
+		fields := strings.Fields(line[start:])
+		if len(fields) < 2 {
+			return nil
+		}
+		estRows, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil
+		}
+		plan := &ExecutionPlan{
+			ID:      line[:start] + fields[0],
+			EstRows: estRows,
+		}
+		rest := fields[2:]
+		// The verbose and cost_trace formats have the estimated cost as third column
+		if len(rest) > 0 {
+			if cost, err := strconv.ParseFloat(rest[0], 64); err == nil {
+				plan.EstCost = cost
+				rest = rest[1:]
+			}
+		}
+		for i, field := range rest {
+			if strings.HasPrefix(field, "root") || strings.HasPrefix(field, "cop[") || strings.HasPrefix(field, "mpp[") || strings.HasPrefix(field, "batchCop[") {
+				plan.Task = field
+				plan.OperatorInfo = strings.Join(rest[i+1:], " ")
+				break
+			}
+		}
+		if retPlan == nil {
+			retPlan = plan
+		} else {
+			currPlan.Next = plan
+		}
+		currPlan = plan
+	}
+	return retPlan
+}
+
+var (
+	explainPrefixRegex = regexp.MustCompile(`(?i)^\s*(?:explain|desc)(?:\s+analyze)?(?:\s+format\s*=\s*['"]?\w+['"]?)?\s+`)
+	hintRegex          = regexp.MustCompile(`/\*\+.*?\*/`)
+	tableRefRegex      = regexp.MustCompile("(?i)\\b(?:from|join|update|into)\\s+`?(\\w+)`?")
+	literalRegex       = regexp.MustCompile(`'[^']*'|"[^"]*"|\b\d+(?:\.\d+)?\b`)
+	literalListRegex   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+)
+
+// queryShape returns the shape of a query for lining up queries on
+// different tables, without EXPLAIN, hints, table names and literals
+func queryShape(query string) string {
+	// The digest texts of the statements summary quote the names
+	shape := strings.ReplaceAll(query, "`", "")
+	shape = explainPrefixRegex.ReplaceAllString(shape, "")
+	shape = hintRegex.ReplaceAllString(shape, "")
+	for _, match := range tableRefRegex.FindAllStringSubmatch(shape, -1) {
+		shape = regexp.MustCompile(`\b`+regexp.QuoteMeta(match[1])+`\b`).ReplaceAllString(shape, "t")
+	}
+	shape = literalRegex.ReplaceAllString(shape, "?")
+	shape = literalListRegex.ReplaceAllString(shape, "(?...)")
+	shape = strings.ToLower(strings.Join(strings.Fields(shape), " "))
+	return strings.TrimSuffix(shape, ";")
+}
+
+// FixtureMatch is a fixture case lined up with a measured cell of the same query shape
+type FixtureMatch struct {
+	Case *FixtureCase
+	Cell *CellAnalysis
+}
+
+// matchFixtures lines up the fixture cases with the measured cells whose
+// unhinted query has the same shape
+func matchFixtures(cases []*FixtureCase, results []*TestExecutionResult, excluded []string) []FixtureMatch {
+	shapes := make(map[string]string)
+	for _, r := range results {
+		if r.ExplainOnly {
+			shapes[r.ScenarioID] = queryShape(r.Query)
+		}
+	}
+	cells := analyzeCells(results, excluded)
+	var matches []FixtureMatch
+	for _, fc := range cases {
+		shape := queryShape(fc.SQL)
+		for _, cell := range cells {
+			if shapes[cell.ScenarioID] == shape {
+				matches = append(matches, FixtureMatch{Case: fc, Cell: cell})
+			}
+		}
+	}
+	return matches
+}
+
+// runReportExpectations implements the report expectations command, lining
+// up the measured cells with the expected plans of TiDB planner test suites
+func runReportExpectations(args []string) error {
+	fs := flag.NewFlagSet("report expectations", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: report expectations <results.json> <suite_out.json>...")
+	}
+	rs, err := readResultSet(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cases, err := readFixtureFiles(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	outputFixtureMatches(matchFixtures(cases, rs.Results, rs.Metadata.ExcludedPlanTypes), len(cases))
+	return nil
+}
+
+// readFixtureFiles reads the test cases of the suite output files
+func readFixtureFiles(paths []string) ([]*FixtureCase, error) {
+	var cases []*FixtureCase
+	for _, path := range paths {
+		fileCases, err := readFixtureCases(path)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, fileCases...)
+	}
+	return cases, nil
+}
+
+// outputFixtureMatches prints the plan type expected by the TiDB test suites
+// next to the plan type chosen and the fastest plan type measured per cell
+func outputFixtureMatches(matches []FixtureMatch, cases int) {
+	fmt.Printf("\n🧪 TiDB Test Suite Expectations\n")
+	fmt.Println("====================")
+	fmt.Printf("%d of %d test cases with a plan have the query shape of a measured cell\n", countMatchedCases(matches), cases)
+	if len(matches) == 0 {
+		return
+	}
+	fmt.Printf("Suite\tScenario\tExpected\tChosen\tFastest\tExpected-est-rows\tExpected-est-cost\n")
+	for _, m := range matches {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%.02f\t%.02f\n", m.Case.Suite, m.Cell.ScenarioID, m.Case.PlanType(),
+			m.Cell.Chosen, m.Cell.Best, m.Case.Plan.EstRows, m.Case.Plan.EstCost)
+	}
+}
+
+// countMatchedCases returns the number of distinct fixture cases matched
+func countMatchedCases(matches []FixtureMatch) int {
+	seen := make(map[*FixtureCase]bool)
+	for _, m := range matches {
+		seen[m.Case] = true
+	}
+	return len(seen)
+}
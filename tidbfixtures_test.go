@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testFixture = `[
+  {
+    "Name": "TestIndexLookUpCost",
+    "Cases": [
+      {
+        "SQL": "explain format = 'brief' select * from t where b = 1",
+        "Plan": [
+          "IndexLookUp 10.00 root  ",
+          "├─IndexRangeScan(Build) 10.00 cop[tikv] table:t, index:b(b) range:[1,1], keep order:false",
+          "└─TableRowIDScan(Probe) 10.00 cop[tikv] table:t keep order:false"
+        ]
+      },
+      {
+        "SQL": "explain format = 'verbose' select * from t where b in (1, 2, 3)",
+        "Plan": [
+          "TableReader_7 30.00 2400.50 root  data:Selection_6",
+          "└─Selection_6 30.00 1500.00 cop[tikv]  in(test.t.b, 1, 2, 3)",
+          "  └─TableFullScan_5 10000.00 1200.00 cop[tikv] table:t keep order:false"
+        ]
+      },
+      {
+        "SQL": "select count(*) from t",
+        "Result": ["10000"]
+      }
+    ]
+  },
+  {
+    "Name": "TestInputOnly",
+    "Cases": ["select 1"]
+  }
+]`
+
+func TestReadFixtureCases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cost_suite_out.json")
+	if err := os.WriteFile(path, []byte(testFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := readFixtureCases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The query result and the input only suite have no plans
+	if len(cases) != 2 {
+		t.Fatalf("expected 2 cases, got %d", len(cases))
+	}
+	if pt := cases[0].PlanType(); pt != "index_lookup" {
+		t.Fatalf("expected index_lookup, got %s", pt)
+	}
+	plan := cases[1].Plan
+	if cases[1].PlanType() != "table_scan" || plan.EstRows != 30 || plan.EstCost != 2400.5 || plan.Task != "root" || plan.OperatorInfo != "data:Selection_6" {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if plan.Next.Next.ID != "  └─TableFullScan_5" {
+		t.Fatalf("unexpected operator id %q", plan.Next.Next.ID)
+	}
+
+	results := []*TestExecutionResult{
+		{ScenarioID: "index_1K_10", PlanType: "index_lookup", ExplainOnly: true, Query: "SELECT * FROM t1K WHERE b = 10"},
+		newTestResult("index_1K_10", "index_lookup", 1),
+		newTestResult("index_1K_10", "table_scan", 2),
+		{ScenarioID: "inlist3_1K_10", PlanType: "index_lookup", ExplainOnly: true, Query: "SELECT * FROM t1K WHERE b IN (10, 999999, 999998)"},
+		newTestResult("inlist3_1K_10", "index_lookup", 3),
+		newTestResult("inlist3_1K_10", "table_scan", 2),
+	}
+	matches := matchFixtures(cases, results, nil)
+	if len(matches) != 2 || matches[0].Cell.ScenarioID != "index_1K_10" || matches[1].Cell.ScenarioID != "inlist3_1K_10" || matches[1].Cell.Best != "table_scan" {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+}
+
+func TestQueryShape(t *testing.T) {
+	for _, tc := range []struct{ a, b string }{
+		{"SELECT /*+ FORCE_INDEX(t1K, b) */ * FROM t1K WHERE b = 10", "explain select * from t where b = 1;"},
+		{"SELECT * FROM t1K_nc JOIN t1K_nc_join ON t1K_nc.id = t1K_nc_join.id WHERE t1K_nc.b = 'x'", "desc format='brief' select * from t1 join t2 on t1.id = t2.id where t1.b = 1"},
+	} {
+		if queryShape(tc.a) != queryShape(tc.b) {
+			t.Fatalf("expected the same shape, got %q and %q", queryShape(tc.a), queryShape(tc.b))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// withTiFlashVariant adds the TiFlash variant to the scenarios of a cell: the
+// unhinted query of the cell, with the table read from TiFlash. Join cells are
+// left alone, their partner tables have no TiFlash replicas, and so are DML
+// cells, write statements do not read from TiFlash.
+func withTiFlashVariant(cell []TestScenario) []TestScenario {
+	for _, scenario := range cell {
+		if !scenario.ExplainOnly || strings.Contains(scenario.Query, " JOIN ") || isDML(scenario.Query) {
+			continue
+		}
+		tiflash := scenario
+		tiflash.Variant = "TiFlash"
+		tiflash.Name = "TiFlash - " + scenario.Name
+		tiflash.Query = withHint(scenario.Query, fmt.Sprintf("READ_FROM_STORAGE(TIFLASH[%s])", scenario.TableName))
+		tiflash.ExplainOnly = false
+		return append(cell, tiflash)
+	}
+	return cell
+}
+
+// readsTiFlash returns true if any operator of the plan runs in TiFlash
+func readsTiFlash(plan *ExecutionPlan) bool {
+	for ; plan != nil; plan = plan.Next {
+		if strings.Contains(plan.Task, "tiflash") {
+			return true
+		}
+	}
+	return false
+}
+
+// setTiFlashReplica creates a TiFlash replica of the table
+func (c *TiDBClient) setTiFlashReplica(tableName string) error {
+	if _, err := c.ExecuteQuery(fmt.Sprintf("ALTER TABLE %s SET TIFLASH REPLICA 1", tableName)); err != nil {
+		return fmt.Errorf("failed to create TiFlash replica of %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// waitForTiFlashReplicas waits until the TiFlash replicas of the tables are available and fully synced
+func (c *TiDBClient) waitForTiFlashReplicas(tables []string, timeout time.Duration) error {
+	fmt.Printf("⏳ Waiting for the TiFlash replicas of %s\n", strings.Join(tables, ", "))
+	deadline := time.Now().Add(timeout)
+	for _, tableName := range tables {
+		query := "SELECT AVAILABLE, PROGRESS FROM information_schema.tiflash_replica WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+		for {
+			var available int
+			var progress float64
+			slog.Debug("Executing query", "query", query, "table", tableName)
+			if err := c.db.QueryRow(query, tableName).Scan(&available, &progress); err != nil {
+				return fmt.Errorf("failed to get the TiFlash replica status of %s: %w", tableName, err)
+			}
+			if available == 1 && progress >= 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("TiFlash replica of %s not available after %s (progress %.0f%%), is there a TiFlash store?",
+					tableName, timeout, progress*100)
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}
+	fmt.Println("✅ TiFlash replicas available")
+	return nil
+}
+
+// tiflashCell is the fastest TiKV and the TiFlash latency of a cell
+type tiflashCell struct {
+	family, tableSize string
+	matching          int
+	tikvPlan          string
+	tikv, tiflash     time.Duration
+}
+
+// tiflashCells returns the cells measured on both TiKV and TiFlash, sorted
+// by family, table size and matching rows
+func tiflashCells(results []*TestExecutionResult) []tiflashCell {
+	scenarioIDs, groups := groupByScenario(results)
+	var cells []tiflashCell
+	for _, scenarioID := range scenarioIDs {
+		parts := strings.Split(scenarioID, "_")
+		if len(parts) != 3 {
+			continue
+		}
+		cell := tiflashCell{family: parts[0], tableSize: parts[1]}
+		cell.matching, _ = strconv.Atoi(parts[2])
+		for pt, runs := range groups[scenarioID] {
+			var total time.Duration
+			for _, r := range runs {
+				total += r.Plan.ExecutionTime
+			}
+			avg := total / time.Duration(len(runs))
+			switch {
+			case strings.HasPrefix(pt, "tiflash_"):
+				if cell.tiflash == 0 || avg < cell.tiflash {
+					cell.tiflash = avg
+				}
+			case cell.tikv == 0 || avg < cell.tikv:
+				cell.tikv, cell.tikvPlan = avg, pt
+			}
+		}
+		if cell.tikv > 0 && cell.tiflash > 0 {
+			cells = append(cells, cell)
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool {
+		a, b := cells[i], cells[j]
+		if a.family != b.family {
+			return a.family < b.family
+		}
+		if ra, rb := parseRowCountName(a.tableSize), parseRowCountName(b.tableSize); ra != rb {
+			return ra < rb
+		}
+		return a.matching < b.matching
+	})
+	return cells
+}
+
+// parseRowCountName parses a table size name like 1K or 10M
+func parseRowCountName(name string) int {
+	counts, err := parseRowCounts(name)
+	if err != nil || len(counts) != 1 {
+		return 0
+	}
+	return counts[0]
+}
+
+// outputTiFlashCrossover compares the fastest TiKV plan with TiFlash per
+// cell, and reports per family and table size from how many matching rows
+// TiFlash is faster
+func outputTiFlashCrossover(results []*TestExecutionResult) {
+	cells := tiflashCells(results)
+	if len(cells) == 0 {
+		return
+	}
+	fmt.Println("\n⚡ TiKV vs TiFlash")
+	fmt.Println("====================")
+	fmt.Printf("Family\tTable_size\tMatching\tTiKV_plan\tTiKV_ms\tTiFlash_ms\tFaster\n")
+	for _, cell := range cells {
+		faster := "tikv"
+		if cell.tiflash < cell.tikv {
+			faster = "tiflash"
+		}
+		fmt.Printf("%s\t%s\t%d\t%s\t%.03f\t%.03f\t%s\n", cell.family, cell.tableSize, cell.matching, cell.tikvPlan,
+			cell.tikv.Seconds()*1000.0, cell.tiflash.Seconds()*1000.0, faster)
+	}
+	fmt.Println()
+	for i := 0; i < len(cells); {
+		j := i
+		crossover := -1
+		for ; j < len(cells) && cells[j].family == cells[i].family && cells[j].tableSize == cells[i].tableSize; j++ {
+			if cells[j].tiflash < cells[j].tikv {
+				if crossover < 0 {
+					crossover = cells[j].matching
+				}
+			} else {
+				crossover = -1
+			}
+		}
+		if crossover >= 0 {
+			fmt.Printf("%s %s rows: TiFlash is faster from %d matching rows\n", cells[i].family, cells[i].tableSize, crossover)
+		} else {
+			fmt.Printf("%s %s rows: TiKV is faster at the largest measured selectivity\n", cells[i].family, cells[i].tableSize)
+		}
+		i = j
+	}
+}
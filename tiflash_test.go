@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTiFlashVariant(t *testing.T) {
+	cell := withTiFlashVariant(GetTestScenarios(tableSpecs([]int{1000}, "", MatrixOptions{}), []float64{0.1}, MatrixOptions{}, "range"))
+	if len(cell) != 4 {
+		t.Fatalf("expected 4 scenarios, got %d", len(cell))
+	}
+	tiflash := cell[3]
+	if tiflash.Variant != "TiFlash" || tiflash.ExplainOnly || !strings.HasPrefix(tiflash.Query, "SELECT /*+ READ_FROM_STORAGE(TIFLASH[t1K]) */ * FROM t1K WHERE") {
+		t.Fatalf("unexpected TiFlash variant: %+v", tiflash)
+	}
+	join := GetTestScenarios(tableSpecs([]int{1000}, "", MatrixOptions{}), []float64{0.1}, MatrixOptions{}, "join")
+	if got := withTiFlashVariant(join); len(got) != len(join) {
+		t.Fatalf("expected no TiFlash variant for joins, got %+v", got)
+	}
+	plan := &ExecutionPlan{ID: "TableReader_7", Task: "root", Next: &ExecutionPlan{ID: "└─Selection_6", Task: "mpp[tiflash]",
+		Next: &ExecutionPlan{ID: "  └─TableFullScan_5", Task: "mpp[tiflash]"}}}
+	if got := determinePlanType(plan); got != "tiflash_table_scan" {
+		t.Fatalf("expected tiflash_table_scan, got %s", got)
+	}
+}
+
+func TestTiFlashCells(t *testing.T) {
+	result := func(id, planType string, ms int) *TestExecutionResult {
+		return &TestExecutionResult{ScenarioID: id, PlanType: planType, Plan: &ExecutionPlan{ExecutionTime: time.Duration(ms) * time.Millisecond}}
+	}
+	cells := tiflashCells([]*TestExecutionResult{
+		result("range_1M_500000", "index_lookup", 900),
+		result("range_1M_500000", "table_scan", 400),
+		result("range_1M_500000", "tiflash_table_scan", 100),
+		result("range_1M_100", "index_lookup", 2),
+		result("range_1M_100", "tiflash_table_scan", 50),
+		result("range_1K_10", "index_lookup", 1),
+	})
+	if len(cells) != 2 || cells[0].matching != 100 || cells[1].tikvPlan != "table_scan" || cells[1].tiflash != 100*time.Millisecond {
+		t.Fatalf("unexpected TiFlash cells: %+v", cells)
+	}
+}
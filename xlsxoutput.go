@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xlsxSheet is a worksheet of the results workbook: a header row, frozen,
+// and the data rows. Numeric values are written as numbers. If
+// MismatchColumns is set, the rows where the two columns differ are
+// highlighted by conditional formatting.
+type xlsxSheet struct {
+	Name            string
+	Header          []string
+	Rows            [][]string
+	MismatchColumns [2]int
+}
+
+// xlsxAggregatedHeader is the column order of the per table size sheets, the
+// aggregated CSV columns and the fastest measured plan type of the scenario
+var xlsxAggregatedHeader = append(append([]string{}, aggregatedCSVHeader...), "fastest_plan")
+
+// xlsxSheets returns the summary sheet and one sheet of aggregated results
+// per table size, highlighting the scenarios where the optimizer did not
+// choose the fastest plan
+func xlsxSheets(results []*TestExecutionResult, excluded []string) []xlsxSheet {
+	best := make(map[string]string)
+	type sizeSummary struct {
+		cells, mispredicted int
+		regret, maxRegret   float64
+	}
+	summaries := make(map[int]*sizeSummary)
+	for _, cell := range analyzeCells(results, excluded) {
+		best[cell.ScenarioID] = cell.Best
+		s := summaries[cell.RowCount]
+		if s == nil {
+			s = &sizeSummary{}
+			summaries[cell.RowCount] = s
+		}
+		s.cells++
+		if cell.Measured && cell.Chosen != cell.Best {
+			s.mispredicted++
+		}
+		s.regret += cell.Regret()
+		s.maxRegret = max(s.maxRegret, cell.Regret())
+	}
+
+	// Without RU the RU columns are left out, like in the CSV files
+	header, records := xlsxAggregatedHeader, aggregatedCSVRecords(results)
+	for i, record := range records {
+		records[i] = append(record, best[record[0]])
+	}
+	if !ruReported(results) {
+		header, records = withoutRUColumns(header, records)
+	}
+	perSize := make(map[int][][]string)
+	for _, record := range records {
+		rowCount, _ := strconv.Atoi(record[1])
+		perSize[rowCount] = append(perSize[rowCount], record)
+	}
+	sizes := make([]int, 0, len(perSize))
+	for rowCount := range perSize {
+		sizes = append(sizes, rowCount)
+	}
+	sort.Ints(sizes)
+
+	summary := xlsxSheet{
+		Name:   "Summary",
+		Header: []string{"table_size", "cells", "mispredicted", "mispredicted_pct", "regret_avg", "regret_max"},
+	}
+	sheets := []xlsxSheet{{}}
+	for _, rowCount := range sizes {
+		if s := summaries[rowCount]; s != nil {
+			summary.Rows = append(summary.Rows, []string{
+				strconv.Itoa(rowCount),
+				strconv.Itoa(s.cells),
+				strconv.Itoa(s.mispredicted),
+				formatCSVFloat(100 * float64(s.mispredicted) / float64(s.cells)),
+				formatCSVFloat(s.regret / float64(s.cells)),
+				formatCSVFloat(s.maxRegret),
+			})
+		}
+		sheets = append(sheets, xlsxSheet{
+			Name:   formatRowCountName(rowCount) + " rows",
+			Header: header,
+			Rows:   perSize[rowCount],
+			// chosen_plan vs fastest_plan
+			MismatchColumns: [2]int{3, len(header) - 1},
+		})
+	}
+	sheets[0] = summary
+	return sheets
+}
+
+// writeResultsXLSX writes the aggregated results as an Excel workbook, with
+// a summary sheet and one sheet per table size
+func writeResultsXLSX(path string, results []*TestExecutionResult, excluded []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err = writeXLSX(f, xlsxSheets(results, excluded)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeXLSX writes a minimal SpreadsheetML workbook of the sheets
+func writeXLSX(out io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(out)
+	var contentTypes, workbook, workbookRels strings.Builder
+	contentTypes.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	files := make(map[string]string)
+	var order []string
+	for i, sheet := range sheets {
+		n := i + 1
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", n)
+		fmt.Fprintf(&contentTypes, `<Override PartName="/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, name)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		files[name] = sheetXML(sheet)
+		order = append(order, name)
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRels.WriteString(`</Relationships>`)
+	files["[Content_Types].xml"] = contentTypes.String()
+	files["_rels/.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	files["xl/workbook.xml"] = workbook.String()
+	files["xl/_rels/workbook.xml.rels"] = workbookRels.String()
+	// Bold header cells (style 1), and a red fill for the mismatches (differential format 0)
+	files["xl/styles.xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`<dxfs count="1"><dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf></dxfs>` +
+		`</styleSheet>`
+	for _, name := range append([]string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"}, order...) {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write([]byte(files[name])); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// sheetXML returns the worksheet XML of a sheet
+func sheetXML(sheet xlsxSheet) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`)
+	writeRow := func(n int, values []string, style int) {
+		fmt.Fprintf(&sb, `<row r="%d">`, n)
+		for i, v := range values {
+			ref := xlsxColumn(i) + strconv.Itoa(n)
+			if _, err := strconv.ParseFloat(v, 64); err == nil && style == 0 {
+				fmt.Fprintf(&sb, `<c r="%s"><v>%s</v></c>`, ref, v)
+			} else {
+				fmt.Fprintf(&sb, `<c r="%s" t="inlineStr" s="%d"><is><t>%s</t></is></c>`, ref, style, xmlEscape(v))
+			}
+		}
+		sb.WriteString(`</row>`)
+	}
+	writeRow(1, sheet.Header, 1)
+	for i, row := range sheet.Rows {
+		writeRow(i+2, row, 0)
+	}
+	sb.WriteString(`</sheetData>`)
+	if a, b := sheet.MismatchColumns[0], sheet.MismatchColumns[1]; a != b && len(sheet.Rows) > 0 {
+		fmt.Fprintf(&sb, `<conditionalFormatting sqref="A2:%s%d"><cfRule type="expression" dxfId="0" priority="1"><formula>%s</formula></cfRule></conditionalFormatting>`,
+			xlsxColumn(len(sheet.Header)-1), len(sheet.Rows)+1,
+			xmlEscape(fmt.Sprintf(`AND($%s2<>"",$%s2<>"",$%s2<>$%s2)`, xlsxColumn(a), xlsxColumn(b), xlsxColumn(a), xlsxColumn(b))))
+	}
+	sb.WriteString(`</worksheet>`)
+	return sb.String()
+}
+
+// xlsxColumn returns the column letters of a 0-based column index, A to Z, AA...
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXSheets(t *testing.T) {
+	result := func(id, variant, planType string, rows int, ms int) *TestExecutionResult {
+		return &TestExecutionResult{ScenarioID: id, Variant: variant, PlanType: planType, RowCount: rows,
+			ExplainOnly: variant == "ExplainOnly", Plan: &ExecutionPlan{ExecutionTime: time.Duration(ms) * time.Millisecond}}
+	}
+	results := []*TestExecutionResult{
+		result("index_1K_10", "ExplainOnly", "table_scan", 1000, 0),
+		result("index_1K_10", "Index", "index_lookup", 1000, 1),
+		result("index_1K_10", "TableScan", "table_scan", 1000, 3),
+		result("index_10K_10", "ExplainOnly", "index_lookup", 10000, 0),
+		result("index_10K_10", "Index", "index_lookup", 10000, 1),
+		result("index_10K_10", "TableScan", "table_scan", 10000, 9),
+	}
+	sheets := xlsxSheets(results, nil)
+	if len(sheets) != 3 || sheets[0].Name != "Summary" || sheets[1].Name != "1K rows" || sheets[2].Name != "10K rows" {
+		t.Fatalf("unexpected sheets %+v", sheets)
+	}
+	if got := strings.Join(sheets[0].Rows[0], ","); got != "1000,1,1,100.000,3.000,3.000" {
+		t.Fatalf("unexpected summary row %s", got)
+	}
+	if row := sheets[1].Rows[0]; row[3] != "table_scan" || row[len(row)-1] != "index_lookup" {
+		t.Fatalf("unexpected chosen and fastest plan in %v", row)
+	}
+	// The executions reported no RU, the RU columns are left out
+	if slices.Contains(sheets[1].Header, "ru_avg") || len(sheets[1].Header) != len(sheets[1].Rows[0]) {
+		t.Fatalf("unexpected header %v", sheets[1].Header)
+	}
+	results[1].RUReported = true
+	if withRU := xlsxSheets(results, nil); !slices.Equal(withRU[1].Header, xlsxAggregatedHeader) {
+		t.Fatalf("expected the RU columns, got %v", withRU[1].Header)
+	}
+
+	var buf bytes.Buffer
+	if err := writeXLSX(&buf, sheets); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		files[f.Name] = string(data)
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="10K rows" sheetId="3" r:id="rId3"/>`) {
+		t.Fatalf("unexpected workbook %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet2.xml"]
+	for _, expected := range []string{`state="frozen"`, `<c r="B2"><v>1000</v></c>`,
+		`<conditionalFormatting sqref="A2:Q3">`, `AND($D2&lt;&gt;&#34;&#34;,$Q2&lt;&gt;&#34;&#34;,$D2&lt;&gt;$Q2)`} {
+		if !strings.Contains(sheet, expected) {
+			t.Fatalf("expected %s in %s", expected, sheet)
+		}
+	}
+	if xlsxColumn(0) != "A" || xlsxColumn(25) != "Z" || xlsxColumn(26) != "AA" {
+		t.Fatalf("unexpected column names")
+	}
+}